@@ -182,6 +182,30 @@ func (jm *jwtMiddleware) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// Authenticate 校验请求中的JWT令牌，成功时返回携带认证信息的上下文。
+// 用于WebSocket升级等无法直接套用中间件的场景，不检查排除路径。
+func (jm *jwtMiddleware) Authenticate(r *http.Request) (context.Context, error) {
+	token, err := jm.extractToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := jm.parseJwtToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAuthContext(r.Context(), claims), nil
+}
+
+// ValidateToken 校验JWT令牌字符串，用于MQTT等非HTTP协议的认证
+func (jm *jwtMiddleware) ValidateToken(token string) (AuthClaims, error) {
+	if token == "" {
+		return nil, ErrMissingJwtToken
+	}
+	return jm.parseJwtToken(token)
+}
+
 // initializeTokenExtractor 初始化令牌提取器
 func (jm *jwtMiddleware) initializeTokenExtractor() error {
 	// 定义不同位置的令牌提取函数
@@ -171,6 +171,31 @@ func TestTokenExtraction(t *testing.T) {
 	}
 }
 
+// TestAuthenticate 测试在中间件之外直接校验请求
+func TestAuthenticate(t *testing.T) {
+	middleware, _ := NewJwt()
+	tokenPair, _ := middleware.GenerateTokenPair(UserInfo{
+		ID:       11,
+		Username: "testuser",
+	})
+
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+	ctx, err := middleware.Authenticate(req)
+	if err != nil {
+		t.Fatalf("未预期的错误: %v", err)
+	}
+	username, err := GetCurrentUser(ctx)
+	if err != nil || username != "testuser" {
+		t.Errorf("期望用户名 testuser，得到 %q (%v)", username, err)
+	}
+
+	req = httptest.NewRequest("GET", "/ws", nil)
+	if _, err := middleware.Authenticate(req); err == nil {
+		t.Error("预期错误，但没有得到")
+	}
+}
+
 // TestGetSigningMethod 测试获取正确的签名方法
 // 测试确保GetSigningMethod函数能够正确返回对应的JWT签名方法
 func TestGetSigningMethod(t *testing.T) {
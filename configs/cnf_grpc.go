@@ -0,0 +1,16 @@
+package configs
+
+// GrpcConfig gRPC服务配置
+type GrpcConfig struct {
+	Address    string `json:"address"`    // 监听地址
+	Reflection bool   `json:"reflection"` // 是否注册反射服务
+	Auth       string `json:"auth"`       // 认证方式：none 或 jwt
+}
+
+func LoadGrpcConfig() *GrpcConfig {
+	return &GrpcConfig{
+		Address:    EnvString(GrpcAddress, ":9090"),
+		Reflection: EnvBool(GrpcReflection, true),
+		Auth:       EnvString(GrpcAuth, "none"),
+	}
+}
@@ -18,6 +18,39 @@ type MqttConfig struct {
 	CertKeyFile          string        `json:"certKeyFile"`          // 客户端密钥
 	LogLevel             int           `json:"logLevel"`             // 日志级别
 	QueueSize            int           `json:"queueSize"`            // 消息队列大小
+
+	Workers        int           `json:"workers"`        // 订阅处理的工作协程数
+	QueueLength    int           `json:"queueLength"`    // 订阅处理的队列长度
+	Overflow       string        `json:"overflow"`       // 队列已满时的策略：block 或 drop
+	OrderKey       string        `json:"orderKey"`       // 按主题中的命名段顺序处理，例如 deviceKey
+	HandlerTimeout time.Duration `json:"handlerTimeout"` // 消息处理超时，为 0 时不限制
+
+	ProtocolVersion int           `json:"protocolVersion"` // 协议版本：4 为 MQTT 3.1.1，5 为 MQTT 5
+	SessionExpiry   int           `json:"sessionExpiry"`   // MQTT 5 会话过期时间（秒）
+	SharedGroup     string        `json:"sharedGroup"`     // 共享订阅分组，多个实例之间负载均衡
+	CallTimeout     time.Duration `json:"callTimeout"`     // 请求/响应的默认超时
+	ReplyPrefix     string        `json:"replyPrefix"`     // 响应主题前缀，实际的响应主题为 {ReplyPrefix}/{实例ID}
+
+	OfflineStore       string `json:"offlineStore"`       // 离线缓冲存储：memory 或 file，为空时不缓冲
+	OfflinePath        string `json:"offlinePath"`        // 文件存储的目录
+	OfflineMaxMessages int    `json:"offlineMaxMessages"` // 离线缓冲最多缓存的消息数
+	OfflineMaxBytes    int    `json:"offlineMaxBytes"`    // 离线缓冲最多缓存的字节数，为 0 时不限制
+	OfflineOverflow    string `json:"offlineOverflow"`    // 离线缓冲已满时的策略：drop_oldest 或 drop_newest
+}
+
+// MqttBrokerConfig 内嵌broker配置
+type MqttBrokerConfig struct {
+	Address   string `json:"address"`   // TCP监听地址
+	WsAddress string `json:"wsAddress"` // WebSocket监听地址，为空时不启用
+	Auth      string `json:"auth"`      // 认证方式：none 不认证，jwt 以密码作为JWT令牌校验
+}
+
+func LoadMqttBrokerConfig() *MqttBrokerConfig {
+	return &MqttBrokerConfig{
+		Address:   EnvString(MqttBrokerAddress, ":1883"),
+		WsAddress: EnvString(MqttBrokerWsAddress, ""),
+		Auth:      EnvString(MqttBrokerAuth, "none"),
+	}
 }
 
 func LoadMqttConfig() *MqttConfig {
@@ -36,6 +69,21 @@ func LoadMqttConfig() *MqttConfig {
 		CertKeyFile:          EnvString(MqttCertKeyFile, ""),
 		LogLevel:             EnvInt(MqttLogLevel, 0),
 		QueueSize:            EnvInt(MqttQueueSize, 100),
+		Workers:              EnvInt(MqttWorkers, 0),
+		QueueLength:          EnvInt(MqttQueueLength, 1024),
+		Overflow:             EnvString(MqttOverflow, "block"),
+		OrderKey:             EnvString(MqttOrderKey, ""),
+		HandlerTimeout:       EnvDuration(MqttHandlerTimeout, time.Duration(0)),
+		ProtocolVersion:      EnvInt(MqttProtocolVersion, 4),
+		SessionExpiry:        EnvInt(MqttSessionExpiry, 0),
+		SharedGroup:          EnvString(MqttSharedGroup, ""),
+		CallTimeout:          EnvDuration(MqttCallTimeout, "10s"),
+		ReplyPrefix:          EnvString(MqttReplyPrefix, "nexframe/reply"),
+		OfflineStore:         EnvString(MqttOfflineStore, ""),
+		OfflinePath:          EnvString(MqttOfflinePath, "data/mqtt"),
+		OfflineMaxMessages:   EnvInt(MqttOfflineMaxMessages, 10000),
+		OfflineMaxBytes:      EnvInt(MqttOfflineMaxBytes, 0),
+		OfflineOverflow:      EnvString(MqttOfflineOverflow, "drop_oldest"),
 	}
 	return config
 }
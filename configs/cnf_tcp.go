@@ -0,0 +1,35 @@
+package configs
+
+import "time"
+
+// TcpConfig 原始TCP设备接入配置
+type TcpConfig struct {
+	Address      string        `json:"address"`      // 监听地址
+	IdleTimeout  time.Duration `json:"idleTimeout"`  // 心跳超时，为 0 时不限制
+	WriteTimeout time.Duration `json:"writeTimeout"` // 写超时
+}
+
+func LoadTcpConfig() *TcpConfig {
+	return &TcpConfig{
+		Address:      EnvString(TcpAddress, ":9000"),
+		IdleTimeout:  EnvDuration(TcpIdleTimeout, "90s"),
+		WriteTimeout: EnvDuration(TcpWriteTimeout, "10s"),
+	}
+}
+
+// UdpConfig 原始UDP设备接入配置
+type UdpConfig struct {
+	Address     string        `json:"address"`     // 监听地址
+	IdleTimeout time.Duration `json:"idleTimeout"` // 会话超时，超过该时间没有收到数据报时移除会话
+	Workers     int           `json:"workers"`     // 处理数据报的工作协程数，为 0 时使用 CPU 核数
+	QueueLength int           `json:"queueLength"` // 每个工作协程的队列长度
+}
+
+func LoadUdpConfig() *UdpConfig {
+	return &UdpConfig{
+		Address:     EnvString(UdpAddress, ":9001"),
+		IdleTimeout: EnvDuration(UdpIdleTimeout, "90s"),
+		Workers:     EnvInt(UdpWorkers, 0),
+		QueueLength: EnvInt(UdpQueueLength, 256),
+	}
+}
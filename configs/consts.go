@@ -122,4 +122,52 @@ const (
 	MqttCertKeyFile          = "mqtt.cert_key_file"
 	MqttLogLevel             = "mqtt.log_level"
 	MqttQueueSize            = "mqtt.queue_size"
+
+	MqttWorkers        = "mqtt.workers"         // 订阅处理的工作协程数
+	MqttQueueLength    = "mqtt.queue_length"    // 订阅处理的队列长度
+	MqttOverflow       = "mqtt.overflow"        // 队列已满时的策略：block 或 drop
+	MqttOrderKey       = "mqtt.order_key"       // 按主题中的命名段顺序处理，例如 deviceKey
+	MqttHandlerTimeout = "mqtt.handler_timeout" // 消息处理超时
+
+	MqttProtocolVersion = "mqtt.protocol_version" // 协议版本：4 为 MQTT 3.1.1，5 为 MQTT 5
+	MqttSessionExpiry   = "mqtt.session_expiry"   // MQTT 5 会话过期时间（秒）
+	MqttSharedGroup     = "mqtt.shared_group"     // 共享订阅分组，设置后以 $share/{group}/{topic} 订阅
+	MqttCallTimeout     = "mqtt.call_timeout"     // 请求/响应的默认超时
+	MqttReplyPrefix     = "mqtt.reply_prefix"     // 响应主题前缀
+
+	MqttOfflineStore       = "mqtt.offline_store"        // 离线缓冲存储：memory 或 file，为空时不缓冲
+	MqttOfflinePath        = "mqtt.offline_path"         // 文件存储的目录
+	MqttOfflineMaxMessages = "mqtt.offline_max_messages" // 离线缓冲最多缓存的消息数
+	MqttOfflineMaxBytes    = "mqtt.offline_max_bytes"    // 离线缓冲最多缓存的字节数
+	MqttOfflineOverflow    = "mqtt.offline_overflow"     // 离线缓冲已满时的策略：drop_oldest 或 drop_newest
+)
+
+// 内嵌mqtt broker配置
+const (
+	MqttBrokerAddress   = "mqtt.broker.address"    // TCP监听地址
+	MqttBrokerWsAddress = "mqtt.broker.ws_address" // WebSocket监听地址，为空时不启用
+	MqttBrokerAuth      = "mqtt.broker.auth"       // 认证方式：none 或 jwt
+)
+
+// tcp/udp设备接入配置
+const (
+	TcpAddress      = "tcp.address"
+	TcpIdleTimeout  = "tcp.idle_timeout"
+	TcpWriteTimeout = "tcp.write_timeout"
+	UdpAddress      = "udp.address"
+	UdpIdleTimeout  = "udp.idle_timeout"
+	UdpWorkers      = "udp.workers"
+	UdpQueueLength  = "udp.queue_length"
+)
+
+// grpc服务配置
+const (
+	GrpcAddress    = "grpc.address"    // 监听地址
+	GrpcReflection = "grpc.reflection" // 是否注册反射服务
+	GrpcAuth       = "grpc.auth"       // 认证方式：none 或 jwt
+)
+
+// 应用启动配置
+const (
+	AppServers = "app.servers" // 需要启动的服务，逗号分隔，命令行参数 -servers 优先
 )
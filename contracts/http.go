@@ -1,5 +1,10 @@
 package contracts
 
+import (
+	"context"
+	"net/http"
+)
+
 const (
 	supportedHttpMethods = "GET,PUT,POST,DELETE,PATCH,HEAD,CONNECT,OPTIONS,TRACE"
 	defaultMethod        = "ALL"
@@ -7,6 +12,11 @@ const (
 	DomainInfoCode       = "DomainInfoCode"
 )
 
+// AuthFunc 在 WebSocket 协议升级前执行的认证函数，返回错误时拒绝连接。
+// 返回的上下文中携带的值（例如 auth.NewAuthContext 写入的认证信息）会附加到连接上，
+// auth.NewJwt() 返回的中间件的 Authenticate 方法可直接使用
+type AuthFunc func(r *http.Request) (context.Context, error)
+
 type DomainInfo struct {
 	FullDomain  string
 	SubDomain   string
@@ -42,6 +42,8 @@ func ResponseFailed(err error) Response {
 }
 
 type Payload struct {
+	Id     string                 `json:"id,omitempty"`   // 消息ID，响应中会原样返回，用于请求与响应的关联
+	Type   string                 `json:"type,omitempty"` // 消息类型，为空表示普通请求
 	Route  string                 `json:"route"`
 	Params map[string]interface{} `json:"params"`
 }
@@ -37,6 +37,36 @@ func JSONResp(w http.ResponseWriter, code int, message string, data ...interface
 	json.NewEncoder(w).Encode(jsonRes)
 }
 
+// JSONStatusResp 返回标准JSON数据并设置HTTP状态码，成功（200）时 code 为 0，失败时 code 与状态码相同，
+// data 为 nil 时返回空对象。
+func JSONStatusResp(w http.ResponseWriter, status int, message string, data interface{}) {
+	code := 0
+	if status != http.StatusOK {
+		code = status
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(JsonRes{Code: code, Message: message, Data: data})
+}
+
+// Authorized 在调用 next 之前执行 authorize，返回错误时以 JSONStatusResp 响应 401。
+// authorize 为 nil 时不鉴权，直接返回 next。
+func Authorized(authorize func(r *http.Request) error, next http.Handler) http.Handler {
+	if authorize == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authorize(r); err != nil {
+			JSONStatusResp(w, http.StatusUnauthorized, err.Error(), nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // JsonExit 返回标准JSON数据并退出当前HTTP执行函数。
 func JsonExit(w http.ResponseWriter, code int, message string, data ...interface{}) {
 	JSONResp(w, code, message, data...)
@@ -1,115 +1,224 @@
 package database
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"runtime"
 	"sync"
 	"time"
 )
 
-// Aggregator 聚合器结构体
-type Aggregator struct {
-	option          AggregatorOption
-	wg              sync.WaitGroup
-	quit            chan struct{}
-	eventQueue      chan interface{}
-	batchProcessor  BatchProcessFunc
-	pool            *sync.Pool
-	lingerTimer     *time.Timer
-	lastProcessTime time.Time
+var (
+	ErrAggregatorStopping = errors.New("aggregator is stopping")
+)
+
+// Aggregator 聚合器结构体，将逐个入队的项目按批次交给 BatchProcessFunc。
+// 启用预写日志（WithWAL）后，入队的项目在处理完成前都保存在磁盘上，
+// Stop 或进程退出时尚未处理的项目会在下次创建聚合器时恢复，即至少处理一次
+type Aggregator[T any] struct {
+	option         AggregatorOption[T]
+	wg             sync.WaitGroup
+	quit           chan struct{}
+	stopOnce       sync.Once
+	eventQueue     chan entry[T]
+	batchProcessor BatchProcessFunc[T]
+	wal            *wal
+	recovered      []entry[T]
+}
+
+// entry 队列中的项目及其在预写日志中的位置
+type entry[T any] struct {
+	ref  walRef
+	item T
 }
 
-// AggregatorOption 聚合器选项
-type AggregatorOption struct {
+// AggregatorOption 聚合器选项，T 为聚合器的项目类型
+type AggregatorOption[T any] struct {
+	Name              string // 用于日志和指标
 	BatchSize         int
 	Workers           int
 	ChannelBufferSize int
 	LingerTime        time.Duration
-	ErrorHandler      ErrorHandlerFunc
+	MaxRetries        int           // 批次失败后的重试次数
+	RetryBackoff      time.Duration // 第一次重试的等待时间，之后每次翻倍
+	MaxRetryBackoff   time.Duration
+	WALDir            string // 为空时不启用预写日志
+	WALSegmentSize    int64
+	WALSyncInterval   time.Duration
+	Metrics           *AggregatorMetrics
+	ErrorHandler      ErrorHandlerFunc[T] // 批次重试后仍然失败时调用
+	DeadLetter        DeadLetterStore[T]  // 保存重试后仍然失败的批次
 	Logger            *log.Logger
 }
 
 // BatchProcessFunc 批处理函数类型
-type BatchProcessFunc func([]interface{}) error
-
-// SetAggregatorOptionFunc 聚合器选项设置函数类型
-type SetAggregatorOptionFunc func(option *AggregatorOption)
-
-// ErrorHandlerFunc 错误处理函数类型
-type ErrorHandlerFunc func(err error, items []interface{}, batchProcessFunc BatchProcessFunc, aggregator *Aggregator)
-
-// NewAggregator 创建新的聚合器实例
-func NewAggregator(batchProcessor BatchProcessFunc, optionFuncs ...SetAggregatorOptionFunc) (*Aggregator, error) {
-	option := AggregatorOption{
-		BatchSize:  8,
-		Workers:    runtime.NumCPU(),
-		LingerTime: 1 * time.Minute,
+type BatchProcessFunc[T any] func([]T) error
+
+// SetAggregatorOptionFunc 聚合器选项设置函数类型，T 必须与聚合器的项目类型一致，例如 WithBatchSize[Order](100)
+type SetAggregatorOptionFunc[T any] func(option *AggregatorOption[T])
+
+// ErrorHandlerFunc 错误处理函数类型，在批次重试后仍然失败时调用
+type ErrorHandlerFunc[T any] func(err error, items []T, batchProcessFunc BatchProcessFunc[T], aggregator *Aggregator[T])
+
+// NewAggregator 创建新的聚合器实例。启用预写日志时恢复上次未处理完的项目，
+// 它们在 Start 后先于新入队的项目处理；项目使用JSON编码，T 为接口类型时恢复后得到的是JSON解码的值
+func NewAggregator[T any](batchProcessor BatchProcessFunc[T], optionFuncs ...SetAggregatorOptionFunc[T]) (*Aggregator[T], error) {
+	option := AggregatorOption[T]{
+		Name:            "default",
+		BatchSize:       8,
+		Workers:         runtime.NumCPU(),
+		LingerTime:      1 * time.Minute,
+		MaxRetries:      3,
+		RetryBackoff:    100 * time.Millisecond,
+		MaxRetryBackoff: 10 * time.Second,
+		WALSegmentSize:  16 << 20,
 	}
 
 	for _, optionFunc := range optionFuncs {
 		optionFunc(&option)
 	}
 
+	if option.BatchSize < 1 {
+		option.BatchSize = 1
+	}
+	if option.Workers < 1 {
+		option.Workers = 1
+	}
 	if option.ChannelBufferSize < option.Workers {
 		option.ChannelBufferSize = option.Workers
 	}
 
-	pool := &sync.Pool{
-		New: func() interface{} {
-			return make([]interface{}, 0, option.BatchSize)
-		},
+	aggregator := &Aggregator[T]{
+		eventQueue:     make(chan entry[T], option.ChannelBufferSize),
+		option:         option,
+		quit:           make(chan struct{}),
+		batchProcessor: batchProcessor,
+	}
+	if option.WALDir != "" {
+		w, records, err := openWAL(option.WALDir, option.WALSegmentSize, option.WALSyncInterval)
+		if err != nil {
+			return nil, fmt.Errorf("open aggregator wal: %w", err)
+		}
+		aggregator.wal = w
+		var invalid []walRef
+		for _, r := range records {
+			var item T
+			if err = json.Unmarshal(r.data, &item); err != nil {
+				aggregator.logf("Aggregator: 无法恢复项目 %d: %v", r.seq, err)
+				invalid = append(invalid, r.walRef)
+				continue
+			}
+			aggregator.recovered = append(aggregator.recovered, entry[T]{ref: r.walRef, item: item})
+		}
+		if err = w.ack(invalid); err != nil {
+			_ = w.close()
+			return nil, err
+		}
+		if len(aggregator.recovered) > 0 {
+			aggregator.logf("Aggregator: 从预写日志恢复了%d个项目", len(aggregator.recovered))
+		}
 	}
 
-	lingerTimer := time.NewTimer(option.LingerTime)
+	return aggregator, nil
+}
 
-	aggregator := &Aggregator{
-		eventQueue:      make(chan interface{}, option.ChannelBufferSize),
-		option:          option,
-		quit:            make(chan struct{}),
-		batchProcessor:  batchProcessor,
-		pool:            pool,
-		lingerTimer:     lingerTimer,
-		lastProcessTime: time.Now(),
+func (agt *Aggregator[T]) logf(format string, v ...interface{}) {
+	if agt.option.Logger != nil {
+		agt.option.Logger.Printf(format, v...)
 	}
+}
 
-	return aggregator, nil
+// Recovered 返回启动时从预写日志恢复的项目数
+func (agt *Aggregator[T]) Recovered() int {
+	return len(agt.recovered)
+}
+
+// Len 返回队列中等待处理的项目数
+func (agt *Aggregator[T]) Len() int {
+	return len(agt.eventQueue)
+}
+
+func (agt *Aggregator[T]) stopping() bool {
+	select {
+	case <-agt.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// persist 将项目写入预写日志，未启用时返回空位置
+func (agt *Aggregator[T]) persist(item T) (walRef, error) {
+	if agt.wal == nil {
+		return walRef{}, nil
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return walRef{}, err
+	}
+	return agt.wal.append(data)
+}
+
+// discard 撤销已写入预写日志但没有进入队列的项目
+func (agt *Aggregator[T]) discard(ref walRef) {
+	if agt.wal != nil {
+		_ = agt.wal.ack([]walRef{ref})
+	}
 }
 
 // TryEnqueue 尝试入队一个项目，非阻塞
-func (agt *Aggregator) TryEnqueue(item interface{}) bool {
+func (agt *Aggregator[T]) TryEnqueue(item T) bool {
+	if agt.stopping() {
+		return false
+	}
+	ref, err := agt.persist(item)
+	if err != nil {
+		agt.logf("Aggregator: 写入预写日志失败: %v", err)
+		return false
+	}
+	e := entry[T]{ref: ref, item: item}
 	select {
-	case agt.eventQueue <- item:
+	case agt.eventQueue <- e:
+		agt.option.Metrics.setDepth(agt.option.Name, len(agt.eventQueue))
 		return true
 	default:
-		if agt.option.Logger != nil {
-			agt.option.Logger.Println("Aggregator: 事件队列已满，尝试重新安排")
-		}
+		agt.logf("Aggregator: 事件队列已满，尝试重新安排")
 		runtime.Gosched() // 让出CPU时间片
 		select {
-		case agt.eventQueue <- item:
+		case agt.eventQueue <- e:
+			agt.option.Metrics.setDepth(agt.option.Name, len(agt.eventQueue))
 			return true
 		default:
-			if agt.option.Logger != nil {
-				agt.option.Logger.Printf("Aggregator: 事件队列仍然已满，并且跳过了 %+v \n", item)
-			}
+			agt.logf("Aggregator: 事件队列仍然已满，并且跳过了 %+v \n", item)
+			agt.discard(ref)
 			return false
 		}
 	}
 }
 
-// Enqueue 入队一个项目，会阻塞直到有空间
-func (agt *Aggregator) Enqueue(item interface{}) error {
+// Enqueue 入队一个项目，会阻塞直到有空间。启用预写日志时返回 nil 表示项目已写入日志
+func (agt *Aggregator[T]) Enqueue(item T) error {
+	if agt.stopping() {
+		return ErrAggregatorStopping
+	}
+	ref, err := agt.persist(item)
+	if err != nil {
+		return err
+	}
 	select {
-	case agt.eventQueue <- item:
+	case agt.eventQueue <- entry[T]{ref: ref, item: item}:
+		agt.option.Metrics.setDepth(agt.option.Name, len(agt.eventQueue))
 		return nil
 	case <-agt.quit:
-		return errors.New("aggregator is stopping")
+		agt.discard(ref)
+		return ErrAggregatorStopping
 	}
 }
 
 // EnqueueWithRetry 入队一个项目，会重试直到成功或者达到最大重试次数
-func (agt *Aggregator) EnqueueWithRetry(item interface{}, maxRetries int, backoff time.Duration) bool {
+func (agt *Aggregator[T]) EnqueueWithRetry(item T, maxRetries int, backoff time.Duration) bool {
 	for i := 0; i < maxRetries; i++ {
 		if err := agt.Enqueue(item); err == nil {
 			return true // 入队成功
@@ -121,118 +230,275 @@ func (agt *Aggregator) EnqueueWithRetry(item interface{}, maxRetries int, backof
 }
 
 // Start 启动聚合器
-func (agt *Aggregator) Start() {
+func (agt *Aggregator[T]) Start() {
+	if len(agt.recovered) > 0 {
+		agt.wg.Add(1)
+		go agt.feed(agt.recovered)
+	}
 	agt.wg.Add(agt.option.Workers)
 	for i := 0; i < agt.option.Workers; i++ {
 		go agt.work()
 	}
 }
 
-// Stop 停止聚合器
-func (agt *Aggregator) Stop() {
-	close(agt.quit)
-	agt.wg.Wait()
+// feed 将恢复的项目放入队列，停止时未放入的项目仍保留在预写日志中
+func (agt *Aggregator[T]) feed(entries []entry[T]) {
+	defer agt.wg.Done()
+	for _, e := range entries {
+		select {
+		case agt.eventQueue <- e:
+		case <-agt.quit:
+			return
+		}
+	}
 }
 
-// SafeStop 安全停止聚合器，确保所有项目都被处理
-func (agt *Aggregator) SafeStop() {
-	close(agt.quit)
-	agt.wg.Wait() // 等待所有工作协程退出
+// Stop 停止聚合器，处理各工作协程中已取出的批次。
+// 队列中剩余的项目在启用预写日志时下次启动恢复，否则丢弃
+func (agt *Aggregator[T]) Stop() {
+	agt.stopOnce.Do(func() {
+		close(agt.quit)
+		agt.wg.Wait()
+		agt.closeWAL()
+	})
+}
 
-	// 等待最后一个计时器完成
-	agt.lingerTimer.Stop()
-	<-agt.lingerTimer.C
+// SafeStop 安全停止聚合器，确保队列中所有项目都被处理
+func (agt *Aggregator[T]) SafeStop() {
+	agt.stopOnce.Do(func() {
+		close(agt.quit)
+		agt.wg.Wait() // 等待所有工作协程退出
+
+		// 处理剩余的事件
+		for {
+			batch := agt.getBatchFromQueue()
+			if len(batch) == 0 {
+				break
+			}
+			agt.processBatch(batch, FlushStop)
+		}
+		agt.closeWAL()
+	})
+}
 
-	// 处理剩余的事件
-	agt.processBatch(agt.getBatchFromQueue())
+func (agt *Aggregator[T]) closeWAL() {
+	if agt.wal == nil {
+		return
+	}
+	if err := agt.wal.close(); err != nil {
+		agt.logf("Aggregator: 关闭预写日志失败: %v", err)
+	}
 }
 
-func (agt *Aggregator) work() {
+func (agt *Aggregator[T]) work() {
 	defer agt.wg.Done()
 
-	batch := agt.pool.Get().([]interface{})
-	defer agt.pool.Put(batch[:0])
+	batch := make([]entry[T], 0, agt.option.BatchSize)
+	linger := time.NewTimer(agt.option.LingerTime)
+	linger.Stop()
+	defer linger.Stop()
+	var lingerC <-chan time.Time // 批次为空时不计时
 
 	for {
 		select {
-		case item := <-agt.eventQueue:
-			batch = append(batch, item)
-			if len(batch) == cap(batch) || time.Since(agt.lastProcessTime) >= agt.option.LingerTime {
-				agt.processBatch(batch)
-				batch = batch[:0]                // 清空切片
-				agt.lastProcessTime = time.Now() // 更新最近处理时间
+		case e := <-agt.eventQueue:
+			agt.option.Metrics.setDepth(agt.option.Name, len(agt.eventQueue))
+			batch = append(batch, e)
+			if len(batch) == 1 {
+				linger.Reset(agt.option.LingerTime)
+				lingerC = linger.C
+			}
+			if len(batch) >= agt.option.BatchSize {
+				linger.Stop()
+				lingerC = nil
+				agt.processBatch(batch, FlushSize)
+				batch = batch[:0] // 清空切片
 			}
+		case <-lingerC:
+			lingerC = nil
+			agt.processBatch(batch, FlushLinger)
+			batch = batch[:0]
 		case <-agt.quit:
 			if len(batch) > 0 {
-				agt.processBatch(batch)
+				agt.processBatch(batch, FlushStop)
 			}
 			return // 退出工作协程
 		}
 	}
 }
 
-func (agt *Aggregator) processBatch(items []interface{}) {
-	defer agt.wg.Add(1)
-	defer agt.wg.Done()
-	if err := agt.batchProcessor(items); err != nil {
-		if agt.option.Logger != nil {
-			agt.option.Logger.Println("Aggregator: 处理批次时发生错误")
+// processBatch 处理一个批次，失败时按退避重试，仍然失败则交给错误处理函数和死信存储。
+// 批次成功或保存到死信后才从预写日志中确认
+func (agt *Aggregator[T]) processBatch(batch []entry[T], reason string) {
+	items := make([]T, len(batch))
+	refs := make([]walRef, len(batch))
+	for i, e := range batch {
+		items[i], refs[i] = e.item, e.ref
+	}
+
+	start := time.Now()
+	aborted, err := agt.processWithRetry(items)
+	agt.option.Metrics.observeBatch(agt.option.Name, reason, time.Since(start), err)
+	if err == nil {
+		agt.logf("Aggregator: 成功处理了%d个项目。\n", len(items))
+		agt.ack(refs)
+		return
+	}
+
+	agt.logf("Aggregator: 处理批次时发生错误: %v", err)
+	if aborted && agt.wal != nil {
+		// 停止时不再等待重试，批次保留在预写日志中，下次启动时处理
+		return
+	}
+	if agt.option.ErrorHandler != nil {
+		agt.option.ErrorHandler(err, items, agt.batchProcessor, agt)
+	}
+	if agt.option.DeadLetter != nil {
+		if storeErr := agt.option.DeadLetter.Store(items, err); storeErr != nil {
+			agt.logf("Aggregator: 保存死信失败: %v", storeErr)
+			return
 		}
-		if agt.option.ErrorHandler != nil {
-			agt.option.ErrorHandler(err, items, agt.batchProcessor, agt)
+		agt.option.Metrics.addDeadLetter(agt.option.Name, len(items))
+	}
+	agt.ack(refs)
+}
+
+// processWithRetry 调用批处理函数，失败时重试。停止期间不再等待重试，aborted 为 true
+func (agt *Aggregator[T]) processWithRetry(items []T) (aborted bool, err error) {
+	backoff := agt.option.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		if err = agt.batchProcessor(items); err == nil || attempt >= agt.option.MaxRetries {
+			return false, err
+		}
+		if agt.stopping() {
+			return true, err
+		}
+		agt.option.Metrics.incRetry(agt.option.Name)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-agt.quit:
+			timer.Stop()
+			return true, err
+		}
+		if backoff *= 2; backoff > agt.option.MaxRetryBackoff {
+			backoff = agt.option.MaxRetryBackoff
 		}
-		// 记录错误信息，例如将错误事件保存到其他地方
-	} else if agt.option.Logger != nil {
-		agt.option.Logger.Printf("Aggregator: 成功处理了%d个项目。\n", len(items))
+	}
+}
+
+func (agt *Aggregator[T]) ack(refs []walRef) {
+	if agt.wal == nil {
+		return
+	}
+	if err := agt.wal.ack(refs); err != nil {
+		agt.logf("Aggregator: 确认预写日志失败: %v", err)
 	}
 }
 
 // 示例: 设置聚合器选项的函数
-func WithBatchSize(batchSize int) SetAggregatorOptionFunc {
-	return func(option *AggregatorOption) {
+func WithBatchSize[T any](batchSize int) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
 		option.BatchSize = batchSize
 	}
 }
 
-func WithWorkers(workers int) SetAggregatorOptionFunc {
-	return func(option *AggregatorOption) {
+func WithWorkers[T any](workers int) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
 		option.Workers = workers
 	}
 }
 
-func WithChannelBufferSize(size int) SetAggregatorOptionFunc {
-	return func(option *AggregatorOption) {
+func WithChannelBufferSize[T any](size int) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
 		option.ChannelBufferSize = size
 	}
 }
 
-func WithLingerTime(duration time.Duration) SetAggregatorOptionFunc {
-	return func(option *AggregatorOption) {
+func WithLingerTime[T any](duration time.Duration) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
 		option.LingerTime = duration
 	}
 }
 
-func WithLogger(logger *log.Logger) SetAggregatorOptionFunc {
-	return func(option *AggregatorOption) {
+func WithLogger[T any](logger *log.Logger) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
 		option.Logger = logger
 	}
 }
 
-func WithErrorHandler(handler ErrorHandlerFunc) SetAggregatorOptionFunc {
-	return func(option *AggregatorOption) {
+// WithErrorHandler 设置错误处理函数
+func WithErrorHandler[T any](handler ErrorHandlerFunc[T]) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
 		option.ErrorHandler = handler
 	}
 }
 
-// getBatchFromQueue 从事件队列中获取一个批处理
-func (agt *Aggregator) getBatchFromQueue() []interface{} {
-	batch := make([]interface{}, 0, agt.option.BatchSize)
-	for {
+// WithName 设置聚合器名称，用于日志和指标的 aggregator 标签
+func WithName[T any](name string) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
+		if name != "" {
+			option.Name = name
+		}
+	}
+}
+
+// WithRetry 设置批次失败后的重试次数和退避时间，backoff 为第一次重试的等待时间，之后每次翻倍，不超过 maxBackoff。
+// 默认重试 3 次，从 100 毫秒开始，最长 10 秒；maxRetries 为 0 时不重试
+func WithRetry[T any](maxRetries int, backoff, maxBackoff time.Duration) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
+		if maxRetries >= 0 {
+			option.MaxRetries = maxRetries
+		}
+		option.RetryBackoff, option.MaxRetryBackoff = backoff, maxBackoff
+		if option.MaxRetryBackoff < option.RetryBackoff {
+			option.MaxRetryBackoff = option.RetryBackoff
+		}
+	}
+}
+
+// WithDeadLetter 设置死信存储，保存重试后仍然失败的批次
+func WithDeadLetter[T any](store DeadLetterStore[T]) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
+		option.DeadLetter = store
+	}
+}
+
+// WithWAL 启用预写日志，dir 为日志目录，同一目录只能由一个聚合器使用。
+// syncInterval 大于 0 时定期同步到磁盘，否则依赖操作系统写回，只能应对进程退出而非断电
+func WithWAL[T any](dir string, syncInterval time.Duration) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
+		option.WALDir = dir
+		option.WALSyncInterval = syncInterval
+	}
+}
+
+// WithWALSegmentSize 设置预写日志的分段大小，默认 16MB
+func WithWALSegmentSize[T any](size int64) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
+		if size > 0 {
+			option.WALSegmentSize = size
+		}
+	}
+}
+
+// WithMetrics 设置 Prometheus 指标
+func WithMetrics[T any](metrics *AggregatorMetrics) SetAggregatorOptionFunc[T] {
+	return func(option *AggregatorOption[T]) {
+		option.Metrics = metrics
+	}
+}
+
+// getBatchFromQueue 从事件队列中取出一个批处理，不等待新的项目
+func (agt *Aggregator[T]) getBatchFromQueue() []entry[T] {
+	batch := make([]entry[T], 0, agt.option.BatchSize)
+	for len(batch) < agt.option.BatchSize {
 		select {
-		case item := <-agt.eventQueue:
-			batch = append(batch, item)
-		case <-time.After(agt.option.LingerTime):
+		case e := <-agt.eventQueue:
+			batch = append(batch, e)
+		default:
 			return batch
 		}
 	}
+	return batch
 }
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetterStore 保存重试后仍然失败的批次。
+// Store 返回错误时批次不会从预写日志中确认，重启后会再次处理
+type DeadLetterStore[T any] interface {
+	Store(items []T, err error) error
+}
+
+// DeadLetter 死信文件中的一条记录
+type DeadLetter[T any] struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+	Items []T       `json:"items"`
+}
+
+// FileDeadLetter 将失败的批次追加到文件，每行一个JSON编码的 DeadLetter
+type FileDeadLetter[T any] struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewFileDeadLetter 打开或创建死信文件
+func NewFileDeadLetter[T any](path string) (*FileDeadLetter[T], error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileDeadLetter[T]{path: path, file: f}, nil
+}
+
+// Store 追加一个失败的批次并同步到磁盘
+func (d *FileDeadLetter[T]) Store(items []T, err error) error {
+	data, e := json.Marshal(DeadLetter[T]{Time: time.Now(), Error: err.Error(), Items: items})
+	if e != nil {
+		return e
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, e = d.file.Write(append(data, '\n')); e != nil {
+		return e
+	}
+	return d.file.Sync()
+}
+
+// Load 读取文件中所有的死信，用于人工处理或重新入队
+func (d *FileDeadLetter[T]) Load() ([]DeadLetter[T], error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, err := os.Open(d.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var letters []DeadLetter[T]
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var letter DeadLetter[T]
+		if err = json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return letters, err
+		}
+		letters = append(letters, letter)
+	}
+	return letters, scanner.Err()
+}
+
+// Close 关闭死信文件
+func (d *FileDeadLetter[T]) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.file.Close()
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 批次触发处理的原因
+const (
+	FlushSize   = "size"   // 达到批次大小
+	FlushLinger = "linger" // 达到等待时间
+	FlushStop   = "stop"   // 聚合器停止
+)
+
+// AggregatorMetrics 聚合器的 Prometheus 指标，多个聚合器通过 WithName 区分
+type AggregatorMetrics struct {
+	depth      *prometheus.GaugeVec
+	duration   *prometheus.HistogramVec
+	flushes    *prometheus.CounterVec
+	retries    *prometheus.CounterVec
+	deadLetter *prometheus.CounterVec
+}
+
+// NewAggregatorMetrics 创建并注册指标，reg 为空时使用 prometheus.DefaultRegisterer。
+// 指标包括：
+//   - nexframe_aggregator_queue_depth{aggregator} 队列中等待的项目数
+//   - nexframe_aggregator_batch_duration_seconds{aggregator,status} 批次处理耗时，包括重试
+//   - nexframe_aggregator_flushes_total{aggregator,reason} 批次处理次数及触发原因
+//   - nexframe_aggregator_batch_retries_total{aggregator} 批次重试次数
+//   - nexframe_aggregator_dead_letter_items_total{aggregator} 进入死信的项目数
+func NewAggregatorMetrics(reg prometheus.Registerer) (*AggregatorMetrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	m := &AggregatorMetrics{
+		depth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "nexframe",
+			Subsystem: "aggregator",
+			Name:      "queue_depth",
+			Help:      "Number of items waiting in the aggregator queue.",
+		}, []string{"aggregator"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "nexframe",
+			Subsystem: "aggregator",
+			Name:      "batch_duration_seconds",
+			Help:      "Duration of batch processing including retries.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"aggregator", "status"}),
+		flushes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nexframe",
+			Subsystem: "aggregator",
+			Name:      "flushes_total",
+			Help:      "Number of batches processed by flush reason.",
+		}, []string{"aggregator", "reason"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nexframe",
+			Subsystem: "aggregator",
+			Name:      "batch_retries_total",
+			Help:      "Number of batch retries.",
+		}, []string{"aggregator"}),
+		deadLetter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nexframe",
+			Subsystem: "aggregator",
+			Name:      "dead_letter_items_total",
+			Help:      "Number of items moved to the dead letter store.",
+		}, []string{"aggregator"}),
+	}
+	for _, c := range []prometheus.Collector{m.depth, m.duration, m.flushes, m.retries, m.deadLetter} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *AggregatorMetrics) setDepth(name string, depth int) {
+	if m != nil {
+		m.depth.WithLabelValues(name).Set(float64(depth))
+	}
+}
+
+func (m *AggregatorMetrics) observeBatch(name, reason string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	status := "success"
+	if err != nil {
+		status = "failed"
+	}
+	m.flushes.WithLabelValues(name, reason).Inc()
+	m.duration.WithLabelValues(name, status).Observe(d.Seconds())
+}
+
+func (m *AggregatorMetrics) incRetry(name string) {
+	if m != nil {
+		m.retries.WithLabelValues(name).Inc()
+	}
+}
+
+func (m *AggregatorMetrics) addDeadLetter(name string, n int) {
+	if m != nil {
+		m.deadLetter.WithLabelValues(name).Add(float64(n))
+	}
+}
@@ -1,10 +1,16 @@
 package database
 
 import (
+	"errors"
 	"log"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 // mockBatchProcessFunc 是一个模拟的批处理函数，用于测试
@@ -25,11 +31,11 @@ func TestAggregator(t *testing.T) {
 	// 创建聚合器实例
 	aggregator, _ := NewAggregator(
 		mockBatchProcessFunc,
-		WithBatchSize(batchSize),
-		WithWorkers(workers),
-		WithChannelBufferSize(channelBufferSize),
-		WithLingerTime(lingerTime),
-		WithLogger(logger),
+		WithBatchSize[interface{}](batchSize),
+		WithWorkers[interface{}](workers),
+		WithChannelBufferSize[interface{}](channelBufferSize),
+		WithLingerTime[interface{}](lingerTime),
+		WithLogger[interface{}](logger),
 	)
 
 	// 开始聚合器
@@ -50,3 +56,174 @@ func TestAggregator(t *testing.T) {
 	aggregator.SafeStop()
 
 }
+
+type record struct {
+	ID int `json:"id"`
+}
+
+// collector 收集处理过的项目，failures 为处理失败的剩余次数
+type collector struct {
+	mu       sync.Mutex
+	items    []record
+	calls    int
+	failures int
+}
+
+func (c *collector) process(items []record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.failures != 0 {
+		c.failures--
+		return errors.New("database unavailable")
+	}
+	c.items = append(c.items, items...)
+	return nil
+}
+
+func (c *collector) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func TestAggregatorLinger(t *testing.T) {
+	c := &collector{}
+	aggregator, err := NewAggregator(c.process, WithBatchSize[record](100), WithWorkers[record](1), WithLingerTime[record](20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aggregator.Start()
+	defer aggregator.Stop()
+
+	// 批次未满时等待时间到达后处理，不需要新的项目触发
+	for i := 0; i < 3; i++ {
+		if err = aggregator.Enqueue(record{ID: i}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for c.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if c.count() != 3 {
+		t.Errorf("Expected 3 items after linger, got %d", c.count())
+	}
+}
+
+func TestAggregatorRetryAndDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	reg := prometheus.NewRegistry()
+	metrics, err := NewAggregatorMetrics(reg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	dead, err := NewFileDeadLetter[record](filepath.Join(dir, "dead.jsonl"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer dead.Close()
+
+	var handled []record
+	c := &collector{failures: 2}
+	aggregator, err := NewAggregator(c.process,
+		WithName[record]("orders"),
+		WithBatchSize[record](2),
+		WithWorkers[record](1),
+		WithRetry[record](1, time.Millisecond, time.Millisecond),
+		WithDeadLetter[record](dead),
+		WithErrorHandler(func(err error, items []record, _ BatchProcessFunc[record], _ *Aggregator[record]) {
+			handled = append(handled, items...)
+		}),
+		WithMetrics[record](metrics),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	aggregator.Start()
+	for i := 0; i < 4; i++ {
+		if err = aggregator.Enqueue(record{ID: i}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	aggregator.SafeStop()
+
+	// 第一个批次重试一次后仍然失败，进入死信；第二个批次成功
+	if c.calls != 3 || c.count() != 2 {
+		t.Errorf("Expected 3 calls and 2 processed items, got %d and %d", c.calls, c.count())
+	}
+	letters, err := dead.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(letters) != 1 || len(letters[0].Items) != 2 || letters[0].Error != "database unavailable" {
+		t.Errorf("Expected one dead letter with 2 items, got %+v", letters)
+	}
+	if len(handled) != 2 {
+		t.Errorf("Expected error handler to receive 2 items, got %v", handled)
+	}
+	if got := testutil.ToFloat64(metrics.flushes.WithLabelValues("orders", FlushSize)); got != 2 {
+		t.Errorf("Expected 2 size flushes, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.retries.WithLabelValues("orders")); got != 1 {
+		t.Errorf("Expected 1 retry, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.deadLetter.WithLabelValues("orders")); got != 2 {
+		t.Errorf("Expected 2 dead letter items, got %v", got)
+	}
+
+}
+
+func TestAggregatorWAL(t *testing.T) {
+	dir := t.TempDir()
+	c := &collector{}
+	open := func() *Aggregator[record] {
+		aggregator, err := NewAggregator(c.process, WithBatchSize[record](4), WithWorkers[record](2),
+			WithChannelBufferSize[record](100), WithLingerTime[record](10*time.Millisecond),
+			WithWAL[record](dir, 0), WithWALSegmentSize[record](64))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return aggregator
+	}
+
+	// 没有启动就停止，队列中的项目保留在预写日志中
+	first := open()
+	for i := 0; i < 10; i++ {
+		if !first.TryEnqueue(record{ID: i}) {
+			t.Fatalf("Failed to enqueue item: %d", i)
+		}
+	}
+	first.Stop()
+	if err := first.Enqueue(record{}); !errors.Is(err, ErrAggregatorStopping) {
+		t.Errorf("Expected ErrAggregatorStopping, got %v", err)
+	}
+
+	second := open()
+	if second.Recovered() != 10 {
+		t.Fatalf("Expected 10 recovered items, got %d", second.Recovered())
+	}
+	second.Start()
+	if err := second.Enqueue(record{ID: 10}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for c.count() < 11 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	second.SafeStop()
+	if c.count() != 11 {
+		t.Errorf("Expected 11 processed items, got %d", c.count())
+	}
+
+	// 全部确认后不再恢复，已确认的段被删除
+	third := open()
+	defer third.Stop()
+	if third.Recovered() != 0 {
+		t.Errorf("Expected nothing to recover, got %d", third.Recovered())
+	}
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+walExt))
+	if len(segments) > 2 {
+		t.Errorf("Expected acknowledged segments to be removed, got %v", segments)
+	}
+}
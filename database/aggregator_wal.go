@@ -0,0 +1,271 @@
+package database
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrWALClosed 预写日志已关闭
+var ErrWALClosed = errors.New("aggregator wal is closed")
+
+const walExt = ".wal"
+
+// walRef 项目在预写日志中的位置，seq 为 0 表示没有写入日志
+type walRef struct {
+	seq uint64
+	seg uint64
+}
+
+// walRecord 启动时从日志恢复的未确认项目
+type walRecord struct {
+	walRef
+	data json.RawMessage
+}
+
+// walLine 日志中的一行，入队记录包含 S 和 I，确认记录只包含 A
+type walLine struct {
+	S uint64          `json:"s,omitempty"`
+	I json.RawMessage `json:"i,omitempty"`
+	A []uint64        `json:"a,omitempty"`
+}
+
+// wal 聚合器的预写日志。
+// 日志按段保存在目录中，每段为一行一条记录的JSON文件：入队时写入项目，批次处理完成后写入确认。
+// 段内的项目全部确认、且之前的段都已删除时删除该段，保证确认记录不会早于对应的项目被删除
+type wal struct {
+	dir     string
+	segSize int64
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	current  uint64
+	order    []uint64       // 现存的段，按编号升序
+	pending  map[uint64]int // 每段未确认的项目数
+	seq      uint64
+	closed   bool
+	stopSync chan struct{}
+	syncDone chan struct{}
+}
+
+// openWAL 打开目录中的预写日志，返回未确认的项目，按入队顺序排列。
+// syncInterval 大于 0 时定期将日志同步到磁盘，否则只在关闭时同步
+func openWAL(dir string, segSize int64, syncInterval time.Duration) (*wal, []walRecord, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	w := &wal{dir: dir, segSize: segSize, pending: make(map[uint64]int)}
+	records, err := w.replay()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = w.rotate(); err != nil {
+		return nil, nil, err
+	}
+	w.purge()
+	if syncInterval > 0 {
+		w.stopSync = make(chan struct{})
+		w.syncDone = make(chan struct{})
+		go w.syncLoop(syncInterval)
+	}
+	return w, records, nil
+}
+
+// replay 读取所有段，去掉已确认的项目
+func (w *wal) replay() ([]walRecord, error) {
+	paths, err := filepath.Glob(filepath.Join(w.dir, "*"+walExt))
+	if err != nil {
+		return nil, err
+	}
+	var segs []uint64
+	for _, path := range paths {
+		num, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(path), walExt), 10, 64)
+		if err == nil {
+			segs = append(segs, num)
+		}
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+
+	unacked := make(map[uint64]walRecord)
+	for _, seg := range segs {
+		if err = w.replaySegment(seg, unacked); err != nil {
+			return nil, err
+		}
+		w.order = append(w.order, seg)
+		w.pending[seg] = 0
+		w.current = seg
+	}
+
+	records := make([]walRecord, 0, len(unacked))
+	for _, r := range unacked {
+		records = append(records, r)
+		w.pending[r.seg]++
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].seq < records[j].seq })
+	return records, nil
+}
+
+func (w *wal) replaySegment(seg uint64, unacked map[uint64]walRecord) error {
+	f, err := os.Open(w.segPath(seg))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var line walLine
+		// 进程退出时最后一行可能不完整，跳过无法解析的行
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.S > 0 {
+			unacked[line.S] = walRecord{walRef: walRef{seq: line.S, seg: seg}, data: line.I}
+			if line.S > w.seq {
+				w.seq = line.S
+			}
+		}
+		for _, seq := range line.A {
+			delete(unacked, seq)
+		}
+	}
+	return scanner.Err()
+}
+
+func (w *wal) segPath(seg uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", seg, walExt))
+}
+
+// rotate 关闭当前段并创建新段
+func (w *wal) rotate() error {
+	if w.file != nil {
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+	w.current++
+	f, err := os.OpenFile(w.segPath(w.current), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file, w.size = f, 0
+	w.order = append(w.order, w.current)
+	w.pending[w.current] = 0
+	return nil
+}
+
+// purge 删除开头已全部确认的段，当前段不删除
+func (w *wal) purge() {
+	for len(w.order) > 0 && w.order[0] != w.current && w.pending[w.order[0]] == 0 {
+		_ = os.Remove(w.segPath(w.order[0]))
+		delete(w.pending, w.order[0])
+		w.order = w.order[1:]
+	}
+}
+
+func (w *wal) write(line walLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	n, err := w.file.Write(data)
+	w.size += int64(n)
+	return err
+}
+
+// append 写入一个项目，返回它在日志中的位置
+func (w *wal) append(data []byte) (walRef, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return walRef{}, ErrWALClosed
+	}
+	if w.size >= w.segSize {
+		if err := w.rotate(); err != nil {
+			return walRef{}, err
+		}
+	}
+	w.seq++
+	ref := walRef{seq: w.seq, seg: w.current}
+	if err := w.write(walLine{S: ref.seq, I: data}); err != nil {
+		return walRef{}, err
+	}
+	w.pending[ref.seg]++
+	return ref, nil
+}
+
+// ack 确认项目已处理完成，之后重启不会再恢复这些项目
+func (w *wal) ack(refs []walRef) error {
+	seqs := make([]uint64, 0, len(refs))
+	for _, ref := range refs {
+		if ref.seq > 0 {
+			seqs = append(seqs, ref.seq)
+		}
+	}
+	if len(seqs) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return ErrWALClosed
+	}
+	if err := w.write(walLine{A: seqs}); err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if ref.seq > 0 {
+			w.pending[ref.seg]--
+		}
+	}
+	w.purge()
+	return nil
+}
+
+func (w *wal) syncLoop(interval time.Duration) {
+	defer close(w.syncDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopSync:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if !w.closed {
+				_ = w.file.Sync()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// close 同步并关闭日志
+func (w *wal) close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := errors.Join(w.file.Sync(), w.file.Close())
+	w.mu.Unlock()
+	if w.stopSync != nil {
+		close(w.stopSync)
+		<-w.syncDone
+	}
+	return err
+}
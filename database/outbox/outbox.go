@@ -0,0 +1,423 @@
+// Package outbox 事务性发件箱：事件与业务数据在同一个 gorm 事务中写入发件箱表，
+// 由后台中继在事务提交后发布到队列或信号总线，进程在写库与发布之间退出也不会丢失事件
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 消息状态
+const (
+	StatusPending = "pending" // 等待发布
+	StatusSent    = "sent"    // 已发布
+	StatusDead    = "dead"    // 超过最大重试次数，不再发布
+)
+
+var (
+	ErrTopicEmpty  = errors.New("outbox topic is empty")
+	ErrNoDB        = errors.New("outbox database is nil")
+	ErrOutboxClose = errors.New("outbox is closed")
+)
+
+// Message 发件箱中的消息
+type Message struct {
+	ID          uint64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	AggregateID string     `gorm:"size:191;index:idx_outbox_aggregate,priority:1" json:"aggregateId"` // 聚合ID，相同聚合的消息按写入顺序发布
+	Topic       string     `gorm:"size:191;not null" json:"topic"`
+	Payload     []byte     `json:"payload"`
+	Status      string     `gorm:"size:16;index:idx_outbox_status,priority:1;index:idx_outbox_aggregate,priority:2" json:"status"`
+	Attempts    int        `json:"attempts"`
+	NextAt      time.Time  `gorm:"index:idx_outbox_status,priority:2" json:"nextAt"` // 下次可以发布的时间
+	LastError   string     `gorm:"size:1024" json:"lastError"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	SentAt      *time.Time `json:"sentAt"`
+}
+
+// TableName 发件箱表名
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+// Add 在事务 tx 中写入一条消息，随事务一起提交或回滚。
+// payload 为 []byte、string 或 json.RawMessage 时原样保存，其他类型使用JSON编码。
+// aggregateID 为空时消息之间不保证顺序
+func Add(tx *gorm.DB, aggregateID, topic string, payload interface{}) error {
+	if topic == "" {
+		return ErrTopicEmpty
+	}
+	var data []byte
+	switch v := payload.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("encode outbox payload: %w", err)
+		}
+	}
+	now := time.Now()
+	return tx.Create(&Message{
+		AggregateID: aggregateID,
+		Topic:       topic,
+		Payload:     data,
+		Status:      StatusPending,
+		NextAt:      now,
+		CreatedAt:   now,
+	}).Error
+}
+
+// Outbox 发件箱中继，轮询待发布的消息并交给 Publisher。
+// 消息至少发布一次；相同聚合ID的消息按写入顺序逐条发布，前一条发布成功（或进入死信）后才发布下一条
+type Outbox struct {
+	db        *gorm.DB
+	publisher Publisher
+
+	batchSize    int
+	pollInterval time.Duration
+	maxAttempts  int
+	backoff      func(attempt int) time.Duration
+	lease        time.Duration
+	retention    time.Duration
+	logger       *slog.Logger
+
+	notify  chan struct{}
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Option 配置 Outbox
+type Option func(*Outbox)
+
+// WithBatchSize 每次轮询最多发布的消息数，默认 100
+func WithBatchSize(n int) Option {
+	return func(o *Outbox) {
+		if n > 0 {
+			o.batchSize = n
+		}
+	}
+}
+
+// WithPollInterval 轮询间隔，默认 1 秒。Transaction 提交后会立即唤醒中继，不必等待轮询
+func WithPollInterval(d time.Duration) Option {
+	return func(o *Outbox) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WithMaxAttempts 最大发布次数，超过后消息标记为 StatusDead，默认 10，为 0 时不限制
+func WithMaxAttempts(n int) Option {
+	return func(o *Outbox) {
+		if n >= 0 {
+			o.maxAttempts = n
+		}
+	}
+}
+
+// WithBackoff 发布失败后的重试间隔，attempt 为已发布的次数，默认从 1 秒开始指数增长，最长 5 分钟
+func WithBackoff(fn func(attempt int) time.Duration) Option {
+	return func(o *Outbox) {
+		if fn != nil {
+			o.backoff = fn
+		}
+	}
+}
+
+// WithLease 消息被中继领取后的租期，默认 30 秒。
+// 中继在租期内没有完成发布（例如进程退出）时，消息会被重新领取
+func WithLease(d time.Duration) Option {
+	return func(o *Outbox) {
+		if d > 0 {
+			o.lease = d
+		}
+	}
+}
+
+// WithRetention 已发布消息的保留时间，默认 7 天，为 0 时不清理
+func WithRetention(d time.Duration) Option {
+	return func(o *Outbox) {
+		if d >= 0 {
+			o.retention = d
+		}
+	}
+}
+
+// WithLogger 设置日志，默认 slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *Outbox) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// ExponentialBackoff 从 base 开始每次翻倍，不超过 max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// New 创建发件箱并迁移发件箱表，调用 Start 后开始中继
+//
+// 示例:
+//
+//	box, err := outbox.New(database.GetDBManager().GetDB(), outbox.NewMux(nil).
+//		Handle("order.created", outbox.SignalPublisher[Order](created)).
+//		Handle("order.paid", outbox.QueuePublisher(q, "")))
+//	box.Start()
+//	err = box.Transaction(ctx, func(tx *gorm.DB) error {
+//		if err := tx.Create(&order).Error; err != nil {
+//			return err
+//		}
+//		return outbox.Add(tx, strconv.FormatUint(uint64(order.ID), 10), "order.created", order)
+//	})
+func New(db *gorm.DB, publisher Publisher, opts ...Option) (*Outbox, error) {
+	if db == nil {
+		return nil, ErrNoDB
+	}
+	if publisher == nil {
+		return nil, ErrNoPublisher
+	}
+	o := &Outbox{
+		db:           db,
+		publisher:    publisher,
+		batchSize:    100,
+		pollInterval: time.Second,
+		maxAttempts:  10,
+		backoff:      ExponentialBackoff(time.Second, 5*time.Minute),
+		lease:        30 * time.Second,
+		retention:    7 * 24 * time.Hour,
+		logger:       slog.Default(),
+		notify:       make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := db.AutoMigrate(&Message{}); err != nil {
+		return nil, fmt.Errorf("migrate outbox table: %w", err)
+	}
+	return o, nil
+}
+
+// Transaction 在事务中执行 fn，fn 中通过 Add 写入的消息在提交后立即唤醒中继
+func (o *Outbox) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if err := o.db.WithContext(ctx).Transaction(fn); err != nil {
+		return err
+	}
+	o.Notify()
+	return nil
+}
+
+// Notify 唤醒中继立即轮询，用于在 Transaction 之外自行提交事务后调用
+func (o *Outbox) Notify() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动中继，重复调用无效
+func (o *Outbox) Start() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutboxClose
+	}
+	if o.started {
+		return nil
+	}
+	o.started = true
+	ctx, cancel := context.WithCancel(context.Background())
+	o.cancel = cancel
+	o.done = make(chan struct{})
+	go o.run(ctx)
+	return nil
+}
+
+// Close 停止中继，等待正在发布的批次结束
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	cancel, done := o.cancel, o.done
+	o.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
+func (o *Outbox) run(ctx context.Context) {
+	defer close(o.done)
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+	var lastPurge time.Time
+	for {
+		for {
+			n, err := o.Relay(ctx)
+			if err != nil && ctx.Err() == nil {
+				o.logger.Error("relay outbox messages failed", "error", err)
+			}
+			// 一批已满时可能还有消息，继续发布
+			if err != nil || n < o.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+		if o.retention > 0 && time.Since(lastPurge) > time.Hour {
+			lastPurge = time.Now()
+			if err := o.purge(ctx); err != nil && ctx.Err() == nil {
+				o.logger.Error("purge outbox messages failed", "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-o.notify:
+		}
+	}
+}
+
+// Relay 发布一批到期的消息，返回处理的消息数。Start 后由中继定时调用，也可以手动调用
+func (o *Outbox) Relay(ctx context.Context) (int, error) {
+	var messages []*Message
+	now := time.Now()
+	// 每个聚合只取最早一条待发布的消息，保证聚合内的顺序
+	err := o.db.WithContext(ctx).Table(Message{}.TableName()+" AS m").
+		Where("m.status = ? AND m.next_at <= ?", StatusPending, now).
+		Where("m.aggregate_id = '' OR NOT EXISTS (?)",
+			o.db.Table(Message{}.TableName()+" AS p").Select("1").
+				Where("p.aggregate_id = m.aggregate_id AND p.status = ? AND p.id < m.id", StatusPending)).
+		Order("m.id").Limit(o.batchSize).Find(&messages).Error
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			break
+		}
+		claimed, err := o.claim(ctx, msg, now)
+		if err != nil {
+			return n, err
+		}
+		if !claimed {
+			continue
+		}
+		n++
+		if err = o.publish(ctx, msg); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// claim 领取消息，多个中继同时运行时只有一个能领取成功
+func (o *Outbox) claim(ctx context.Context, msg *Message, now time.Time) (bool, error) {
+	res := o.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ? AND status = ? AND attempts = ?", msg.ID, StatusPending, msg.Attempts).
+		Updates(map[string]interface{}{
+			"attempts": msg.Attempts + 1,
+			"next_at":  now.Add(o.lease),
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	msg.Attempts++
+	return res.RowsAffected == 1, nil
+}
+
+func (o *Outbox) publish(ctx context.Context, msg *Message) error {
+	pubErr := o.publisher.Publish(ctx, msg)
+	// 使用独立的上下文记录结果，避免 Close 时已发布的消息未被标记
+	db := o.db.WithContext(context.WithoutCancel(ctx)).Model(&Message{}).Where("id = ?", msg.ID)
+	if pubErr == nil {
+		now := time.Now()
+		return db.Updates(map[string]interface{}{
+			"status":     StatusSent,
+			"sent_at":    now,
+			"last_error": "",
+		}).Error
+	}
+
+	errMsg := pubErr.Error()
+	if len(errMsg) > 1024 {
+		errMsg = errMsg[:1024]
+	}
+	if o.maxAttempts > 0 && msg.Attempts >= o.maxAttempts {
+		o.logger.Error("outbox message dead", "id", msg.ID, "topic", msg.Topic, "attempts", msg.Attempts, "error", pubErr)
+		return db.Updates(map[string]interface{}{
+			"status":     StatusDead,
+			"last_error": errMsg,
+		}).Error
+	}
+	o.logger.Warn("publish outbox message failed", "id", msg.ID, "topic", msg.Topic, "attempts", msg.Attempts, "error", pubErr)
+	return db.Updates(map[string]interface{}{
+		"next_at":    time.Now().Add(o.backoff(msg.Attempts)),
+		"last_error": errMsg,
+	}).Error
+}
+
+// purge 删除超过保留时间的已发布消息
+func (o *Outbox) purge(ctx context.Context) error {
+	return o.db.WithContext(ctx).
+		Where("status = ? AND sent_at < ?", StatusSent, time.Now().Add(-o.retention)).
+		Delete(&Message{}).Error
+}
+
+// Pending 返回待发布的消息数
+func (o *Outbox) Pending(ctx context.Context) (int64, error) {
+	var n int64
+	err := o.db.WithContext(ctx).Model(&Message{}).Where("status = ?", StatusPending).Count(&n).Error
+	return n, err
+}
+
+// Dead 返回进入死信的消息，按ID倒序
+func (o *Outbox) Dead(ctx context.Context, limit int) ([]Message, error) {
+	var messages []Message
+	err := o.db.WithContext(ctx).Where("status = ?", StatusDead).Order("id DESC").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// Requeue 将死信消息重新设为待发布，重置发布次数
+func (o *Outbox) Requeue(ctx context.Context, id uint64) error {
+	err := o.db.WithContext(ctx).Model(&Message{}).
+		Where("id = ? AND status = ?", id, StatusDead).
+		Updates(map[string]interface{}{
+			"status":   StatusPending,
+			"attempts": 0,
+			"next_at":  time.Now(),
+		}).Error
+	if err == nil {
+		o.Notify()
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/signals"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type order struct {
+	ID     string `gorm:"primaryKey" json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "outbox.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err)
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	assert.NoError(t, db.AutoMigrate(&order{}))
+	return db
+}
+
+// recorder 记录发布的消息，fail 返回 true 时发布失败
+type recorder struct {
+	mu   sync.Mutex
+	got  []*Message
+	fail func(msg *Message) bool
+}
+
+func (r *recorder) Publish(_ context.Context, msg *Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail != nil && r.fail(msg) {
+		return errors.New("broker unavailable")
+	}
+	r.got = append(r.got, msg)
+	return nil
+}
+
+func (r *recorder) payloads(aggregateID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var payloads []string
+	for _, msg := range r.got {
+		if msg.AggregateID == aggregateID {
+			payloads = append(payloads, string(msg.Payload))
+		}
+	}
+	return payloads
+}
+
+func TestTransaction(t *testing.T) {
+	db := openDB(t)
+	rec := &recorder{}
+	box, err := New(db, rec, WithPollInterval(time.Hour))
+	assert.NoError(t, err)
+	assert.NoError(t, box.Start())
+	defer box.Close()
+
+	ctx := context.Background()
+	err = box.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Create(&order{ID: "o-1", Amount: 3}).Error; err != nil {
+			return err
+		}
+		return Add(tx, "o-1", "order.created", order{ID: "o-1", Amount: 3})
+	})
+	assert.NoError(t, err)
+	// 提交后立即发布，不必等待轮询
+	assert.Eventually(t, func() bool { return len(rec.payloads("o-1")) == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.JSONEq(t, `{"id":"o-1","amount":3}`, rec.payloads("o-1")[0])
+
+	// 回滚的事务不会发布消息
+	err = box.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := Add(tx, "o-2", "order.created", "ignored"); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	pending, err := box.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), pending)
+	assert.Empty(t, rec.payloads("o-2"))
+
+	var msg Message
+	assert.NoError(t, db.First(&msg).Error)
+	assert.Equal(t, StatusSent, msg.Status)
+	assert.NotNil(t, msg.SentAt)
+	assert.ErrorIs(t, Add(db, "", "", nil), ErrTopicEmpty)
+}
+
+func TestRelayOrder(t *testing.T) {
+	db := openDB(t)
+	failing := true
+	rec := &recorder{fail: func(msg *Message) bool {
+		return failing && msg.AggregateID == "a" && string(msg.Payload) == "a1"
+	}}
+	box, err := New(db, rec, WithBackoff(func(int) time.Duration { return 0 }), WithBatchSize(10))
+	assert.NoError(t, err)
+
+	for _, m := range []struct{ aggregate, payload string }{
+		{"a", "a1"}, {"b", "b1"}, {"a", "a2"}, {"b", "b2"}, {"", "x"}, {"a", "a3"},
+	} {
+		assert.NoError(t, Add(db, m.aggregate, "topic", m.payload))
+	}
+
+	ctx := context.Background()
+	// a1 发布失败时阻塞聚合 a 的后续消息，其他聚合不受影响
+	for i := 0; i < 3; i++ {
+		_, err = box.Relay(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Empty(t, rec.payloads("a"))
+	assert.Equal(t, []string{"b1", "b2"}, rec.payloads("b"))
+	assert.Equal(t, []string{"x"}, rec.payloads(""))
+
+	failing = false
+	for i := 0; i < 3; i++ {
+		_, err = box.Relay(ctx)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"a1", "a2", "a3"}, rec.payloads("a"))
+
+	var msg Message
+	assert.NoError(t, db.Where("payload = ?", []byte("a1")).First(&msg).Error)
+	assert.Equal(t, 4, msg.Attempts)
+	assert.Empty(t, msg.LastError)
+}
+
+func TestDeadLetter(t *testing.T) {
+	db := openDB(t)
+	failing := true
+	rec := &recorder{fail: func(msg *Message) bool { return failing && string(msg.Payload) == "a1" }}
+	box, err := New(db, rec, WithMaxAttempts(2), WithBackoff(func(int) time.Duration { return 0 }))
+	assert.NoError(t, err)
+	assert.NoError(t, Add(db, "a", "topic", "a1"))
+	assert.NoError(t, Add(db, "a", "topic", "a2"))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err = box.Relay(ctx)
+		assert.NoError(t, err)
+	}
+	// 进入死信后不再阻塞聚合的后续消息
+	assert.Equal(t, []string{"a2"}, rec.payloads("a"))
+	dead, err := box.Dead(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, 2, dead[0].Attempts)
+		assert.Equal(t, "broker unavailable", dead[0].LastError)
+	}
+
+	failing = false
+	assert.NoError(t, box.Requeue(ctx, dead[0].ID))
+	_, err = box.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a2", "a1"}, rec.payloads("a"))
+}
+
+func TestLease(t *testing.T) {
+	db := openDB(t)
+	rec := &recorder{}
+	box, err := New(db, rec, WithLease(50*time.Millisecond))
+	assert.NoError(t, err)
+	assert.NoError(t, Add(db, "a", "topic", "a1"))
+
+	// 模拟中继领取后退出，租期结束前不会被重新领取
+	var msg Message
+	assert.NoError(t, db.First(&msg).Error)
+	claimed, err := box.claim(context.Background(), &msg, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	n, err := box.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	time.Sleep(60 * time.Millisecond)
+	n, err = box.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"a1"}, rec.payloads("a"))
+}
+
+func TestPublishers(t *testing.T) {
+	ctx := context.Background()
+	sig := signals.NewSync[order]()
+	var got order
+	sig.AddListener(func(ctx context.Context, o order) { got = o })
+
+	mux := NewMux(nil).Handle("order.created", SignalPublisher[order](sig))
+	assert.NoError(t, mux.Publish(ctx, &Message{Topic: "order.created", Payload: []byte(`{"id":"o-1","amount":2}`)}))
+	assert.Equal(t, order{ID: "o-1", Amount: 2}, got)
+	assert.ErrorIs(t, mux.Publish(ctx, &Message{Topic: "order.paid"}), ErrNoPublisher)
+	assert.Error(t, mux.Publish(ctx, &Message{Topic: "order.created", Payload: []byte("bad")}))
+
+	assert.Equal(t, time.Second, ExponentialBackoff(time.Second, time.Minute)(1))
+	assert.Equal(t, 4*time.Second, ExponentialBackoff(time.Second, time.Minute)(3))
+	assert.Equal(t, time.Minute, ExponentialBackoff(time.Second, time.Minute)(20))
+}
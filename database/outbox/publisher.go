@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sagoo-cloud/nexframe/servers/queue"
+	"github.com/sagoo-cloud/nexframe/signals"
+)
+
+var (
+	ErrNoPublisher   = errors.New("no publisher for topic")
+	ErrEnqueueFailed = errors.New("enqueue outbox message failed")
+)
+
+// Publisher 将消息发布到下游，返回错误时消息按退避策略重试。
+// 消息至少发布一次，进程在发布后、标记已发送前退出时会重复发布，下游需要按 Message.ID 去重
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// PublisherFunc 函数形式的 Publisher
+type PublisherFunc func(ctx context.Context, msg *Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// QueuePublisher 发布到 servers/queue，队列名称为 prefix + 主题，消息内容为负载
+func QueuePublisher(q queue.Queue, prefix string) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		ok, err := q.Enqueue(ctx, prefix+msg.Topic, string(msg.Payload))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrEnqueueFailed
+		}
+		return nil
+	})
+}
+
+// SignalPublisher 将JSON负载解码为 T 后发射信号，可以是 signals.Event 返回的总线事件。
+// 信号的监听器返回错误（超时、panic）时消息会重试
+func SignalPublisher[T any](signal signals.Signal[T]) Publisher {
+	return PublisherFunc(func(ctx context.Context, msg *Message) error {
+		var payload T
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			return fmt.Errorf("decode outbox message %d: %w", msg.ID, err)
+		}
+		return signal.Emit(ctx, payload)
+	})
+}
+
+// Mux 按主题选择 Publisher
+type Mux struct {
+	mu         sync.RWMutex
+	publishers map[string]Publisher
+	fallback   Publisher
+}
+
+// NewMux 创建按主题分发的 Publisher，fallback 处理没有注册的主题，为 nil 时返回 ErrNoPublisher
+func NewMux(fallback Publisher) *Mux {
+	return &Mux{publishers: make(map[string]Publisher), fallback: fallback}
+}
+
+// Handle 注册主题的 Publisher
+func (m *Mux) Handle(topic string, publisher Publisher) *Mux {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publishers[topic] = publisher
+	return m
+}
+
+func (m *Mux) Publish(ctx context.Context, msg *Message) error {
+	m.mu.RLock()
+	publisher, ok := m.publishers[msg.Topic]
+	m.mu.RUnlock()
+	if !ok {
+		publisher = m.fallback
+	}
+	if publisher == nil {
+		return fmt.Errorf("%w: %s", ErrNoPublisher, msg.Topic)
+	}
+	return publisher.Publish(ctx, msg)
+}
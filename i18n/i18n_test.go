@@ -14,7 +14,11 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// testLangDir 测试用的翻译文件目录，避免覆盖仓库中的 config/lang
+var testLangDir string
+
 func setupTestEnvironment() {
+	testLangDir, _ = os.MkdirTemp("", "i18n-lang")
 	createTestTranslationFile("en", map[string]string{
 		"hello":   "Hello",
 		"world":   "World",
@@ -28,17 +32,16 @@ func setupTestEnvironment() {
 }
 
 func cleanupTestEnvironment() {
-	os.RemoveAll("./test_translations")
+	os.RemoveAll(testLangDir)
 }
 
 func createTestTranslationFile(lang string, translations map[string]string) {
 	data, _ := json.Marshal(translations)
-	os.MkdirAll("./config/lang", 0755)
-	os.WriteFile(filepath.Join("./config/lang", lang+".json"), data, 0644)
+	os.WriteFile(filepath.Join(testLangDir, lang+".json"), data, 0644)
 }
 
 func TestInitGlobal(t *testing.T) {
-	err := InitGlobal("en", "./config/lang")
+	err := InitGlobal("en", testLangDir)
 	if err != nil {
 		t.Errorf("InitGlobal failed: %v", err)
 	}
@@ -46,14 +49,14 @@ func TestInitGlobal(t *testing.T) {
 		t.Errorf("Expected current language to be 'en', got '%s'", GetCurrentLang())
 	}
 
-	err = InitGlobal("invalid", "./config/lang")
+	err = InitGlobal("invalid", testLangDir)
 	if err == nil {
 		t.Error("Expected error for invalid language, got nil")
 	}
 }
 
 func TestT(t *testing.T) {
-	InitGlobal("en", "./config/lang")
+	InitGlobal("en", testLangDir)
 
 	if T("hello") != "Hello" {
 		t.Errorf("Expected 'Hello', got '%s'", T("hello"))
@@ -75,7 +78,7 @@ func TestT(t *testing.T) {
 }
 
 func TestSetLang(t *testing.T) {
-	InitGlobal("en", "./config/lang")
+	InitGlobal("en", testLangDir)
 
 	err := SetLang("zh")
 	if err != nil {
@@ -92,7 +95,7 @@ func TestSetLang(t *testing.T) {
 }
 
 func TestFormatTranslation(t *testing.T) {
-	InitGlobal("en", "./config/lang")
+	InitGlobal("en", testLangDir)
 
 	result := FormatTranslation("welcome", "John")
 	if result != "Welcome, John!" {
@@ -107,7 +110,7 @@ func TestFormatTranslation(t *testing.T) {
 }
 
 func TestLoadTranslationsFromBytes(t *testing.T) {
-	InitGlobal("en", "./config/lang")
+	InitGlobal("en", testLangDir)
 
 	newTranslations := []byte(`{"new": "New Translation"}`)
 	err := LoadTranslationsFromBytes("fr", newTranslations)
@@ -122,7 +125,7 @@ func TestLoadTranslationsFromBytes(t *testing.T) {
 }
 
 func TestConcurrency(t *testing.T) {
-	InitGlobal("en", "./config/lang")
+	InitGlobal("en", testLangDir)
 
 	done := make(chan bool)
 	go func() {
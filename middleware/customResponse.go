@@ -1,16 +1,20 @@
 package middleware
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"net"
 	"net/http"
 )
 
 // customResponseWriter is a custom response writer that captures the status code and response size
 type customResponseWriter struct {
 	http.ResponseWriter
-	status int
-	size   int
-	buf    bytes.Buffer
+	status   int
+	size     int
+	buf      bytes.Buffer
+	hijacked bool
 }
 
 func (crw *customResponseWriter) WriteHeader(status int) {
@@ -23,3 +27,22 @@ func (crw *customResponseWriter) Write(b []byte) (int, error) {
 	crw.size += size
 	return size, err
 }
+
+// Hijack implements http.Hijacker so that WebSocket upgrades keep working behind this middleware
+func (crw *customResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := crw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		crw.hijacked = true
+		crw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the original ResponseWriter for use by http.ResponseController
+func (crw *customResponseWriter) Unwrap() http.ResponseWriter {
+	return crw.ResponseWriter
+}
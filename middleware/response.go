@@ -24,6 +24,11 @@ func HandlerResponse(next http.Handler) http.Handler {
 		// Call the next handler
 		next.ServeHTTP(crw, r)
 
+		// The connection was taken over (e.g. a WebSocket upgrade), nothing more can be written
+		if crw.hijacked {
+			return
+		}
+
 		// If there's custom buffer content, then exit current handler
 		if crw.buf.Len() > 0 {
 			w.Write(crw.buf.Bytes())
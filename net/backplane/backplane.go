@@ -0,0 +1,80 @@
+// Package backplane 为WebSocket等长连接服务提供跨节点的消息分发和在线状态登记能力。
+//
+// 单个进程只持有连接到自身的客户端，当服务水平扩展为多个实例时，
+// 需要通过 Backplane 将广播、定向发送等消息转发到所有节点，
+// 再由持有目标连接的节点完成最终投递。
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	KindBroadcast  = "broadcast"  // 广播到所有连接
+	KindConnection = "connection" // 发送到指定连接ID
+	KindUser       = "user"       // 发送到指定用户的所有连接
+	KindRoom       = "room"       // 发送到指定房间的所有成员
+)
+
+var (
+	ErrClosed         = errors.New("backplane is closed")
+	ErrNilHandler     = errors.New("backplane handler cannot be nil")
+	ErrInvalidChannel = errors.New("backplane channel cannot be empty")
+)
+
+// Envelope 定义在节点之间传输的消息
+type Envelope struct {
+	Kind    string `json:"kind"`    // 消息类型
+	Target  string `json:"target"`  // 消息目标，根据Kind的不同为连接ID、用户ID等
+	Origin  string `json:"origin"`  // 发送节点ID，节点可据此忽略自身发出的消息
+	Payload []byte `json:"payload"` // 消息内容
+}
+
+// Encode 将消息编码为字节
+func (e *Envelope) Encode() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Decode 从字节解码消息
+func Decode(data []byte) (*Envelope, error) {
+	env := &Envelope{}
+	if err := json.Unmarshal(data, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Handler 定义消息处理函数
+type Handler func(env *Envelope)
+
+// Subscription 定义订阅句柄
+type Subscription interface {
+	// Unsubscribe 取消订阅
+	Unsubscribe() error
+}
+
+// Backplane 定义跨节点消息分发接口，所有实现都需要实现以下接口
+type Backplane interface {
+	// Publish 发布消息到指定频道，所有订阅该频道的节点（包括自身）都会收到
+	Publish(ctx context.Context, channel string, env *Envelope) error
+
+	// Subscribe 订阅指定频道
+	Subscribe(ctx context.Context, channel string, handler Handler) (Subscription, error)
+
+	// Close 关闭并释放资源
+	Close() error
+}
+
+// Presence 定义集群范围的在线状态登记接口，用于维护用户ID与连接ID的对应关系
+type Presence interface {
+	// Join 登记用户的一个连接
+	Join(ctx context.Context, userId, connectionId string) error
+
+	// Leave 注销用户的一个连接
+	Leave(ctx context.Context, userId, connectionId string) error
+
+	// Connections 获取用户在所有节点上的连接ID
+	Connections(ctx context.Context, userId string) ([]string, error)
+}
@@ -0,0 +1,144 @@
+package backplane
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Memory 基于进程内存的 Backplane 和 Presence 实现。
+// 多个服务实例共享同一个 Memory 即可模拟多节点部署，主要用于测试和单机运行。
+type Memory struct {
+	mu       sync.RWMutex
+	seq      uint64
+	channels map[string]map[uint64]Handler
+	users    map[string]map[string]struct{}
+	closed   bool
+}
+
+// NewMemory 创建一个新的内存实现
+func NewMemory() *Memory {
+	return &Memory{
+		channels: make(map[string]map[uint64]Handler),
+		users:    make(map[string]map[string]struct{}),
+	}
+}
+
+// Publish 将消息同步投递给频道的所有订阅者
+func (m *Memory) Publish(ctx context.Context, channel string, env *Envelope) error {
+	if channel == "" {
+		return ErrInvalidChannel
+	}
+
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return ErrClosed
+	}
+	handlers := make([]Handler, 0, len(m.channels[channel]))
+	for _, handler := range m.channels[channel] {
+		handlers = append(handlers, handler)
+	}
+	m.mu.RUnlock()
+
+	for _, handler := range handlers {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		// 每个订阅者拿到独立的副本，避免相互修改
+		cp := *env
+		handler(&cp)
+	}
+	return nil
+}
+
+// Subscribe 订阅指定频道
+func (m *Memory) Subscribe(ctx context.Context, channel string, handler Handler) (Subscription, error) {
+	if channel == "" {
+		return nil, ErrInvalidChannel
+	}
+	if handler == nil {
+		return nil, ErrNilHandler
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrClosed
+	}
+	m.seq++
+	id := m.seq
+	if m.channels[channel] == nil {
+		m.channels[channel] = make(map[uint64]Handler)
+	}
+	m.channels[channel][id] = handler
+
+	return &memorySubscription{memory: m, channel: channel, id: id}, nil
+}
+
+// Close 关闭并清除所有订阅
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	clear(m.channels)
+	return nil
+}
+
+// Join 登记用户的一个连接
+func (m *Memory) Join(ctx context.Context, userId, connectionId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.users[userId] == nil {
+		m.users[userId] = make(map[string]struct{})
+	}
+	m.users[userId][connectionId] = struct{}{}
+	return nil
+}
+
+// Leave 注销用户的一个连接
+func (m *Memory) Leave(ctx context.Context, userId, connectionId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if conns, ok := m.users[userId]; ok {
+		delete(conns, connectionId)
+		if len(conns) == 0 {
+			delete(m.users, userId)
+		}
+	}
+	return nil
+}
+
+// Connections 获取用户的所有连接ID
+func (m *Memory) Connections(ctx context.Context, userId string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ids := make([]string, 0, len(m.users[userId]))
+	for id := range m.users[userId] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+type memorySubscription struct {
+	memory  *Memory
+	channel string
+	id      uint64
+}
+
+// Unsubscribe 取消订阅
+func (s *memorySubscription) Unsubscribe() error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+	if handlers, ok := s.memory.channels[s.channel]; ok {
+		delete(handlers, s.id)
+		if len(handlers) == 0 {
+			delete(s.memory.channels, s.channel)
+		}
+	}
+	return nil
+}
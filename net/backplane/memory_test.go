@@ -0,0 +1,69 @@
+package backplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryPublishSubscribe(t *testing.T) {
+	ctx := context.Background()
+	bp := NewMemory()
+
+	var received []*Envelope
+	sub, err := bp.Subscribe(ctx, "test", func(env *Envelope) {
+		received = append(received, env)
+	})
+	assert.NoError(t, err)
+
+	err = bp.Publish(ctx, "test", &Envelope{Kind: KindBroadcast, Origin: "node1", Payload: []byte("hello")})
+	assert.NoError(t, err)
+	err = bp.Publish(ctx, "other", &Envelope{Kind: KindBroadcast, Payload: []byte("ignored")})
+	assert.NoError(t, err)
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, "node1", received[0].Origin)
+	assert.Equal(t, []byte("hello"), received[0].Payload)
+
+	assert.NoError(t, sub.Unsubscribe())
+	assert.NoError(t, bp.Publish(ctx, "test", &Envelope{Kind: KindBroadcast}))
+	assert.Len(t, received, 1)
+
+	_, err = bp.Subscribe(ctx, "", func(env *Envelope) {})
+	assert.ErrorIs(t, err, ErrInvalidChannel)
+	_, err = bp.Subscribe(ctx, "test", nil)
+	assert.ErrorIs(t, err, ErrNilHandler)
+
+	assert.NoError(t, bp.Close())
+	assert.ErrorIs(t, bp.Publish(ctx, "test", &Envelope{}), ErrClosed)
+}
+
+func TestMemoryPresence(t *testing.T) {
+	ctx := context.Background()
+	bp := NewMemory()
+
+	assert.NoError(t, bp.Join(ctx, "u1", "c2"))
+	assert.NoError(t, bp.Join(ctx, "u1", "c1"))
+	assert.NoError(t, bp.Join(ctx, "u2", "c3"))
+
+	ids, err := bp.Connections(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c1", "c2"}, ids)
+
+	assert.NoError(t, bp.Leave(ctx, "u1", "c1"))
+	assert.NoError(t, bp.Leave(ctx, "u1", "c2"))
+	ids, err = bp.Connections(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestEnvelopeEncode(t *testing.T) {
+	env := &Envelope{Kind: KindConnection, Target: "c1", Origin: "n1", Payload: []byte(`{"a":1}`)}
+	data, err := env.Encode()
+	assert.NoError(t, err)
+
+	decoded, err := Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, env, decoded)
+}
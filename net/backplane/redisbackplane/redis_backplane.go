@@ -0,0 +1,181 @@
+package redisbackplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/database/redisdb"
+	"github.com/sagoo-cloud/nexframe/net/backplane"
+)
+
+// Options 定义Redis实现的配置
+type Options struct {
+	client      redis.UniversalClient
+	prefix      string        // 频道和键前缀，默认 nexframe:ws:
+	presenceTTL time.Duration // 在线状态过期时间，防止节点异常退出后残留数据，默认 24 小时
+}
+
+// WithClient 设置 Redis 客户端，未设置时使用 redisdb.DB() 的客户端
+func WithClient(client redis.UniversalClient) func(*Options) {
+	return func(options *Options) {
+		options.client = client
+	}
+}
+
+// WithPrefix 设置频道和键前缀
+func WithPrefix(prefix string) func(*Options) {
+	return func(options *Options) {
+		options.prefix = prefix
+	}
+}
+
+// WithPresenceTTL 设置在线状态过期时间
+func WithPresenceTTL(ttl time.Duration) func(*Options) {
+	return func(options *Options) {
+		if ttl > 0 {
+			options.presenceTTL = ttl
+		}
+	}
+}
+
+// RedisBackplane 基于 Redis pub/sub 的 Backplane 实现，同时基于 Redis Set 实现 Presence
+type RedisBackplane struct {
+	ops    Options
+	mu     sync.Mutex
+	subs   map[*redisSubscription]struct{}
+	closed bool
+}
+
+// New 创建一个新的 RedisBackplane 实例
+func New(options ...func(*Options)) (*RedisBackplane, error) {
+	ops := &Options{
+		prefix:      "nexframe:ws:",
+		presenceTTL: 24 * time.Hour,
+	}
+	for _, f := range options {
+		f(ops)
+	}
+	if ops.client == nil {
+		if db := redisdb.DB(); db != nil {
+			ops.client = db.GetClient()
+		}
+	}
+	if ops.client == nil {
+		return nil, errors.New("redis client must not be nil")
+	}
+	return &RedisBackplane{
+		ops:  *ops,
+		subs: make(map[*redisSubscription]struct{}),
+	}, nil
+}
+
+// Publish 发布消息到指定频道
+func (b *RedisBackplane) Publish(ctx context.Context, channel string, env *backplane.Envelope) error {
+	if channel == "" {
+		return backplane.ErrInvalidChannel
+	}
+	data, err := env.Encode()
+	if err != nil {
+		return err
+	}
+	return b.ops.client.Publish(ctx, b.ops.prefix+channel, data).Err()
+}
+
+// Subscribe 订阅指定频道，消息在独立的 goroutine 中按顺序回调
+func (b *RedisBackplane) Subscribe(ctx context.Context, channel string, handler backplane.Handler) (backplane.Subscription, error) {
+	if channel == "" {
+		return nil, backplane.ErrInvalidChannel
+	}
+	if handler == nil {
+		return nil, backplane.ErrNilHandler
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, backplane.ErrClosed
+	}
+
+	pubsub := b.ops.client.Subscribe(ctx, b.ops.prefix+channel)
+	// 等待订阅确认，确保返回后发布的消息不会丢失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	sub := &redisSubscription{backplane: b, pubsub: pubsub}
+	b.subs[sub] = struct{}{}
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			env, err := backplane.Decode([]byte(msg.Payload))
+			if err != nil {
+				continue
+			}
+			handler(env)
+		}
+	}()
+
+	return sub, nil
+}
+
+// Close 关闭所有订阅
+func (b *RedisBackplane) Close() error {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = make(map[*redisSubscription]struct{})
+	b.closed = true
+	b.mu.Unlock()
+
+	var errs []error
+	for sub := range subs {
+		if err := sub.pubsub.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Join 登记用户的一个连接
+func (b *RedisBackplane) Join(ctx context.Context, userId, connectionId string) error {
+	key := b.presenceKey(userId)
+	pipe := b.ops.client.TxPipeline()
+	pipe.SAdd(ctx, key, connectionId)
+	pipe.Expire(ctx, key, b.ops.presenceTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Leave 注销用户的一个连接
+func (b *RedisBackplane) Leave(ctx context.Context, userId, connectionId string) error {
+	return b.ops.client.SRem(ctx, b.presenceKey(userId), connectionId).Err()
+}
+
+// Connections 获取用户在所有节点上的连接ID
+func (b *RedisBackplane) Connections(ctx context.Context, userId string) ([]string, error) {
+	ids, err := b.ops.client.SMembers(ctx, b.presenceKey(userId)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return ids, err
+}
+
+func (b *RedisBackplane) presenceKey(userId string) string {
+	return b.ops.prefix + "presence:" + userId
+}
+
+type redisSubscription struct {
+	backplane *RedisBackplane
+	pubsub    *redis.PubSub
+}
+
+// Unsubscribe 取消订阅
+func (s *redisSubscription) Unsubscribe() error {
+	s.backplane.mu.Lock()
+	delete(s.backplane.subs, s)
+	s.backplane.mu.Unlock()
+	return s.pubsub.Close()
+}
@@ -109,12 +109,19 @@ type Config struct {
 	Logger               Logger        // 日志记录器
 	LogLevel             LogLevel      // 日志级别
 	QueueSize            int           // 消息队列大小
+
+	ProtocolVersion       int    // 协议版本，4 为 MQTT 3.1.1（默认），5 为 MQTT 5
+	SessionExpiryInterval uint32 // MQTT 5 会话过期时间（秒），为 0 时断开连接即结束会话
+
+	Offline *OfflineConfig // 离线缓冲，断开连接期间缓存 QoS 1/2 的消息并在重连后按顺序发布，为 nil 时不缓存
 }
 
 // Client 实现MQTT客户端
 type Client struct {
 	sync.RWMutex
 	client        paho.Client
+	v5            *v5Conn        // MQTT 5 连接，使用 MQTT 3.1.1 时为 nil
+	offline       *offlineBuffer // 离线缓冲，未配置时为 nil
 	msgHandlerMap map[string]Handler
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -125,7 +132,8 @@ type Client struct {
 	config        Config
 }
 
-// NewClient 创建新的MQTT客户端实例
+// NewClient 创建新的MQTT客户端实例。
+// ctx 只用于等待初次连接，连接建立后取消 ctx 不会断开客户端，需调用 Close 关闭
 func NewClient(ctx context.Context, conf Config) (*Client, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -135,7 +143,9 @@ func NewClient(ctx context.Context, conf Config) (*Client, error) {
 		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
+	// ctx 只用于建立初次连接，客户端的生命周期由 Close 控制
+	connectCtx := ctx
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	client := &Client{
 		msgHandlerMap: make(map[string]Handler),
 		ctx:           ctx,
@@ -150,6 +160,23 @@ func NewClient(ctx context.Context, conf Config) (*Client, error) {
 		client.logger = &defaultLogger{}
 	}
 
+	if conf.Offline != nil {
+		client.offline = newOfflineBuffer(conf.Offline)
+	}
+
+	if conf.ProtocolVersion == ProtocolV5 {
+		v5, err := newV5Conn(connectCtx, ctx, client, conf)
+		if err != nil {
+			cancel()
+			client.closeOffline()
+			client.log(LogLevelError, "MQTT 5 connection failed: %v", err)
+			return nil, err
+		}
+		client.v5 = v5
+		client.log(LogLevelInfo, "MQTT 5 client initialized successfully")
+		return client, nil
+	}
+
 	// 设置客户端选项
 	opts := paho.NewClientOptions()
 	opts.AddBroker(conf.Server)
@@ -171,6 +198,8 @@ func NewClient(ctx context.Context, conf Config) (*Client, error) {
 
 	// 配置TLS
 	if tlsConfig, err := newTLSConfig(conf.CAFile, conf.CertFile, conf.CertKeyFile); err != nil {
+		cancel()
+		client.closeOffline()
 		client.log(LogLevelError, "TLS configuration failed: %v", err)
 		return nil, fmt.Errorf("tls config error: %w", err)
 	} else if tlsConfig != nil {
@@ -180,8 +209,9 @@ func NewClient(ctx context.Context, conf Config) (*Client, error) {
 	client.client = paho.NewClient(opts)
 
 	// 建立连接
-	if err := client.connect(ctx); err != nil {
+	if err := client.connect(connectCtx); err != nil {
 		cancel()
+		client.closeOffline()
 		return nil, err
 	}
 
@@ -189,8 +219,12 @@ func NewClient(ctx context.Context, conf Config) (*Client, error) {
 	return client, nil
 }
 
-// GetClient 获取底层的paho.Client
+// GetClient 获取底层的paho.Client。
+// 使用 MQTT 5 时返回转发到当前客户端的适配器，与处理函数收到的第一个参数相同
 func (c *Client) GetClient() paho.Client {
+	if c.v5 != nil {
+		return c.v5.client
+	}
 	return c.client
 }
 
@@ -209,6 +243,20 @@ func (c *Client) RegisterHandler(handler Handler) error {
 
 	c.log(LogLevelDebug, "Registering handler for topic: %s", handler.Topic)
 
+	if c.v5 != nil {
+		// 先更新处理器快照，订阅成功后立即到达的消息也能被处理
+		c.msgHandlerMap[handler.Topic] = handler
+		c.v5.setHandlers(c.handlerList())
+		if err := c.v5.subscribe(c.ctx, handler); err != nil {
+			delete(c.msgHandlerMap, handler.Topic)
+			c.v5.setHandlers(c.handlerList())
+			c.log(LogLevelError, "Subscribe failed for topic %s: %v", handler.Topic, err)
+			return err
+		}
+		c.log(LogLevelInfo, "Handler registered successfully for topic: %s", handler.Topic)
+		return nil
+	}
+
 	if err := c.subscribeHandler(handler); err != nil {
 		return err
 	}
@@ -229,12 +277,15 @@ func (c *Client) UnregisterHandler(topic string) error {
 
 	c.log(LogLevelDebug, "Unregistering handler for topic: %s", topic)
 
-	if token := c.client.Unsubscribe(topic); token.Wait() && token.Error() != nil {
-		c.log(LogLevelError, "Failed to unsubscribe from topic %s: %v", topic, token.Error())
-		return fmt.Errorf("%w: %v", ErrUnsubscribeFailed, token.Error())
+	if err := c.unsubscribe(topic); err != nil {
+		c.log(LogLevelError, "Failed to unsubscribe from topic %s: %v", topic, err)
+		return err
 	}
 
 	delete(c.msgHandlerMap, topic)
+	if c.v5 != nil {
+		c.v5.setHandlers(c.handlerList())
+	}
 	c.log(LogLevelInfo, "Handler unregistered successfully for topic: %s", topic)
 	return nil
 }
@@ -261,15 +312,20 @@ func (c *Client) Close() error {
 
 	// 取消所有订阅
 	for topic := range c.msgHandlerMap {
-		if token := c.client.Unsubscribe(topic); token.Wait() && token.Error() != nil {
-			c.log(LogLevelWarn, "Failed to unsubscribe topic %s: %v", topic, token.Error())
+		if err := c.unsubscribe(topic); err != nil {
+			c.log(LogLevelWarn, "Failed to unsubscribe topic %s: %v", topic, err)
 		}
 	}
 
 	// 清理资源
 	c.cancel()
-	c.client.Disconnect(1000)
+	if c.v5 != nil {
+		c.v5.disconnect(time.Second)
+	} else {
+		c.client.Disconnect(1000)
+	}
 	c.wg.Wait()
+	c.closeOffline()
 
 	c.log(LogLevelInfo, "MQTT client closed successfully")
 	return nil
@@ -277,13 +333,46 @@ func (c *Client) Close() error {
 
 // Publish 发布消息
 func (c *Client) Publish(topic string, qos byte, data []byte) error {
-	if c.closed {
+	return c.PublishWithOptions(c.ctx, topic, qos, data)
+}
+
+// PublishWithOptions 发布消息，可以设置保留标志以及 MQTT 5 的用户属性、响应主题、关联数据等。
+// 使用 MQTT 5 时服务端返回的失败原因码以 *ReasonCodeError 返回，可通过 errors.As 获取。
+func (c *Client) PublishWithOptions(ctx context.Context, topic string, qos byte, data []byte, opts ...PublishOption) error {
+	c.RLock()
+	closed := c.closed
+	c.RUnlock()
+	if closed {
 		return ErrClientClosed
 	}
 
 	c.log(LogLevelDebug, "Publishing message to topic: %s", topic)
 
-	token := c.client.Publish(topic, qos, false, data)
+	o := newPublishOptions(opts)
+	// 断开连接或仍有未发布的离线消息时先进入缓冲区，保证消息按顺序发布
+	if c.offline != nil && qos > 0 && (!c.connectionOpen() || c.offline.pending()) {
+		return c.bufferMessage(topic, qos, data, o)
+	}
+
+	err := c.publish(ctx, topic, qos, data, o)
+	if err != nil && c.offline != nil && qos > 0 && !c.connectionOpen() {
+		return c.bufferMessage(topic, qos, data, o)
+	}
+	return err
+}
+
+// publish 直接发布消息，不经过离线缓冲
+func (c *Client) publish(ctx context.Context, topic string, qos byte, data []byte, o *publishOptions) error {
+	if c.v5 != nil {
+		if err := c.v5.publish(ctx, topic, qos, data, o); err != nil {
+			c.log(LogLevelError, "Failed to publish to topic %s: %v", topic, err)
+			return err
+		}
+		c.log(LogLevelDebug, "Message published successfully to topic: %s", topic)
+		return nil
+	}
+
+	token := c.client.Publish(topic, qos, o.retain, data)
 	if token.Wait() && token.Error() != nil {
 		c.log(LogLevelError, "Failed to publish to topic %s: %v", topic, token.Error())
 		return fmt.Errorf("%w: %v", ErrPublishFailed, token.Error())
@@ -311,9 +400,20 @@ func (c *Client) SetLogLevel(level LogLevel) {
 
 // IsConnected 检查客户端是否已连接
 func (c *Client) IsConnected() bool {
+	if c.v5 != nil {
+		return c.v5.isConnected()
+	}
 	return c.client.IsConnected()
 }
 
+// ProtocolVersion 返回客户端使用的协议版本
+func (c *Client) ProtocolVersion() int {
+	if c.v5 != nil {
+		return ProtocolV5
+	}
+	return ProtocolV311
+}
+
 // 内部方法
 
 func (c *Client) log(level LogLevel, format string, v ...interface{}) {
@@ -343,6 +443,26 @@ func (c *Client) onConnected(client paho.Client) {
 			c.log(LogLevelError, "Failed to resubscribe topic %s: %v", handler.Topic, err)
 		}
 	}
+	go c.drainOffline()
+}
+
+// handlerList 返回已注册处理器的副本，调用方需持有锁
+func (c *Client) handlerList() []Handler {
+	handlers := make([]Handler, 0, len(c.msgHandlerMap))
+	for _, handler := range c.msgHandlerMap {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
+func (c *Client) unsubscribe(topic string) error {
+	if c.v5 != nil {
+		return c.v5.unsubscribe(c.ctx, topic)
+	}
+	if token := c.client.Unsubscribe(topic); token.Wait() && token.Error() != nil {
+		return fmt.Errorf("%w: %v", ErrUnsubscribeFailed, token.Error())
+	}
+	return nil
 }
 
 func (c *Client) onConnectionLost(client paho.Client, err error) {
@@ -403,6 +523,14 @@ func validateConfig(conf *Config) error {
 		return errors.New("server address is required")
 	}
 
+	switch conf.ProtocolVersion {
+	case 0:
+		conf.ProtocolVersion = ProtocolV311
+	case ProtocolV311, ProtocolV5:
+	default:
+		return fmt.Errorf("unsupported protocol version %d", conf.ProtocolVersion)
+	}
+
 	if conf.QueueSize < 0 {
 		conf.QueueSize = DefaultQueueSize
 	}
@@ -0,0 +1,304 @@
+package mqttclient
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	paho5 "github.com/eclipse/paho.golang/paho"
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// 协议版本
+const (
+	ProtocolV311 = 4 // MQTT 3.1.1，默认
+	ProtocolV5   = 5 // MQTT 5
+)
+
+const sharePrefix = "$share/"
+
+// SharedTopic 生成共享订阅主题 $share/{group}/{filter}，同一分组内的客户端轮流接收消息
+func SharedTopic(group, filter string) string {
+	if group == "" {
+		return filter
+	}
+	return sharePrefix + group + "/" + filter
+}
+
+// topicFilter 去掉共享订阅前缀，返回实际的主题过滤器
+func topicFilter(topic string) string {
+	if !strings.HasPrefix(topic, sharePrefix) {
+		return topic
+	}
+	parts := strings.SplitN(topic, "/", 3)
+	if len(parts) < 3 {
+		return topic
+	}
+	return parts[2]
+}
+
+// topicMatch 判断主题是否匹配订阅过滤器，支持 + 和 # 通配符以及共享订阅
+func topicMatch(filter, topic string) bool {
+	route := strings.Split(topicFilter(filter), "/")
+	levels := strings.Split(topic, "/")
+	for i, level := range route {
+		if level == "#" {
+			return true
+		}
+		if i >= len(levels) {
+			return false
+		}
+		if level != "+" && level != levels[i] {
+			return false
+		}
+	}
+	return len(route) == len(levels)
+}
+
+// Properties MQTT 5 消息属性
+type Properties struct {
+	ResponseTopic   string            // 响应主题
+	CorrelationData []byte            // 关联数据
+	ContentType     string            // 内容类型
+	MessageExpiry   time.Duration     // 消息过期时间，为 0 时不过期
+	User            map[string]string // 用户属性
+}
+
+// Message MQTT 5 消息，实现 paho.Message 接口，可以直接交给 Handler 处理
+type Message struct {
+	topic      string
+	payload    []byte
+	qos        byte
+	retained   bool
+	duplicate  bool
+	messageID  uint16
+	properties *Properties
+}
+
+func (m *Message) Duplicate() bool         { return m.duplicate }
+func (m *Message) Qos() byte               { return m.qos }
+func (m *Message) Retained() bool          { return m.retained }
+func (m *Message) Topic() string           { return m.topic }
+func (m *Message) MessageID() uint16       { return m.messageID }
+func (m *Message) Payload() []byte         { return m.payload }
+func (m *Message) Ack()                    {}
+func (m *Message) Properties() *Properties { return m.properties }
+
+// PropertiesOf 获取消息的 MQTT 5 属性，MQTT 3.1.1 的消息返回 nil
+func PropertiesOf(msg paho.Message) *Properties {
+	if m, ok := msg.(*Message); ok {
+		return m.properties
+	}
+	return nil
+}
+
+func newMessage(p *paho5.Publish) *Message {
+	m := &Message{
+		topic:     p.Topic,
+		payload:   p.Payload,
+		qos:       p.QoS,
+		retained:  p.Retain,
+		duplicate: p.Duplicate(),
+		messageID: p.PacketID,
+	}
+	if p.Properties != nil {
+		props := &Properties{
+			ResponseTopic:   p.Properties.ResponseTopic,
+			CorrelationData: p.Properties.CorrelationData,
+			ContentType:     p.Properties.ContentType,
+		}
+		if p.Properties.MessageExpiry != nil {
+			props.MessageExpiry = time.Duration(*p.Properties.MessageExpiry) * time.Second
+		}
+		if len(p.Properties.User) > 0 {
+			props.User = make(map[string]string, len(p.Properties.User))
+			for _, u := range p.Properties.User {
+				props.User[u.Key] = u.Value
+			}
+		}
+		m.properties = props
+	}
+	return m
+}
+
+// ReasonCodeError MQTT 5 服务端返回的失败原因码（>= 0x80）
+type ReasonCodeError struct {
+	Code   byte
+	Reason string
+}
+
+func (e *ReasonCodeError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("mqtt reason code 0x%02x: %s", e.Code, e.Reason)
+	}
+	return fmt.Sprintf("mqtt reason code 0x%02x", e.Code)
+}
+
+// v5Conn 基于 autopaho 的 MQTT 5 连接，断线后自动重连并重新订阅
+type v5Conn struct {
+	cm        *autopaho.ConnectionManager
+	connected atomic.Bool
+	handlers  atomic.Pointer[[]Handler] // 处理器快照，收到消息时无需获取客户端的锁
+	logf      func(level LogLevel, format string, v ...interface{})
+	onUp      func()      // 连接建立并完成重新订阅后调用
+	client    paho.Client // 传给处理函数的客户端适配器
+}
+
+// newV5Conn 建立 MQTT 5 连接，等待初次连接时使用 connectCtx，连接在 ctx 取消后关闭
+func newV5Conn(connectCtx, ctx context.Context, c *Client, conf Config) (*v5Conn, error) {
+	server, err := url.Parse(conf.Server)
+	if err != nil {
+		return nil, fmt.Errorf("parse server url: %w", err)
+	}
+	tlsConfig, err := newTLSConfig(conf.CAFile, conf.CertFile, conf.CertKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls config error: %w", err)
+	}
+
+	conn := &v5Conn{logf: c.log, onUp: c.drainOffline, client: &v5Client{c: c}}
+	conn.setHandlers(nil)
+	cfg := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{server},
+		TlsCfg:                        tlsConfig,
+		KeepAlive:                     30,
+		CleanStartOnInitialConnection: conf.CleanSession,
+		SessionExpiryInterval:         conf.SessionExpiryInterval,
+		ReconnectBackoff: func(attempt int) time.Duration {
+			delay := time.Duration(attempt+1) * 2 * time.Second
+			if max := c.getReconnectInterval(conf.MaxReconnectInterval); delay > max {
+				return max
+			}
+			return delay
+		},
+		ConnectUsername: conf.Username,
+		ConnectPassword: []byte(conf.Password),
+		OnConnectionUp:  conn.onConnectionUp,
+		OnConnectError: func(err error) {
+			c.log(LogLevelWarn, "Connection failed, retrying: %v", err)
+		},
+		ClientConfig: paho5.ClientConfig{
+			ClientID:          c.getClientID(conf.ClientID),
+			OnPublishReceived: []func(paho5.PublishReceived) (bool, error){conn.onPublishReceived},
+			OnClientError: func(err error) {
+				conn.connected.Store(false)
+				c.log(LogLevelWarn, "Connection lost: %v", err)
+			},
+			OnServerDisconnect: func(d *paho5.Disconnect) {
+				conn.connected.Store(false)
+				c.log(LogLevelWarn, "Disconnected by server, reason code 0x%02x", d.ReasonCode)
+			},
+		},
+	}
+
+	conn.cm, err = autopaho.NewConnection(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	if err = conn.cm.AwaitConnection(connectCtx); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectionFailed, err)
+	}
+	return conn, nil
+}
+
+func (v *v5Conn) setHandlers(handlers []Handler) {
+	v.handlers.Store(&handlers)
+}
+
+func (v *v5Conn) onConnectionUp(cm *autopaho.ConnectionManager, _ *paho5.Connack) {
+	v.connected.Store(true)
+	v.logf(LogLevelInfo, "Connected to MQTT broker")
+
+	// 重新订阅所有主题
+	for _, handler := range *v.handlers.Load() {
+		if err := v.subscribe(context.Background(), handler); err != nil {
+			v.logf(LogLevelError, "Failed to resubscribe topic %s: %v", handler.Topic, err)
+		}
+	}
+	go v.onUp()
+}
+
+// onPublishReceived 将消息分发给所有匹配的处理器
+func (v *v5Conn) onPublishReceived(pr paho5.PublishReceived) (bool, error) {
+	msg := newMessage(pr.Packet)
+	handled := false
+	for _, handler := range *v.handlers.Load() {
+		if topicMatch(handler.Topic, msg.topic) {
+			handler.Handle(v.client, msg)
+			handled = true
+		}
+	}
+	return handled, nil
+}
+
+func (v *v5Conn) subscribe(ctx context.Context, handler Handler) error {
+	suback, err := v.cm.Subscribe(ctx, &paho5.Subscribe{
+		Subscriptions: []paho5.SubscribeOptions{{Topic: handler.Topic, QoS: handler.Qos}},
+	})
+	if err != nil {
+		if suback != nil && len(suback.Reasons) > 0 && suback.Reasons[0] >= 0x80 {
+			e := &ReasonCodeError{Code: suback.Reasons[0]}
+			if suback.Properties != nil {
+				e.Reason = suback.Properties.ReasonString
+			}
+			return fmt.Errorf("%w: %w", ErrSubscriptionFailed, e)
+		}
+		return fmt.Errorf("%w: %v", ErrSubscriptionFailed, err)
+	}
+	return nil
+}
+
+func (v *v5Conn) unsubscribe(ctx context.Context, topic string) error {
+	if _, err := v.cm.Unsubscribe(ctx, &paho5.Unsubscribe{Topics: []string{topic}}); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsubscribeFailed, err)
+	}
+	return nil
+}
+
+func (v *v5Conn) publish(ctx context.Context, topic string, qos byte, data []byte, o *publishOptions) error {
+	p := &paho5.Publish{
+		Topic:   topic,
+		QoS:     qos,
+		Retain:  o.retain,
+		Payload: data,
+		Properties: &paho5.PublishProperties{
+			ResponseTopic:   o.responseTopic,
+			CorrelationData: o.correlationData,
+			ContentType:     o.contentType,
+		},
+	}
+	if o.messageExpiry > 0 {
+		expiry := uint32(o.messageExpiry / time.Second)
+		p.Properties.MessageExpiry = &expiry
+	}
+	for _, u := range o.userProperties {
+		p.Properties.User.Add(u[0], u[1])
+	}
+
+	resp, err := v.cm.Publish(ctx, p)
+	if resp != nil && resp.ReasonCode >= 0x80 {
+		e := &ReasonCodeError{Code: resp.ReasonCode}
+		if resp.Properties != nil {
+			e.Reason = resp.Properties.ReasonString
+		}
+		return fmt.Errorf("%w: %w", ErrPublishFailed, e)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPublishFailed, err)
+	}
+	return nil
+}
+
+func (v *v5Conn) isConnected() bool {
+	return v.connected.Load()
+}
+
+func (v *v5Conn) disconnect(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_ = v.cm.Disconnect(ctx)
+	v.connected.Store(false)
+}
@@ -0,0 +1,108 @@
+package mqttclient
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// v5Client 为 MQTT 5 连接实现 paho.Client，作为消息处理函数的第一个参数和 GetClient 的返回值，
+// 使按 MQTT 3.1.1 编写的处理函数无需修改即可使用。所有操作都转发到 Client 上执行：
+// Publish 经过离线缓冲，Subscribe 和 AddRoute 等同于 RegisterHandler，Unsubscribe 等同于 UnregisterHandler，
+// Disconnect 等同于 Close，连接由客户端自动维护，Connect 不做任何操作
+type v5Client struct {
+	c *Client
+}
+
+var _ paho.Client = (*v5Client)(nil)
+
+func (a *v5Client) IsConnected() bool {
+	return a.c.IsConnected()
+}
+
+func (a *v5Client) IsConnectionOpen() bool {
+	return a.c.connectionOpen()
+}
+
+func (a *v5Client) Connect() paho.Token {
+	return newDoneToken(nil)
+}
+
+func (a *v5Client) Disconnect(uint) {
+	_ = a.c.Close()
+}
+
+func (a *v5Client) Publish(topic string, qos byte, retained bool, payload interface{}) paho.Token {
+	var data []byte
+	switch p := payload.(type) {
+	case string:
+		data = []byte(p)
+	case []byte:
+		data = p
+	case bytes.Buffer:
+		data = p.Bytes()
+	case *bytes.Buffer:
+		data = p.Bytes()
+	default:
+		return newDoneToken(errors.New("unknown payload type"))
+	}
+	return newDoneToken(a.c.PublishWithOptions(a.c.ctx, topic, qos, data, WithRetain(retained)))
+}
+
+func (a *v5Client) Subscribe(topic string, qos byte, callback paho.MessageHandler) paho.Token {
+	return newDoneToken(a.c.RegisterHandler(Handler{Topic: topic, Qos: qos, Handle: callback}))
+}
+
+func (a *v5Client) SubscribeMultiple(filters map[string]byte, callback paho.MessageHandler) paho.Token {
+	for topic, qos := range filters {
+		if err := a.c.RegisterHandler(Handler{Topic: topic, Qos: qos, Handle: callback}); err != nil {
+			return newDoneToken(err)
+		}
+	}
+	return newDoneToken(nil)
+}
+
+func (a *v5Client) Unsubscribe(topics ...string) paho.Token {
+	for _, topic := range topics {
+		if err := a.c.UnregisterHandler(topic); err != nil {
+			return newDoneToken(err)
+		}
+	}
+	return newDoneToken(nil)
+}
+
+func (a *v5Client) AddRoute(topic string, callback paho.MessageHandler) {
+	if err := a.c.RegisterHandler(Handler{Topic: topic, Handle: callback}); err != nil {
+		a.c.log(LogLevelError, "Failed to add route for topic %s: %v", topic, err)
+	}
+}
+
+func (a *v5Client) OptionsReader() paho.ClientOptionsReader {
+	conf := a.c.config
+	opts := paho.NewClientOptions()
+	opts.AddBroker(conf.Server)
+	opts.SetUsername(conf.Username)
+	opts.SetPassword(conf.Password)
+	opts.SetCleanSession(conf.CleanSession)
+	opts.SetClientID(a.c.getClientID(conf.ClientID))
+	return paho.NewOptionsReader(opts)
+}
+
+// doneToken 已完成的 paho.Token，MQTT 5 连接上的操作在返回前已经完成
+type doneToken struct {
+	err  error
+	done chan struct{}
+}
+
+func newDoneToken(err error) *doneToken {
+	done := make(chan struct{})
+	close(done)
+	return &doneToken{err: err, done: done}
+}
+
+func (t *doneToken) Wait() bool                     { return true }
+func (t *doneToken) WaitTimeout(time.Duration) bool { return true }
+func (t *doneToken) Done() <-chan struct{}          { return t.done }
+func (t *doneToken) Error() error                   { return t.err }
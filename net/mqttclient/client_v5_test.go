@@ -0,0 +1,91 @@
+package mqttclient
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	paho5 "github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopicMatch(t *testing.T) {
+	assert.Equal(t, "$share/g1/device/+/status", SharedTopic("g1", "device/+/status"))
+	assert.Equal(t, "device/+/status", SharedTopic("", "device/+/status"))
+
+	cases := []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"device/+/status", "device/d1/status", true},
+		{"$share/g1/device/+/status", "device/d1/status", true},
+		{"device/#", "device", true},
+		{"device/#", "device/d1/event/alarm", true},
+		{"device/+", "device/d1/status", false},
+		{"device/d1", "device/d2", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.match, topicMatch(c.filter, c.topic), c.filter+" "+c.topic)
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	expiry := uint32(30)
+	p := &paho5.Publish{
+		Topic:   "device/d1/status",
+		QoS:     1,
+		Payload: []byte("on"),
+		Properties: &paho5.PublishProperties{
+			ResponseTopic:   "reply/d1",
+			CorrelationData: []byte("c1"),
+			MessageExpiry:   &expiry,
+			User:            paho5.UserProperties{{Key: "tenant", Value: "t1"}},
+		},
+	}
+	msg := newMessage(p)
+	assert.Equal(t, "device/d1/status", msg.Topic())
+	assert.Equal(t, []byte("on"), msg.Payload())
+
+	props := PropertiesOf(msg)
+	if assert.NotNil(t, props) {
+		assert.Equal(t, "reply/d1", props.ResponseTopic)
+		assert.Equal(t, []byte("c1"), props.CorrelationData)
+		assert.Equal(t, 30*time.Second, props.MessageExpiry)
+		assert.Equal(t, "t1", props.User["tenant"])
+	}
+}
+
+func TestPublishOptions(t *testing.T) {
+	o := newPublishOptions([]PublishOption{
+		WithRetain(true),
+		WithResponseTopic("reply"),
+		WithCorrelationData([]byte("c1")),
+		WithUserProperty("a", "1"),
+		WithUserProperty("b", "2"),
+		WithMessageExpiry(time.Minute),
+	})
+	assert.True(t, o.retain)
+	assert.Equal(t, "reply", o.responseTopic)
+	assert.Equal(t, [][2]string{{"a", "1"}, {"b", "2"}}, o.userProperties)
+	assert.Equal(t, time.Minute, o.messageExpiry)
+}
+
+func TestReasonCodeError(t *testing.T) {
+	err := fmt.Errorf("%w: %w", ErrPublishFailed, &ReasonCodeError{Code: 0x87, Reason: "not authorized"})
+	assert.ErrorIs(t, err, ErrPublishFailed)
+
+	var rc *ReasonCodeError
+	assert.True(t, errors.As(err, &rc))
+	assert.Equal(t, byte(0x87), rc.Code)
+	assert.Contains(t, err.Error(), "not authorized")
+}
+
+func TestValidateProtocolVersion(t *testing.T) {
+	conf := Config{Server: "tcp://127.0.0.1:1883"}
+	assert.NoError(t, validateConfig(&conf))
+	assert.Equal(t, ProtocolV311, conf.ProtocolVersion)
+
+	conf = Config{Server: "tcp://127.0.0.1:1883", ProtocolVersion: 3}
+	assert.Error(t, validateConfig(&conf))
+}
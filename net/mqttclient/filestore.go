@@ -0,0 +1,202 @@
+package mqttclient
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	fileStoreLog  = "offline.log"  // 消息记录，每条记录为 4 字节长度 + JSON
+	fileStoreHead = "offline.head" // 已发布到的位置
+)
+
+// FileStore 基于磁盘文件的存储，进程重启后继续发布未完成的消息。
+// 消息追加写入日志文件，发布成功后只移动读取位置，缓冲区清空时截断文件。
+type FileStore struct {
+	mu      sync.Mutex
+	log     *os.File
+	head    *os.File
+	records []fileRecord // 未发布记录的位置
+	front   *OfflineMessage
+	size    int64
+}
+
+type fileRecord struct {
+	offset int64
+	length int64 // 含长度前缀
+	size   int64 // 消息占用的字节数
+}
+
+// NewFileStore 在目录 dir 中打开或创建文件存储，并加载上次未发布的消息
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store dir: %w", err)
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, fileStoreLog), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open store log: %w", err)
+	}
+	headFile, err := os.OpenFile(filepath.Join(dir, fileStoreHead), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		_ = logFile.Close()
+		return nil, fmt.Errorf("open store head: %w", err)
+	}
+
+	s := &FileStore{log: logFile, head: headFile}
+	if err = s.load(); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load 从读取位置扫描日志文件，丢弃末尾不完整的记录
+func (s *FileStore) load() error {
+	var buf [8]byte
+	offset := int64(0)
+	if n, _ := s.head.ReadAt(buf[:], 0); n == len(buf) {
+		offset = int64(binary.BigEndian.Uint64(buf[:]))
+	}
+
+	info, err := s.log.Stat()
+	if err != nil {
+		return err
+	}
+	for offset < info.Size() {
+		msg, length, err := s.readAt(offset)
+		if err != nil {
+			// 写入过程中退出导致的不完整记录
+			if err = s.log.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+		s.records = append(s.records, fileRecord{offset: offset, length: length, size: msg.size()})
+		s.size += msg.size()
+		offset += length
+	}
+	if len(s.records) == 0 {
+		return s.reset()
+	}
+	_, err = s.log.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (s *FileStore) readAt(offset int64) (*OfflineMessage, int64, error) {
+	var prefix [4]byte
+	if _, err := s.log.ReadAt(prefix[:], offset); err != nil {
+		return nil, 0, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(prefix[:]))
+	if _, err := s.log.ReadAt(data, offset+int64(len(prefix))); err != nil {
+		return nil, 0, err
+	}
+	msg := &OfflineMessage{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, 0, err
+	}
+	return msg, int64(len(prefix) + len(data)), nil
+}
+
+// reset 清空日志文件和读取位置
+func (s *FileStore) reset() error {
+	if err := s.log.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.records = nil
+	s.front = nil
+	s.size = 0
+	return s.writeHead(0)
+}
+
+func (s *FileStore) writeHead(offset int64) error {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(offset))
+	_, err := s.head.WriteAt(buf[:], 0)
+	return err
+}
+
+func (s *FileStore) Push(msg *OfflineMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	record := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	copy(record[4:], data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset, err := s.log.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err = s.log.Write(record); err != nil {
+		return err
+	}
+	s.records = append(s.records, fileRecord{offset: offset, length: int64(len(record)), size: msg.size()})
+	s.size += msg.size()
+	return nil
+}
+
+func (s *FileStore) Peek() (*OfflineMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.records) == 0 {
+		return nil, false, nil
+	}
+	if s.front == nil {
+		msg, _, err := s.readAt(s.records[0].offset)
+		if err != nil {
+			return nil, false, err
+		}
+		s.front = msg
+	}
+	return s.front, true, nil
+}
+
+func (s *FileStore) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.records) == 0 {
+		return nil
+	}
+	first := s.records[0]
+	s.records = s.records[1:]
+	s.front = nil
+	s.size -= first.size
+	if len(s.records) == 0 {
+		return s.reset()
+	}
+	return s.writeHead(first.offset + first.length)
+}
+
+func (s *FileStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+func (s *FileStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.log.Close()
+	if e := s.head.Close(); err == nil {
+		err = e
+	}
+	return err
+}
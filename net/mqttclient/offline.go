@@ -0,0 +1,364 @@
+package mqttclient
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrBufferFull     = errors.New("offline buffer is full")
+	ErrMessageExpired = errors.New("offline message expired")
+)
+
+// OfflineOverflow 离线缓冲区已满时的策略
+type OfflineOverflow string
+
+const (
+	OfflineDropOldest OfflineOverflow = "drop_oldest" // 丢弃最早的消息，保留最新的数据，默认
+	OfflineDropNewest OfflineOverflow = "drop_newest" // 丢弃新消息，发布时返回 ErrBufferFull
+)
+
+// OfflineMessage 断开连接期间缓存的待发布消息
+type OfflineMessage struct {
+	Topic      string      `json:"topic"`
+	Qos        byte        `json:"qos"`
+	Retained   bool        `json:"retained"`
+	Payload    []byte      `json:"payload"`
+	Properties *Properties `json:"properties,omitempty"` // MQTT 5 属性
+	Timestamp  time.Time   `json:"timestamp"`            // 进入缓冲区的时间
+}
+
+// size 消息占用的字节数，用于容量限制
+func (m *OfflineMessage) size() int64 {
+	return int64(len(m.Topic) + len(m.Payload))
+}
+
+// expired 判断 MQTT 5 消息在缓存期间是否已经过期
+func (m *OfflineMessage) expired(now time.Time) bool {
+	return m.Properties != nil && m.Properties.MessageExpiry > 0 && now.Sub(m.Timestamp) >= m.Properties.MessageExpiry
+}
+
+// options 还原发布参数，MQTT 5 的剩余过期时间扣除已缓存的时长
+func (m *OfflineMessage) options(now time.Time) *publishOptions {
+	o := &publishOptions{retain: m.Retained}
+	if p := m.Properties; p != nil {
+		o.responseTopic = p.ResponseTopic
+		o.correlationData = p.CorrelationData
+		o.contentType = p.ContentType
+		if p.MessageExpiry > 0 {
+			o.messageExpiry = p.MessageExpiry - now.Sub(m.Timestamp)
+		}
+		for k, v := range p.User {
+			o.userProperties = append(o.userProperties, [2]string{k, v})
+		}
+	}
+	return o
+}
+
+// OfflineStore 离线消息存储，按先进先出的顺序保存消息。
+// 内置内存环形缓冲区 NewMemoryStore 和磁盘文件存储 NewFileStore。
+type OfflineStore interface {
+	Push(msg *OfflineMessage) error       // 追加消息，存储已满时返回 ErrBufferFull
+	Peek() (*OfflineMessage, bool, error) // 获取最早的消息但不移除
+	Pop() error                           // 移除最早的消息
+	Len() int                             // 消息数量
+	Size() int64                          // 消息占用的字节数
+	Close() error
+}
+
+// OfflineConfig 离线缓冲配置，只缓存 QoS 1 和 QoS 2 的消息
+type OfflineConfig struct {
+	Store       OfflineStore                            // 消息存储，为 nil 时使用容量为 MaxMessages 的内存存储
+	MaxMessages int                                     // 最多缓存的消息数，为 0 时仅受存储自身容量限制
+	MaxBytes    int64                                   // 最多缓存的字节数，为 0 时不限制
+	Overflow    OfflineOverflow                         // 缓冲区已满时的策略
+	OnDrop      func(msg *OfflineMessage, reason error) // 消息被丢弃时的回调，reason 为 ErrBufferFull 或 ErrMessageExpired
+}
+
+// offlineBuffer 在存储之上实现容量限制和溢出策略
+type offlineBuffer struct {
+	mu       sync.Mutex
+	config   OfflineConfig
+	draining atomic.Bool
+}
+
+func newOfflineBuffer(config *OfflineConfig) *offlineBuffer {
+	c := *config
+	if c.Store == nil {
+		capacity := c.MaxMessages
+		if capacity <= 0 {
+			capacity = DefaultQueueSize
+		}
+		c.Store = NewMemoryStore(capacity)
+	}
+	if c.Overflow == "" {
+		c.Overflow = OfflineDropOldest
+	}
+	return &offlineBuffer{config: c}
+}
+
+// full 判断加入 msg 后是否超出限制
+func (b *offlineBuffer) full(msg *OfflineMessage) bool {
+	store := b.config.Store
+	if b.config.MaxMessages > 0 && store.Len() >= b.config.MaxMessages {
+		return true
+	}
+	return b.config.MaxBytes > 0 && store.Len() > 0 && store.Size()+msg.size() > b.config.MaxBytes
+}
+
+// push 加入消息，超出限制时按溢出策略丢弃消息，丢弃回调在释放锁之后调用
+func (b *offlineBuffer) push(msg *OfflineMessage) error {
+	dropped, err := b.pushLocked(msg)
+	for _, m := range dropped {
+		b.drop(m, ErrBufferFull)
+	}
+	return err
+}
+
+func (b *offlineBuffer) pushLocked(msg *OfflineMessage) ([]*OfflineMessage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var dropped []*OfflineMessage
+	store := b.config.Store
+	for {
+		if !b.full(msg) {
+			err := store.Push(msg)
+			if !errors.Is(err, ErrBufferFull) {
+				return dropped, err
+			}
+			if store.Len() == 0 {
+				// 单条消息超出存储容量
+				return append(dropped, msg), ErrBufferFull
+			}
+		}
+		if b.config.Overflow == OfflineDropNewest {
+			return append(dropped, msg), ErrBufferFull
+		}
+
+		oldest, ok, err := store.Peek()
+		if err != nil {
+			return dropped, err
+		}
+		if !ok {
+			return append(dropped, msg), ErrBufferFull
+		}
+		if err = store.Pop(); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, oldest)
+	}
+}
+
+func (b *offlineBuffer) peek() (*OfflineMessage, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config.Store.Peek()
+}
+
+// pop 移除已发布的消息，如果它在发布期间已被溢出策略移除则不做处理
+func (b *offlineBuffer) pop(msg *OfflineMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	front, ok, err := b.config.Store.Peek()
+	if err != nil || !ok || front != msg {
+		return err
+	}
+	return b.config.Store.Pop()
+}
+
+func (b *offlineBuffer) pending() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config.Store.Len() > 0
+}
+
+func (b *offlineBuffer) drop(msg *OfflineMessage, reason error) {
+	if b.config.OnDrop != nil {
+		b.config.OnDrop(msg, reason)
+	}
+}
+
+func (b *offlineBuffer) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.config.Store.Close()
+}
+
+// MemoryStore 基于环形缓冲区的内存存储，进程退出后消息丢失
+type MemoryStore struct {
+	mu       sync.Mutex
+	messages []*OfflineMessage
+	head     int
+	count    int
+	size     int64
+}
+
+// NewMemoryStore 创建容量为 capacity 条消息的内存存储
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = DefaultQueueSize
+	}
+	return &MemoryStore{messages: make([]*OfflineMessage, capacity)}
+}
+
+func (s *MemoryStore) Push(msg *OfflineMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == len(s.messages) {
+		return ErrBufferFull
+	}
+	s.messages[(s.head+s.count)%len(s.messages)] = msg
+	s.count++
+	s.size += msg.size()
+	return nil
+}
+
+func (s *MemoryStore) Peek() (*OfflineMessage, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return nil, false, nil
+	}
+	return s.messages[s.head], true, nil
+}
+
+func (s *MemoryStore) Pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return nil
+	}
+	s.size -= s.messages[s.head].size()
+	s.messages[s.head] = nil
+	s.head = (s.head + 1) % len(s.messages)
+	s.count--
+	return nil
+}
+
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *MemoryStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// connectionOpen 判断连接当前是否可用。
+// paho 3.1.1 客户端在自动重连期间 IsConnected 也返回 true，这里需要实际的连接状态。
+func (c *Client) connectionOpen() bool {
+	if c.v5 != nil {
+		return c.v5.isConnected()
+	}
+	return c.client.IsConnectionOpen()
+}
+
+// bufferMessage 将消息放入离线缓冲区，连接可用时触发发布
+func (c *Client) bufferMessage(topic string, qos byte, data []byte, o *publishOptions) error {
+	msg := &OfflineMessage{
+		Topic:     topic,
+		Qos:       qos,
+		Retained:  o.retain,
+		Payload:   data,
+		Timestamp: time.Now(),
+	}
+	if c.v5 != nil && (o.responseTopic != "" || o.correlationData != nil || o.contentType != "" || o.messageExpiry > 0 || len(o.userProperties) > 0) {
+		msg.Properties = &Properties{
+			ResponseTopic:   o.responseTopic,
+			CorrelationData: o.correlationData,
+			ContentType:     o.contentType,
+			MessageExpiry:   o.messageExpiry,
+		}
+		if len(o.userProperties) > 0 {
+			msg.Properties.User = make(map[string]string, len(o.userProperties))
+			for _, u := range o.userProperties {
+				msg.Properties.User[u[0]] = u[1]
+			}
+		}
+	}
+
+	if err := c.offline.push(msg); err != nil {
+		c.log(LogLevelWarn, "Failed to buffer message for topic %s: %v", topic, err)
+		return fmt.Errorf("%w: %w", ErrPublishFailed, err)
+	}
+	c.log(LogLevelDebug, "Message buffered for topic: %s", topic)
+	if c.connectionOpen() {
+		go c.drainOffline()
+	}
+	return nil
+}
+
+// drainOffline 按顺序发布离线缓冲区中的消息，发布失败时停止，等待下次重连。
+// 同一时间只有一个协程在发布，退出前再次检查以免遗漏期间新加入的消息。
+func (c *Client) drainOffline() {
+	if c.offline == nil {
+		return
+	}
+	for {
+		if !c.offline.draining.CompareAndSwap(false, true) {
+			return
+		}
+		ok := c.drainOnce()
+		c.offline.draining.Store(false)
+		if !ok || !c.connectionOpen() || !c.offline.pending() {
+			return
+		}
+	}
+}
+
+// drainOnce 发布缓冲区中的全部消息，全部发布成功时返回 true
+func (c *Client) drainOnce() bool {
+	for c.connectionOpen() {
+		msg, ok, err := c.offline.peek()
+		if err != nil {
+			c.log(LogLevelError, "Failed to read offline message: %v", err)
+			return false
+		}
+		if !ok {
+			return true
+		}
+
+		now := time.Now()
+		if msg.expired(now) {
+			c.offline.drop(msg, ErrMessageExpired)
+		} else if err = c.publish(c.ctx, msg.Topic, msg.Qos, msg.Payload, msg.options(now)); err != nil {
+			c.log(LogLevelWarn, "Failed to publish offline message to topic %s: %v", msg.Topic, err)
+			return false
+		}
+		if err = c.offline.pop(msg); err != nil {
+			c.log(LogLevelError, "Failed to remove offline message: %v", err)
+			return false
+		}
+	}
+	return false
+}
+
+// OfflineLen 返回离线缓冲区中等待发布的消息数
+func (c *Client) OfflineLen() int {
+	if c.offline == nil {
+		return 0
+	}
+	c.offline.mu.Lock()
+	defer c.offline.mu.Unlock()
+	return c.offline.config.Store.Len()
+}
+
+func (c *Client) closeOffline() {
+	if c.offline == nil {
+		return
+	}
+	if err := c.offline.close(); err != nil {
+		c.log(LogLevelWarn, "Failed to close offline store: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package mqttclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func offlineMessage(topic string) *OfflineMessage {
+	return &OfflineMessage{Topic: topic, Qos: 1, Payload: []byte("data"), Timestamp: time.Now()}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(2)
+	assert.NoError(t, s.Push(offlineMessage("a")))
+	assert.NoError(t, s.Push(offlineMessage("b")))
+	assert.ErrorIs(t, s.Push(offlineMessage("c")), ErrBufferFull)
+
+	msg, ok, _ := s.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "a", msg.Topic)
+	assert.NoError(t, s.Pop())
+	assert.NoError(t, s.Push(offlineMessage("c")))
+
+	var topics []string
+	for s.Len() > 0 {
+		msg, _, _ = s.Peek()
+		topics = append(topics, msg.Topic)
+		_ = s.Pop()
+	}
+	assert.Equal(t, []string{"b", "c"}, topics)
+	assert.Equal(t, int64(0), s.Size())
+}
+
+func TestOfflineBufferOverflow(t *testing.T) {
+	var dropped []string
+	onDrop := func(msg *OfflineMessage, reason error) {
+		assert.ErrorIs(t, reason, ErrBufferFull)
+		dropped = append(dropped, msg.Topic)
+	}
+
+	b := newOfflineBuffer(&OfflineConfig{MaxMessages: 2, OnDrop: onDrop})
+	for _, topic := range []string{"a", "b", "c"} {
+		assert.NoError(t, b.push(offlineMessage(topic)))
+	}
+	assert.Equal(t, []string{"a"}, dropped)
+	msg, _, _ := b.peek()
+	assert.Equal(t, "b", msg.Topic)
+
+	dropped = nil
+	b = newOfflineBuffer(&OfflineConfig{MaxMessages: 2, Overflow: OfflineDropNewest, OnDrop: onDrop})
+	for _, topic := range []string{"a", "b"} {
+		assert.NoError(t, b.push(offlineMessage(topic)))
+	}
+	assert.ErrorIs(t, b.push(offlineMessage("c")), ErrBufferFull)
+	assert.Equal(t, []string{"c"}, dropped)
+
+	// 按字节数限制，每条消息 5 字节
+	dropped = nil
+	b = newOfflineBuffer(&OfflineConfig{MaxMessages: 10, MaxBytes: 10, OnDrop: onDrop})
+	for _, topic := range []string{"a", "b", "c"} {
+		assert.NoError(t, b.push(offlineMessage(topic)))
+	}
+	assert.Equal(t, []string{"a"}, dropped)
+
+	// 发布期间被溢出策略移除的消息不再重复移除
+	msg, _, _ = b.peek()
+	assert.NoError(t, b.push(offlineMessage("d")))
+	assert.NoError(t, b.pop(msg))
+	assert.Equal(t, 2, b.config.Store.Len())
+}
+
+func TestOfflineMessageExpiry(t *testing.T) {
+	msg := offlineMessage("a")
+	msg.Timestamp = time.Now().Add(-20 * time.Second)
+	msg.Properties = &Properties{MessageExpiry: time.Minute, User: map[string]string{"k": "v"}}
+
+	assert.False(t, msg.expired(time.Now()))
+	o := msg.options(time.Now())
+	assert.InDelta(t, float64(40*time.Second), float64(o.messageExpiry), float64(time.Second))
+	assert.Equal(t, [][2]string{{"k", "v"}}, o.userProperties)
+	assert.True(t, msg.expired(time.Now().Add(time.Minute)))
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, topic := range []string{"a", "b", "c"} {
+		assert.NoError(t, s.Push(offlineMessage(topic)))
+	}
+	assert.NoError(t, s.Pop())
+	assert.NoError(t, s.Close())
+
+	// 重新打开后从上次的位置继续，末尾不完整的记录被丢弃
+	f, err := os.OpenFile(filepath.Join(dir, fileStoreLog), os.O_APPEND|os.O_WRONLY, 0o644)
+	assert.NoError(t, err)
+	_, _ = f.Write([]byte{0, 0, 1})
+	_ = f.Close()
+
+	s, err = NewFileStore(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, int64(10), s.Size())
+
+	msg, ok, err := s.Peek()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", msg.Topic)
+	assert.Equal(t, []byte("data"), msg.Payload)
+
+	assert.NoError(t, s.Push(offlineMessage("d")))
+	var topics []string
+	for s.Len() > 0 {
+		msg, _, _ = s.Peek()
+		topics = append(topics, msg.Topic)
+		assert.NoError(t, s.Pop())
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, topics)
+
+	info, err := os.Stat(filepath.Join(dir, fileStoreLog))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+}
@@ -0,0 +1,66 @@
+package mqttclient
+
+import "time"
+
+// PublishOption 发布消息的可选参数。
+// 除 WithRetain 外均为 MQTT 5 属性，使用 MQTT 3.1.1 时会被忽略。
+type PublishOption func(*publishOptions)
+
+type publishOptions struct {
+	retain          bool
+	responseTopic   string
+	correlationData []byte
+	contentType     string
+	messageExpiry   time.Duration
+	userProperties  [][2]string
+}
+
+// WithRetain 设置保留消息标志
+func WithRetain(retain bool) PublishOption {
+	return func(o *publishOptions) {
+		o.retain = retain
+	}
+}
+
+// WithResponseTopic 设置响应主题，用于请求/响应模式
+func WithResponseTopic(topic string) PublishOption {
+	return func(o *publishOptions) {
+		o.responseTopic = topic
+	}
+}
+
+// WithCorrelationData 设置关联数据，响应方原样带回以匹配请求
+func WithCorrelationData(data []byte) PublishOption {
+	return func(o *publishOptions) {
+		o.correlationData = data
+	}
+}
+
+// WithContentType 设置内容类型，例如 application/json
+func WithContentType(contentType string) PublishOption {
+	return func(o *publishOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithMessageExpiry 设置消息过期时间，精度为秒
+func WithMessageExpiry(expiry time.Duration) PublishOption {
+	return func(o *publishOptions) {
+		o.messageExpiry = expiry
+	}
+}
+
+// WithUserProperty 添加用户属性，可以多次调用
+func WithUserProperty(key, value string) PublishOption {
+	return func(o *publishOptions) {
+		o.userProperties = append(o.userProperties, [2]string{key, value})
+	}
+}
+
+func newPublishOptions(opts []PublishOption) *publishOptions {
+	o := &publishOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
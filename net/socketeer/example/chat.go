@@ -43,13 +43,8 @@ func (c *ChatStoreObject) OnMessage(manager *socketeer.Manager, ctx *socketeer.M
 
 	switch message.Type {
 	case "room":
-		if room, ok := c.Rooms[message.Room]; ok {
-			for _, user := range room {
-				err := manager.SendToId(user, ctx.Body)
-				if err != nil {
-					log.Println("User not found")
-				}
-			}
+		if err := manager.PublishToRoom(message.Room, ctx.Body); err != nil {
+			log.Println("Room not found")
 		}
 	case "private":
 		err := manager.SendToId(message.To, ctx.Body)
@@ -58,13 +53,12 @@ func (c *ChatStoreObject) OnMessage(manager *socketeer.Manager, ctx *socketeer.M
 		}
 
 	case "join":
-		if _, ok := c.Rooms[message.Room]; ok {
-			c.Lock()
-			c.Rooms[message.Room] = append(c.Rooms[message.Room], ctx.From)
-			//	You can send an acknowledgement message here
-		} else {
-			//	Send a message back if room doesnt exit
+		if err := manager.JoinRoom(ctx.From, message.Room); err != nil {
+			//	Send a message back if join is rejected
+			log.Printf("Join room %s failed: %s", message.Room, err.Error())
 		}
+	case "leave":
+		manager.LeaveRoom(ctx.From, message.Room)
 	default:
 		ErrorMessage, err := json.Marshal(&struct {
 			Error   bool   `json:"error"`
@@ -1,13 +1,17 @@
 package socketeer
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 type Dispatcher interface {
 	Run(commander *Manager)
 }
 type MessageContext struct {
-	From string
-	Body []byte
+	From    string
+	Body    []byte
+	Context context.Context // 连接认证时返回的上下文
 }
 
 type MessageHandler interface {
@@ -28,6 +32,12 @@ type OnDisconnectFunc func(*Manager, string)
 
 type OnConnectFunc func(*Manager, *http.Request, string)
 
+// OnJoinRoomFunc 加入房间时的钩子，返回错误时拒绝加入
+type OnJoinRoomFunc func(manager *Manager, room string, connectionId string) error
+
+// OnLeaveRoomFunc 离开房间时的钩子
+type OnLeaveRoomFunc func(manager *Manager, room string, connectionId string)
+
 type Identifier interface {
 	GetUniqueId() string
 }
@@ -44,4 +54,9 @@ type Config struct {
 	MaxMessageSize     int64
 	MaxReadBufferSize  int
 	MaxWriteBufferSize int
+	AllowedOrigins     []string // 允许的 Origin，支持完整来源、主机名、*.example.com 和 *，为空时不校验
+	MessageRate        float64  // 单个连接每秒允许的消息数，超出的消息不会被处理，并向连接返回错误，为 0 时不限制
+	MessageBurst       int      // 单个连接允许的突发消息数
+	IdleTimeout        int      // 空闲超时（秒），连接在该时间内没有收到消息时关闭，为 0 时不限制
+	SendBufferSize     int      // 每个连接的发送队列长度，默认 256，队列已满时新消息会被丢弃
 }
@@ -0,0 +1,76 @@
+package socketeer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/utils/httputil"
+	"golang.org/x/time/rate"
+)
+
+// AuthFunc 在协议升级前执行的认证函数，返回错误时以 401 拒绝连接。
+// 返回的上下文可通过 ConnectionContext 或 MessageContext.Context 获取
+type AuthFunc = contracts.AuthFunc
+
+// ConnectionContext 获取连接认证时返回的上下文，未设置 Authenticate 时返回 context.Background()
+func (s *Manager) ConnectionContext(connectionId string) context.Context {
+	s.Lock()
+	defer s.Unlock()
+	if ctx, ok := s.contexts[connectionId]; ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+// newUpgrader 根据配置创建升级器
+func (s *Manager) newUpgrader() *websocket.Upgrader {
+	u := &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+		ReadBufferSize:  maxReadBufferSize,
+		WriteBufferSize: maxWriteBufferSize,
+	}
+	if s.Config != nil && len(s.Config.AllowedOrigins) > 0 {
+		u.CheckOrigin = httputil.OriginChecker(s.Config.AllowedOrigins)
+	}
+	return u
+}
+
+// newLimiter 为连接创建限流器，未配置时返回 nil
+func (s *Manager) newLimiter() *rate.Limiter {
+	if s.Config == nil || s.Config.MessageRate <= 0 {
+		return nil
+	}
+	burst := s.Config.MessageBurst
+	if burst <= 0 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(s.Config.MessageRate), burst)
+}
+
+// idleTimeout 返回空闲超时时间，未配置时为 0
+func (s *Manager) idleTimeout() time.Duration {
+	if s.Config == nil {
+		return 0
+	}
+	return time.Duration(s.Config.IdleTimeout) * time.Second
+}
+
+// reject 拒绝连接发来的消息，向该连接返回 contracts.ResponseFailed 格式的错误
+func (s *Manager) reject(connectionId string, reason error) {
+	data, err := json.Marshal(contracts.ResponseFailed(reason))
+	if err != nil {
+		return
+	}
+	s.Lock()
+	channel, ok := s.sendChannels[connectionId]
+	s.Unlock()
+	if ok {
+		s.send(connectionId, channel, data)
+	}
+}
@@ -0,0 +1,264 @@
+package socketeer
+
+import (
+	"context"
+	"log"
+	"sort"
+
+	"github.com/sagoo-cloud/nexframe/net/backplane"
+)
+
+// 房间用于按主题对连接分组，例如以 "device:{deviceKey}" 作为房间名，
+// 只有正在查看该设备的连接才会收到它的遥测数据。
+
+// allRooms 注册钩子时使用该房间名表示对所有房间生效
+const allRooms = "*"
+
+// OnJoinRoom 注册加入房间时的钩子，room 为 "*" 时对所有房间生效。
+// 钩子返回错误时拒绝加入，可用于做订阅权限校验。
+func (s *Manager) OnJoinRoom(room string, hook OnJoinRoomFunc) {
+	s.Lock()
+	defer s.Unlock()
+	if s.joinHooks == nil {
+		s.joinHooks = make(map[string][]OnJoinRoomFunc)
+	}
+	s.joinHooks[room] = append(s.joinHooks[room], hook)
+}
+
+// OnLeaveRoom 注册离开房间时的钩子，room 为 "*" 时对所有房间生效。
+// 连接断开时会对其所在的每个房间触发一次。
+func (s *Manager) OnLeaveRoom(room string, hook OnLeaveRoomFunc) {
+	s.Lock()
+	defer s.Unlock()
+	if s.leaveHooks == nil {
+		s.leaveHooks = make(map[string][]OnLeaveRoomFunc)
+	}
+	s.leaveHooks[room] = append(s.leaveHooks[room], hook)
+}
+
+// JoinRoom 将本节点上的连接加入房间
+func (s *Manager) JoinRoom(connectionId, room string) error {
+	if room == "" {
+		return RoomNameIsEmpty
+	}
+
+	s.Lock()
+	_, exists := s.sendChannels[connectionId]
+	_, joined := s.rooms[room][connectionId]
+	hooks := append(append([]OnJoinRoomFunc{}, s.joinHooks[allRooms]...), s.joinHooks[room]...)
+	s.Unlock()
+
+	if !exists {
+		return ConnectionIdDoestExist
+	}
+	if joined {
+		return nil
+	}
+
+	for _, hook := range hooks {
+		if err := hook(s, room, connectionId); err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// 钩子执行期间连接可能已断开，此时不再加入，否则会残留在房间中
+	if _, exists = s.sendChannels[connectionId]; !exists {
+		return ConnectionIdDoestExist
+	}
+	if s.rooms[room] == nil {
+		s.rooms[room] = make(map[string]struct{})
+	}
+	s.rooms[room][connectionId] = struct{}{}
+	if s.connRooms[connectionId] == nil {
+		s.connRooms[connectionId] = make(map[string]struct{})
+	}
+	s.connRooms[connectionId][room] = struct{}{}
+	return nil
+}
+
+// LeaveRoom 将连接移出房间
+func (s *Manager) LeaveRoom(connectionId, room string) error {
+	if room == "" {
+		return RoomNameIsEmpty
+	}
+
+	s.Lock()
+	_, joined := s.rooms[room][connectionId]
+	if joined {
+		s.removeFromRoom(connectionId, room)
+	}
+	hooks := append(append([]OnLeaveRoomFunc{}, s.leaveHooks[allRooms]...), s.leaveHooks[room]...)
+	s.Unlock()
+
+	if joined {
+		for _, hook := range hooks {
+			hook(s, room, connectionId)
+		}
+	}
+	return nil
+}
+
+// PublishToRoom 向房间内的所有成员发送消息，设置了 Backplane 时同时转发到其他节点
+func (s *Manager) PublishToRoom(room string, message []byte) error {
+	if room == "" {
+		return RoomNameIsEmpty
+	}
+	s.deliverRoom(room, message)
+	return s.publish(backplane.KindRoom, room, message)
+}
+
+// RoomMembers 获取本节点上房间内的连接ID
+func (s *Manager) RoomMembers(room string) []string {
+	s.Lock()
+	defer s.Unlock()
+	return sortedKeys(s.rooms[room])
+}
+
+// ConnectionRooms 获取连接所在的房间
+func (s *Manager) ConnectionRooms(connectionId string) []string {
+	s.Lock()
+	defer s.Unlock()
+	return sortedKeys(s.connRooms[connectionId])
+}
+
+// BindUser 将本节点上的连接绑定到已认证的用户ID，一个用户可以有多个设备同时在线
+func (s *Manager) BindUser(connectionId, userId string) error {
+	if userId == "" {
+		return UserIdIsEmpty
+	}
+
+	s.Lock()
+	if _, exists := s.sendChannels[connectionId]; !exists {
+		s.Unlock()
+		return ConnectionIdDoestExist
+	}
+	old := s.connUsers[connectionId]
+	if old != "" {
+		s.removeUser(connectionId, old)
+	}
+	s.connUsers[connectionId] = userId
+	if s.users[userId] == nil {
+		s.users[userId] = make(map[string]struct{})
+	}
+	s.users[userId][connectionId] = struct{}{}
+	s.Unlock()
+
+	if s.Presence != nil {
+		if old != "" && old != userId {
+			if err := s.Presence.Leave(context.Background(), old, connectionId); err != nil {
+				return err
+			}
+		}
+		return s.Presence.Join(context.Background(), userId, connectionId)
+	}
+	return nil
+}
+
+// UserOf 获取连接绑定的用户ID
+func (s *Manager) UserOf(connectionId string) string {
+	s.Lock()
+	defer s.Unlock()
+	return s.connUsers[connectionId]
+}
+
+// UserConnections 获取用户的连接ID，设置了 Presence 时返回集群范围的结果
+func (s *Manager) UserConnections(userId string) ([]string, error) {
+	if s.Presence != nil {
+		return s.Presence.Connections(context.Background(), userId)
+	}
+	s.Lock()
+	defer s.Unlock()
+	return sortedKeys(s.users[userId]), nil
+}
+
+// SendToUser 向用户的所有连接发送消息，设置了 Backplane 时同时转发到其他节点
+func (s *Manager) SendToUser(userId string, message []byte) error {
+	if userId == "" {
+		return UserIdIsEmpty
+	}
+	s.deliverUser(userId, message)
+	return s.publish(backplane.KindUser, userId, message)
+}
+
+// releaseConnection 连接断开时退出所有房间并解除用户绑定
+func (s *Manager) releaseConnection(connectionId string) {
+	s.Lock()
+	rooms := sortedKeys(s.connRooms[connectionId])
+	s.Unlock()
+	for _, room := range rooms {
+		_ = s.LeaveRoom(connectionId, room)
+	}
+
+	s.Lock()
+	userId := s.connUsers[connectionId]
+	if userId != "" {
+		s.removeUser(connectionId, userId)
+	}
+	s.Unlock()
+
+	if userId != "" && s.Presence != nil {
+		if err := s.Presence.Leave(context.Background(), userId, connectionId); err != nil {
+			log.Printf("Socketeer presence leave failed: %s \n", err.Error())
+		}
+	}
+}
+
+func (s *Manager) deliverRoom(room string, message []byte) {
+	s.Lock()
+	channels := make(map[string]chan []byte, len(s.rooms[room]))
+	for id := range s.rooms[room] {
+		if channel, ok := s.sendChannels[id]; ok {
+			channels[id] = channel
+		}
+	}
+	s.Unlock()
+	for id, channel := range channels {
+		s.send(id, channel, message)
+	}
+}
+
+func (s *Manager) deliverUser(userId string, message []byte) {
+	s.Lock()
+	channels := make(map[string]chan []byte, len(s.users[userId]))
+	for id := range s.users[userId] {
+		if channel, ok := s.sendChannels[id]; ok {
+			channels[id] = channel
+		}
+	}
+	s.Unlock()
+	for id, channel := range channels {
+		s.send(id, channel, message)
+	}
+}
+
+// removeFromRoom 需要在持有锁时调用
+func (s *Manager) removeFromRoom(connectionId, room string) {
+	delete(s.rooms[room], connectionId)
+	if len(s.rooms[room]) == 0 {
+		delete(s.rooms, room)
+	}
+	delete(s.connRooms[connectionId], room)
+	if len(s.connRooms[connectionId]) == 0 {
+		delete(s.connRooms, connectionId)
+	}
+}
+
+// removeUser 需要在持有锁时调用
+func (s *Manager) removeUser(connectionId, userId string) {
+	delete(s.connUsers, connectionId)
+	delete(s.users[userId], connectionId)
+	if len(s.users[userId]) == 0 {
+		delete(s.users, userId)
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
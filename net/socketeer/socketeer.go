@@ -1,11 +1,15 @@
 package socketeer
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/websocket"
+	"github.com/sagoo-cloud/nexframe/net/backplane"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,6 +28,20 @@ type Manager struct {
 	onDisconnect    OnDisconnectFunc
 	IdGen           IdFactory
 	Config          *Config
+	Authenticate    AuthFunc            // 连接认证函数，为空时不认证
+	Backplane       backplane.Backplane // 跨节点消息分发，为空时仅在本节点内投递
+	Presence        backplane.Presence  // 集群范围的用户在线状态，为空时仅记录本节点
+	Channel         string              // Backplane 频道名，默认 socketeer
+	NodeId          string              // 节点ID，默认随机生成
+	subscription    backplane.Subscription
+	rooms           map[string]map[string]struct{}
+	connRooms       map[string]map[string]struct{}
+	users           map[string]map[string]struct{}
+	connUsers       map[string]string
+	joinHooks       map[string][]OnJoinRoomFunc
+	leaveHooks      map[string][]OnLeaveRoomFunc
+	contexts        map[string]context.Context
+	upgrader        *websocket.Upgrader
 }
 
 func (s *Manager) OnConnect(onConnectHandler OnConnectFunc) {
@@ -47,6 +65,20 @@ func (s *Manager) Init() {
 		s.Unlock()
 	}
 
+	s.Lock()
+	if s.rooms == nil {
+		s.rooms = make(map[string]map[string]struct{})
+		s.connRooms = make(map[string]map[string]struct{})
+	}
+	if s.users == nil {
+		s.users = make(map[string]map[string]struct{})
+		s.connUsers = make(map[string]string)
+	}
+	if s.contexts == nil {
+		s.contexts = make(map[string]context.Context)
+	}
+	s.Unlock()
+
 	if s.Config != nil {
 		if s.Config.MaxMessageSize != 0 {
 			maxMessageSize = s.Config.MaxMessageSize
@@ -71,6 +103,27 @@ func (s *Manager) Init() {
 		if s.Config.WriteWait != 0 {
 			writeWait = time.Duration(s.Config.WriteWait) * time.Second
 		}
+
+		if s.Config.SendBufferSize != 0 {
+			sendBufferSize = s.Config.SendBufferSize
+		}
+	}
+
+	s.upgrader = s.newUpgrader()
+
+	if s.Backplane != nil {
+		if s.NodeId == "" {
+			s.NodeId = guid.S()
+		}
+		if s.Channel == "" {
+			s.Channel = defaultChannel
+		}
+		sub, err := s.Backplane.Subscribe(context.Background(), s.Channel, s.onEnvelope)
+		if err != nil {
+			log.Printf("Socketeer backplane subscribe failed: %s \n", err.Error())
+		} else {
+			s.subscription = sub
+		}
 	}
 
 	for _, dispatcher := range s.dispatchers {
@@ -80,9 +133,8 @@ func (s *Manager) Init() {
 	s.initialized = true
 }
 
-func (s *Manager) runWriter(connectionId string) {
+func (s *Manager) runWriter(connectionId string, connection *websocket.Conn, send chan []byte) {
 	ticker := time.NewTicker(pingPeriod)
-	connection := s.allConnection[connectionId]
 	defer func() {
 		ticker.Stop()
 		connection.Close()
@@ -90,7 +142,7 @@ func (s *Manager) runWriter(connectionId string) {
 
 	for {
 		select {
-		case message, ok := <-s.sendChannels[connectionId]:
+		case message, ok := <-send:
 
 			connection.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
@@ -112,12 +164,24 @@ func (s *Manager) runWriter(connectionId string) {
 	}
 }
 
-func (s *Manager) runReader(connectionId string) {
-	connection := s.allConnection[connectionId]
+func (s *Manager) runReader(ctx context.Context, connectionId string, connection *websocket.Conn) {
 	defer func() {
 		connection.Close()
 	}()
 
+	limiter := s.newLimiter()
+	idleTimeout := s.idleTimeout()
+	var idle *time.Timer
+	var idleExpired atomic.Bool
+	if idleTimeout > 0 {
+		idle = time.AfterFunc(idleTimeout, func() {
+			idleExpired.Store(true)
+			log.Printf("Socketeer connection %s idle timeout \n", connectionId)
+			connection.Close()
+		})
+		defer idle.Stop()
+	}
+
 	for {
 		connection.SetReadLimit(maxMessageSize)
 		connection.SetReadDeadline(time.Now().Add(pongWait))
@@ -129,20 +193,8 @@ func (s *Manager) runReader(connectionId string) {
 		_, message, err := connection.ReadMessage()
 
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) || websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				s.Lock()
-				delete(s.allConnection, connectionId)
-				delete(s.sendChannels, connectionId)
-				s.Unlock()
-				if s.onDisconnect != nil {
-					s.onDisconnect(s, connectionId)
-				}
-				// if all handlers have an onDisconnectFunction
-				for _, handler := range s.messageHandlers {
-					if instanceDisconnectFunc, ok := handler.(OnDisconnectHandler); ok {
-						instanceDisconnectFunc.OnDisconnect(s, connectionId)
-					}
-				}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) || websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) || idleExpired.Load() {
+				s.disconnect(connectionId)
 				return
 			} else {
 				fmt.Printf("Socketeer Error for connection %s ==> %s \n", connectionId, err.Error())
@@ -150,38 +202,71 @@ func (s *Manager) runReader(connectionId string) {
 			}
 		}
 
+		if idle != nil {
+			idle.Reset(idleTimeout)
+		}
+		if limiter != nil && !limiter.Allow() {
+			log.Printf("Socketeer connection %s exceeded message rate, message rejected \n", connectionId)
+			s.reject(connectionId, MessageRateExceeded)
+			continue
+		}
+
 		// call MessageHandlers
 		for _, handler := range s.messageHandlers {
 			handler.OnMessage(s, &MessageContext{
-				From: connectionId,
-				Body: message,
+				From:    connectionId,
+				Body:    message,
+				Context: ctx,
 			})
 		}
 	}
 }
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-	ReadBufferSize:  maxReadBufferSize,
-	WriteBufferSize: maxWriteBufferSize,
+// disconnect 注销已断开的连接并触发断开回调
+func (s *Manager) disconnect(connectionId string) {
+	s.Lock()
+	delete(s.allConnection, connectionId)
+	delete(s.sendChannels, connectionId)
+	delete(s.contexts, connectionId)
+	s.Unlock()
+	s.releaseConnection(connectionId)
+	if s.onDisconnect != nil {
+		s.onDisconnect(s, connectionId)
+	}
+	// if all handlers have an onDisconnectFunction
+	for _, handler := range s.messageHandlers {
+		if instanceDisconnectFunc, ok := handler.(OnDisconnectHandler); ok {
+			instanceDisconnectFunc.OnDisconnect(s, connectionId)
+		}
+	}
 }
 
+// Manage 认证并升级连接，设置了 Authenticate 且认证失败时返回 401 和认证错误
 func (s *Manager) Manage(response http.ResponseWriter, request *http.Request) (string, error) {
-	connection, err := upgrader.Upgrade(response, request, nil)
 	if s.initialized == false {
 		panic("Socketeer not Initialized, Call Init()")
 	}
+	ctx := context.Background()
+	if s.Authenticate != nil {
+		authCtx, err := s.Authenticate(request)
+		if err != nil {
+			http.Error(response, err.Error(), http.StatusUnauthorized)
+			return "", err
+		}
+		// 请求结束后上下文会被取消，连接只保留其中的值
+		ctx = context.WithoutCancel(authCtx)
+	}
+	connection, err := s.upgrader.Upgrade(response, request, nil)
 	if err != nil {
 		return "", err
 	}
 	id := s.IdGen()
 	s.Lock()
 	s.allConnection[id] = connection
-	s.sendChannels[id] = make(chan []byte)
-	go s.runWriter(id)
-	go s.runReader(id)
+	s.sendChannels[id] = make(chan []byte, sendBufferSize)
+	s.contexts[id] = ctx
+	go s.runWriter(id, connection, s.sendChannels[id])
+	go s.runReader(ctx, id, connection)
 	s.Unlock()
 
 	if s.onConnect != nil {
@@ -197,19 +282,44 @@ func (s *Manager) Manage(response http.ResponseWriter, request *http.Request) (s
 	return id, nil
 }
 
+// Broadcast 向所有连接广播消息，设置了 Backplane 时同时转发到其他节点
 func (s *Manager) Broadcast(message []byte) {
-	for _, channel := range s.sendChannels {
-		channel <- message
+	s.broadcastLocal(message)
+	if err := s.publish(backplane.KindBroadcast, "", message); err != nil {
+		log.Printf("Socketeer backplane publish failed: %s \n", err.Error())
+	}
+}
+
+func (s *Manager) broadcastLocal(message []byte) {
+	for id, channel := range s.localChannels() {
+		s.send(id, channel, message)
+	}
+}
+
+// send 将消息放入连接的发送队列，队列已满时丢弃消息并返回 false。
+// 投递可能发生在 Backplane 的订阅协程中，不能因为某个连接写入缓慢而阻塞其他连接
+func (s *Manager) send(connectionId string, channel chan []byte, message []byte) bool {
+	select {
+	case channel <- message:
+		return true
+	default:
+		log.Printf("Socketeer connection %s send buffer is full, message dropped \n", connectionId)
+		return false
 	}
 }
 
 func (s *Manager) Remove(connectionId string) {
-	if connection, ok := s.allConnection[connectionId]; ok {
+	s.Lock()
+	connection, ok := s.allConnection[connectionId]
+	s.Unlock()
+	if ok {
 		connection.Close()
 		s.Lock()
-		defer s.Unlock()
 		delete(s.allConnection, connectionId)
 		delete(s.sendChannels, connectionId)
+		delete(s.contexts, connectionId)
+		s.Unlock()
+		s.releaseConnection(connectionId)
 	}
 }
 
@@ -234,14 +344,91 @@ func (s *Manager) AddDispatcher(dispatcher Dispatcher) {
 	s.dispatchers = append(s.dispatchers, dispatcher)
 }
 
+// SendToId 向指定连接发送消息，连接不在本节点且设置了 Backplane 时转发到其他节点。
+// 连接的发送队列已满时丢弃消息并返回 SendBufferIsFull
 func (s *Manager) SendToId(connectionId string, message []byte) error {
-	if user, ok := s.sendChannels[connectionId]; ok {
-		user <- message
+	s.Lock()
+	user, ok := s.sendChannels[connectionId]
+	s.Unlock()
+	if ok {
+		if !s.send(connectionId, user, message) {
+			return SendBufferIsFull
+		}
 		return nil
-	} else {
+	}
+	if s.Backplane == nil {
 		return ConnectionIdDoestExist
 	}
+	return s.publish(backplane.KindConnection, connectionId, message)
+}
+
+// ServeHTTP 实现 http.Handler，可挂载到已有的路由上，例如 nf.APIFramework.BindWebSocket
+func (s *Manager) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	if _, err := s.Manage(response, request); err != nil {
+		log.Printf("Socketeer manage connection failed: %s \n", err.Error())
+	}
+}
+
+// Close 取消 Backplane 订阅并关闭本节点上的所有连接
+func (s *Manager) Close() error {
+	s.Lock()
+	ids := make([]string, 0, len(s.allConnection))
+	for id := range s.allConnection {
+		ids = append(ids, id)
+	}
+	s.Unlock()
+	for _, id := range ids {
+		s.Remove(id)
+	}
 
+	if s.subscription != nil {
+		return s.subscription.Unsubscribe()
+	}
+	return nil
+}
+
+// onEnvelope 处理来自其他节点的消息
+func (s *Manager) onEnvelope(env *backplane.Envelope) {
+	if env.Origin == s.NodeId {
+		return
+	}
+	switch env.Kind {
+	case backplane.KindBroadcast:
+		s.broadcastLocal(env.Payload)
+	case backplane.KindConnection:
+		s.Lock()
+		channel, ok := s.sendChannels[env.Target]
+		s.Unlock()
+		if ok {
+			s.send(env.Target, channel, env.Payload)
+		}
+	case backplane.KindRoom:
+		s.deliverRoom(env.Target, env.Payload)
+	case backplane.KindUser:
+		s.deliverUser(env.Target, env.Payload)
+	}
+}
+
+func (s *Manager) publish(kind, target string, message []byte) error {
+	if s.Backplane == nil {
+		return nil
+	}
+	return s.Backplane.Publish(context.Background(), s.Channel, &backplane.Envelope{
+		Kind:    kind,
+		Target:  target,
+		Origin:  s.NodeId,
+		Payload: message,
+	})
+}
+
+func (s *Manager) localChannels() map[string]chan []byte {
+	s.Lock()
+	defer s.Unlock()
+	channels := make(map[string]chan []byte, len(s.sendChannels))
+	for id, channel := range s.sendChannels {
+		channels[id] = channel
+	}
+	return channels
 }
 
 func (s *Manager) AddIdFactory(idGen IdFactory) {
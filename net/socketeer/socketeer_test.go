@@ -2,6 +2,7 @@ package socketeer
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
@@ -10,6 +11,7 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -133,3 +135,240 @@ func TestSocketeerManager_main(t *testing.T) {
 	})
 
 }
+
+func TestSocketeerManager_rooms(t *testing.T) {
+	var seq int
+	manager := Manager{
+		IdGen: func() string {
+			seq++
+			return "conn" + strconv.Itoa(seq)
+		},
+	}
+	manager.Init()
+
+	var (
+		hookMu       sync.Mutex
+		joined, left []string
+	)
+	leftSnapshot := func() []string {
+		hookMu.Lock()
+		defer hookMu.Unlock()
+		return append([]string{}, left...)
+	}
+	manager.OnJoinRoom("*", func(manager *Manager, room string, connectionId string) error {
+		joined = append(joined, room+":"+connectionId)
+		return nil
+	})
+	manager.OnJoinRoom("private", func(manager *Manager, room string, connectionId string) error {
+		return fmt.Errorf("forbidden")
+	})
+	manager.OnLeaveRoom("device:1", func(manager *Manager, room string, connectionId string) {
+		hookMu.Lock()
+		defer hookMu.Unlock()
+		left = append(left, connectionId)
+	})
+
+	ids := make(chan string, 3)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		id, _ := manager.Manage(writer, request)
+		ids <- id
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	conn1, _ := ConnectToTestServer(t, server)
+	id1 := <-ids
+	conn2, _ := ConnectToTestServer(t, server)
+	id2 := <-ids
+	conn3, _ := ConnectToTestServer(t, server)
+	id3 := <-ids
+	defer conn3.Close()
+
+	read := func(conn *websocket.Conn) string {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read failed: %s", err.Error())
+		}
+		return string(message)
+	}
+
+	t.Run("Publish only to room members", func(t *testing.T) {
+		assert.NoError(t, manager.JoinRoom(id1, "device:1"))
+		assert.NoError(t, manager.JoinRoom(id2, "device:1"))
+		assert.Equal(t, []string{id1, id2}, manager.RoomMembers("device:1"))
+		assert.Equal(t, []string{"device:1"}, manager.ConnectionRooms(id1))
+
+		assert.NoError(t, manager.PublishToRoom("device:1", []byte("telemetry")))
+		assert.Equal(t, "telemetry", read(conn1))
+		assert.Equal(t, "telemetry", read(conn2))
+		assert.NoError(t, manager.SendToId(id3, []byte("only3")))
+		assert.Equal(t, "only3", read(conn3))
+		assert.Equal(t, []string{"device:1:" + id1, "device:1:" + id2}, joined)
+	})
+
+	t.Run("Join hook can reject", func(t *testing.T) {
+		assert.Error(t, manager.JoinRoom(id1, "private"))
+		assert.Empty(t, manager.RoomMembers("private"))
+		assert.ErrorIs(t, manager.JoinRoom("missing", "device:1"), ConnectionIdDoestExist)
+		assert.ErrorIs(t, manager.JoinRoom(id1, ""), RoomNameIsEmpty)
+	})
+
+	t.Run("Send to all devices of a user", func(t *testing.T) {
+		assert.NoError(t, manager.BindUser(id1, "alice"))
+		assert.NoError(t, manager.BindUser(id3, "alice"))
+		assert.Equal(t, "alice", manager.UserOf(id3))
+		conns, err := manager.UserConnections("alice")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{id1, id3}, conns)
+
+		assert.NoError(t, manager.SendToUser("alice", []byte("hi alice")))
+		assert.Equal(t, "hi alice", read(conn1))
+		assert.Equal(t, "hi alice", read(conn3))
+	})
+
+	t.Run("Leave room and release on disconnect", func(t *testing.T) {
+		assert.NoError(t, manager.LeaveRoom(id2, "device:1"))
+		assert.Equal(t, []string{id2}, leftSnapshot())
+
+		conn1.Close()
+		conn2.Close()
+		deadline := time.Now().Add(2 * time.Second)
+		for len(leftSnapshot()) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.Empty(t, manager.RoomMembers("device:1"))
+		assert.Equal(t, []string{id2, id1}, leftSnapshot())
+		conns, _ := manager.UserConnections("alice")
+		assert.Equal(t, []string{id3}, conns)
+	})
+}
+
+type ctxKey struct{}
+
+type contextRecorder struct {
+	mu    sync.Mutex
+	users []interface{}
+}
+
+func (r *contextRecorder) OnMessage(manager *Manager, msg *MessageContext) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users = append(r.users, msg.Context.Value(ctxKey{}))
+}
+
+func (r *contextRecorder) snapshot() []interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]interface{}{}, r.users...)
+}
+
+func TestSocketeerManager_policy(t *testing.T) {
+	recorder := &contextRecorder{}
+	disconnected := make(chan string, 1)
+	manager := Manager{
+		IdGen: func() string {
+			return "user" + strconv.Itoa(int(time.Now().UnixNano()))
+		},
+		Config: &Config{
+			AllowedOrigins: []string{"app.example.com"},
+			MessageRate:    1,
+			MessageBurst:   1,
+			IdleTimeout:    1,
+		},
+		Authenticate: func(r *http.Request) (context.Context, error) {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				return nil, fmt.Errorf("missing token")
+			}
+			return context.WithValue(r.Context(), ctxKey{}, token), nil
+		},
+	}
+	manager.AddMessageHandler(recorder)
+	manager.OnDisconnect(func(manager *Manager, connectionId string) {
+		disconnected <- connectionId
+	})
+	manager.Init()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		manager.Manage(writer, request)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/"
+
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL+"?token=alice", http.Header{"Origin": []string{"https://evil.com"}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?token=alice", http.Header{"Origin": []string{"https://app.example.com"}})
+	if err != nil {
+		t.Fatalf("Couldnt connect to websocket : %s", err.Error())
+	}
+	defer conn.Close()
+
+	// 第二条消息超出速率被拒绝并返回错误
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("first")))
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("second")))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, reply, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Contains(t, string(reply), MessageRateExceeded.Error())
+
+	// 空闲超时后连接被关闭并触发断开回调
+	select {
+	case <-disconnected:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("idle connection was not closed")
+	}
+	assert.Equal(t, []interface{}{"alice"}, recorder.snapshot())
+}
+
+func TestSocketeerManager_fullSendBuffer(t *testing.T) {
+	manager := &Manager{IdGen: func() string { return "conn" }}
+	manager.Init()
+
+	// 没有写协程消费的连接，发送队列满后的投递不能阻塞调用方
+	stalled := make(chan []byte, 1)
+	manager.Lock()
+	manager.sendChannels["stalled"] = stalled
+	manager.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.Nil(t, manager.SendToId("stalled", []byte("first")))
+		assert.Equal(t, SendBufferIsFull, manager.SendToId("stalled", []byte("second")))
+		manager.broadcastLocal([]byte("broadcast"))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("delivery blocked on a full send buffer")
+	}
+	assert.Equal(t, []byte("first"), <-stalled)
+}
+
+func TestSocketeerManager_joinRoomAfterDisconnect(t *testing.T) {
+	manager := &Manager{IdGen: func() string { return "conn" }}
+	manager.Init()
+	manager.Lock()
+	manager.sendChannels["conn1"] = make(chan []byte, 1)
+	manager.Unlock()
+
+	// 钩子执行期间连接断开，连接不能残留在房间中
+	manager.OnJoinRoom("*", func(manager *Manager, room string, connectionId string) error {
+		manager.disconnect(connectionId)
+		return nil
+	})
+	assert.Equal(t, ConnectionIdDoestExist, manager.JoinRoom("conn1", "device:1"))
+	assert.Empty(t, manager.RoomMembers("device:1"))
+	assert.Empty(t, manager.ConnectionRooms("conn1"))
+}
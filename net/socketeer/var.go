@@ -12,5 +12,15 @@ var (
 	maxMessageSize     = int64(512)
 	maxReadBufferSize  = 1024
 	maxWriteBufferSize = 1024
+	sendBufferSize     = 256 // 每个连接的发送队列长度
+)
+
+const defaultChannel = "socketeer"
+
+var (
+	ConnectionIdDoestExist = errors.New("ConnectionId Does not Exist")
+	RoomNameIsEmpty        = errors.New("Room name is empty")
+	UserIdIsEmpty          = errors.New("UserId is empty")
+	SendBufferIsFull       = errors.New("Send buffer is full")
+	MessageRateExceeded    = errors.New("message rate limit exceeded")
 )
-var ConnectionIdDoestExist = errors.New("ConnectionId Does not Exist")
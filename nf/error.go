@@ -1,8 +1,11 @@
 package nf
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"github.com/sagoo-cloud/nexframe/contracts"
+	"net"
 	"net/http"
 )
 
@@ -101,6 +104,20 @@ func (crw *customResponseWriter) Write(b []byte) (int, error) {
 	return crw.ResponseWriter.Write(b)
 }
 
+// Hijack 实现 http.Hijacker，使WebSocket等需要接管连接的处理器在中间件之后仍可正常工作
+func (crw *customResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := crw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap 返回原始的 ResponseWriter，供 http.ResponseController 使用
+func (crw *customResponseWriter) Unwrap() http.ResponseWriter {
+	return crw.ResponseWriter
+}
+
 // UseErrorHandlingMiddleware 在APIFramework结构体中添加一个方法来应用这个中间件
 func (f *APIFramework) UseErrorHandlingMiddleware() {
 	f.WithMiddleware(f.ErrorHandlingMiddleware)
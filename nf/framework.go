@@ -2,7 +2,6 @@ package nf
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -89,6 +88,10 @@ type APIFramework struct {
 	HTTPSKeyPath   string
 	ctx            context.Context
 	logger         *log.Logger
+	webSockets     []webSocketRoute
+	httpServers    []*http.Server
+	serversMu      sync.Mutex
+	closed         bool // 已调用 Close，之后 Serve 不再启动监听
 }
 
 // NewAPIFramework 创建新的APIFramework实例
@@ -1047,13 +1050,8 @@ func (f *APIFramework) Run(httpServes ...weaver.Listener) (err error) {
 		swaggerUrl := fmt.Sprintf("API Doc: http://localhost%s/swagger/index.html", f.addr)
 		log.Printf(swaggerUrl)
 		// 创建 HTTP 服务器
-		srv := &http.Server{
-			Addr:         f.addr,
-			Handler:      f.GetServer(),
-			ReadTimeout:  f.config.ReadTimeout,
-			WriteTimeout: f.config.WriteTimeout,
-			IdleTimeout:  f.config.IdleTimeout,
-		}
+		srv := f.newHTTPServer(f.addr)
+		f.trackServer(srv)
 
 		// 启动 HTTP 服务器
 		go func() {
@@ -1066,14 +1064,8 @@ func (f *APIFramework) Run(httpServes ...weaver.Listener) (err error) {
 		if f.config.HTTPSAddress != "" && f.config.HTTPSCertPath != "" && f.config.HTTPSKeyPath != "" {
 			go func() {
 				log.Printf("%s Starting HTTPS server on %s", f.config.Name, f.config.HTTPSAddress)
-				httpsServer := &http.Server{
-					Addr:         f.config.HTTPSAddress,
-					Handler:      f.GetServer(),
-					ReadTimeout:  f.config.ReadTimeout,
-					WriteTimeout: f.config.WriteTimeout,
-					IdleTimeout:  f.config.IdleTimeout,
-					TLSConfig:    &tls.Config{MinVersion: tls.VersionTLS12},
-				}
+				httpsServer := f.newHTTPSServer(f.config.HTTPSAddress)
+				f.trackServer(httpsServer)
 				if err := httpsServer.ListenAndServeTLS(f.config.HTTPSCertPath, f.config.HTTPSKeyPath); err != nil && !errors.Is(err, http.ErrServerClosed) {
 					log.Fatalf("HTTPS server error: %v", err)
 				}
@@ -1090,13 +1082,8 @@ func (f *APIFramework) Run(httpServes ...weaver.Listener) (err error) {
 		log.Printf(swaggerUrl)
 
 		//创建 HTTP 服务器
-		srv := &http.Server{
-			Handler: f.GetServer(),
-
-			ReadTimeout:  f.config.ReadTimeout,
-			WriteTimeout: f.config.WriteTimeout,
-			IdleTimeout:  f.config.IdleTimeout,
-		}
+		srv := f.newHTTPServer("")
+		f.trackServer(srv)
 		//启动 HTTP 服务器
 		go func() {
 			log.Printf("%s Starting HTTP server on %s", f.config.Name, f.addr)
@@ -1108,13 +1095,8 @@ func (f *APIFramework) Run(httpServes ...weaver.Listener) (err error) {
 		if f.config.HTTPSAddress != "" && f.config.HTTPSCertPath != "" && f.config.HTTPSKeyPath != "" {
 			go func() {
 				log.Printf("%s Starting HTTPS server on %s", f.config.Name, f.config.HTTPSAddress)
-				httpsServer := &http.Server{
-					Handler:      f.GetServer(),
-					ReadTimeout:  f.config.ReadTimeout,
-					WriteTimeout: f.config.WriteTimeout,
-					IdleTimeout:  f.config.IdleTimeout,
-					TLSConfig:    &tls.Config{MinVersion: tls.VersionTLS12},
-				}
+				httpsServer := f.newHTTPSServer("")
+				f.trackServer(httpsServer)
 
 				if err := httpsServer.ServeTLS(web, f.config.HTTPSCertPath, f.config.HTTPSKeyPath); err != nil && err != http.ErrServerClosed {
 					log.Fatalf("HTTPS server error: %v", err)
@@ -1139,6 +1121,11 @@ func (f *APIFramework) PrintAPIRoutes() {
 		routes = append(routes, route)
 	}
 
+	for _, ws := range f.webSockets {
+		route := fmt.Sprintf(dumpTextFormat, "WS", ws.path, fmt.Sprintf("WebSocket (%T)", ws.server))
+		routes = append(routes, route)
+	}
+
 	// 排序路由以便更容易阅读
 	sort.Strings(routes)
 
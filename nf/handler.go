@@ -1,6 +1,10 @@
 package nf
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
 
 func (f *APIFramework) BindHandler(prefix string, handler http.Handler) error {
 	f.router.Handle(prefix, handler)
@@ -25,3 +29,14 @@ func (f *APIFramework) BindStatusHandlerByMap(handlers map[int]http.HandlerFunc)
 		f.BindStatusHandler(status, handler)
 	}
 }
+
+// BindPrefixHandler 将自带路由的处理器挂载到路径前缀下，处理器收到的路径已去掉前缀，
+// 例如挂载任务管理接口：f.BindPrefixHandler("/admin", manager.Handler())
+func (f *APIFramework) BindPrefixHandler(prefix string, handler http.Handler) error {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return errors.New("prefix cannot be empty")
+	}
+	f.router.PathPrefix(prefix + "/").Handler(http.StripPrefix(prefix, handler))
+	return nil
+}
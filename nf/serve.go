@@ -0,0 +1,89 @@
+package nf
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout Close 等待处理中的请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
+// Serve 启动HTTP服务并阻塞，直到调用 Close；配置了HTTPS时同时启动HTTPS服务。
+// 与 Run 不同，监听失败时返回错误而不是退出进程，用于 servers/app 统一启动多个服务。
+func (f *APIFramework) Serve() error {
+	if f.addr == "" {
+		f.addr = f.config.Address
+	}
+
+	srv := f.newHTTPServer(f.addr)
+	tracked := []*http.Server{srv}
+	servers := []func() error{func() error {
+		log.Printf("%s Starting HTTP server on %s", f.config.Name, f.addr)
+		return srv.ListenAndServe()
+	}}
+	if f.config.HTTPSAddress != "" && f.config.HTTPSCertPath != "" && f.config.HTTPSKeyPath != "" {
+		httpsServer := f.newHTTPSServer(f.config.HTTPSAddress)
+		tracked = append(tracked, httpsServer)
+		servers = append(servers, func() error {
+			log.Printf("%s Starting HTTPS server on %s", f.config.Name, f.config.HTTPSAddress)
+			return httpsServer.ListenAndServeTLS(f.config.HTTPSCertPath, f.config.HTTPSKeyPath)
+		})
+	}
+	// Close 已经调用时不再监听，否则 Serve 会一直阻塞
+	if !f.trackServer(tracked...) {
+		return nil
+	}
+
+	errCh := make(chan error, len(servers))
+	for _, serve := range servers {
+		go func(serve func() error) {
+			if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+				return
+			}
+			errCh <- nil
+		}(serve)
+	}
+
+	// 任一监听失败时关闭其他服务，返回第一个错误
+	var err error
+	for range servers {
+		if e := <-errCh; e != nil && err == nil {
+			err = e
+			_ = f.Close()
+		}
+	}
+	return err
+}
+
+// newHTTPServer 按配置的超时创建HTTP服务，addr 为空时由调用方提供监听器
+func (f *APIFramework) newHTTPServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      f.GetServer(),
+		ReadTimeout:  f.config.ReadTimeout,
+		WriteTimeout: f.config.WriteTimeout,
+		IdleTimeout:  f.config.IdleTimeout,
+	}
+}
+
+// newHTTPSServer 创建HTTPS服务，最低使用 TLS 1.2
+func (f *APIFramework) newHTTPSServer(addr string) *http.Server {
+	srv := f.newHTTPServer(addr)
+	srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	return srv
+}
+
+// Close 优雅关闭 Serve 或 Run 启动的HTTP服务，最多等待 10 秒
+func (f *APIFramework) Close() error {
+	f.serversMu.Lock()
+	f.closed = true
+	f.serversMu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return f.Shutdown(ctx)
+}
@@ -0,0 +1,23 @@
+package nf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeAfterClose(t *testing.T) {
+	f := NewAPIFramework()
+	f.SetPort("127.0.0.1:0")
+	assert.NoError(t, f.Close())
+
+	done := make(chan error, 1)
+	go func() { done <- f.Serve() }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+}
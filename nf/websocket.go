@@ -0,0 +1,67 @@
+package nf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// WebSocketServer 可挂载到框架路由上的WebSocket服务，
+// websockets.Server 和 socketeer.Manager 均实现了该接口
+type WebSocketServer interface {
+	http.Handler
+	Close() error
+}
+
+// webSocketRoute 记录已挂载的WebSocket服务
+type webSocketRoute struct {
+	path   string
+	server WebSocketServer
+}
+
+// BindWebSocket 将WebSocket服务挂载到指定路径。
+// 挂载后与HTTP接口共用端口、TLS、全局中间件和域名检查，并在 Shutdown 时一起关闭。
+func (f *APIFramework) BindWebSocket(path string, server WebSocketServer) error {
+	if path == "" {
+		return errors.New("websocket path cannot be empty")
+	}
+	if server == nil {
+		return errors.New("websocket server cannot be nil")
+	}
+	f.router.Handle(path, server).Methods(http.MethodGet)
+	f.webSockets = append(f.webSockets, webSocketRoute{path: path, server: server})
+	return nil
+}
+
+// Shutdown 关闭已挂载的WebSocket服务，并优雅关闭 Run 启动的HTTP服务
+func (f *APIFramework) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, ws := range f.webSockets {
+		if err := ws.server.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	f.serversMu.Lock()
+	servers := f.httpServers
+	f.httpServers = nil
+	f.serversMu.Unlock()
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// trackServer 记录 Run 或 Serve 启动的HTTP服务，用于 Shutdown；已调用 Close 时返回 false
+func (f *APIFramework) trackServer(servers ...*http.Server) bool {
+	f.serversMu.Lock()
+	defer f.serversMu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.httpServers = append(f.httpServers, servers...)
+	return true
+}
@@ -0,0 +1,48 @@
+package nf
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/servers/websockets"
+	"github.com/stretchr/testify/assert"
+)
+
+type echoHandler struct{}
+
+func (h *echoHandler) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+func TestBindWebSocket(t *testing.T) {
+	f := NewAPIFramework()
+	server := websockets.NewServer()
+	server.Register("echo", &commons.CommHandler{Handler: &echoHandler{}})
+
+	assert.Error(t, f.BindWebSocket("", server))
+	assert.NoError(t, f.BindWebSocket("/ws", server))
+
+	testServer := httptest.NewServer(f.GetServer())
+	defer testServer.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(testServer.URL, "http")+"/ws", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ws.Close()
+
+	assert.NoError(t, ws.WriteJSON(contracts.Payload{Id: "1", Route: "echo", Params: map[string]interface{}{"a": "b"}}))
+	var msg websockets.Message
+	assert.NoError(t, ws.ReadJSON(&msg))
+	assert.Equal(t, "1", msg.Id)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, msg.Data)
+
+	assert.NoError(t, f.Shutdown(context.Background()))
+	_, _, err = ws.ReadMessage()
+	assert.Error(t, err)
+}
@@ -17,10 +17,15 @@ type GPool struct {
 
 // NewGPool 创建并初始化具有指定容量的新GPool。
 func NewGPool(capacity int) *GPool {
+	return NewGPoolWithQueue(capacity, capacity*2) // Double capacity for work queue
+}
+
+// NewGPoolWithQueue 创建具有指定容量和队列长度的GPool。
+func NewGPoolWithQueue(capacity, queueSize int) *GPool {
 	ctx, cancel := context.WithCancel(context.Background())
 	p := &GPool{
 		workers:   make(chan struct{}, capacity),
-		workQueue: make(chan func(), capacity*2), // Double capacity for work queue
+		workQueue: make(chan func(), queueSize),
 		ctx:       ctx,
 		cancel:    cancel,
 	}
@@ -81,6 +86,23 @@ func (p *GPool) AddJob(job func(ctx context.Context) error) {
 	}
 }
 
+// TryAddJob 尝试将作业添加到池中。如果队列已满，立即返回 false 且不执行该作业。
+func (p *GPool) TryAddJob(job func(ctx context.Context) error) bool {
+	p.wg.Add(1)
+	select {
+	case p.workQueue <- func() {
+		err := job(p.ctx)
+		if err != nil {
+			println("Error executing job:", err.Error())
+		}
+	}:
+		return true
+	default:
+		p.wg.Done()
+		return false
+	}
+}
+
 // Wait 直到所有提交的任务都完成。
 func (p *GPool) Wait() {
 	p.wg.Wait()
@@ -92,6 +114,11 @@ func (p *GPool) Shutdown() {
 	p.Wait()
 }
 
+// QueueLength 返回队列中等待执行的任务数。
+func (p *GPool) QueueLength() int {
+	return len(p.workQueue)
+}
+
 // RunningCount 返回当前正在运行的任务数。
 func (p *GPool) RunningCount() int64 {
 	return atomic.LoadInt64(&p.running)
@@ -222,3 +222,36 @@ func TestHighConcurrency(t *testing.T) {
 		t.Errorf("Expected 0 running goroutines after all tasks completed, but got %d", runningCount)
 	}
 }
+
+// TestTryAddJob 测试队列已满时 TryAddJob 立即返回 false
+func TestTryAddJob(t *testing.T) {
+	pool := NewGPoolWithQueue(1, 1)
+	defer pool.Shutdown()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if !pool.TryAddJob(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}) {
+		t.Fatal("Expected first job to be accepted")
+	}
+	<-started
+
+	if !pool.TryAddJob(func(ctx context.Context) error { return nil }) {
+		t.Fatal("Expected second job to be queued")
+	}
+	if pool.QueueLength() != 1 {
+		t.Errorf("Expected queue length 1, got %d", pool.QueueLength())
+	}
+	if pool.TryAddJob(func(ctx context.Context) error { return nil }) {
+		t.Error("Expected job to be rejected when queue is full")
+	}
+
+	close(release)
+	pool.Wait()
+	if pool.QueueLength() != 0 {
+		t.Errorf("Expected empty queue, got %d", pool.QueueLength())
+	}
+}
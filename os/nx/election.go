@@ -0,0 +1,243 @@
+package nx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Leadership 领导权变化的通知
+type Leadership struct {
+	Key    string // 选举的租约键
+	ID     string // 当前节点的标识
+	Leader bool   // 是否成为领导者，false 表示失去领导权
+	Token  int64  // 成为领导者时的 fencing token
+}
+
+// ElectionOptions 选举的参数
+type ElectionOptions struct {
+	id     string
+	ttl    time.Duration
+	renew  time.Duration
+	logger *slog.Logger
+}
+
+// WithNodeID 设置节点标识，默认为 主机名-随机串
+func WithNodeID(id string) func(*ElectionOptions) {
+	return func(o *ElectionOptions) {
+		o.id = id
+	}
+}
+
+// WithLeaseTTL 设置租约的有效期，默认 15 秒。领导者宕机后，其他节点最多等待 ttl 后接任
+func WithLeaseTTL(ttl time.Duration) func(*ElectionOptions) {
+	return func(o *ElectionOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithRenewInterval 设置续期和竞选的间隔，默认为 ttl 的三分之一
+func WithRenewInterval(interval time.Duration) func(*ElectionOptions) {
+	return func(o *ElectionOptions) {
+		o.renew = interval
+	}
+}
+
+// WithElectionLogger 设置日志记录器
+func WithElectionLogger(logger *slog.Logger) func(*ElectionOptions) {
+	return func(o *ElectionOptions) {
+		o.logger = logger
+	}
+}
+
+// Elector 基于租约的领导者选举。
+// 所有节点以相同的 key 竞争租约，持有租约的节点为领导者并定期续期；
+// 续期失败时立即放弃领导权，取消 Leadership 返回的上下文。
+type Elector struct {
+	locker Locker
+	key    string
+	ops    ElectionOptions
+
+	mu        sync.RWMutex
+	leader    bool
+	token     int64
+	leaderCtx context.Context
+	stepDown  context.CancelFunc
+	hooks     []func(Leadership)
+
+	startOnce sync.Once
+	closeOnce sync.Once
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewElector 创建选举，调用 Start 后开始竞选
+func NewElector(locker Locker, key string, options ...func(*ElectionOptions)) (*Elector, error) {
+	if locker == nil {
+		return nil, errors.New("locker must not be nil")
+	}
+	if key == "" {
+		return nil, errors.New("election key must not be empty")
+	}
+	ops := ElectionOptions{ttl: 15 * time.Second, logger: slog.Default()}
+	for _, f := range options {
+		f(&ops)
+	}
+	if ops.ttl <= 0 {
+		return nil, errors.New("lease ttl must be greater than zero")
+	}
+	if ops.renew <= 0 {
+		ops.renew = ops.ttl / 3
+	}
+	if ops.renew >= ops.ttl {
+		return nil, errors.New("renew interval must be less than lease ttl")
+	}
+	if ops.id == "" {
+		host, _ := os.Hostname()
+		ops.id = fmt.Sprintf("%s-%s", host, uuid.NewString()[:8])
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return &Elector{
+		locker:    locker,
+		key:       key,
+		ops:       ops,
+		leaderCtx: ctx,
+		stepDown:  cancel,
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// ID 返回当前节点的标识
+func (e *Elector) ID() string {
+	return e.ops.id
+}
+
+// OnChange 注册领导权变化的回调，回调在选举的 goroutine 中同步执行，不应长时间阻塞
+func (e *Elector) OnChange(hook func(Leadership)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.hooks = append(e.hooks, hook)
+}
+
+// IsLeader 当前节点是否为领导者
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Leadership 返回领导权的上下文和 fencing token，上下文在失去领导权时取消，
+// 并通过 TokenFromContext 携带 token。不是领导者时 ok 为 false
+func (e *Elector) Leadership() (ctx context.Context, token int64, ok bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leaderCtx, e.token, e.leader
+}
+
+// Start 开始竞选，重复调用无效
+func (e *Elector) Start() {
+	e.startOnce.Do(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		e.cancel = cancel
+		go e.run(ctx)
+	})
+}
+
+// Close 停止竞选并释放租约，重复调用无效
+func (e *Elector) Close() error {
+	e.closeOnce.Do(func() {
+		e.startOnce.Do(func() { close(e.done) })
+		if e.cancel != nil {
+			e.cancel()
+		}
+	})
+	<-e.done
+	return nil
+}
+
+func (e *Elector) run(ctx context.Context) {
+	defer close(e.done)
+	ticker := time.NewTicker(e.ops.renew)
+	defer ticker.Stop()
+	for {
+		e.campaign(ctx)
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				e.lose()
+				releaseCtx, cancel := context.WithTimeout(context.Background(), e.ops.renew)
+				if err := e.locker.Release(releaseCtx, e.key, e.ops.id); err != nil {
+					e.ops.logger.Warn("release lease failed", "key", e.key, "error", err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign 领导者续期，其他节点尝试获取租约
+func (e *Elector) campaign(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.ops.renew)
+	defer cancel()
+
+	if e.IsLeader() {
+		ok, err := e.locker.Renew(ctx, e.key, e.ops.id, e.ops.ttl)
+		if err == nil && ok {
+			return
+		}
+		// 无法确认租约仍然有效，立即放弃，避免出现两个领导者
+		e.ops.logger.Warn("renew lease failed, stepping down", "key", e.key, "id", e.ops.id, "error", err)
+		e.lose()
+		return
+	}
+
+	token, ok, err := e.locker.Acquire(ctx, e.key, e.ops.id, e.ops.ttl)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.ops.logger.Warn("acquire lease failed", "key", e.key, "error", err)
+		}
+		return
+	}
+	if ok {
+		e.win(token)
+	}
+}
+
+func (e *Elector) win(token int64) {
+	e.mu.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	e.leader, e.token = true, token
+	e.leaderCtx, e.stepDown = ContextWithToken(ctx, token), cancel
+	hooks := append([]func(Leadership){}, e.hooks...)
+	e.mu.Unlock()
+
+	e.ops.logger.Info("became leader", "key", e.key, "id", e.ops.id, "token", token)
+	e.notify(hooks, Leadership{Key: e.key, ID: e.ops.id, Leader: true, Token: token})
+}
+
+func (e *Elector) lose() {
+	e.mu.Lock()
+	e.leader = false
+	e.stepDown()
+	hooks := append([]func(Leadership){}, e.hooks...)
+	token := e.token
+	e.mu.Unlock()
+
+	e.ops.logger.Info("lost leadership", "key", e.key, "id", e.ops.id, "token", token)
+	e.notify(hooks, Leadership{Key: e.key, ID: e.ops.id, Leader: false, Token: token})
+}
+
+func (e *Elector) notify(hooks []func(Leadership), l Leadership) {
+	for _, hook := range hooks {
+		hook(l)
+	}
+}
@@ -0,0 +1,138 @@
+package nx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLocker(t *testing.T, locker Locker) {
+	ctx := context.Background()
+	key := "nx-test-" + time.Now().Format("150405.000000")
+
+	token, ok, err := locker.Acquire(ctx, key, "a", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), token)
+
+	// 其他节点无法获取，持有者重复获取返回原 token
+	_, ok, err = locker.Acquire(ctx, key, "b", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	again, ok, _ := locker.Acquire(ctx, key, "a", time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, token, again)
+
+	ok, err = locker.Renew(ctx, key, "b", time.Second)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, _ = locker.Renew(ctx, key, "a", 50*time.Millisecond)
+	assert.True(t, ok)
+
+	// 非持有者不能释放
+	assert.NoError(t, locker.Release(ctx, key, "b"))
+	_, ok, _ = locker.Acquire(ctx, key, "b", time.Second)
+	assert.False(t, ok)
+
+	// 过期后 token 递增
+	time.Sleep(100 * time.Millisecond)
+	token, ok, _ = locker.Acquire(ctx, key, "b", time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), token)
+	ok, _ = locker.Renew(ctx, key, "a", time.Second)
+	assert.False(t, ok)
+
+	assert.NoError(t, locker.Release(ctx, key, "b"))
+	token, ok, _ = locker.Acquire(ctx, key, "a", time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), token)
+	assert.NoError(t, locker.Release(ctx, key, "a"))
+}
+
+func TestMemoryLocker(t *testing.T) {
+	testLocker(t, NewMemoryLocker())
+}
+
+func TestRedisLocker(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("redis not available: %v", err)
+	}
+	locker, err := NewRedisLocker(client)
+	assert.NoError(t, err)
+	testLocker(t, locker)
+}
+
+func TestElector(t *testing.T) {
+	locker := NewMemoryLocker()
+	opts := []func(*ElectionOptions){WithLeaseTTL(150 * time.Millisecond), WithRenewInterval(20 * time.Millisecond)}
+
+	var (
+		mu     sync.Mutex
+		events []Leadership
+	)
+	hook := func(l Leadership) {
+		mu.Lock()
+		events = append(events, l)
+		mu.Unlock()
+	}
+	a, err := NewElector(locker, "election", append(opts, WithNodeID("a"))...)
+	assert.NoError(t, err)
+	b, err := NewElector(locker, "election", append(opts, WithNodeID("b"))...)
+	assert.NoError(t, err)
+	a.OnChange(hook)
+	b.OnChange(hook)
+	defer b.Close()
+
+	a.Start()
+	assert.Eventually(t, a.IsLeader, time.Second, 5*time.Millisecond)
+	b.Start()
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, b.IsLeader())
+
+	ctx, token, ok := a.Leadership()
+	assert.True(t, ok)
+	got, _ := TokenFromContext(ctx)
+	assert.Equal(t, token, got)
+
+	// 领导者停止后释放租约，其他节点接任，token 递增
+	assert.NoError(t, a.Close())
+	assert.Error(t, ctx.Err())
+	assert.False(t, a.IsLeader())
+	assert.Eventually(t, b.IsLeader, time.Second, 5*time.Millisecond)
+	_, next, _ := b.Leadership()
+	assert.Greater(t, next, token)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []Leadership{
+		{Key: "election", ID: "a", Leader: true, Token: token},
+		{Key: "election", ID: "a", Leader: false, Token: token},
+		{Key: "election", ID: "b", Leader: true, Token: next},
+	}, events)
+}
+
+func TestElectorStepDown(t *testing.T) {
+	locker := NewMemoryLocker()
+	e, err := NewElector(locker, "election", WithNodeID("a"), WithLeaseTTL(100*time.Millisecond), WithRenewInterval(20*time.Millisecond))
+	assert.NoError(t, err)
+	defer e.Close()
+	e.Start()
+	assert.Eventually(t, e.IsLeader, time.Second, 5*time.Millisecond)
+	ctx, _, _ := e.Leadership()
+
+	// 租约被其他节点抢占后续期失败，放弃领导权
+	assert.NoError(t, locker.Release(context.Background(), "election", "a"))
+	_, ok, _ := locker.Acquire(context.Background(), "election", "b", time.Minute)
+	assert.True(t, ok)
+	assert.Eventually(t, func() bool { return !e.IsLeader() }, time.Second, 5*time.Millisecond)
+	assert.Error(t, ctx.Err())
+
+	_, err = NewElector(locker, "election", WithLeaseTTL(time.Second), WithRenewInterval(time.Second))
+	assert.Error(t, err)
+}
@@ -1,3 +1,11 @@
+// Package nx 提供基于 Redis 的分布式锁。
+//
+// Nx 是简单的互斥锁：固定的键，每次加锁写入随机的持有者值，Unlock 只在值仍然匹配时删除，
+// 适合短时间互斥，例如 worker 扫描周期任务。
+//
+// Locker 是同一 SET NX + 过期时间方式的租约锁，但由调用方指定持有者并附带
+// fencing token，支持毫秒级的续期和按持有者释放，供跨进程的领导者选举使用。
+// 两者没有合并：Lock 的重试语义已被现有调用方依赖。
 package nx
 
 import (
@@ -5,12 +13,31 @@ import (
 	"errors"
 	"sync"
 	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
+)
+
+var (
+	lockScript = redis.NewScript(`
+		if redis.call("get", KEYS[1]) == false then
+			return redis.call("setex", KEYS[1], ARGV[1], ARGV[2])
+		else
+			return 0
+		end
+	`)
+	unlockScript = redis.NewScript(`
+		if redis.call("get", KEYS[1]) == ARGV[1] then
+			return redis.call("del", KEYS[1])
+		end
+		return 0
+	`)
 )
 
 type Nx struct {
 	ops    Options
 	mu     sync.Mutex
-	owners map[string]bool
+	owners map[string]string // 键 -> 本实例写入的持有者值
 }
 
 // New 创建一个新的 nx 锁实例
@@ -24,7 +51,7 @@ func New(options ...func(*Options)) (*Nx, error) {
 	}
 	return &Nx{
 		ops:    *ops,
-		owners: make(map[string]bool),
+		owners: make(map[string]string),
 	}, nil
 }
 
@@ -54,29 +81,31 @@ func (nx *Nx) Lock(ctx context.Context) error {
 
 // tryLock 尝试获取锁。如果获取成功，则返回 true，否则返回 false
 func (nx *Nx) tryLock(ctx context.Context) (bool, error) {
-	script := `
-		if redis.call("get", KEYS[1]) == false then
-			return redis.call("setex", KEYS[1], ARGV[1], "locked")
-		else
-			return 0
-		end
-	`
-	result, err := nx.ops.redis.Eval(ctx, script, []string{nx.ops.key}, nx.ops.expire).Result()
+	value := guid.S()
+	result, err := lockScript.Run(ctx, nx.ops.redis, []string{nx.ops.key}, nx.ops.expire, value).Result()
 	if err != nil {
 		return false, err
 	}
-	return result == "OK", nil
+	if result != "OK" {
+		return false, nil
+	}
+	// 记录持有者值，Unlock 时只删除仍由该值持有的键
+	nx.mu.Lock()
+	nx.owners[nx.ops.key] = value
+	nx.mu.Unlock()
+	return true, nil
 }
 
-// Unlock 释放锁
+// Unlock 释放锁。锁已过期并被其他节点获取时不做任何操作
 func (nx *Nx) Unlock(ctx context.Context) error {
 	nx.mu.Lock()
 	defer nx.mu.Unlock()
 
-	if !nx.owners[nx.ops.key] {
+	value, ok := nx.owners[nx.ops.key]
+	if !ok {
 		return nil
 	}
 	delete(nx.owners, nx.ops.key)
 
-	return nx.ops.redis.Del(ctx, nx.ops.key).Err()
+	return unlockScript.Run(ctx, nx.ops.redis, []string{nx.ops.key}, value).Err()
 }
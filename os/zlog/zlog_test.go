@@ -2,6 +2,7 @@ package zlog
 
 import (
 	"github.com/sagoo-cloud/nexframe/configs"
+	"path/filepath"
 	"testing"
 )
 
@@ -17,7 +18,7 @@ func TestLoggerImpl(t *testing.T) {
 		Pattern: "development",
 		Output:  "file",
 		LogRotate: configs.LogRotate{
-			Filename: "app.log",
+			Filename: filepath.Join(t.TempDir(), "app.log"),
 			MaxSize:  50,
 		},
 	}
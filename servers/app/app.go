@@ -0,0 +1,230 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/configs"
+	"github.com/sagoo-cloud/nexframe/os/command/args"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+var ErrUnknownServer = errors.New("unknown server")
+
+// All 选择所有已注册的服务
+const All = "all"
+
+// App 按名称注册服务，启动 -servers 参数选择的服务。
+// 选择的服务并发启动，任一服务返回错误时关闭所有服务并返回该错误；
+// 收到 SIGINT/SIGTERM 或上下文取消时按注册的逆序关闭。
+type App struct {
+	Logger          *slog.Logger
+	Servers         []string      // 需要启动的服务名称，为 nil 时使用 Selected()
+	ShutdownTimeout time.Duration // 关闭后等待 Serve 返回的最长时间，默认 30 秒
+
+	entries []entry
+	mu      sync.Mutex
+}
+
+type entry struct {
+	name   string
+	server commons.Server
+}
+
+func New() *App {
+	return &App{
+		Logger:          slog.Default(),
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// Selected 返回需要启动的服务名称，显式传入的 -servers 参数优先于 app.servers 配置
+func Selected() []string {
+	names, _ := selectedNames()
+	return names
+}
+
+// selectedNames 返回需要启动的服务名称，两者都未指定而使用 -servers 的默认值时 byDefault 为 true
+func selectedNames() (names []string, byDefault bool) {
+	value := args.Server
+	if !flagSet("servers") {
+		if servers := configs.EnvString(configs.AppServers, ""); servers != "" {
+			value = servers
+		} else {
+			byDefault = true
+		}
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, byDefault
+}
+
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Register 注册服务，同名服务会被替换。关闭顺序与注册顺序相反，被依赖的服务应先注册。
+func (a *App) Register(name string, server commons.Server) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := range a.entries {
+		if a.entries[i].name == name {
+			a.entries[i].server = server
+			return
+		}
+	}
+	a.entries = append(a.entries, entry{name: name, server: server})
+}
+
+// selected 按注册顺序返回被选择的服务和服务名称，包含未注册的名称时返回错误。
+// 服务名称来自 -servers 的默认值时，未注册的名称只记录警告，例如只注册了 http 时默认值中的 event
+func (a *App) selected() ([]entry, []string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	names, byDefault := a.Servers, false
+	if names == nil {
+		names, byDefault = selectedNames()
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == All {
+			return append([]entry(nil), a.entries...), names, nil
+		}
+		wanted[name] = true
+	}
+
+	var list []entry
+	for _, e := range a.entries {
+		if wanted[e.name] {
+			list = append(list, e)
+			delete(wanted, e.name)
+		}
+	}
+	for _, name := range names {
+		if !wanted[name] {
+			continue
+		}
+		if !byDefault {
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnknownServer, name)
+		}
+		a.Logger.Warn("Default server not registered, skipped", "server", name)
+	}
+	return list, names, nil
+}
+
+type result struct {
+	name string
+	err  error
+}
+
+// Run 启动选择的服务并阻塞。
+// 上下文取消、收到退出信号或任一服务返回错误时，按注册的逆序关闭所有服务；
+// 所有服务的 Serve 都正常返回时（例如 cli 命令执行完成）也会结束。
+// 返回第一个致命错误，没有时返回关闭过程中的错误。
+func (a *App) Run(ctx context.Context) error {
+	list, names, err := a.selected()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		a.Logger.Warn("No server selected", "servers", strings.Join(names, ","))
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	results := make(chan result, len(list))
+	for _, e := range list {
+		go func(e entry) {
+			a.Logger.Info("Server starting", "name", e.name)
+			results <- result{name: e.name, err: e.server.Serve()}
+		}(e)
+	}
+
+	var fatal error
+	running := len(list)
+wait:
+	for running > 0 {
+		select {
+		case <-ctx.Done():
+			a.Logger.Info("Shutting down", "cause", context.Cause(ctx))
+			break wait
+		case r := <-results:
+			running--
+			if r.err != nil {
+				fatal = fmt.Errorf("%s: %w", r.name, r.err)
+				a.Logger.Error("Server failed", "name", r.name, "error", r.err)
+				break wait
+			}
+			a.Logger.Info("Server stopped", "name", r.name)
+		}
+	}
+
+	closeErr := a.shutdown(list, results, running)
+	if fatal != nil {
+		return fatal
+	}
+	return closeErr
+}
+
+// shutdown 按逆序关闭服务，并等待仍在运行的 Serve 返回
+func (a *App) shutdown(list []entry, results <-chan result, running int) error {
+	var errs []error
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := list[i].server.Close(); err != nil {
+			a.Logger.Error("Server close failed", "name", list[i].name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", list[i].name, err))
+		}
+	}
+
+	timeout := time.After(a.ShutdownTimeout)
+	for ; running > 0; running-- {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				a.Logger.Warn("Server stopped with error", "name", r.name, "error", r.err)
+			}
+		case <-timeout:
+			a.Logger.Error("Shutdown timeout", "running", running)
+			return errors.Join(append(errs, context.DeadlineExceeded)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// funcServer 将启动和关闭函数适配为 commons.Server
+type funcServer struct {
+	serve func() error
+	close func() error
+}
+
+func (s *funcServer) Serve() error { return s.serve() }
+func (s *funcServer) Close() error { return s.close() }
+
+// Wrap 将启动和关闭函数适配为 commons.Server，
+// 例如 Wrap(consumer.Run, consumer.Stop)
+func Wrap(serve func() error, close func() error) commons.Server {
+	if close == nil {
+		close = func() error { return nil }
+	}
+	return &funcServer{serve: serve, close: close}
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/nf"
+	"github.com/sagoo-cloud/nexframe/servers/commands"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/servers/grpc"
+	"github.com/sagoo-cloud/nexframe/servers/mqttbroker"
+	"github.com/sagoo-cloud/nexframe/servers/mqtts"
+	"github.com/sagoo-cloud/nexframe/servers/tcp"
+	"github.com/sagoo-cloud/nexframe/servers/timers"
+	"github.com/sagoo-cloud/nexframe/servers/udp"
+	"github.com/sagoo-cloud/nexframe/servers/websockets"
+	"github.com/stretchr/testify/assert"
+)
+
+// 各传输层的服务都实现了 commons.Server
+var (
+	_ commons.Server = (*nf.APIFramework)(nil)
+	_ commons.Server = (*commands.Server)(nil)
+	_ commons.Server = (*grpc.Server)(nil)
+	_ commons.Server = (*mqttbroker.Server)(nil)
+	_ commons.Server = (*mqtts.Server)(nil)
+	_ commons.Server = (*tcp.Server)(nil)
+	_ commons.Server = (*timers.Server)(nil)
+	_ commons.Server = (*udp.Server)(nil)
+	_ commons.Server = (*websockets.Server)(nil)
+)
+
+// fakeServer 阻塞直到 Close，记录关闭顺序
+type fakeServer struct {
+	name    string
+	err     error // Serve 立即返回的错误
+	exit    bool  // Serve 立即正常返回
+	order   *[]string
+	mu      *sync.Mutex
+	started chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newFake(name string, order *[]string, mu *sync.Mutex) *fakeServer {
+	return &fakeServer{name: name, order: order, mu: mu, started: make(chan struct{}), closed: make(chan struct{})}
+}
+
+func (s *fakeServer) Serve() error {
+	close(s.started)
+	if s.err != nil || s.exit {
+		return s.err
+	}
+	<-s.closed
+	return nil
+}
+
+func (s *fakeServer) Close() error {
+	s.once.Do(func() {
+		s.mu.Lock()
+		*s.order = append(*s.order, s.name)
+		s.mu.Unlock()
+		close(s.closed)
+	})
+	return nil
+}
+
+func newApp(servers ...string) *App {
+	a := New()
+	a.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	a.Servers = servers
+	return a
+}
+
+func TestRunCancel(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	http, grpcs, event := newFake("http", &order, &mu), newFake("grpc", &order, &mu), newFake("event", &order, &mu)
+	a := newApp("event", "http")
+	a.Register("http", http)
+	a.Register("grpc", grpcs)
+	a.Register("event", event)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	<-http.started
+	<-event.started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("run not stopped")
+	}
+	// 未选择的服务不启动，关闭顺序与注册顺序相反
+	assert.Equal(t, []string{"event", "http"}, order)
+	select {
+	case <-grpcs.started:
+		t.Fatal("unselected server started")
+	default:
+	}
+}
+
+func TestRunFatal(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	http, broken := newFake("http", &order, &mu), newFake("tcp", &order, &mu)
+	broken.err = errors.New("address in use")
+	a := newApp(All)
+	a.Register("http", http)
+	a.Register("tcp", broken)
+
+	err := a.Run(context.Background())
+	assert.ErrorContains(t, err, "tcp: address in use")
+	assert.Equal(t, []string{"tcp", "http"}, order)
+}
+
+func TestRunFinished(t *testing.T) {
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	cmd := newFake("cmd", &order, &mu)
+	cmd.exit = true
+	a := newApp("cmd")
+	a.Register("cmd", cmd)
+	assert.NoError(t, a.Run(context.Background()))
+	assert.Equal(t, []string{"cmd"}, order)
+}
+
+func TestRunUnknown(t *testing.T) {
+	a := newApp("http", "mqtt")
+	a.Register("http", Wrap(func() error { return nil }, nil))
+	assert.ErrorIs(t, a.Run(context.Background()), ErrUnknownServer)
+}
+
+func TestRunDefaultServers(t *testing.T) {
+	if _, byDefault := selectedNames(); !byDefault {
+		t.Skip("-servers or app.servers is set")
+	}
+	a := New()
+	a.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	var (
+		order []string
+		mu    sync.Mutex
+	)
+	// 默认值 http,event 中的 event 没有注册，只启动 http
+	http := newFake("http", &order, &mu)
+	a.Register("http", http)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	select {
+	case <-http.started:
+	case err := <-done:
+		t.Fatalf("run returned early: %v", err)
+	}
+	cancel()
+	assert.NoError(t, <-done)
+
+	// 显式指定的名称仍然必须已注册
+	a = newApp("http", "event")
+	a.Register("http", newFake("http", &order, &mu))
+	assert.ErrorIs(t, a.Run(context.Background()), ErrUnknownServer)
+}
+
+func TestRunServers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ts := tcp.NewServer()
+	ts.Address = "127.0.0.1:0"
+	ts.Logger = logger
+	us := udp.NewServer()
+	us.Address = "127.0.0.1:0"
+	us.Logger = logger
+	gs, err := grpc.NewServer(grpc.WithAddress("127.0.0.1:0"), grpc.WithLogger(logger))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ws := websockets.NewServer(websockets.WithAddress("127.0.0.1:0"), websockets.WithLogger(logger))
+
+	a := newApp("tcp", "udp", "grpc", "websocket")
+	a.Register("tcp", ts)
+	a.Register("udp", us)
+	a.Register("grpc", gs)
+	a.Register("websocket", ws)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	assert.Eventually(t, func() bool {
+		return ts.Addr() != nil && us.Addr() != nil && gs.Addr() != nil && ws.Addr() != nil
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers not stopped")
+	}
+}
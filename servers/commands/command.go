@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/utils/tag"
+)
+
+var (
+	ErrInvalidCommand = errors.New("invalid command")
+	ErrCommandExists  = errors.New("command already exists")
+)
+
+// Meta 在结构体中声明命令的元数据，标签写在名为 Meta 的字段上，例如
+//
+//	type HttpInput struct {
+//		commands.Meta `name:"http" brief:"启动HTTP服务"`
+//		Port int `short:"p" brief:"监听端口" d:"8000"`
+//	}
+type Meta struct{}
+
+// 命令和参数使用的标签，其余标签使用 utils/tag 中的定义
+const (
+	tagName   = "name"   // 命令名称或参数名称
+	tagShort  = "short"  // 参数的短名称，例如 p 对应 -p
+	tagHidden = "hidden" // 不在帮助和补全中显示
+)
+
+// Command 命令行命令，可以包含子命令。
+// 设置 Input 时，命令行参数按标签绑定到 Input 类型的新实例上，处理器收到该实例的指针；
+// 未设置 Input 时，处理器收到位置参数 []string。Handler 为 nil 的命令只作为命令组，执行时显示帮助。
+type Command struct {
+	Name        string
+	Brief       string               // 简要说明，显示在父命令的帮助中
+	Description string               // 详细说明
+	Examples    string               // 使用示例
+	Additional  string               // 帮助末尾的补充说明
+	Hidden      bool                 // 不在帮助和补全中显示
+	Input       interface{}          // 参数结构体的原型，例如 (*HttpInput)(nil)
+	Handler     *commons.CommHandler // 命令的处理器
+
+	parent    *Command
+	children  []*Command
+	inputType reflect.Type
+	options   []*option // 以 -name 或 --name 传入的参数
+	arguments []*option // 按顺序传入的位置参数
+}
+
+// AddCommand 添加子命令
+func (c *Command) AddCommand(children ...*Command) error {
+	for _, child := range children {
+		if child == nil || child.Name == "" || strings.HasPrefix(child.Name, "-") || strings.ContainsAny(child.Name, " \t") {
+			return fmt.Errorf("%w: empty or invalid name", ErrInvalidCommand)
+		}
+		if c.Child(child.Name) != nil {
+			return fmt.Errorf("%w: %s %s", ErrCommandExists, c.Path(), child.Name)
+		}
+		if err := child.init(); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidCommand, child.Name, err)
+		}
+		child.parent = c
+		c.children = append(c.children, child)
+	}
+	return nil
+}
+
+// Child 按名称查找直接的子命令
+func (c *Command) Child(name string) *Command {
+	for _, child := range c.children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// Children 返回子命令
+func (c *Command) Children() []*Command {
+	return c.children
+}
+
+// Path 返回从根命令开始的完整命令，例如 app db migrate
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+// init 解析 Input 的字段，生成参数定义
+func (c *Command) init() error {
+	if c.Input == nil || c.inputType != nil {
+		return nil
+	}
+	t := reflect.TypeOf(c.Input)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("input must be a struct, got %s", t.Kind())
+	}
+	options, arguments, err := parseOptions(t)
+	if err != nil {
+		return err
+	}
+	c.inputType, c.options, c.arguments = t, options, arguments
+	return nil
+}
+
+// HandlerFunc 类型化的命令处理函数，in 由命令行参数绑定并校验后得到
+type HandlerFunc[T any] = commons.TypedFunc[T]
+
+// Func 根据类型化的处理函数创建命令，名称和说明来自 T 的 Meta 字段标签
+func Func[T any](fn HandlerFunc[T]) (*Command, error) {
+	c := &Command{
+		Input:   (*T)(nil),
+		Handler: &commons.CommHandler{Handler: commons.NewTypedHandler(fn)},
+	}
+	if err := c.setMeta(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		return nil, err
+	}
+	return c, c.init()
+}
+
+// setMeta 读取结构体 Meta 字段的标签
+func (c *Command) setMeta(t reflect.Type) error {
+	meta, ok := t.FieldByName("Meta")
+	if !ok {
+		return fmt.Errorf("%w: %s has no Meta field", ErrInvalidCommand, t)
+	}
+	c.Name = meta.Tag.Get(tagName)
+	if c.Name == "" {
+		return fmt.Errorf("%w: %s has no name tag", ErrInvalidCommand, t)
+	}
+	c.Brief = meta.Tag.Get(tag.Brief)
+	c.Description = lookupTag(meta.Tag, tag.Description, tag.DescriptionShort, tag.DescriptionShort2)
+	c.Examples = lookupTag(meta.Tag, tag.Examples, tag.ExampleShort)
+	c.Additional = lookupTag(meta.Tag, tag.Additional, tag.AdditionalShort)
+	c.Hidden = meta.Tag.Get(tagHidden) == "true"
+	return nil
+}
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Object 以结构体对象定义命令组，参照 GoFrame 的命令对象：
+// 对象 Meta 字段的 name/brief 标签定义命令本身，root 标签指定作为命令本身处理函数的方法名；
+// 其余形如 func(ctx context.Context, in *XxxInput) (out interface{}, err error) 的导出方法作为子命令，
+// 子命令的名称和说明来自输入结构体 Meta 字段的标签。
+func Object(object interface{}) (*Command, error) {
+	v := reflect.ValueOf(object)
+	t := v.Type()
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: object must be a struct, got %s", ErrInvalidCommand, t)
+	}
+	c := &Command{}
+	if err := c.setMeta(st); err != nil {
+		return nil, err
+	}
+	meta, _ := st.FieldByName("Meta")
+	root := meta.Tag.Get(tag.Root)
+
+	for i := 0; i < t.NumMethod(); i++ {
+		method := t.Method(i)
+		input, handler, ok := methodHandler(v.Method(i))
+		if !ok {
+			continue
+		}
+		if method.Name == root {
+			c.Input, c.Handler = reflect.Zero(reflect.PointerTo(input)).Interface(), handler
+			if err := c.init(); err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCommand, method.Name, err)
+			}
+			continue
+		}
+		child := &Command{Input: reflect.Zero(reflect.PointerTo(input)).Interface(), Handler: handler}
+		if err := child.setMeta(input); err != nil {
+			return nil, err
+		}
+		if err := c.AddCommand(child); err != nil {
+			return nil, err
+		}
+	}
+	if root != "" && c.Handler == nil {
+		return nil, fmt.Errorf("%w: root method %s not found", ErrInvalidCommand, root)
+	}
+	return c, nil
+}
+
+// methodHandler 检查方法签名，返回输入结构体类型和处理器
+func methodHandler(method reflect.Value) (reflect.Type, *commons.CommHandler, bool) {
+	mt := method.Type()
+	if mt.NumIn() != 2 || mt.NumOut() != 2 || mt.In(0) != contextType || mt.Out(1) != errorType {
+		return nil, nil, false
+	}
+	in := mt.In(1)
+	if in.Kind() != reflect.Ptr || in.Elem().Kind() != reflect.Struct {
+		return nil, nil, false
+	}
+	return in.Elem(), &commons.CommHandler{Handler: &methodCaller{method: method}}, true
+}
+
+// methodCaller 通过反射调用命令对象的方法
+type methodCaller struct {
+	method reflect.Value
+}
+
+func (m *methodCaller) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	out := m.method.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(request)})
+	err, _ := out[1].Interface().(error)
+	if err != nil {
+		return nil, err
+	}
+	return out[0].Interface(), nil
+}
+
+func lookupTag(st reflect.StructTag, keys ...string) string {
+	for _, key := range keys {
+		if v := st.Get(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
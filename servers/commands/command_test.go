@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/stretchr/testify/assert"
+)
+
+type serveInput struct {
+	Meta    `name:"serve" brief:"启动服务" dc:"启动HTTP服务并监听端口"`
+	Name    string        `arg:"true" brief:"服务名称" v:"required"`
+	Port    int           `short:"p" brief:"监听端口" d:"8000"`
+	Debug   bool          `short:"d" brief:"调试模式"`
+	Tags    []string      `brief:"标签"`
+	Timeout time.Duration `brief:"超时时间" d:"5s"`
+}
+
+type userRow struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// dbCommand 命令对象，Root 作为 db 命令本身，其余方法为子命令
+type dbCommand struct {
+	Meta `name:"db" brief:"数据库管理" root:"Root"`
+}
+
+type dbRootInput struct {
+	Meta `name:"db"`
+}
+
+type dbMigrateInput struct {
+	Meta  `name:"migrate" brief:"执行迁移"`
+	Step  int      `brief:"迁移步数" d:"1"`
+	Files []string `arg:"true" brief:"迁移文件"`
+}
+
+type dbUsersInput struct {
+	Meta `name:"users" brief:"用户列表"`
+}
+
+func (c *dbCommand) Root(ctx context.Context, in *dbRootInput) (interface{}, error) {
+	return "db ready", nil
+}
+
+func (c *dbCommand) Migrate(ctx context.Context, in *dbMigrateInput) (interface{}, error) {
+	if in.Step < 0 {
+		return nil, Exit(3, errors.New("negative step"))
+	}
+	return map[string]interface{}{"step": in.Step, "files": in.Files}, nil
+}
+
+func (c *dbCommand) Users(ctx context.Context, in *dbUsersInput) (interface{}, error) {
+	return []userRow{{Id: 1, Name: "alice"}, {Id: 2, Name: "bob"}}, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	s := NewServer()
+	s.Root.Name = "app"
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	s.Out, s.Err = out, errOut
+
+	serve, err := Func(func(ctx context.Context, in *serveInput) (interface{}, error) {
+		return in, nil
+	})
+	assert.NoError(t, err)
+	db, err := Object(&dbCommand{})
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddCommand(serve, db))
+	return s, out, errOut
+}
+
+func TestExecuteBind(t *testing.T) {
+	s, out, _ := newTestServer(t)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Execute(ctx, []string{"serve", "web", "-p", "9000", "--debug", "--tags=a,b", "--tags", "c", "-o", "json"}))
+	assert.JSONEq(t, `{"Name":"web","Port":9000,"Debug":true,"Tags":["a","b","c"],"Timeout":5000000000}`, out.String())
+
+	// 默认值
+	out.Reset()
+	assert.NoError(t, s.Execute(ctx, []string{"serve", "--timeout=1m", "web", "-o=json"}))
+	assert.JSONEq(t, `{"Name":"web","Port":8000,"Debug":false,"Tags":null,"Timeout":60000000000}`, out.String())
+}
+
+func TestExecuteErrors(t *testing.T) {
+	s, _, errOut := newTestServer(t)
+	ctx := context.Background()
+
+	// 缺少必填的位置参数
+	err := s.Execute(ctx, []string{"serve"})
+	assert.Equal(t, CodeUsage, ExitCode(err))
+	assert.Contains(t, errOut.String(), "Usage: app serve [OPTION] NAME")
+
+	err = s.Execute(ctx, []string{"serve", "web", "--port", "abc"})
+	assert.Equal(t, CodeUsage, ExitCode(err))
+	err = s.Execute(ctx, []string{"serve", "web", "--unknown"})
+	assert.Equal(t, CodeUsage, ExitCode(err))
+	err = s.Execute(ctx, []string{"serve", "web", "extra"})
+	assert.Equal(t, CodeUsage, ExitCode(err))
+	err = s.Execute(ctx, []string{"nope"})
+	assert.Equal(t, CodeUsage, ExitCode(err))
+
+	// 处理器指定的退出码
+	err = s.Execute(ctx, []string{"db", "migrate", "--step", "-1"})
+	assert.Equal(t, 3, ExitCode(err))
+	assert.Contains(t, errOut.String(), "negative step")
+
+	assert.Equal(t, CodeFailure, ExitCode(errors.New("failed")))
+	assert.Equal(t, CodeOK, ExitCode(nil))
+}
+
+func TestObjectCommand(t *testing.T) {
+	s, out, _ := newTestServer(t)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Execute(ctx, []string{"db"}))
+	assert.Equal(t, "db ready\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, s.Execute(ctx, []string{"db", "migrate", "a.sql", "b.sql"}))
+	assert.Equal(t, "KEY    VALUE\nfiles  [\"a.sql\",\"b.sql\"]\nstep   1\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, s.Execute(ctx, []string{"db", "users"}))
+	assert.Equal(t, "ID  NAME\n1   alice\n2   bob\n", out.String())
+}
+
+func TestHelp(t *testing.T) {
+	s, out, _ := newTestServer(t)
+	ctx := context.Background()
+
+	assert.NoError(t, s.Execute(ctx, []string{"serve", "--help"}))
+	help := out.String()
+	assert.Contains(t, help, "启动HTTP服务并监听端口")
+	assert.Contains(t, help, "USAGE\n    app serve [OPTION] NAME")
+	assert.Contains(t, help, "-p, --port int")
+	assert.Contains(t, help, "监听端口 (默认 8000)")
+	assert.Contains(t, help, "--timeout duration")
+
+	out.Reset()
+	assert.NoError(t, s.Execute(ctx, []string{"help", "db"}))
+	assert.Contains(t, out.String(), "migrate")
+	assert.Contains(t, out.String(), "执行迁移")
+
+	// 没有子命令时显示根命令的帮助
+	out.Reset()
+	assert.NoError(t, s.Execute(ctx, nil))
+	assert.Contains(t, out.String(), "COMMAND")
+	assert.Contains(t, out.String(), "completion")
+}
+
+func TestCompletion(t *testing.T) {
+	s, out, _ := newTestServer(t)
+	ctx := context.Background()
+
+	complete := func(words ...string) []string {
+		out.Reset()
+		assert.NoError(t, s.Execute(ctx, append([]string{completeCommand}, words...)))
+		return strings.Fields(out.String())
+	}
+	assert.Equal(t, []string{"serve"}, complete("se"))
+	assert.Equal(t, []string{"migrate"}, complete("db", "m"))
+	assert.Equal(t, []string{"--step"}, complete("db", "migrate", "--s"))
+	assert.Equal(t, []string{"json"}, complete("serve", "-o", "j"))
+	assert.Empty(t, complete("serve", "--port", ""))
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		out.Reset()
+		assert.NoError(t, s.Execute(ctx, []string{"completion", shell}))
+		assert.Contains(t, out.String(), completeCommand)
+	}
+	assert.Equal(t, CodeUsage, ExitCode(s.Execute(ctx, []string{"completion", "cmd"})))
+}
+
+type legacyHandler struct{}
+
+func (h *legacyHandler) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	return "args: " + request.(string), nil
+}
+
+func TestRegister(t *testing.T) {
+	s, out, _ := newTestServer(t)
+	s.Register("legacy", &commons.CommHandler{Handler: &legacyHandler{}})
+
+	assert.NoError(t, s.Execute(context.Background(), []string{"legacy", `{"id":1}`}))
+	assert.Equal(t, "args: {\"id\":1}\n", out.String())
+
+	assert.ErrorIs(t, s.AddCommand(&Command{Name: "legacy"}), ErrCommandExists)
+	_, err := Func(func(ctx context.Context, in *struct{ Port int }) (interface{}, error) { return nil, nil })
+	assert.ErrorIs(t, err, ErrInvalidCommand)
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// completeCommand 补全脚本调用的隐藏命令，参数为已输入的单词，最后一个为正在输入的单词
+const completeCommand = "__complete"
+
+var identRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// CompletionInput completion 命令的参数
+type CompletionInput struct {
+	Meta  `name:"completion" brief:"生成 shell 自动补全脚本" eg:"source <(app completion bash)"`
+	Shell string `arg:"true" brief:"bash、zsh 或 fish" d:"bash"`
+}
+
+// WriteCompletion 输出指定 shell 的补全脚本，脚本通过隐藏的 __complete 命令获取候选词
+func WriteCompletion(w io.Writer, program, shell string) error {
+	fn := "_" + identRegex.ReplaceAllString(program, "_") + "_complete"
+	bash := fmt.Sprintf(`%[1]s() {
+    local IFS=$'\n'
+    COMPREPLY=($("%[2]s" %[3]s "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F %[1]s %[2]s
+`, fn, program, completeCommand)
+
+	var script string
+	switch shell {
+	case "bash":
+		script = "# bash completion for " + program + "\n" + bash
+	case "zsh":
+		script = "#compdef " + program + "\nautoload -U +X bashcompinit && bashcompinit\n" + bash
+	case "fish":
+		script = fmt.Sprintf("complete -c %[1]s -f -a '(%[1]s %[2]s (commandline -opc)[2..-1] (commandline -ct))'\n", program, completeCommand)
+	default:
+		return usageErrorf("unsupported shell %q, expected bash, zsh or fish", shell)
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// complete 根据已输入的单词返回候选的子命令或参数名称
+func (c *Command) complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	cmd := c
+	var expectValue string
+	for _, word := range words[:len(words)-1] {
+		if expectValue != "" {
+			expectValue = ""
+			continue
+		}
+		if strings.HasPrefix(word, "-") {
+			if !strings.Contains(word, "=") {
+				expectValue = cmd.valueOption(strings.TrimLeft(word, "-"))
+			}
+			continue
+		}
+		if child := cmd.Child(word); child != nil {
+			cmd = child
+		}
+	}
+
+	var candidates []string
+	switch {
+	case expectValue == "output":
+		candidates = []string{FormatText, FormatTable, FormatJSON}
+	case expectValue != "":
+		return nil
+	case strings.HasPrefix(current, "-"):
+		for _, o := range cmd.options {
+			if o.hidden {
+				continue
+			}
+			candidates = append(candidates, "--"+o.name)
+			if o.short != "" {
+				candidates = append(candidates, "-"+o.short)
+			}
+		}
+		candidates = append(candidates, "--output", "--help")
+	default:
+		for _, child := range cmd.visibleChildren() {
+			candidates = append(candidates, child.Name)
+		}
+	}
+
+	var matched []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matched = append(matched, candidate)
+		}
+	}
+	return matched
+}
+
+// valueOption 参数需要单独的值时返回参数名，布尔参数和未知参数返回空
+func (c *Command) valueOption(name string) string {
+	if name == "o" || name == "output" {
+		return "output"
+	}
+	if o := c.findOption(name); o != nil && !o.isBool() {
+		return o.name
+	}
+	return ""
+}
+
+// completionCommand 生成 completion 命令
+func completionCommand(root *Command, out io.Writer) *Command {
+	c, _ := Func(func(ctx context.Context, in *CompletionInput) (interface{}, error) {
+		return nil, WriteCompletion(out, root.Name, in.Shell)
+	})
+	return c
+}
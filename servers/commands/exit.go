@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 进程退出码
+const (
+	CodeOK      = 0 // 执行成功
+	CodeFailure = 1 // 处理器返回错误
+	CodeUsage   = 2 // 命令或参数错误，例如未知的命令、参数值无效、校验失败
+)
+
+// ErrUsage 命令或参数错误，退出码为 CodeUsage
+var ErrUsage = errors.New("usage error")
+
+func usageErrorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// ExitError 指定退出码的错误，处理器可以通过 Exit 返回
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Exit 返回指定退出码的错误，err 为 nil 时只设置退出码
+func Exit(code int, err error) error {
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode 返回错误对应的进程退出码，通常在 main 中使用：os.Exit(commands.ExitCode(err))
+func ExitCode(err error) int {
+	if err == nil {
+		return CodeOK
+	}
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit.Code
+	}
+	if errors.Is(err, ErrUsage) {
+		return CodeUsage
+	}
+	return CodeFailure
+}
@@ -0,0 +1,354 @@
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sagoo-cloud/nexframe/utils/tag"
+)
+
+// option 由 Input 字段生成的参数定义
+type option struct {
+	name     string // 长名称，--name
+	short    string // 短名称，-n
+	brief    string
+	def      string // 默认值
+	index    []int  // 字段索引
+	typ      reflect.Type
+	argument bool // 位置参数
+	hidden   bool
+}
+
+// isBool 布尔参数不需要值，-v 等同于 -v=true
+func (o *option) isBool() bool {
+	return o.typ.Kind() == reflect.Bool
+}
+
+// isSlice 切片参数可重复传入，也可以用逗号分隔多个值
+func (o *option) isSlice() bool {
+	return o.typ.Kind() == reflect.Slice
+}
+
+// placeholder 帮助中参数值的占位符
+func (o *option) placeholder() string {
+	t := o.typ
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// reserved 所有命令共用的参数：-h/--help 显示帮助，-o/--output 指定输出格式
+var reserved = map[string]bool{"h": true, "help": true, "o": true, "output": true}
+
+// parseOptions 按标签解析结构体字段：
+// arg:"true" 为位置参数，按字段顺序传入；其余导出字段为 --name 参数，
+// name 标签指定名称（默认为字段名的短横线形式），short 指定短名称，brief 为说明，d/default 为默认值，
+// 匿名嵌入的结构体字段会被展开，Meta 字段和 name:"-" 的字段被忽略。
+func parseOptions(t reflect.Type) (options, arguments []*option, err error) {
+	names := make(map[string]bool)
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldIndex := append(append([]int(nil), index...), i)
+			if field.Name == "Meta" || !field.IsExported() {
+				continue
+			}
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := walk(field.Type, fieldIndex); err != nil {
+					return err
+				}
+				continue
+			}
+			name := field.Tag.Get(tagName)
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = kebabCase(field.Name)
+			}
+			o := &option{
+				name:     name,
+				short:    field.Tag.Get(tagShort),
+				brief:    field.Tag.Get(tag.Brief),
+				def:      lookupTag(field.Tag, tag.Default, tag.DefaultShort),
+				index:    fieldIndex,
+				typ:      field.Type,
+				argument: field.Tag.Get(tag.Arg) == "true",
+				hidden:   field.Tag.Get(tagHidden) == "true",
+			}
+			if !supported(o.typ) {
+				return fmt.Errorf("field %s: unsupported type %s", field.Name, o.typ)
+			}
+			if o.argument {
+				if n := len(arguments); n > 0 && arguments[n-1].isSlice() {
+					return fmt.Errorf("field %s: argument after variadic argument %s", field.Name, arguments[n-1].name)
+				}
+				arguments = append(arguments, o)
+				continue
+			}
+			for _, key := range []string{o.name, o.short} {
+				if key == "" {
+					continue
+				}
+				if names[key] || reserved[key] {
+					return fmt.Errorf("field %s: duplicate option %s", field.Name, key)
+				}
+				names[key] = true
+			}
+			options = append(options, o)
+		}
+		return nil
+	}
+	err = walk(t, nil)
+	return
+}
+
+func supported(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// kebabCase 将字段名转换为参数名，例如 MaxConn -> max-conn
+func kebabCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// setValue 将字符串转换为字段类型并赋值，切片字段追加，支持逗号分隔的多个值
+func setValue(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Slice {
+		for _, item := range strings.Split(value, ",") {
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err := setScalar(elem, strings.TrimSpace(item)); err != nil {
+				return err
+			}
+			field.Set(reflect.Append(field, elem))
+		}
+		return nil
+	}
+	return setScalar(field, value)
+}
+
+func setScalar(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// parsed 一次命令行解析的结果
+type parsed struct {
+	values    map[*option][]string
+	arguments []string
+	help      bool
+	output    string
+}
+
+// findOption 按长名称或短名称查找参数
+func (c *Command) findOption(name string) *option {
+	for _, o := range c.options {
+		if o.name == name || o.short != "" && o.short == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// parseArgs 解析命令的参数，-- 之后的内容都作为位置参数。
+// 支持 --name value、--name=value、-n value、-n=value，布尔参数可以省略值。
+func (c *Command) parseArgs(argv []string) (*parsed, error) {
+	p := &parsed{values: make(map[*option][]string)}
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			p.arguments = append(p.arguments, argv[i+1:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			p.arguments = append(p.arguments, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if n := strings.IndexByte(name, '='); n >= 0 {
+			name, value, hasValue = name[:n], name[n+1:], true
+		}
+		switch name {
+		case "h", "help":
+			p.help = true
+			continue
+		case "o", "output":
+			if !hasValue {
+				if i+1 >= len(argv) {
+					return nil, usageErrorf("option %s requires a value", arg)
+				}
+				i++
+				value = argv[i]
+			}
+			p.output = value
+			continue
+		}
+
+		o := c.findOption(name)
+		if o == nil {
+			return nil, usageErrorf("unknown option %s", arg)
+		}
+		if !hasValue {
+			if o.isBool() {
+				value = "true"
+			} else {
+				if i+1 >= len(argv) {
+					return nil, usageErrorf("option %s requires a value", arg)
+				}
+				i++
+				value = argv[i]
+			}
+		}
+		p.values[o] = append(p.values[o], value)
+	}
+	return p, nil
+}
+
+// bind 创建 Input 类型的新实例，依次写入默认值、命令行参数和位置参数
+func (c *Command) bind(p *parsed) (interface{}, error) {
+	in := reflect.New(c.inputType)
+	elem := in.Elem()
+
+	for _, o := range append(append([]*option(nil), c.options...), c.arguments...) {
+		if o.def == "" {
+			continue
+		}
+		if err := setValue(elem.FieldByIndex(o.index), o.def); err != nil {
+			return nil, fmt.Errorf("%w: invalid default for %s: %v", ErrInvalidCommand, o.name, err)
+		}
+	}
+
+	for _, o := range c.options {
+		values, ok := p.values[o]
+		if !ok {
+			continue
+		}
+		field := elem.FieldByIndex(o.index)
+		if o.isSlice() {
+			// 传入的值替换默认值
+			field.Set(reflect.MakeSlice(field.Type(), 0, len(values)))
+		}
+		for _, value := range values {
+			if err := setValue(field, value); err != nil {
+				return nil, usageErrorf("invalid value %q for option --%s: %v", value, o.name, err)
+			}
+		}
+	}
+
+	args := p.arguments
+	for _, o := range c.arguments {
+		if len(args) == 0 {
+			break
+		}
+		field := elem.FieldByIndex(o.index)
+		if o.isSlice() {
+			field.Set(reflect.MakeSlice(field.Type(), 0, len(args)))
+			for _, value := range args {
+				if err := setScalarAppend(field, value); err != nil {
+					return nil, usageErrorf("invalid value %q for argument %s: %v", value, o.name, err)
+				}
+			}
+			args = nil
+			break
+		}
+		if err := setScalar(field, args[0]); err != nil {
+			return nil, usageErrorf("invalid value %q for argument %s: %v", args[0], o.name, err)
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		return nil, usageErrorf("unexpected argument %q", args[0])
+	}
+	return in.Interface(), nil
+}
+
+// setScalarAppend 位置参数的每一项作为切片的一个元素，不按逗号分隔
+func setScalarAppend(field reflect.Value, value string) error {
+	elem := reflect.New(field.Type().Elem()).Elem()
+	if err := setScalar(elem, value); err != nil {
+		return err
+	}
+	field.Set(reflect.Append(field, elem))
+	return nil
+}
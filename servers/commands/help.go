@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteHelp 输出命令的帮助，包括用法、位置参数、参数和子命令
+func (c *Command) WriteHelp(w io.Writer) {
+	if c.Description != "" {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(c.Description))
+	} else if c.Brief != "" {
+		fmt.Fprintf(w, "%s\n\n", c.Brief)
+	}
+
+	fmt.Fprintf(w, "USAGE\n    %s\n", c.usage())
+
+	tw := tabwriter.NewWriter(w, 0, 4, 4, ' ', 0)
+	if len(c.arguments) > 0 {
+		fmt.Fprintln(w, "\nARGUMENT")
+		for _, o := range c.arguments {
+			fmt.Fprintf(tw, "    %s\t%s\n", strings.ToUpper(o.name), optionBrief(o))
+		}
+		_ = tw.Flush()
+	}
+
+	fmt.Fprintln(w, "\nOPTION")
+	for _, o := range c.options {
+		if o.hidden {
+			continue
+		}
+		flag := "    --" + o.name
+		if o.short != "" {
+			flag = "-" + o.short + ", --" + o.name
+		}
+		if p := o.placeholder(); p != "" {
+			flag += " " + p
+		}
+		fmt.Fprintf(tw, "    %s\t%s\n", flag, optionBrief(o))
+	}
+	fmt.Fprintf(tw, "    -o, --output string\t输出格式：text、table 或 json\n")
+	fmt.Fprintf(tw, "    -h, --help\t显示帮助\n")
+	_ = tw.Flush()
+
+	if children := c.visibleChildren(); len(children) > 0 {
+		fmt.Fprintln(w, "\nCOMMAND")
+		for _, child := range children {
+			fmt.Fprintf(tw, "    %s\t%s\n", child.Name, child.Brief)
+		}
+		_ = tw.Flush()
+	}
+
+	if c.Examples != "" {
+		fmt.Fprintf(w, "\nEXAMPLE\n%s\n", indent(c.Examples))
+	}
+	if c.Additional != "" {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace(c.Additional))
+	}
+	if len(c.children) > 0 {
+		fmt.Fprintf(w, "\n使用 \"%s COMMAND --help\" 查看子命令的帮助\n", c.Path())
+	}
+}
+
+// usage 用法行，例如 app http [OPTION] NAME [FILES...]
+func (c *Command) usage() string {
+	parts := []string{c.Path()}
+	if len(c.children) > 0 {
+		parts = append(parts, "COMMAND")
+	}
+	parts = append(parts, "[OPTION]")
+	for _, o := range c.arguments {
+		name := strings.ToUpper(o.name)
+		if o.isSlice() {
+			name = "[" + name + "...]"
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *Command) visibleChildren() []*Command {
+	var list []*Command
+	for _, child := range c.children {
+		if !child.Hidden {
+			list = append(list, child)
+		}
+	}
+	return list
+}
+
+func optionBrief(o *option) string {
+	if o.def != "" {
+		return fmt.Sprintf("%s (默认 %s)", o.brief, o.def)
+	}
+	return o.brief
+}
+
+func indent(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = "    " + strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// 命令结果的输出格式，通过 -o/--output 指定
+const (
+	FormatText  = "text"  // 字符串原样输出，其余结果按表格输出
+	FormatTable = "table" // 表格，切片的每个元素为一行，单个结构体或 map 输出为键值两列
+	FormatJSON  = "json"  // 缩进的JSON
+)
+
+// Render 按格式输出命令的结果，format 为空时使用 FormatText
+func Render(w io.Writer, format string, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch format {
+	case "", FormatText:
+		switch s := v.(type) {
+		case string:
+			return writeLine(w, s)
+		case []byte:
+			return writeLine(w, string(s))
+		case fmt.Stringer:
+			return writeLine(w, s.String())
+		}
+		if isScalar(reflect.ValueOf(v)) {
+			return writeLine(w, fmt.Sprint(v))
+		}
+		return renderTable(w, v)
+	case FormatTable:
+		return renderTable(w, v)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	default:
+		return usageErrorf("unsupported output format %q, expected text, table or json", format)
+	}
+}
+
+func writeLine(w io.Writer, s string) error {
+	if !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	_, err := io.WriteString(w, s)
+	return err
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isScalar(v reflect.Value) bool {
+	switch indirect(v).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+// renderTable 切片按行输出，表头为结构体字段名或 map 的键；单个结构体或 map 输出为 KEY/VALUE 两列
+func renderTable(w io.Writer, v interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	rv := indirect(reflect.ValueOf(v))
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return writeLine(w, string(rv.Bytes()))
+		}
+		var (
+			header []string
+			rows   []map[string]string
+		)
+		seen := make(map[string]bool)
+		for i := 0; i < rv.Len(); i++ {
+			keys, values := columns(rv.Index(i))
+			row := make(map[string]string, len(keys))
+			for j, key := range keys {
+				if !seen[key] {
+					seen[key] = true
+					header = append(header, key)
+				}
+				row[key] = values[j]
+			}
+			rows = append(rows, row)
+		}
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			cells := make([]string, len(header))
+			for i, key := range header {
+				cells[i] = row[key]
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+	case reflect.Struct, reflect.Map:
+		keys, values := columns(rv)
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		for i, key := range keys {
+			fmt.Fprintf(tw, "%s\t%s\n", key, values[i])
+		}
+	default:
+		fmt.Fprintln(tw, formatCell(rv))
+	}
+	return tw.Flush()
+}
+
+// columns 返回一行的列名和值，结构体使用json标签或字段名，map 按键排序，其他值为单列 VALUE
+func columns(v reflect.Value) ([]string, []string) {
+	v = indirect(v)
+	var keys, values []string
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if tag := field.Tag.Get("json"); tag != "" {
+				if tag = strings.Split(tag, ",")[0]; tag == "-" {
+					continue
+				} else if tag != "" {
+					name = tag
+				}
+			}
+			keys = append(keys, name)
+			values = append(values, formatCell(v.Field(i)))
+		}
+	case reflect.Map:
+		mapKeys := v.MapKeys()
+		sort.Slice(mapKeys, func(i, j int) bool {
+			return fmt.Sprint(mapKeys[i].Interface()) < fmt.Sprint(mapKeys[j].Interface())
+		})
+		for _, key := range mapKeys {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+			values = append(values, formatCell(v.MapIndex(key)))
+		}
+	default:
+		keys, values = []string{"value"}, []string{formatCell(v)}
+	}
+	return keys, values
+}
+
+// formatCell 标量直接输出，嵌套的结构体、切片和 map 输出为紧凑的JSON
+func formatCell(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	iv := indirect(v)
+	if (iv.Kind() == reflect.Ptr || iv.Kind() == reflect.Interface) && iv.IsNil() {
+		return ""
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if isScalar(iv) {
+		return fmt.Sprint(iv.Interface())
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
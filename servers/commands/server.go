@@ -3,44 +3,201 @@ package commands
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
 	"github.com/sagoo-cloud/nexframe/os/command/args"
 	"github.com/sagoo-cloud/nexframe/servers/commons"
-	"log/slog"
+	"github.com/sagoo-cloud/nexframe/utils/valid"
 )
 
+// Server 命令行服务。
+// 命令行中 flag 参数之后的部分按命令树执行，例如 app -mode=prod db migrate --step 2；
+// 没有子命令时兼容 -cmd 和 -args 参数，执行 Register 注册的处理器。
 type Server struct {
 	handlers map[string]*commons.CommHandler
 	Logger   *slog.Logger
+	Root     *Command  // 根命令，名称为 -name 参数
+	Out      io.Writer // 命令结果和帮助的输出，默认 os.Stdout
+	Err      io.Writer // 错误信息的输出，默认 os.Stderr
+	Args     []string  // 需要执行的命令行，为 nil 时使用 flag.Args()
 }
 
 func NewServer() *Server {
 	s := &Server{
 		Logger:   slog.Default(),
 		handlers: make(map[string]*commons.CommHandler),
+		Root:     &Command{Name: args.Name},
+		Out:      os.Stdout,
+		Err:      os.Stderr,
 	}
 	return s
 }
 
+// Register 注册处理器，可以通过 -cmd=name -args='{}' 执行，也可以作为子命令执行：app name '{}'。
+// 处理器收到JSON参数字符串，子命令没有传入参数时使用 -args 的值。
 func (s *Server) Register(name string, handler *commons.CommHandler) {
 	s.handlers[name] = handler
+	if s.Root.Child(name) != nil {
+		return
+	}
+	err := s.Root.AddCommand(&Command{
+		Name:    name,
+		Handler: &commons.CommHandler{Handler: &jsonArgsHandler{handler: handler}},
+	})
+	if err != nil {
+		s.Logger.Error("Register command failed", "name", name, "error", err)
+	}
+}
 
+// jsonArgsHandler 将位置参数转换为旧的JSON参数字符串
+type jsonArgsHandler struct {
+	handler *commons.CommHandler
 }
 
+func (h *jsonArgsHandler) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	if list, ok := request.([]string); ok && len(list) > 0 {
+		return h.handler.Handle(ctx, list[0])
+	}
+	return h.handler.Handle(ctx, args.Args)
+}
+
+// AddCommand 添加子命令
+func (s *Server) AddCommand(commands ...*Command) error {
+	return s.Root.AddCommand(commands...)
+}
+
+// Serve 执行命令行，执行完成后返回，错误可通过 ExitCode 转换为进程退出码
 func (s *Server) Serve() error {
-	if args.Cmd != "" {
-		handler, isExist := s.handlers[args.Cmd]
-		if isExist == false {
-			return errors.New("handler not exist")
+	argv := s.Args
+	if argv == nil {
+		argv = flag.Args()
+	}
+	if len(argv) == 0 {
+		if handler, ok := s.handlers[args.Cmd]; ok {
+			return s.runLegacy(handler)
 		}
-		ctx := context.Background()
-		response, err := handler.Handle(ctx, args.Args)
-		if err != nil {
+		if isFlagSet("cmd") {
+			err := usageErrorf("handler %s not exist", args.Cmd)
+			fmt.Fprintf(s.Err, "Error: %v\n", err)
 			return err
 		}
-		s.Logger.Info("response:", response)
+	}
+	return s.Execute(context.Background(), argv)
+}
+
+// runLegacy 执行 -cmd 指定的处理器
+func (s *Server) runLegacy(handler *commons.CommHandler) error {
+	response, err := handler.Handle(context.Background(), args.Args)
+	if err != nil {
+		fmt.Fprintf(s.Err, "Error: %v\n", err)
+		return err
+	}
+	return Render(s.Out, FormatText, response)
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// Execute 解析并执行命令行，argv 不包含程序名。
+// 错误信息写入 Err，命令或参数错误时同时输出用法，返回的错误可通过 ExitCode 转换为退出码。
+func (s *Server) Execute(ctx context.Context, argv []string) error {
+	root := s.Root
+	if root.Child("completion") == nil {
+		_ = root.AddCommand(completionCommand(root, s.Out))
+	}
+
+	if len(argv) > 0 {
+		switch argv[0] {
+		case completeCommand:
+			for _, candidate := range root.complete(argv[1:]) {
+				fmt.Fprintln(s.Out, candidate)
+			}
+			return nil
+		case "help":
+			if root.Child("help") == nil {
+				cmd, rest := root.find(argv[1:])
+				if len(rest) > 0 {
+					return s.fail(cmd, usageErrorf("unknown command %q", rest[0]))
+				}
+				cmd.WriteHelp(s.Out)
+				return nil
+			}
+		}
+	}
+
+	cmd, rest := root.find(argv)
+	p, err := cmd.parseArgs(rest)
+	if err != nil {
+		return s.fail(cmd, err)
+	}
+	if p.help {
+		cmd.WriteHelp(s.Out)
+		return nil
+	}
+	if cmd.Handler == nil {
+		if len(p.arguments) > 0 {
+			return s.fail(cmd, usageErrorf("unknown command %q", p.arguments[0]))
+		}
+		cmd.WriteHelp(s.Out)
+		return nil
+	}
+
+	var request interface{} = p.arguments
+	if cmd.inputType != nil {
+		in, err := cmd.bind(p)
+		if err != nil {
+			return s.fail(cmd, err)
+		}
+		if err = valid.New().Data(in).Run(ctx); err != nil {
+			return s.fail(cmd, fmt.Errorf("%w: %v", ErrUsage, err))
+		}
+		request = in
+	}
+
+	resp, err := cmd.Handler.Handle(ctx, request)
+	if err != nil {
+		return s.fail(nil, err)
+	}
+	if err = Render(s.Out, p.output, resp); err != nil {
+		return s.fail(cmd, err)
 	}
 	return nil
 }
-func (s *Server) Close() {
 
+// find 按前面的单词查找子命令，返回命令和剩余的参数
+func (c *Command) find(argv []string) (*Command, []string) {
+	cmd := c
+	for len(argv) > 0 && !strings.HasPrefix(argv[0], "-") {
+		child := cmd.Child(argv[0])
+		if child == nil {
+			break
+		}
+		cmd, argv = child, argv[1:]
+	}
+	return cmd, argv
+}
+
+// fail 输出错误，命令或参数错误时附带用法
+func (s *Server) fail(cmd *Command, err error) error {
+	fmt.Fprintf(s.Err, "Error: %v\n", err)
+	if cmd != nil && errors.Is(err, ErrUsage) {
+		fmt.Fprintf(s.Err, "Usage: %s\n使用 \"%s --help\" 查看帮助\n", cmd.usage(), cmd.Path())
+	}
+	return err
+}
+
+func (s *Server) Close() error {
+	return nil
 }
@@ -0,0 +1,8 @@
+package commons
+
+// Server 可由 servers/app 统一启动和关闭的服务。
+// Serve 阻塞直到 Close 被调用，正常关闭时返回 nil，监听失败等致命错误时返回错误。
+type Server interface {
+	Serve() error
+	Close() error
+}
@@ -0,0 +1,57 @@
+package commons
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sagoo-cloud/nexframe/utils/valid"
+)
+
+// ErrInvalidPayload 请求内容无法按JSON解码
+var ErrInvalidPayload = errors.New("invalid payload")
+
+// TypedFunc 类型化的处理函数，req 由请求内容解码并校验后得到
+type TypedFunc[T any] func(ctx context.Context, req *T) (interface{}, error)
+
+// TypedHandler 将类型化的处理函数适配为 Handler，供 mqtts、grpc、commands 等服务注册类型化的处理函数。
+// 请求为 []byte 时按JSON解码到 T，随后调用 Bind，再使用 utils/valid 按 v 标签校验，与 nf 控制器绑定HTTP请求的方式一致；
+// 请求为 *T 时视为调用方已完成绑定和校验，直接交给 Func。
+type TypedHandler[T any] struct {
+	Func     TypedFunc[T]
+	Bind     func(ctx context.Context, req *T) // 解码后、校验前调用，例如绑定主题中的命名段
+	MapError func(err error) error             // 转换解码和校验的错误，为 nil 时原样返回
+}
+
+// NewTypedHandler 创建类型化的处理器
+func NewTypedHandler[T any](fn TypedFunc[T]) *TypedHandler[T] {
+	return &TypedHandler[T]{Func: fn}
+}
+
+func (h *TypedHandler[T]) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	if req, ok := request.(*T); ok {
+		return h.Func(ctx, req)
+	}
+	req := new(T)
+	payload, _ := request.([]byte)
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return nil, h.mapError(fmt.Errorf("%w: %v", ErrInvalidPayload, err))
+		}
+	}
+	if h.Bind != nil {
+		h.Bind(ctx, req)
+	}
+	if err := valid.New().Data(req).Run(ctx); err != nil {
+		return nil, h.mapError(err)
+	}
+	return h.Func(ctx, req)
+}
+
+func (h *TypedHandler[T]) mapError(err error) error {
+	if h.MapError == nil {
+		return err
+	}
+	return h.MapError(err)
+}
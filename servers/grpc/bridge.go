@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// BridgeService JSON桥接服务的服务名，注册的处理器对应方法 /nexframe.Bridge/{name}
+const BridgeService = "nexframe.Bridge"
+
+var (
+	ErrInvalidPayload = commons.ErrInvalidPayload
+	ErrInvalidMethod  = errors.New("invalid method name")
+)
+
+// bridge 将 commons.Handler 暴露为gRPC方法。
+// 请求和响应均为 google.protobuf.BytesValue，内容为JSON，任何gRPC客户端无需生成代码即可调用。
+type bridge struct {
+	mu       sync.RWMutex
+	handlers map[string]*commons.CommHandler
+}
+
+func newBridge() *bridge {
+	return &bridge{handlers: make(map[string]*commons.CommHandler)}
+}
+
+// Register 将处理器注册为桥接服务的方法，处理器收到请求的JSON内容（[]byte），
+// 返回值编码为JSON，返回 []byte 或 json.RawMessage 时原样返回。需要在 Serve 之前调用。
+func (s *Server) Register(name string, handler *commons.CommHandler) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("%w: %q", ErrInvalidMethod, name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrServerStarted
+	}
+	s.bridge.mu.Lock()
+	s.bridge.handlers[name] = handler
+	s.bridge.mu.Unlock()
+	return nil
+}
+
+// HandlerFunc 类型化的处理函数，req 由请求的JSON内容解码并校验后得到
+type HandlerFunc[T any] = commons.TypedFunc[T]
+
+// Handle 注册类型化的桥接方法，请求的解码和校验见 commons.TypedHandler，失败时返回 InvalidArgument
+func Handle[T any](s *Server, name string, fn HandlerFunc[T]) error {
+	h := commons.NewTypedHandler(fn)
+	h.MapError = func(err error) error {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.Register(name, &commons.CommHandler{Handler: h})
+}
+
+// serviceDesc 根据已注册的处理器生成服务描述，没有处理器时返回 nil
+func (b *bridge) serviceDesc() *ggrpc.ServiceDesc {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.handlers) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(b.handlers))
+	for name := range b.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	desc := &ggrpc.ServiceDesc{
+		ServiceName: BridgeService,
+		HandlerType: (*interface{})(nil),
+		Metadata:    "nexframe/bridge",
+	}
+	for _, name := range names {
+		desc.Methods = append(desc.Methods, ggrpc.MethodDesc{
+			MethodName: name,
+			Handler:    b.methodHandler(name, b.handlers[name]),
+		})
+	}
+	return desc
+}
+
+func (b *bridge) methodHandler(name string, handler *commons.CommHandler) func(interface{}, context.Context, func(interface{}) error, ggrpc.UnaryServerInterceptor) (interface{}, error) {
+	fullMethod := "/" + BridgeService + "/" + name
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor ggrpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(wrapperspb.BytesValue)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		call := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return invoke(ctx, handler, req.(*wrapperspb.BytesValue))
+		}
+		if interceptor == nil {
+			return call(ctx, in)
+		}
+		return interceptor(ctx, in, &ggrpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}, call)
+	}
+}
+
+// invoke 调用处理器并将结果编码为JSON
+func invoke(ctx context.Context, handler *commons.CommHandler, in *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error) {
+	resp, err := handler.Handle(ctx, in.GetValue())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	var data []byte
+	switch v := resp.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	default:
+		if data, err = json.Marshal(resp); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+// toStatus 保留处理器返回的 status 错误，上下文错误转换为对应的状态码，其余为 Unknown
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	if errors.Is(err, ErrInvalidPayload) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// Invoke 客户端调用桥接方法，req 编码为JSON，响应解码到 resp（为 nil 时忽略响应内容）
+func Invoke(ctx context.Context, conn ggrpc.ClientConnInterface, name string, req, resp interface{}, opts ...ggrpc.CallOption) error {
+	var payload []byte
+	switch v := req.(type) {
+	case nil:
+	case []byte:
+		payload = v
+	case json.RawMessage:
+		payload = v
+	default:
+		var err error
+		if payload, err = json.Marshal(req); err != nil {
+			return err
+		}
+	}
+
+	out := new(wrapperspb.BytesValue)
+	if err := conn.Invoke(ctx, "/"+BridgeService+"/"+name, wrapperspb.Bytes(payload), out, opts...); err != nil {
+		return err
+	}
+	if resp == nil || len(out.GetValue()) == 0 {
+		return nil
+	}
+	return json.Unmarshal(out.GetValue(), resp)
+}
@@ -0,0 +1,202 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/auth"
+	"github.com/sagoo-cloud/nexframe/middleware"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader 请求ID所在的元数据键，与HTTP的请求头一致
+const RequestIDHeader = "x-request-id"
+
+// TokenValidator 校验JWT令牌，auth.NewJwt 返回的中间件实现了该接口
+type TokenValidator interface {
+	ValidateToken(token string) (auth.AuthClaims, error)
+}
+
+// serverStream 替换流的上下文
+type serverStream struct {
+	ggrpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// wrapStream 返回使用新上下文的流
+func wrapStream(ss ggrpc.ServerStream, ctx context.Context) ggrpc.ServerStream {
+	if s, ok := ss.(*serverStream); ok {
+		s.ctx = ctx
+		return s
+	}
+	return &serverStream{ServerStream: ss, ctx: ctx}
+}
+
+// RequestIDFromContext 获取当前请求的请求ID
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(middleware.RequestIDKey).(string)
+	return id
+}
+
+// withRequestID 沿用客户端传入的请求ID，没有时生成新的，并通过响应头返回给客户端
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = middleware.GenerateUUID()
+	}
+	_ = ggrpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+	return context.WithValue(ctx, middleware.RequestIDKey, id)
+}
+
+// UnaryRequestID 为每个请求生成请求ID，与 middleware.RequestID 使用相同的上下文键
+func UnaryRequestID() ggrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// StreamRequestID 流式调用的请求ID
+func StreamRequestID() ggrpc.StreamServerInterceptor {
+	return func(srv interface{}, ss ggrpc.ServerStream, info *ggrpc.StreamServerInfo, handler ggrpc.StreamHandler) error {
+		return handler(srv, wrapStream(ss, withRequestID(ss.Context())))
+	}
+}
+
+// recovered 记录 panic 并转换为 Internal 错误
+func recovered(logger *slog.Logger, method string, r interface{}) error {
+	logger.Error("gRPC panic", "method", method, "panic", r, "stack", string(debug.Stack()))
+	return status.Error(codes.Internal, "Internal Server Error")
+}
+
+// UnaryRecovery 捕获处理器中的 panic 并返回 Internal 错误
+func UnaryRecovery(logger *slog.Logger) ggrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery 流式调用的 panic 恢复
+func StreamRecovery(logger *slog.Logger) ggrpc.StreamServerInterceptor {
+	return func(srv interface{}, ss ggrpc.ServerStream, info *ggrpc.StreamServerInfo, handler ggrpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(logger, info.FullMethod, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// authenticate 从 authorization 元数据中读取 Bearer 令牌并校验，成功后将声明写入上下文
+func authenticate(ctx context.Context, validator TokenValidator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+	token := strings.TrimSpace(values[0])
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	claims, err := validator.ValidateToken(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return auth.NewAuthContext(ctx, claims), nil
+}
+
+func skipMethods(methods []string) map[string]bool {
+	skip := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		skip[m] = true
+	}
+	return skip
+}
+
+// UnaryJWT 校验JWT令牌，处理器中可通过 auth.ClaimsFromContext 获取声明。
+// skip 为不需要认证的完整方法名，例如 /grpc.health.v1.Health/Check。
+func UnaryJWT(validator TokenValidator, skip ...string) ggrpc.UnaryServerInterceptor {
+	excluded := skipMethods(skip)
+	return func(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+		if excluded[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, validator)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamJWT 流式调用的JWT认证
+func StreamJWT(validator TokenValidator, skip ...string) ggrpc.StreamServerInterceptor {
+	excluded := skipMethods(skip)
+	return func(srv interface{}, ss ggrpc.ServerStream, info *ggrpc.StreamServerInfo, handler ggrpc.StreamHandler) error {
+		if excluded[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), validator)
+		if err != nil {
+			return err
+		}
+		return handler(srv, wrapStream(ss, ctx))
+	}
+}
+
+func logRequest(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", status.Code(err).String()),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String(middleware.RequestIDKey, id))
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, "Request processed", attrs...)
+}
+
+// UnaryLogging 记录方法名、状态码和耗时，与 middleware.RequestLog 的字段一致
+func UnaryLogging(logger *slog.Logger) ggrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *ggrpc.UnaryServerInfo, handler ggrpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logRequest(ctx, logger, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLogging 流式调用结束时记录日志
+func StreamLogging(logger *slog.Logger) ggrpc.StreamServerInterceptor {
+	return func(srv interface{}, ss ggrpc.ServerStream, info *ggrpc.StreamServerInfo, handler ggrpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logRequest(ss.Context(), logger, info.FullMethod, start, err)
+		return err
+	}
+}
@@ -0,0 +1,212 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/sagoo-cloud/nexframe/auth"
+	"github.com/sagoo-cloud/nexframe/configs"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+var ErrServerStarted = errors.New("grpc server already started")
+
+// Server gRPC服务，内置请求ID、日志、panic恢复和JWT认证拦截器，以及健康检查和反射服务。
+// Server 实现了 grpc.ServiceRegistrar，protoc 生成的 RegisterXxxServer 可以直接使用；
+// 也可以通过 Register 将 commons.Handler 以JSON的形式暴露。
+type Server struct {
+	Logger     *slog.Logger
+	Address    string
+	Reflection bool           // 是否注册反射服务
+	Validator  TokenValidator // 不为 nil 时启用JWT认证
+	SkipAuth   []string       // 不需要认证的完整方法名，健康检查和反射服务始终不需要认证
+
+	options  []ggrpc.ServerOption
+	unary    []ggrpc.UnaryServerInterceptor
+	stream   []ggrpc.StreamServerInterceptor
+	server   *ggrpc.Server
+	health   *health.Server
+	bridge   *bridge
+	listener net.Listener
+	started  bool
+	closed   bool
+	mu       sync.Mutex
+}
+
+// Option 配置服务
+type Option func(*Server)
+
+// WithAddress 设置监听地址，使用 127.0.0.1:0 时由系统分配端口，可通过 Addr 获取
+func WithAddress(address string) Option {
+	return func(s *Server) {
+		s.Address = address
+	}
+}
+
+// WithLogger 设置日志记录器
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.Logger = logger
+	}
+}
+
+// WithReflection 设置是否注册反射服务
+func WithReflection(enable bool) Option {
+	return func(s *Server) {
+		s.Reflection = enable
+	}
+}
+
+// WithValidator 启用JWT认证，skip 为不需要认证的完整方法名
+func WithValidator(validator TokenValidator, skip ...string) Option {
+	return func(s *Server) {
+		s.Validator = validator
+		s.SkipAuth = append(s.SkipAuth, skip...)
+	}
+}
+
+// WithUnaryInterceptor 追加一元拦截器，在内置拦截器之后执行
+func WithUnaryInterceptor(interceptors ...ggrpc.UnaryServerInterceptor) Option {
+	return func(s *Server) {
+		s.unary = append(s.unary, interceptors...)
+	}
+}
+
+// WithStreamInterceptor 追加流拦截器，在内置拦截器之后执行
+func WithStreamInterceptor(interceptors ...ggrpc.StreamServerInterceptor) Option {
+	return func(s *Server) {
+		s.stream = append(s.stream, interceptors...)
+	}
+}
+
+// WithServerOption 追加原生的 grpc.ServerOption，例如TLS证书、消息大小限制
+func WithServerOption(opts ...ggrpc.ServerOption) Option {
+	return func(s *Server) {
+		s.options = append(s.options, opts...)
+	}
+}
+
+// NewServer 根据 grpc 配置创建服务，认证方式为 jwt 时使用 auth.NewJwt 校验 authorization 元数据中的令牌
+func NewServer(opts ...Option) (*Server, error) {
+	config := configs.LoadGrpcConfig()
+	s := &Server{
+		Logger:     slog.Default(),
+		Address:    config.Address,
+		Reflection: config.Reflection,
+		bridge:     newBridge(),
+	}
+
+	switch config.Auth {
+	case "", "none":
+	case "jwt":
+		jm, err := auth.NewJwt()
+		if err != nil {
+			return nil, err
+		}
+		s.Validator = jm
+	default:
+		return nil, fmt.Errorf("unsupported grpc auth: %s", config.Auth)
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// 拦截器顺序：请求ID -> 日志 -> panic恢复 -> 认证 -> 自定义
+	unary := []ggrpc.UnaryServerInterceptor{UnaryRequestID(), UnaryLogging(s.Logger), UnaryRecovery(s.Logger)}
+	stream := []ggrpc.StreamServerInterceptor{StreamRequestID(), StreamLogging(s.Logger), StreamRecovery(s.Logger)}
+	if s.Validator != nil {
+		skip := append([]string{
+			"/grpc.health.v1.Health/Check",
+			"/grpc.health.v1.Health/Watch",
+			"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+		}, s.SkipAuth...)
+		unary = append(unary, UnaryJWT(s.Validator, skip...))
+		stream = append(stream, StreamJWT(s.Validator, skip...))
+	}
+	options := append([]ggrpc.ServerOption{
+		ggrpc.ChainUnaryInterceptor(append(unary, s.unary...)...),
+		ggrpc.ChainStreamInterceptor(append(stream, s.stream...)...),
+	}, s.options...)
+
+	s.server = ggrpc.NewServer(options...)
+	s.health = health.NewServer()
+	healthpb.RegisterHealthServer(s.server, s.health)
+	return s, nil
+}
+
+// RegisterService 注册 protoc 生成的服务，需要在 Serve 之前调用
+func (s *Server) RegisterService(desc *ggrpc.ServiceDesc, impl interface{}) {
+	s.server.RegisterService(desc, impl)
+}
+
+// Health 返回健康检查服务，可按服务名设置状态
+func (s *Server) Health() *health.Server {
+	return s.health
+}
+
+// Addr 返回实际监听的地址，未启动时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Serve 开始监听并阻塞，直到调用 Close
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.started {
+		s.mu.Unlock()
+		return ErrServerStarted
+	}
+	l, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.listener = l
+	s.started = true
+	if desc := s.bridge.serviceDesc(); desc != nil {
+		s.server.RegisterService(desc, s.bridge)
+	}
+	if s.Reflection {
+		reflection.Register(s.server)
+	}
+	s.mu.Unlock()
+
+	s.Logger.Info("gRPC Server Start", "address", l.Addr().String())
+	err = s.server.Serve(l)
+	if errors.Is(err, ggrpc.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// Close 将健康状态设置为 NOT_SERVING，并等待处理中的请求完成后停止服务
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	s.health.Shutdown()
+	s.server.GracefulStop()
+	return nil
+}
@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/auth"
+	"github.com/stretchr/testify/assert"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+type tokenValidator map[string]string
+
+func (v tokenValidator) ValidateToken(token string) (auth.AuthClaims, error) {
+	username, ok := v[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &auth.TokenClaims{Username: username}, nil
+}
+
+type greetReq struct {
+	Name string `json:"name" v:"required"`
+}
+
+type greetResp struct {
+	Message   string `json:"message"`
+	User      string `json:"user"`
+	RequestId string `json:"requestId"`
+}
+
+func startServer(t *testing.T, opts ...Option) (*Server, *ggrpc.ClientConn) {
+	t.Helper()
+	opts = append([]Option{
+		WithAddress("127.0.0.1:0"),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+	s, err := NewServer(opts...)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.NoError(t, Handle(s, "Greet", func(ctx context.Context, req *greetReq) (interface{}, error) {
+		resp := &greetResp{Message: "hello " + req.Name, RequestId: RequestIDFromContext(ctx)}
+		if claims, ok := auth.ClaimsFromContext(ctx); ok {
+			resp.User = claims.GetUsername()
+		}
+		return resp, nil
+	}))
+	assert.NoError(t, Handle(s, "Panic", func(ctx context.Context, req *struct{}) (interface{}, error) {
+		panic("boom")
+	}))
+
+	go func() { _ = s.Serve() }()
+	assert.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, 5*time.Millisecond)
+	t.Cleanup(func() { _ = s.Close() })
+
+	conn, err := ggrpc.NewClient(s.Addr().String(), ggrpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return s, conn
+}
+
+func TestBridge(t *testing.T) {
+	s, conn := startServer(t)
+	ctx := context.Background()
+
+	var resp greetResp
+	var header metadata.MD
+	callCtx := metadata.AppendToOutgoingContext(ctx, RequestIDHeader, "req-1")
+	assert.NoError(t, Invoke(callCtx, conn, "Greet", &greetReq{Name: "nf"}, &resp, ggrpc.Header(&header)))
+	assert.Equal(t, greetResp{Message: "hello nf", RequestId: "req-1"}, resp)
+	assert.Equal(t, []string{"req-1"}, header.Get(RequestIDHeader))
+
+	// 没有传入请求ID时自动生成
+	header = nil
+	assert.NoError(t, Invoke(ctx, conn, "Greet", &greetReq{Name: "nf"}, &resp, ggrpc.Header(&header)))
+	assert.NotEmpty(t, resp.RequestId)
+	assert.Equal(t, []string{resp.RequestId}, header.Get(RequestIDHeader))
+
+	err := Invoke(ctx, conn, "Greet", &greetReq{}, &resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = Invoke(ctx, conn, "Greet", []byte("{"), &resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = Invoke(ctx, conn, "Panic", nil, nil)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	err = Invoke(ctx, conn, "Missing", nil, nil)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	// 启动后不能再注册
+	assert.ErrorIs(t, s.Register("Late", nil), ErrServerStarted)
+}
+
+func TestJWT(t *testing.T) {
+	_, conn := startServer(t, WithValidator(tokenValidator{"t1": "alice"}))
+	ctx := context.Background()
+
+	var resp greetResp
+	err := Invoke(ctx, conn, "Greet", &greetReq{Name: "nf"}, &resp)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	bad := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer t2")
+	err = Invoke(bad, conn, "Greet", &greetReq{Name: "nf"}, &resp)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	good := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer t1")
+	assert.NoError(t, Invoke(good, conn, "Greet", &greetReq{Name: "nf"}, &resp))
+	assert.Equal(t, "alice", resp.User)
+
+	// 健康检查不需要认证
+	check, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check.GetStatus())
+}
+
+func TestReflection(t *testing.T) {
+	_, conn := startServer(t, WithReflection(true))
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var names []string
+	for _, svc := range resp.GetListServicesResponse().GetService() {
+		names = append(names, svc.GetName())
+	}
+	assert.Contains(t, names, BridgeService)
+	assert.Contains(t, names, "grpc.health.v1.Health")
+	_ = stream.CloseSend()
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// History 执行历史的存储
+type History interface {
+	// Add 保存一次执行的记录
+	Add(ctx context.Context, run Run) error
+	// List 返回任务最近的执行记录，按开始时间倒序，limit <= 0 时返回全部
+	List(ctx context.Context, source, job string, limit int) ([]Run, error)
+}
+
+// MemoryHistory 内存中的执行历史，每个任务保留最近 size 条记录
+type MemoryHistory struct {
+	mu    sync.RWMutex
+	size  int
+	rings map[string]*ring
+}
+
+// ring 固定容量的环形缓冲区
+type ring struct {
+	runs []Run
+	next int
+	full bool
+}
+
+// NewMemoryHistory 创建内存执行历史，size 为每个任务保留的记录数，默认 100
+func NewMemoryHistory(size int) *MemoryHistory {
+	if size <= 0 {
+		size = 100
+	}
+	return &MemoryHistory{size: size, rings: make(map[string]*ring)}
+}
+
+func (h *MemoryHistory) Add(_ context.Context, run Run) error {
+	key := run.Source + "/" + run.Job
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rings[key]
+	if !ok {
+		r = &ring{runs: make([]Run, h.size)}
+		h.rings[key] = r
+	}
+	r.runs[r.next] = run
+	r.next = (r.next + 1) % h.size
+	if r.next == 0 {
+		r.full = true
+	}
+	return nil
+}
+
+func (h *MemoryHistory) List(_ context.Context, source, job string, limit int) ([]Run, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	r, ok := h.rings[source+"/"+job]
+	if !ok {
+		return []Run{}, nil
+	}
+	count := r.next
+	if r.full {
+		count = h.size
+	}
+	if limit > 0 && limit < count {
+		count = limit
+	}
+	runs := make([]Run, 0, count)
+	for i := 1; i <= count; i++ {
+		runs = append(runs, r.runs[(r.next-i+h.size)%h.size])
+	}
+	return runs, nil
+}
+
+// RunModel 执行历史的数据表
+type RunModel struct {
+	ID         uint      `gorm:"primaryKey"`
+	Source     string    `gorm:"size:64;index:idx_job_runs_job,priority:1"`
+	Job        string    `gorm:"size:128;index:idx_job_runs_job,priority:2"`
+	StartedAt  time.Time `gorm:"index:idx_job_runs_job,priority:3"`
+	FinishedAt time.Time
+	Duration   int64  // 毫秒
+	Status     string `gorm:"size:16"`
+	Error      string `gorm:"type:text"`
+}
+
+func (RunModel) TableName() string {
+	return "job_runs"
+}
+
+// DBHistory 保存在数据库中的执行历史，多个实例可以共享
+type DBHistory struct {
+	db *gorm.DB
+}
+
+// NewDBHistory 创建数据库执行历史，并自动创建 job_runs 表
+func NewDBHistory(db *gorm.DB) (*DBHistory, error) {
+	if err := db.AutoMigrate(&RunModel{}); err != nil {
+		return nil, err
+	}
+	return &DBHistory{db: db}, nil
+}
+
+func (h *DBHistory) Add(ctx context.Context, run Run) error {
+	return h.db.WithContext(ctx).Create(&RunModel{
+		Source:     run.Source,
+		Job:        run.Job,
+		StartedAt:  run.Start,
+		FinishedAt: run.End,
+		Duration:   run.Duration.Milliseconds(),
+		Status:     string(run.Status),
+		Error:      run.Error,
+	}).Error
+}
+
+func (h *DBHistory) List(ctx context.Context, source, job string, limit int) ([]Run, error) {
+	query := h.db.WithContext(ctx).
+		Where("source = ? AND job = ?", source, job).
+		Order("started_at DESC, id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var models []RunModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(models))
+	for _, m := range models {
+		runs = append(runs, Run{
+			Source:   m.Source,
+			Job:      m.Job,
+			Start:    m.StartedAt,
+			End:      m.FinishedAt,
+			Duration: time.Duration(m.Duration) * time.Millisecond,
+			Status:   Status(m.Status),
+			Error:    m.Error,
+		})
+	}
+	return runs, nil
+}
@@ -0,0 +1,70 @@
+// Package jobs 汇总定时服务和周期任务的执行历史、指标，并提供管理接口。
+// timers.Server 和 worker.Worker 的周期任务通过 Source 接入 Manager。
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSourceNotFound = errors.New("job source not found")
+	ErrSourceExists   = errors.New("job source already exists")
+	ErrJobNotFound    = errors.New("job not found")
+)
+
+// Status 执行结果
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+)
+
+// Run 一次执行的记录
+type Run struct {
+	Source   string        `json:"source"` // 任务来源，例如 timers、worker
+	Job      string        `json:"job"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Job 任务的状态
+type Job struct {
+	Source   string    `json:"source"`
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Paused   bool      `json:"paused"`
+	Next     time.Time `json:"next"` // 下一次执行时间，未知或暂停时为零值
+}
+
+// Source 任务来源，提供任务列表、手动执行、暂停和恢复，并报告每次执行的结果
+type Source interface {
+	Name() string
+	Jobs(ctx context.Context) ([]Job, error)
+	Trigger(ctx context.Context, job string) error
+	Pause(ctx context.Context, job string) error
+	Resume(ctx context.Context, job string) error
+	// OnRun 注册执行结束后的回调
+	OnRun(hook func(Run))
+}
+
+// newRun 根据执行的开始时间、耗时和错误生成记录
+func newRun(source, job string, start time.Time, cost time.Duration, err error) Run {
+	run := Run{
+		Source:   source,
+		Job:      job,
+		Start:    start,
+		End:      start.Add(cost),
+		Duration: cost,
+		Status:   StatusSuccess,
+	}
+	if err != nil {
+		run.Status, run.Error = StatusFailed, err.Error()
+	}
+	return run
+}
@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sagoo-cloud/nexframe/servers/timers"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func testHistory(t *testing.T, h History) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		var err error
+		if i%2 == 1 {
+			err = errors.New("boom")
+		}
+		assert.NoError(t, h.Add(ctx, newRun("timers", "report", start.Add(time.Duration(i)*time.Minute), time.Second, err)))
+	}
+	assert.NoError(t, h.Add(ctx, newRun("timers", "other", start, time.Second, nil)))
+
+	runs, err := h.List(ctx, "timers", "report", 2)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 2)
+	assert.Equal(t, start.Add(4*time.Minute), runs[0].Start.UTC())
+	assert.Equal(t, StatusSuccess, runs[0].Status)
+	assert.Equal(t, StatusFailed, runs[1].Status)
+	assert.Equal(t, "boom", runs[1].Error)
+	assert.Equal(t, time.Second, runs[1].Duration)
+	assert.Equal(t, start.Add(3*time.Minute+time.Second), runs[1].End.UTC())
+
+	runs, _ = h.List(ctx, "timers", "missing", 0)
+	assert.Empty(t, runs)
+}
+
+func TestMemoryHistory(t *testing.T) {
+	testHistory(t, NewMemoryHistory(3))
+
+	// 超出容量后只保留最近的记录
+	h := NewMemoryHistory(3)
+	testHistory(t, h)
+	runs, _ := h.List(context.Background(), "timers", "report", 0)
+	assert.Len(t, runs, 3)
+}
+
+func TestDBHistory(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	h, err := NewDBHistory(db)
+	assert.NoError(t, err)
+	testHistory(t, h)
+}
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewMetrics(reg)
+	assert.NoError(t, err)
+	m.Observe(newRun("timers", "report", time.Now(), time.Second, nil))
+	m.Observe(newRun("timers", "report", time.Now(), time.Second, errors.New("boom")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.runs.WithLabelValues("timers", "report", "failed")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.duration))
+
+	_, err = NewMetrics(reg)
+	assert.Error(t, err)
+}
+
+func doJSON(t *testing.T, h http.Handler, method, path string) (int, json.RawMessage) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	var res struct {
+		Code int             `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return w.Code, res.Data
+}
+
+func TestManager(t *testing.T) {
+	server := timers.NewServer(log.New(io.Discard, "", 0))
+	t.Cleanup(func() { _ = server.Close() })
+	fail := make(chan error, 1)
+	assert.NoError(t, server.Register("report", time.Hour, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		select {
+		case err := <-fail:
+			return nil, err
+		default:
+			return nil, nil
+		}
+	}, nil))
+	assert.NoError(t, server.Run())
+
+	reg := prometheus.NewRegistry()
+	metrics, _ := NewMetrics(reg)
+	m := NewManager(WithMetrics(metrics))
+	assert.NoError(t, m.Add(FromTimers("", server)))
+	assert.ErrorIs(t, m.Add(FromTimers("", server)), ErrSourceExists)
+	h := http.StripPrefix("/admin", m.Handler(func(r *http.Request) error {
+		if r.Header.Get("X-Admin") == "" && !strings.HasPrefix(r.URL.Path, "/jobs") {
+			return errors.New("forbidden")
+		}
+		return nil
+	}))
+
+	code, data := doJSON(t, h, http.MethodGet, "/admin/jobs")
+	assert.Equal(t, http.StatusOK, code)
+	var jobs []Job
+	assert.NoError(t, json.Unmarshal(data, &jobs))
+	assert.Equal(t, []string{"timers", "report", "@every 1h0m0s"}, []string{jobs[0].Source, jobs[0].Name, jobs[0].Schedule})
+
+	code, _ = doJSON(t, h, http.MethodPost, "/admin/jobs/timers/report/trigger")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.runs.WithLabelValues("timers", "report", "success")) == 1
+	}, time.Second, 5*time.Millisecond)
+	fail <- errors.New("boom")
+	code, _ = doJSON(t, h, http.MethodPost, "/admin/jobs/timers/report/trigger")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Eventually(t, func() bool {
+		runs, _ := m.Runs(context.Background(), "timers", "report", 0)
+		return len(runs) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	code, data = doJSON(t, h, http.MethodGet, "/admin/jobs/timers/report/runs?limit=1")
+	assert.Equal(t, http.StatusOK, code)
+	var runs []Run
+	assert.NoError(t, json.Unmarshal(data, &runs))
+	assert.Len(t, runs, 1)
+	assert.Equal(t, StatusFailed, runs[0].Status)
+	assert.Equal(t, "boom", runs[0].Error)
+
+	code, _ = doJSON(t, h, http.MethodPost, "/admin/jobs/timers/report/pause")
+	assert.Equal(t, http.StatusOK, code)
+	status, _ := server.Job("report")
+	assert.True(t, status.Paused)
+	code, _ = doJSON(t, h, http.MethodPost, "/admin/jobs/timers/report/resume")
+	assert.Equal(t, http.StatusOK, code)
+	status, _ = server.Job("report")
+	assert.False(t, status.Paused)
+
+	code, _ = doJSON(t, h, http.MethodPost, "/admin/jobs/timers/missing/pause")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = doJSON(t, h, http.MethodPost, "/admin/jobs/worker/report/trigger")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = doJSON(t, h, http.MethodGet, "/admin/jobs/timers/report/runs?limit=x")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = doJSON(t, h, http.MethodGet, "/admin/other")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
@@ -0,0 +1,212 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/contracts"
+)
+
+// Manager 汇总多个任务来源，记录执行历史和指标，并提供管理接口
+type Manager struct {
+	History History
+	Metrics *Metrics // 为 nil 时不记录指标
+	Logger  *slog.Logger
+
+	mu      sync.RWMutex
+	sources map[string]Source
+	order   []string
+}
+
+// Option 配置 Manager
+type Option func(*Manager)
+
+// WithHistory 设置执行历史的存储，默认每个任务在内存中保留 100 条
+func WithHistory(history History) Option {
+	return func(m *Manager) {
+		m.History = history
+	}
+}
+
+// WithMetrics 设置 Prometheus 指标
+func WithMetrics(metrics *Metrics) Option {
+	return func(m *Manager) {
+		m.Metrics = metrics
+	}
+}
+
+// WithLogger 设置日志记录器
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Manager) {
+		m.Logger = logger
+	}
+}
+
+// NewManager 创建任务管理
+func NewManager(opts ...Option) *Manager {
+	m := &Manager{
+		History: NewMemoryHistory(100),
+		Logger:  slog.Default(),
+		sources: make(map[string]Source),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Add 添加任务来源，并开始记录其执行历史
+func (m *Manager) Add(source Source) error {
+	name := source.Name()
+	m.mu.Lock()
+	if _, ok := m.sources[name]; ok {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrSourceExists, name)
+	}
+	m.sources[name] = source
+	m.order = append(m.order, name)
+	m.mu.Unlock()
+
+	source.OnRun(m.record)
+	return nil
+}
+
+// record 保存执行记录并更新指标
+func (m *Manager) record(run Run) {
+	if m.Metrics != nil {
+		m.Metrics.Observe(run)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.History.Add(ctx, run); err != nil {
+		m.Logger.Error("save job run failed", "source", run.Source, "job", run.Job, "error", err)
+	}
+}
+
+func (m *Manager) source(name string) (Source, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	source, ok := m.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, name)
+	}
+	return source, nil
+}
+
+// Jobs 返回所有来源的任务，按来源的添加顺序和任务名称排序
+func (m *Manager) Jobs(ctx context.Context) ([]Job, error) {
+	m.mu.RLock()
+	names := append([]string{}, m.order...)
+	m.mu.RUnlock()
+
+	jobs := make([]Job, 0)
+	for _, name := range names {
+		source, err := m.source(name)
+		if err != nil {
+			return nil, err
+		}
+		list, err := source.Jobs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		jobs = append(jobs, list...)
+	}
+	return jobs, nil
+}
+
+// Runs 返回任务最近的执行记录
+func (m *Manager) Runs(ctx context.Context, source, job string, limit int) ([]Run, error) {
+	if _, err := m.source(source); err != nil {
+		return nil, err
+	}
+	return m.History.List(ctx, source, job, limit)
+}
+
+// Trigger 立即执行一次任务
+func (m *Manager) Trigger(ctx context.Context, source, job string) error {
+	s, err := m.source(source)
+	if err != nil {
+		return err
+	}
+	return s.Trigger(ctx, job)
+}
+
+// Pause 暂停任务
+func (m *Manager) Pause(ctx context.Context, source, job string) error {
+	s, err := m.source(source)
+	if err != nil {
+		return err
+	}
+	return s.Pause(ctx, job)
+}
+
+// Resume 恢复任务
+func (m *Manager) Resume(ctx context.Context, source, job string) error {
+	s, err := m.source(source)
+	if err != nil {
+		return err
+	}
+	return s.Resume(ctx, job)
+}
+
+// Handler 返回各来源定时任务的管理接口，authorize 的用法见 contracts.Authorized：
+//
+//	GET  /jobs                              任务列表
+//	GET  /jobs/{source}/{job}/runs?limit=20 执行历史，默认 20 条
+//	POST /jobs/{source}/{job}/trigger       立即执行
+//	POST /jobs/{source}/{job}/pause         暂停
+//	POST /jobs/{source}/{job}/resume        恢复
+func (m *Manager) Handler(authorize func(r *http.Request) error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		jobs, err := m.Jobs(r.Context())
+		m.respond(w, jobs, err)
+	})
+	mux.HandleFunc("GET /jobs/{source}/{job}/runs", func(w http.ResponseWriter, r *http.Request) {
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				contracts.JSONStatusResp(w, http.StatusBadRequest, "invalid limit", nil)
+				return
+			}
+			limit = n
+		}
+		runs, err := m.Runs(r.Context(), r.PathValue("source"), r.PathValue("job"), limit)
+		m.respond(w, runs, err)
+	})
+	actions := map[string]func(context.Context, string, string) error{
+		"trigger": m.Trigger,
+		"pause":   m.Pause,
+		"resume":  m.Resume,
+	}
+	for name, action := range actions {
+		action := action
+		mux.HandleFunc("POST /jobs/{source}/{job}/"+name, func(w http.ResponseWriter, r *http.Request) {
+			err := action(r.Context(), r.PathValue("source"), r.PathValue("job"))
+			m.respond(w, nil, err)
+		})
+	}
+
+	return contracts.Authorized(authorize, mux)
+}
+
+func (m *Manager) respond(w http.ResponseWriter, data interface{}, err error) {
+	switch {
+	case err == nil:
+		contracts.JSONStatusResp(w, http.StatusOK, "ok", data)
+	case errors.Is(err, ErrSourceNotFound), errors.Is(err, ErrJobNotFound):
+		contracts.JSONStatusResp(w, http.StatusNotFound, err.Error(), nil)
+	default:
+		m.Logger.Error("job admin request failed", "error", err)
+		contracts.JSONStatusResp(w, http.StatusInternalServerError, err.Error(), nil)
+	}
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics 任务执行的 Prometheus 指标
+type Metrics struct {
+	runs     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	last     *prometheus.GaugeVec
+}
+
+// NewMetrics 创建并注册指标，reg 为空时使用 prometheus.DefaultRegisterer。
+// 指标包括：
+//   - nexframe_job_runs_total{source,job,status} 执行次数
+//   - nexframe_job_duration_seconds{source,job} 执行耗时
+//   - nexframe_job_last_success_timestamp_seconds{source,job} 最后一次成功的时间
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	m := &Metrics{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "nexframe",
+			Subsystem: "job",
+			Name:      "runs_total",
+			Help:      "Number of job runs by outcome.",
+		}, []string{"source", "job", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "nexframe",
+			Subsystem: "job",
+			Name:      "duration_seconds",
+			Help:      "Duration of job runs.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 4, 8),
+		}, []string{"source", "job"}),
+		last: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "nexframe",
+			Subsystem: "job",
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix time of the last successful job run.",
+		}, []string{"source", "job"}),
+	}
+	for _, c := range []prometheus.Collector{m.runs, m.duration, m.last} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Observe 记录一次执行
+func (m *Metrics) Observe(run Run) {
+	m.runs.WithLabelValues(run.Source, run.Job, string(run.Status)).Inc()
+	m.duration.WithLabelValues(run.Source, run.Job).Observe(run.Duration.Seconds())
+	if run.Status == StatusSuccess {
+		m.last.WithLabelValues(run.Source, run.Job).Set(float64(run.End.Unix()))
+	}
+}
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sagoo-cloud/nexframe/servers/timers"
+	"github.com/sagoo-cloud/nexframe/worker"
+)
+
+// timersSource 将 timers.Server 接入 Manager
+type timersSource struct {
+	name   string
+	server *timers.Server
+}
+
+// FromTimers 将定时服务作为任务来源，name 为空时使用 timers
+func FromTimers(name string, server *timers.Server) Source {
+	if name == "" {
+		name = "timers"
+	}
+	return &timersSource{name: name, server: server}
+}
+
+func (s *timersSource) Name() string {
+	return s.name
+}
+
+func (s *timersSource) Jobs(context.Context) ([]Job, error) {
+	list := s.server.Jobs()
+	jobs := make([]Job, 0, len(list))
+	for _, status := range list {
+		jobs = append(jobs, Job{
+			Source:   s.name,
+			Name:     status.Name,
+			Schedule: status.Schedule,
+			Paused:   status.Paused,
+			Next:     status.Next,
+		})
+	}
+	return jobs, nil
+}
+
+func (s *timersSource) Trigger(_ context.Context, job string) error {
+	return timersError(s.server.Trigger(job))
+}
+
+func (s *timersSource) Pause(_ context.Context, job string) error {
+	return timersError(s.server.Pause(job))
+}
+
+func (s *timersSource) Resume(_ context.Context, job string) error {
+	return timersError(s.server.Resume(job))
+}
+
+func (s *timersSource) OnRun(hook func(Run)) {
+	s.server.OnRun(func(info timers.RunInfo) {
+		hook(newRun(s.name, info.Job, info.Start, info.Duration, info.Err))
+	})
+}
+
+func timersError(err error) error {
+	if errors.Is(err, timers.ErrJobNotFound) {
+		return fmt.Errorf("%w: %v", ErrJobNotFound, err)
+	}
+	return err
+}
+
+// workerSource 将 worker.Worker 的周期任务接入 Manager，任务名称为周期任务的 uid
+type workerSource struct {
+	name   string
+	worker *worker.Worker
+}
+
+// FromWorker 将周期任务作为任务来源，name 为空时使用 worker
+func FromWorker(name string, wk *worker.Worker) Source {
+	if name == "" {
+		name = "worker"
+	}
+	return &workerSource{name: name, worker: wk}
+}
+
+func (s *workerSource) Name() string {
+	return s.name
+}
+
+func (s *workerSource) Jobs(ctx context.Context) ([]Job, error) {
+	list, err := s.worker.CronTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(list))
+	for _, task := range list {
+		job := Job{
+			Source:   s.name,
+			Name:     task.Uid,
+			Schedule: task.Expr,
+			Paused:   task.Paused,
+		}
+		if !task.Paused {
+			job.Next = task.Next
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (s *workerSource) Trigger(ctx context.Context, job string) error {
+	return workerError(s.worker.TriggerCron(ctx, job))
+}
+
+func (s *workerSource) Pause(ctx context.Context, job string) error {
+	return workerError(s.worker.PauseCron(ctx, job))
+}
+
+func (s *workerSource) Resume(ctx context.Context, job string) error {
+	return workerError(s.worker.ResumeCron(ctx, job))
+}
+
+func (s *workerSource) OnRun(hook func(Run)) {
+	s.worker.OnRun(func(info worker.RunInfo) {
+		hook(newRun(s.name, info.Uid, info.Start, info.Duration, info.Err))
+	})
+}
+
+func workerError(err error) error {
+	if errors.Is(err, worker.ErrCronNotFound) {
+		return fmt.Errorf("%w: %v", ErrJobNotFound, err)
+	}
+	return err
+}
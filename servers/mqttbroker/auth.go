@@ -0,0 +1,129 @@
+package mqttbroker
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/sagoo-cloud/nexframe/auth"
+)
+
+var (
+	ErrBadCredentials = errors.New("bad username or password")
+	ErrSignExpired    = errors.New("signature expired")
+)
+
+// Session 已认证的客户端信息
+type Session struct {
+	ClientID string
+	Username string
+	Claims   auth.AuthClaims // JWT认证时为令牌中的信息，其他方式为 nil
+}
+
+// Authenticator 校验客户端连接时提交的用户名和密码
+type Authenticator func(clientID, username string, password []byte) (*Session, error)
+
+// ACLFunc 校验客户端对主题的读写权限，write 为 true 时表示发布
+type ACLFunc func(session *Session, topic string, write bool) bool
+
+// TokenValidator 校验JWT令牌，auth.NewJwt 返回的中间件实现了该接口
+type TokenValidator interface {
+	ValidateToken(token string) (auth.AuthClaims, error)
+}
+
+// JWTAuthenticator 以密码作为JWT令牌进行认证，令牌由 auth 包签发
+func JWTAuthenticator(validator TokenValidator) Authenticator {
+	return func(clientID, username string, password []byte) (*Session, error) {
+		claims, err := validator.ValidateToken(string(password))
+		if err != nil {
+			return nil, err
+		}
+		if claims.GetUsername() != "" {
+			username = claims.GetUsername()
+		}
+		return &Session{ClientID: clientID, Username: username, Claims: claims}, nil
+	}
+}
+
+// AKSKAuthenticator 使用 AK/SK 签名认证，用户名为 AK，密码为 "{时间戳}:{签名}"，
+// 签名由 auth.GenerateSignature 计算，与 auth.VerifySignature 一致；maxSkew 限制时间戳的偏差，为 0 时不限制。
+func AKSKAuthenticator(secret func(ak string) (sk string, ok bool), maxSkew time.Duration) Authenticator {
+	return func(clientID, username string, password []byte) (*Session, error) {
+		sk, ok := secret(username)
+		if !ok {
+			return nil, ErrBadCredentials
+		}
+		timeStr, sign, found := strings.Cut(string(password), ":")
+		if !found || !auth.VerifySignature(username, sk, timeStr, sign) {
+			return nil, ErrBadCredentials
+		}
+		if maxSkew > 0 {
+			timestamp, _ := strconv.ParseInt(timeStr, 10, 64)
+			if d := time.Since(time.Unix(timestamp, 0)); d > maxSkew || d < -maxSkew {
+				return nil, ErrSignExpired
+			}
+		}
+		return &Session{ClientID: clientID, Username: username}, nil
+	}
+}
+
+// AKSKPassword 生成 AKSKAuthenticator 认证使用的密码
+func AKSKPassword(ak, sk string, t time.Time) string {
+	timeStr := strconv.FormatInt(t.Unix(), 10)
+	return timeStr + ":" + auth.GenerateSignature("ak="+ak+"&time="+timeStr, sk)
+}
+
+// authHook 将 Authenticator 和 ACLFunc 接入 broker 的认证和权限检查
+type authHook struct {
+	mqtt.HookBase
+	authenticate Authenticator
+	acl          ACLFunc
+	sessions     sync.Map // *mqtt.Client -> *Session，同一客户端ID重连时为不同的连接
+}
+
+func (h *authHook) ID() string {
+	return "nexframe-auth"
+}
+
+func (h *authHook) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnConnectAuthenticate,
+		mqtt.OnACLCheck,
+		mqtt.OnDisconnect,
+	}, []byte{b})
+}
+
+func (h *authHook) OnConnectAuthenticate(cl *mqtt.Client, pk packets.Packet) bool {
+	username := string(pk.Connect.Username)
+	session := &Session{ClientID: cl.ID, Username: username}
+	if h.authenticate != nil {
+		var err error
+		session, err = h.authenticate(cl.ID, username, pk.Connect.Password)
+		if err != nil {
+			h.Log.Warn("mqtt client authentication failed", "client", cl.ID, "username", username, "error", err)
+			return false
+		}
+	}
+	h.sessions.Store(cl, session)
+	return true
+}
+
+func (h *authHook) OnACLCheck(cl *mqtt.Client, topic string, write bool) bool {
+	if h.acl == nil || cl.Net.Inline {
+		return true
+	}
+	session, ok := h.sessions.Load(cl)
+	if !ok {
+		return false
+	}
+	return h.acl(session.(*Session), topic, write)
+}
+
+func (h *authHook) OnDisconnect(cl *mqtt.Client, err error, expire bool) {
+	h.sessions.Delete(cl)
+}
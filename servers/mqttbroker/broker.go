@@ -0,0 +1,186 @@
+package mqttbroker
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/sagoo-cloud/nexframe/auth"
+	"github.com/sagoo-cloud/nexframe/configs"
+)
+
+// Server 内嵌的MQTT broker，基于 mochi-mqtt，支持 MQTT 3.1.1 和 MQTT 5。
+// 用于本地开发、集成测试，或将单个程序作为小型物联网网关。
+type Server struct {
+	Logger    *slog.Logger
+	Address   string // TCP监听地址，例如 :1883，为空时不监听TCP
+	WsAddress string // WebSocket监听地址，为空时不启用
+
+	broker   *mqtt.Server
+	hook     *authHook
+	listener net.Listener
+	started  bool
+	closed   chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+}
+
+// Option 配置broker
+type Option func(*Server)
+
+// WithAddress 设置TCP监听地址，使用 127.0.0.1:0 时由系统分配端口，可通过 Addr 获取
+func WithAddress(address string) Option {
+	return func(s *Server) {
+		s.Address = address
+	}
+}
+
+// WithWsAddress 设置WebSocket监听地址
+func WithWsAddress(address string) Option {
+	return func(s *Server) {
+		s.WsAddress = address
+	}
+}
+
+// WithAuthenticator 设置连接认证，未设置时允许所有客户端连接
+func WithAuthenticator(authenticator Authenticator) Option {
+	return func(s *Server) {
+		s.hook.authenticate = authenticator
+	}
+}
+
+// WithACL 设置主题的读写权限检查，未设置时不限制
+func WithACL(acl ACLFunc) Option {
+	return func(s *Server) {
+		s.hook.acl = acl
+	}
+}
+
+// WithLogger 设置日志记录器
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.Logger = logger
+	}
+}
+
+// NewServer 根据 mqtt.broker 配置创建broker，认证方式为 jwt 时使用 auth.NewJwt 校验密码中的令牌
+func NewServer(opts ...Option) (*Server, error) {
+	config := configs.LoadMqttBrokerConfig()
+	s := &Server{
+		Logger:    slog.Default(),
+		Address:   config.Address,
+		WsAddress: config.WsAddress,
+		hook:      &authHook{},
+		closed:    make(chan struct{}),
+	}
+
+	switch config.Auth {
+	case "", "none":
+	case "jwt":
+		jm, err := auth.NewJwt()
+		if err != nil {
+			return nil, err
+		}
+		s.hook.authenticate = JWTAuthenticator(jm)
+	default:
+		return nil, fmt.Errorf("unsupported mqtt broker auth: %s", config.Auth)
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.broker = mqtt.New(&mqtt.Options{
+		InlineClient: true,
+		Logger:       s.Logger,
+	})
+	if err := s.broker.AddHook(s.hook, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start 开始监听，不阻塞
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+
+	if s.Address != "" {
+		l, err := net.Listen("tcp", s.Address)
+		if err != nil {
+			return err
+		}
+		if err = s.broker.AddListener(listeners.NewNet("tcp", l)); err != nil {
+			_ = l.Close()
+			return err
+		}
+		s.listener = l
+	}
+	if s.WsAddress != "" {
+		ws := listeners.NewWebsocket(listeners.Config{ID: "ws", Address: s.WsAddress})
+		if err := s.broker.AddListener(ws); err != nil {
+			return err
+		}
+	}
+
+	if err := s.broker.Serve(); err != nil {
+		return err
+	}
+	s.started = true
+	s.Logger.Info("MQTT Broker Start", "address", s.Address, "wsAddress", s.WsAddress)
+	return nil
+}
+
+// Serve 开始监听并阻塞，直到调用 Close
+func (s *Server) Serve() error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+	<-s.closed
+	return nil
+}
+
+// Addr 返回TCP监听的实际地址，未监听时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Publish 以内置客户端发布消息，不经过网络
+func (s *Server) Publish(topic string, payload []byte, retain bool, qos byte) error {
+	return s.broker.Publish(topic, payload, retain, qos)
+}
+
+// Subscribe 以内置客户端订阅主题，subscriptionId 用于取消订阅
+func (s *Server) Subscribe(filter string, subscriptionId int, handler func(topic string, payload []byte)) error {
+	return s.broker.Subscribe(filter, subscriptionId, func(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
+		handler(pk.TopicName, pk.Payload)
+	})
+}
+
+// Unsubscribe 取消内置客户端的订阅
+func (s *Server) Unsubscribe(filter string, subscriptionId int) error {
+	return s.broker.Unsubscribe(filter, subscriptionId)
+}
+
+// Close 关闭所有监听和客户端连接
+func (s *Server) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.broker.Close()
+		close(s.closed)
+		s.Logger.Info("MQTT Broker Closed")
+	})
+	return err
+}
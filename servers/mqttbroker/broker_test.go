@@ -0,0 +1,180 @@
+package mqttbroker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sagoo-cloud/nexframe/net/mqttclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func startBroker(t *testing.T, address string, opts ...Option) *Server {
+	t.Helper()
+	opts = append([]Option{
+		WithAddress(address),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func newClient(t *testing.T, s *Server, conf mqttclient.Config) *mqttclient.Client {
+	t.Helper()
+	conf.Server = "tcp://" + s.Addr().String()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client, err := mqttclient.NewClient(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func receive(t *testing.T, ch <-chan paho.Message) paho.Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for message")
+		return nil
+	}
+}
+
+func TestBrokerPubSub(t *testing.T) {
+	s := startBroker(t, "127.0.0.1:0")
+
+	for _, version := range []int{mqttclient.ProtocolV311, mqttclient.ProtocolV5} {
+		client := newClient(t, s, mqttclient.Config{ProtocolVersion: version})
+		received := make(chan paho.Message, 1)
+		err := client.RegisterHandler(mqttclient.Handler{
+			Topic: "device/+/status",
+			Qos:   1,
+			Handle: func(c paho.Client, msg paho.Message) {
+				received <- msg
+			},
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, client.PublishWithOptions(context.Background(), "device/d1/status", 1, []byte("on"),
+			mqttclient.WithUserProperty("tenant", "t1")))
+		msg := receive(t, received)
+		assert.Equal(t, "device/d1/status", msg.Topic())
+		assert.Equal(t, []byte("on"), msg.Payload())
+		if version == mqttclient.ProtocolV5 {
+			assert.Equal(t, "t1", mqttclient.PropertiesOf(msg).User["tenant"])
+		}
+
+		// 内置客户端发布的消息同样会投递给外部客户端
+		assert.NoError(t, s.Publish("device/d2/status", []byte("off"), false, 1))
+		assert.Equal(t, []byte("off"), receive(t, received).Payload())
+		assert.NoError(t, client.Close())
+	}
+}
+
+func TestHandlerClientArgument(t *testing.T) {
+	s := startBroker(t, "127.0.0.1:0")
+
+	// 处理函数通过第一个参数回复消息，MQTT 5 下也不能是 nil
+	for _, version := range []int{mqttclient.ProtocolV311, mqttclient.ProtocolV5} {
+		client := newClient(t, s, mqttclient.Config{ProtocolVersion: version})
+		replies := make(chan paho.Message, 1)
+		assert.NoError(t, client.RegisterHandler(mqttclient.Handler{
+			Topic: "ping",
+			Qos:   1,
+			Handle: func(c paho.Client, msg paho.Message) {
+				c.Publish("pong", 1, false, msg.Payload())
+			},
+		}))
+		assert.NoError(t, client.RegisterHandler(mqttclient.Handler{
+			Topic: "pong",
+			Qos:   1,
+			Handle: func(c paho.Client, msg paho.Message) {
+				replies <- msg
+			},
+		}))
+
+		assert.NoError(t, client.Publish("ping", 1, []byte("hello")))
+		assert.Equal(t, []byte("hello"), receive(t, replies).Payload())
+		assert.True(t, client.GetClient().IsConnectionOpen())
+	}
+}
+
+func TestBrokerAuth(t *testing.T) {
+	secrets := map[string]string{"ak1": "sk1"}
+	s := startBroker(t, "127.0.0.1:0",
+		WithAuthenticator(AKSKAuthenticator(func(ak string) (string, bool) {
+			sk, ok := secrets[ak]
+			return sk, ok
+		}, time.Minute)),
+		WithACL(func(session *Session, topic string, write bool) bool {
+			return !write || topic == "up/"+session.Username
+		}),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := mqttclient.NewClient(ctx, mqttclient.Config{
+		Server:   "tcp://" + s.Addr().String(),
+		Username: "ak1",
+		Password: AKSKPassword("ak1", "wrong", time.Now()),
+	})
+	assert.Error(t, err)
+
+	client := newClient(t, s, mqttclient.Config{
+		ProtocolVersion: mqttclient.ProtocolV5,
+		Username:        "ak1",
+		Password:        AKSKPassword("ak1", "sk1", time.Now()),
+	})
+	assert.NoError(t, client.Publish("up/ak1", 1, []byte("ok")))
+
+	err = client.Publish("up/ak2", 1, []byte("denied"))
+	var rc *mqttclient.ReasonCodeError
+	assert.True(t, errors.As(err, &rc), err)
+}
+
+func TestOfflineDrain(t *testing.T) {
+	s := startBroker(t, "127.0.0.1:0")
+	address := s.Addr().String()
+
+	client := newClient(t, s, mqttclient.Config{
+		MaxReconnectInterval: 200 * time.Millisecond,
+		Offline:              &mqttclient.OfflineConfig{MaxMessages: 10},
+	})
+	assert.NoError(t, s.Close())
+	assert.Eventually(t, func() bool { return !client.GetClient().IsConnectionOpen() }, 5*time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, client.Publish("telemetry/d1", 1, []byte{byte(i)}))
+	}
+	assert.Equal(t, 3, client.OfflineLen())
+
+	// 在同一地址重新启动broker，客户端重连后按顺序发布缓存的消息
+	restarted := startBroker(t, address)
+	order := make(chan byte, 10)
+	assert.NoError(t, restarted.Subscribe("telemetry/#", 1, func(topic string, payload []byte) {
+		order <- payload[0]
+	}))
+	for i := 0; i < 3; i++ {
+		select {
+		case b := <-order:
+			assert.Equal(t, byte(i), b)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for offline message")
+		}
+	}
+	assert.Eventually(t, func() bool { return client.OfflineLen() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
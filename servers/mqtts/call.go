@@ -0,0 +1,262 @@
+package mqtts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sagoo-cloud/nexframe/configs"
+	"github.com/sagoo-cloud/nexframe/net/mqttclient"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
+)
+
+// 请求/响应模式。
+// MQTT 5 使用响应主题和关联数据属性，请求和响应的内容均为JSON，处理失败时在用户属性 error 中返回错误信息；
+// MQTT 3.1.1 没有这两个属性，请求和响应以 Envelope 封装，通过 id 和 replyTo 字段关联。
+
+const errorProperty = "error" // MQTT 5 响应中携带错误信息的用户属性
+
+var (
+	ErrCallTimeout = errors.New("mqtt call timeout")
+	ErrCallFailed  = errors.New("mqtt call failed")
+)
+
+// Envelope MQTT 3.1.1 下请求和响应的封装
+type Envelope struct {
+	Id      string          `json:"id"`
+	ReplyTo string          `json:"replyTo,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// reply 收到的响应
+type reply struct {
+	data []byte
+	err  error
+}
+
+// Caller 发送请求并等待响应，所有请求共用一个响应主题 {ReplyPrefix}/{实例ID}
+type Caller struct {
+	Client      *mqttclient.Client
+	Qos         byte
+	Timeout     time.Duration // ctx 未设置截止时间时的默认超时
+	ReplyPrefix string
+
+	replyTopic string
+	pending    sync.Map // 关联ID -> chan reply
+	mu         sync.Mutex
+	ready      bool // 已订阅响应主题
+}
+
+// NewCaller 创建基于 client 的请求者
+func NewCaller(client *mqttclient.Client) *Caller {
+	config := configs.LoadMqttConfig()
+	return &Caller{
+		Client:      client,
+		Qos:         config.PublishQos,
+		Timeout:     config.CallTimeout,
+		ReplyPrefix: config.ReplyPrefix,
+	}
+}
+
+var (
+	defaultCaller *Caller
+	callerOnce    sync.Once
+)
+
+// Call 使用默认的MQTT客户端发送请求，payload 编码为JSON，返回响应的JSON内容。
+// 用于向设备下发指令并等待应答，超时返回 ErrCallTimeout，对端处理失败返回 ErrCallFailed。
+func Call(ctx context.Context, topic string, payload interface{}) ([]byte, error) {
+	if GetIns() == nil {
+		return nil, errors.New("MQTT链接失败")
+	}
+	callerOnce.Do(func() {
+		defaultCaller = NewCaller(GetIns())
+	})
+	return defaultCaller.Call(ctx, topic, payload)
+}
+
+// init 订阅响应主题，订阅失败时下次调用会重试
+func (c *Caller) init() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ready {
+		return nil
+	}
+	prefix := strings.TrimSuffix(c.ReplyPrefix, "/")
+	if prefix == "" {
+		prefix = "nexframe/reply"
+	}
+	topic := prefix + "/" + guid.S()
+	if err := c.Client.RegisterHandler(mqttclient.Handler{
+		Topic:  topic,
+		Qos:    1,
+		Handle: c.onReply,
+	}); err != nil {
+		return err
+	}
+	c.replyTopic = topic
+	c.ready = true
+	return nil
+}
+
+// Call 发送请求并等待响应
+func (c *Caller) Call(ctx context.Context, topic string, payload interface{}) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	id := guid.S()
+	ch := make(chan reply, 1)
+	c.pending.Store(id, ch)
+	defer c.pending.Delete(id)
+
+	var opts []mqttclient.PublishOption
+	if c.Client.ProtocolVersion() == mqttclient.ProtocolV5 {
+		opts = append(opts,
+			mqttclient.WithResponseTopic(c.replyTopic),
+			mqttclient.WithCorrelationData([]byte(id)),
+			mqttclient.WithContentType("application/json"),
+		)
+	} else if data, err = json.Marshal(&Envelope{Id: id, ReplyTo: c.replyTopic, Data: data}); err != nil {
+		return nil, err
+	}
+	if err = c.Client.PublishWithOptions(ctx, topic, c.Qos, data, opts...); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-ch:
+		return r.data, r.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %s", ErrCallTimeout, topic)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// onReply 按关联ID将响应交给等待中的请求，未知的响应直接丢弃
+func (c *Caller) onReply(_ mqtt.Client, msg mqtt.Message) {
+	var (
+		id string
+		r  reply
+	)
+	if props := mqttclient.PropertiesOf(msg); props != nil && props.CorrelationData != nil {
+		id = string(props.CorrelationData)
+		r.data = msg.Payload()
+		if e := props.User[errorProperty]; e != "" {
+			r.err = fmt.Errorf("%w: %s", ErrCallFailed, e)
+		}
+	} else {
+		var env Envelope
+		if err := json.Unmarshal(msg.Payload(), &env); err != nil {
+			return
+		}
+		id = env.Id
+		r.data = env.Data
+		if env.Error != "" {
+			r.err = fmt.Errorf("%w: %s", ErrCallFailed, env.Error)
+		}
+	}
+
+	if ch, ok := c.pending.Load(id); ok {
+		select {
+		case ch.(chan reply) <- r:
+		default:
+		}
+	}
+}
+
+// request 收到的请求
+type request struct {
+	payload     []byte
+	replyTo     string
+	correlation []byte
+	envelope    bool // 请求以 Envelope 封装，响应也以 Envelope 封装
+	id          string
+}
+
+// parseRequest 解析请求中的响应主题和关联数据，没有响应主题时 replyTo 为空
+func parseRequest(msg mqtt.Message) *request {
+	if props := mqttclient.PropertiesOf(msg); props != nil && props.ResponseTopic != "" {
+		return &request{payload: msg.Payload(), replyTo: props.ResponseTopic, correlation: props.CorrelationData}
+	}
+	var env Envelope
+	if err := json.Unmarshal(msg.Payload(), &env); err == nil && env.Id != "" && env.ReplyTo != "" {
+		return &request{payload: env.Data, replyTo: env.ReplyTo, envelope: true, id: env.Id}
+	}
+	return &request{payload: msg.Payload()}
+}
+
+// RegisterCommand 注册指令路由，处理函数的返回值编码为JSON后自动发布到请求的响应主题，
+// 返回错误时将错误信息发送给请求方。请求方使用 Call 发送指令。
+func (s *Server) RegisterCommand(name string, handler *commons.CommHandler) {
+	if err := s.registerCommand(name, handler); err != nil {
+		s.Logger.Error("Register command failed", "topic", name, "error", err)
+	}
+}
+
+func (s *Server) registerCommand(name string, handler *commons.CommHandler) error {
+	if err := s.register(name, handler); err != nil {
+		return err
+	}
+	s.topics[name].reply = true
+	return nil
+}
+
+// HandleCommand 注册类型化的指令路由，请求的绑定和校验与 Handle 一致，返回值自动发布到响应主题
+func HandleCommand[T any](s *Server, pattern string, fn HandlerFunc[T]) error {
+	return s.registerCommand(pattern, typedHandler(fn))
+}
+
+// reply 将处理结果发布到响应主题
+func (s *Server) reply(ctx context.Context, req *request, qos byte, resp interface{}, handleErr error) {
+	var (
+		data []byte
+		err  error
+		opts []mqttclient.PublishOption
+	)
+	if handleErr == nil {
+		data, err = json.Marshal(resp)
+		if err != nil {
+			handleErr = err
+			data = nil
+		}
+	}
+
+	if req.envelope {
+		env := &Envelope{Id: req.id, Data: data}
+		if handleErr != nil {
+			env.Error = handleErr.Error()
+		}
+		if data, err = json.Marshal(env); err != nil {
+			s.Logger.Error("Encode reply failed", "topic", req.replyTo, "error", err)
+			return
+		}
+	} else {
+		opts = append(opts, mqttclient.WithCorrelationData(req.correlation), mqttclient.WithContentType("application/json"))
+		if handleErr != nil {
+			opts = append(opts, mqttclient.WithUserProperty(errorProperty, handleErr.Error()))
+		}
+	}
+
+	if err = s.client().PublishWithOptions(context.WithoutCancel(ctx), req.replyTo, qos, data, opts...); err != nil {
+		s.Logger.Error("Publish reply failed", "topic", req.replyTo, "error", err)
+	}
+}
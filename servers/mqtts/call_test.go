@@ -0,0 +1,122 @@
+package mqtts
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/net/mqttclient"
+	"github.com/sagoo-cloud/nexframe/servers/mqttbroker"
+	"github.com/stretchr/testify/assert"
+)
+
+type switchReq struct {
+	DeviceKey string `topic:"deviceKey"`
+	On        bool   `json:"on"`
+}
+
+func TestCall(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	broker, err := mqttbroker.NewServer(mqttbroker.WithAddress("127.0.0.1:0"), mqttbroker.WithLogger(logger))
+	if !assert.NoError(t, err) || !assert.NoError(t, broker.Start()) {
+		return
+	}
+	defer broker.Close()
+
+	newClient := func(version int) *mqttclient.Client {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client, err := mqttclient.NewClient(ctx, mqttclient.Config{
+			Server:          "tcp://" + broker.Addr().String(),
+			ProtocolVersion: version,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	for _, version := range []int{mqttclient.ProtocolV311, mqttclient.ProtocolV5} {
+		s := &Server{topics: make(map[string]*route), Logger: logger, SubscribeQos: 1, Client: newClient(version)}
+		assert.NoError(t, HandleCommand(s, "device/{deviceKey}/switch", func(ctx context.Context, req *switchReq) (interface{}, error) {
+			if req.DeviceKey == "offline" {
+				return nil, errors.New("device offline")
+			}
+			return map[string]interface{}{"deviceKey": req.DeviceKey, "on": req.On}, nil
+		}))
+		assert.NoError(t, s.work())
+
+		caller := NewCaller(newClient(version))
+		caller.Qos = 1
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		resp, err := caller.Call(ctx, "device/d1/switch", map[string]interface{}{"on": true})
+		cancel()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"deviceKey":"d1","on":true}`, string(resp))
+
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		_, err = caller.Call(ctx, "device/offline/switch", map[string]interface{}{"on": true})
+		cancel()
+		assert.ErrorIs(t, err, ErrCallFailed)
+		assert.Contains(t, err.Error(), "device offline")
+
+		// 没有处理器的主题等待超时
+		caller.Timeout = 200 * time.Millisecond
+		_, err = caller.Call(context.Background(), "device/d1/unknown", nil)
+		assert.ErrorIs(t, err, ErrCallTimeout)
+
+		_ = caller.Client.Close()
+		assert.NoError(t, s.Close())
+	}
+}
+
+func TestCloseDrainsQueuedReplies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	broker, err := mqttbroker.NewServer(mqttbroker.WithAddress("127.0.0.1:0"), mqttbroker.WithLogger(logger))
+	if !assert.NoError(t, err) || !assert.NoError(t, broker.Start()) {
+		return
+	}
+	defer broker.Close()
+
+	newClient := func() *mqttclient.Client {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client, err := mqttclient.NewClient(ctx, mqttclient.Config{Server: "tcp://" + broker.Addr().String()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	s := &Server{topics: make(map[string]*route), Logger: logger, SubscribeQos: 1, Client: newClient(),
+		Parallel: true, Workers: 1, QueueLength: 4}
+	assert.NoError(t, HandleCommand(s, "device/{deviceKey}/switch", func(ctx context.Context, req *switchReq) (interface{}, error) {
+		time.Sleep(200 * time.Millisecond)
+		return map[string]interface{}{"deviceKey": req.DeviceKey}, nil
+	}))
+	assert.NoError(t, s.work())
+
+	caller := NewCaller(newClient())
+	caller.Qos = 1
+	defer caller.Client.Close()
+
+	errs := make(chan error, 2)
+	for _, device := range []string{"d1", "d2"} {
+		go func(device string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := caller.Call(ctx, "device/"+device+"/switch", map[string]interface{}{"on": true})
+			errs <- err
+		}(device)
+	}
+
+	// 一条消息正在处理，另一条在队列中等待时关闭，两条消息的响应都应在客户端关闭前发布
+	assert.Eventually(t, func() bool { return s.Stats().QueueDepth == 1 }, 5*time.Second, 5*time.Millisecond)
+	assert.NoError(t, s.Close())
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, <-errs)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/sagoo-cloud/nexframe/configs"
 	"github.com/sagoo-cloud/nexframe/net/mqttclient"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -30,6 +31,16 @@ func initMqtt() *mqttclient.Client {
 		Logger:    nil,
 		LogLevel:  mqttclient.IntToLogLevel(config.LogLevel), // 设置日志级别为INFO
 		QueueSize: 100,                                       // 设置消息队列大小
+
+		ProtocolVersion:       config.ProtocolVersion,
+		SessionExpiryInterval: uint32(config.SessionExpiry),
+	}
+	if config.OfflineStore != "" {
+		offline, err := newOfflineConfig(config)
+		if err != nil {
+			panic(err)
+		}
+		conf.Offline = offline
 	}
 	if config.CAFile != "" && config.CertFile != "" {
 		conf.CAFile = config.CAFile
@@ -42,7 +53,6 @@ func initMqtt() *mqttclient.Client {
 	if err != nil {
 		panic(err)
 	}
-	defer client.Close()
 
 	// 监控连接状态
 	go func() {
@@ -61,3 +71,28 @@ func initMqtt() *mqttclient.Client {
 
 	return client
 }
+
+// newOfflineConfig 根据配置创建离线缓冲，断开连接期间缓存 QoS 1/2 的消息
+func newOfflineConfig(config *configs.MqttConfig) (*mqttclient.OfflineConfig, error) {
+	offline := &mqttclient.OfflineConfig{
+		MaxMessages: config.OfflineMaxMessages,
+		MaxBytes:    int64(config.OfflineMaxBytes),
+		Overflow:    mqttclient.OfflineOverflow(config.OfflineOverflow),
+		OnDrop: func(msg *mqttclient.OfflineMessage, reason error) {
+			slog.Warn("MQTT offline message dropped", "topic", msg.Topic, "reason", reason)
+		},
+	}
+	switch config.OfflineStore {
+	case "memory":
+		offline.Store = mqttclient.NewMemoryStore(config.OfflineMaxMessages)
+	case "file":
+		store, err := mqttclient.NewFileStore(config.OfflinePath)
+		if err != nil {
+			return nil, err
+		}
+		offline.Store = store
+	default:
+		return nil, fmt.Errorf("unsupported offline store: %s", config.OfflineStore)
+	}
+	return offline, nil
+}
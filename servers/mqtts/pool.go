@@ -0,0 +1,100 @@
+package mqtts
+
+import (
+	"context"
+	"hash/fnv"
+	"sync/atomic"
+
+	"github.com/sagoo-cloud/nexframe/os/gpool"
+)
+
+// OverflowPolicy 处理队列已满时的策略
+type OverflowPolicy string
+
+const (
+	OverflowBlock OverflowPolicy = "block" // 阻塞订阅回调直到队列有空位，向 broker 施加背压
+	OverflowDrop  OverflowPolicy = "drop"  // 丢弃新到达的消息
+)
+
+// Stats 订阅处理的运行指标
+type Stats struct {
+	Workers    int   // 工作协程数，未启用工作池时为 0
+	QueueDepth int   // 队列中等待处理的消息数
+	Processed  int64 // 已处理的消息数
+	Failed     int64 // 处理返回错误的消息数
+	Timeouts   int64 // 处理超时的消息数
+	Dropped    int64 // 因队列已满被丢弃的消息数
+}
+
+// metrics 订阅处理的计数器
+type metrics struct {
+	processed atomic.Int64
+	failed    atomic.Int64
+	timeouts  atomic.Int64
+	dropped   atomic.Int64
+}
+
+// dispatcher 基于 gpool 的有界工作池。
+// 设置了顺序键时按键的哈希分配到单协程的工作池，保证同一个键的消息按到达顺序处理。
+type dispatcher struct {
+	pools    []*gpool.GPool
+	workers  int
+	overflow OverflowPolicy
+}
+
+func newDispatcher(workers, queueLength int, overflow OverflowPolicy, ordered bool) *dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueLength <= 0 {
+		queueLength = 1
+	}
+	d := &dispatcher{workers: workers, overflow: overflow}
+	if !ordered {
+		d.pools = []*gpool.GPool{gpool.NewGPoolWithQueue(workers, queueLength)}
+		return d
+	}
+
+	laneQueue := queueLength / workers
+	if laneQueue <= 0 {
+		laneQueue = 1
+	}
+	d.pools = make([]*gpool.GPool, workers)
+	for i := range d.pools {
+		d.pools[i] = gpool.NewGPoolWithQueue(1, laneQueue)
+	}
+	return d
+}
+
+// submit 提交任务，队列已满且策略为丢弃时返回 false
+func (d *dispatcher) submit(key string, job func(ctx context.Context) error) bool {
+	pool := d.pools[0]
+	if len(d.pools) > 1 {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(key))
+		pool = d.pools[h.Sum32()%uint32(len(d.pools))]
+	}
+
+	if d.overflow == OverflowDrop {
+		return pool.TryAddJob(job)
+	}
+	pool.AddJob(job)
+	return true
+}
+
+// queueDepth 返回所有队列中等待处理的任务数
+func (d *dispatcher) queueDepth() int {
+	depth := 0
+	for _, pool := range d.pools {
+		depth += pool.QueueLength()
+	}
+	return depth
+}
+
+// close 等待已提交的任务处理完成后关闭工作池
+func (d *dispatcher) close() {
+	for _, pool := range d.pools {
+		pool.Wait()
+		pool.Shutdown()
+	}
+}
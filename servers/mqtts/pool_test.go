@@ -0,0 +1,87 @@
+package mqtts
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMessage struct {
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Duplicate() bool   { return false }
+func (m *fakeMessage) Qos() byte         { return 0 }
+func (m *fakeMessage) Retained() bool    { return false }
+func (m *fakeMessage) Topic() string     { return m.topic }
+func (m *fakeMessage) MessageID() uint16 { return 0 }
+func (m *fakeMessage) Payload() []byte   { return m.payload }
+func (m *fakeMessage) Ack()              {}
+
+type recordHandler struct {
+	mu    sync.Mutex
+	seen  map[string][]string
+	delay time.Duration
+}
+
+func (h *recordHandler) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	time.Sleep(h.delay)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	device := TopicParam(ctx, "deviceKey")
+	h.seen[device] = append(h.seen[device], string(request.([]byte)))
+	return nil, ctx.Err()
+}
+
+func newTestServer(t *testing.T, h commons.Handler) (*Server, *route) {
+	s := &Server{topics: make(map[string]*route), Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	assert.NoError(t, s.register("/sys/{productKey}/{deviceKey}/property/post", &commons.CommHandler{Handler: h}))
+	return s, s.topics["/sys/{productKey}/{deviceKey}/property/post"]
+}
+
+func TestOrderedDispatch(t *testing.T) {
+	h := &recordHandler{seen: make(map[string][]string), delay: time.Millisecond}
+	s, r := newTestServer(t, h)
+	s.OrderKey = "deviceKey"
+	s.dispatcher = newDispatcher(4, 64, OverflowBlock, true)
+
+	devices := []string{"d1", "d2", "d3"}
+	for i := 0; i < 10; i++ {
+		for _, d := range devices {
+			s.dispatch(r, &fakeMessage{topic: "/sys/p1/" + d + "/property/post", payload: []byte{byte('0' + i)}})
+		}
+	}
+	s.dispatcher.close()
+
+	for _, d := range devices {
+		assert.Equal(t, []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, h.seen[d], d)
+	}
+	assert.Equal(t, int64(30), s.Stats().Processed)
+}
+
+func TestDropAndTimeout(t *testing.T) {
+	h := &recordHandler{seen: make(map[string][]string), delay: 50 * time.Millisecond}
+	s, r := newTestServer(t, h)
+	s.HandlerTimeout = 10 * time.Millisecond
+	s.dispatcher = newDispatcher(1, 1, OverflowDrop, false)
+
+	for i := 0; i < 5; i++ {
+		s.dispatch(r, &fakeMessage{topic: "/sys/p1/d1/property/post", payload: []byte("x")})
+	}
+	stats := s.Stats()
+	assert.Equal(t, 1, stats.Workers)
+	assert.Greater(t, stats.Dropped, int64(0))
+
+	s.dispatcher.close()
+	stats = s.Stats()
+	assert.Equal(t, int64(5), stats.Processed+stats.Dropped)
+	assert.Equal(t, stats.Processed, stats.Timeouts)
+	assert.Equal(t, 0, stats.QueueDepth)
+}
@@ -0,0 +1,184 @@
+package mqtts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+// 路由模式中的命名段，例如 /sys/{productKey}/{deviceKey}/property/post，
+// 订阅时转换为 /sys/+/+/property/post；以 {name...} 结尾时转换为 #，绑定剩余的所有层级。
+
+const topicTag = "topic" // 结构体字段标签，用于绑定主题中的命名段
+
+var (
+	ErrInvalidPattern = errors.New("invalid topic pattern")
+	ErrInvalidPayload = commons.ErrInvalidPayload
+)
+
+type topicKey struct{}
+
+// topicInfo 保存在处理上下文中的主题信息
+type topicInfo struct {
+	topic  string
+	params map[string]string
+}
+
+// topicPattern 解析后的路由模式
+type topicPattern struct {
+	pattern  string
+	filter   string   // 订阅使用的主题过滤器
+	levels   []string // 订阅过滤器的每一层
+	segments []string // 每一层绑定的名称，未命名时为空
+	multi    bool     // 最后一层是否为多层通配
+}
+
+// parsePattern 解析路由模式，不含命名段的模式原样作为订阅过滤器
+func parsePattern(pattern string) (*topicPattern, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("%w: empty pattern", ErrInvalidPattern)
+	}
+	levels := strings.Split(pattern, "/")
+	p := &topicPattern{
+		pattern:  pattern,
+		segments: make([]string, len(levels)),
+	}
+	filter := make([]string, len(levels))
+	for i, level := range levels {
+		last := i == len(levels)-1
+		switch {
+		case strings.HasPrefix(level, "{") && strings.HasSuffix(level, "...}"):
+			if !last {
+				return nil, fmt.Errorf("%w: %s must be the last level of %s", ErrInvalidPattern, level, pattern)
+			}
+			p.segments[i] = strings.TrimSuffix(strings.TrimPrefix(level, "{"), "...}")
+			p.multi = true
+			filter[i] = "#"
+		case strings.HasPrefix(level, "{") && strings.HasSuffix(level, "}"):
+			p.segments[i] = strings.TrimSuffix(strings.TrimPrefix(level, "{"), "}")
+			filter[i] = "+"
+		case level == "#":
+			if !last {
+				return nil, fmt.Errorf("%w: # must be the last level of %s", ErrInvalidPattern, pattern)
+			}
+			p.multi = true
+			filter[i] = level
+		case level == "+":
+			filter[i] = level
+		case strings.ContainsAny(level, "{}+#"):
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPattern, pattern)
+		default:
+			filter[i] = level
+		}
+		if filter[i] != level && p.segments[i] == "" {
+			return nil, fmt.Errorf("%w: empty name in %s", ErrInvalidPattern, pattern)
+		}
+	}
+	p.levels = filter
+	p.filter = strings.Join(filter, "/")
+	return p, nil
+}
+
+// match 判断主题是否匹配，匹配时返回命名段的值
+func (p *topicPattern) match(topic string) (map[string]string, bool) {
+	levels := strings.Split(topic, "/")
+	n := len(p.levels)
+	if p.multi {
+		// # 同时匹配父级本身，例如 a/# 匹配 a
+		if len(levels) < n-1 {
+			return nil, false
+		}
+	} else if len(levels) != n {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, level := range p.levels {
+		switch level {
+		case "#":
+			if p.segments[i] != "" {
+				params[p.segments[i]] = strings.Join(levels[i:], "/")
+			}
+			return params, true
+		case "+":
+			if p.segments[i] != "" {
+				params[p.segments[i]] = levels[i]
+			}
+		default:
+			if levels[i] != level {
+				return nil, false
+			}
+		}
+	}
+	return params, true
+}
+
+// withTopic 将主题和命名段写入上下文
+func withTopic(ctx context.Context, topic string, params map[string]string) context.Context {
+	return context.WithValue(ctx, topicKey{}, &topicInfo{topic: topic, params: params})
+}
+
+// TopicFromContext 获取当前消息的主题
+func TopicFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(topicKey{}).(*topicInfo); ok {
+		return info.topic
+	}
+	return ""
+}
+
+// TopicParams 获取当前消息主题中绑定的全部命名段
+func TopicParams(ctx context.Context) map[string]string {
+	if info, ok := ctx.Value(topicKey{}).(*topicInfo); ok {
+		return info.params
+	}
+	return nil
+}
+
+// TopicParam 获取当前消息主题中指定命名段的值，例如 TopicParam(ctx, "deviceKey")
+func TopicParam(ctx context.Context, name string) string {
+	return TopicParams(ctx)[name]
+}
+
+// HandlerFunc 类型化的消息处理函数，req 由消息的JSON内容解码并校验后得到
+type HandlerFunc[T any] = commons.TypedFunc[T]
+
+// Handle 注册类型化的路由，消息的解码和校验见 commons.TypedHandler，
+// 校验前带有 topic 标签的字段会绑定主题中同名的命名段。
+func Handle[T any](s *Server, pattern string, fn HandlerFunc[T]) error {
+	return s.register(pattern, typedHandler(fn))
+}
+
+// typedHandler 将类型化的处理函数适配为 commons.CommHandler，校验前绑定主题中的命名段
+func typedHandler[T any](fn HandlerFunc[T]) *commons.CommHandler {
+	h := commons.NewTypedHandler(fn)
+	h.Bind = func(ctx context.Context, req *T) {
+		bindTopicParams(req, TopicParams(ctx))
+	}
+	return &commons.CommHandler{Handler: h}
+}
+
+// bindTopicParams 将命名段写入带有 topic 标签的字符串字段
+func bindTopicParams(req interface{}, params map[string]string) {
+	if len(params) == 0 {
+		return
+	}
+	v := reflect.ValueOf(req).Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get(topicTag)
+		if name == "" {
+			continue
+		}
+		field := v.Field(i)
+		if value, ok := params[name]; ok && field.CanSet() && field.Kind() == reflect.String {
+			field.SetString(value)
+		}
+	}
+}
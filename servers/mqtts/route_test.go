@@ -0,0 +1,74 @@
+package mqtts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePattern(t *testing.T) {
+	p, err := parsePattern("/sys/{productKey}/{deviceKey}/property/post")
+	assert.NoError(t, err)
+	assert.Equal(t, "/sys/+/+/property/post", p.filter)
+
+	params, ok := p.match("/sys/p1/d1/property/post")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"productKey": "p1", "deviceKey": "d1"}, params)
+
+	_, ok = p.match("/sys/p1/d1/event/post")
+	assert.False(t, ok)
+	_, ok = p.match("/sys/p1/d1/property/post/reply")
+	assert.False(t, ok)
+
+	p, err = parsePattern("/sys/{productKey}/{path...}")
+	assert.NoError(t, err)
+	assert.Equal(t, "/sys/+/#", p.filter)
+	params, ok = p.match("/sys/p1/d1/event/alarm")
+	assert.True(t, ok)
+	assert.Equal(t, "d1/event/alarm", params["path"])
+
+	p, err = parsePattern("device/+/status")
+	assert.NoError(t, err)
+	assert.Equal(t, "device/+/status", p.filter)
+	params, ok = p.match("device/d1/status")
+	assert.True(t, ok)
+	assert.Empty(t, params)
+
+	for _, invalid := range []string{"", "a/#/b", "a/{}/b", "a/{rest...}/b", "a/b{c}"} {
+		_, err = parsePattern(invalid)
+		assert.ErrorIs(t, err, ErrInvalidPattern, invalid)
+	}
+}
+
+type propertyPostReq struct {
+	ProductKey string  `topic:"productKey"`
+	DeviceKey  string  `topic:"deviceKey"`
+	Id         string  `json:"id" v:"required"`
+	Value      float64 `json:"value"`
+}
+
+func TestTypedHandler(t *testing.T) {
+	var got *propertyPostReq
+	h := typedHandler(func(ctx context.Context, req *propertyPostReq) (interface{}, error) {
+		got = req
+		return TopicParam(ctx, "deviceKey"), nil
+	})
+
+	p, _ := parsePattern("/sys/{productKey}/{deviceKey}/property/post")
+	topic := "/sys/p1/d1/property/post"
+	params, _ := p.match(topic)
+	ctx := withTopic(context.Background(), topic, params)
+
+	resp, err := h.Handle(ctx, []byte(`{"id":"1","value":2.5}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "d1", resp)
+	assert.Equal(t, &propertyPostReq{ProductKey: "p1", DeviceKey: "d1", Id: "1", Value: 2.5}, got)
+	assert.Equal(t, topic, TopicFromContext(ctx))
+
+	_, err = h.Handle(ctx, []byte(`{"value":1}`))
+	assert.Error(t, err)
+
+	_, err = h.Handle(ctx, []byte(`not json`))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+}
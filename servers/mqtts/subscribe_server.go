@@ -2,93 +2,248 @@ package mqtts
 
 import (
 	"context"
+	"errors"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/sagoo-cloud/nexframe/configs"
 	"github.com/sagoo-cloud/nexframe/net/mqttclient"
 	"github.com/sagoo-cloud/nexframe/servers/commons"
 	"log/slog"
+	"runtime"
+	"sync"
+	"time"
 )
 
+// ErrNotConnected MQTT客户端未连接
+var ErrNotConnected = errors.New("MQTT链接失败")
+
 type Server struct {
-	topics       map[string]*commons.CommHandler
-	Logger       *slog.Logger
-	Parallel     bool //并行处理
-	SubscribeQos byte
+	topics         map[string]*route
+	Logger         *slog.Logger
+	Parallel       bool //并行处理，使用有界工作池
+	SubscribeQos   byte
+	Workers        int                // 工作池协程数，默认为 CPU 核数
+	QueueLength    int                // 工作池队列长度
+	Overflow       OverflowPolicy     // 队列已满时的策略，默认阻塞
+	OrderKey       string             // 按主题中的命名段顺序处理，例如 deviceKey，同一设备的消息依次处理
+	HandlerTimeout time.Duration      // 单条消息的处理超时，为 0 时不限制
+	SharedGroup    string             // 共享订阅分组，多个实例以 $share/{group}/{topic} 订阅同一主题时由 broker 负载均衡
+	Client         *mqttclient.Client // 使用的MQTT客户端，为 nil 时使用 GetIns
+	dispatcher     *dispatcher
+	metrics        metrics
+	replies        sync.WaitGroup // 正在发布的响应
+	closeMu        sync.RWMutex   // 分发消息时持有读锁，Close 持有写锁标记 closing
+	closing        bool           // 已开始关闭，不再接收新消息
+	closed         chan struct{}
+	doneOnce       sync.Once
+	closeOnce      sync.Once
 }
 
 func NewServer() *Server {
 	config := configs.LoadMqttConfig()
 	ss := &Server{
-		topics:       make(map[string]*commons.CommHandler),
-		Parallel:     config.Parallel,
-		SubscribeQos: config.SubscribeQos,
+		topics:         make(map[string]*route),
+		Logger:         slog.Default(),
+		Parallel:       config.Parallel,
+		SubscribeQos:   config.SubscribeQos,
+		Workers:        config.Workers,
+		QueueLength:    config.QueueLength,
+		Overflow:       OverflowPolicy(config.Overflow),
+		OrderKey:       config.OrderKey,
+		HandlerTimeout: config.HandlerTimeout,
+		SharedGroup:    config.SharedGroup,
 	}
 	return ss
 }
+
+// route 表示一个已注册的主题路由
+type route struct {
+	pattern *topicPattern
+	handler *commons.CommHandler
+	reply   bool // 将处理结果发布到请求的响应主题
+}
+
+// client 返回订阅和发布使用的MQTT客户端
+func (s *Server) client() *mqttclient.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return GetIns()
+}
+
+// Register 注册主题路由，name 可以是普通主题、MQTT通配主题，
+// 或带有命名段的路由模式，例如 /sys/{productKey}/{deviceKey}/property/post。
+// 命名段的值可在处理函数中通过 TopicParam 获取，消息内容以 []byte 传入处理函数。
 func (s *Server) Register(name string, handler *commons.CommHandler) {
-	s.topics[name] = handler
+	if err := s.register(name, handler); err != nil {
+		s.Logger.Error("Register topic failed", "topic", name, "error", err)
+	}
+}
 
+func (s *Server) register(name string, handler *commons.CommHandler) error {
+	pattern, err := parsePattern(name)
+	if err != nil {
+		return err
+	}
+	s.topics[name] = &route{pattern: pattern, handler: handler}
+	return nil
 }
+
+// Serve 订阅所有已注册的主题并阻塞，直到调用 Close
 func (s *Server) Serve() error {
-	if GetIns() != nil {
-		errChans := make(map[string]chan error)
-		s.work(errChans)
-		for _, errChan := range errChans {
-			if errChan != nil {
-				s.Logger.Info("errChan:", <-errChan)
-			}
-		}
-	} else {
+	if s.client() == nil {
 		s.Logger.Info("MQTT链接失败")
+		return ErrNotConnected
+	}
+	if err := s.work(); err != nil {
+		return err
 	}
+	<-s.done()
 	return nil
 }
 
-func (s *Server) work(errChans map[string]chan error) {
+func (s *Server) work() error {
 	s.Logger.Info("MQTT Subscribe Server Start")
-	for topic, handler := range s.topics {
-		errChans[topic] = make(chan error)
-		go s.worker(topic, handler, errChans[topic])
+	// 并行处理或按键顺序处理时使用有界工作池，否则在订阅回调中依次处理
+	if s.Parallel || s.OrderKey != "" {
+		workers := s.Workers
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		s.dispatcher = newDispatcher(workers, s.QueueLength, s.Overflow, s.OrderKey != "")
 	}
-
+	for _, r := range s.topics {
+		if err := s.worker(r); err != nil {
+			return err
+		}
+	}
+	return nil
 }
-func (s *Server) worker(t string, h *commons.CommHandler, e chan error) {
-	s.Logger.Info("Subscribe topic:%s", t)
+
+func (s *Server) worker(r *route) error {
+	topic := mqttclient.SharedTopic(s.SharedGroup, r.pattern.filter)
+	s.Logger.Info("Subscribe topic", "pattern", r.pattern.pattern, "topic", topic)
 	// 创建消息处理器
 	handler := mqttclient.Handler{
-		Topic: t,
+		Topic: topic,
 		Qos:   s.SubscribeQos,
 		Handle: func(
 			client mqtt.Client, message mqtt.Message) {
-			if s.Parallel {
-				go s.process(h, message)
-			} else {
-				s.process(h, message)
-			}
+			s.dispatch(r, message)
 		},
 	}
 	// 注册处理器
-	if err := GetIns().RegisterHandler(handler); err != nil {
-		panic(err)
+	return s.client().RegisterHandler(handler)
+}
+
+// done 返回 Close 时关闭的通道
+func (s *Server) done() chan struct{} {
+	s.doneOnce.Do(func() {
+		s.closed = make(chan struct{})
+	})
+	return s.closed
+}
+
+// dispatch 将消息交给工作池处理，未启用工作池时直接处理
+func (s *Server) dispatch(r *route, message mqtt.Message) {
+	params, ok := r.pattern.match(message.Topic())
+	if !ok {
+		return
+	}
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closing {
+		return
+	}
+	if s.dispatcher == nil {
+		s.process(r, message, params)
+		return
+	}
+
+	key := message.Topic()
+	if value, ok := params[s.OrderKey]; ok {
+		key = value
+	}
+	accepted := s.dispatcher.submit(key, func(ctx context.Context) error {
+		s.process(r, message, params)
+		return nil
+	})
+	if !accepted {
+		s.metrics.dropped.Add(1)
+		s.Logger.Warn("MQTT message dropped, queue is full", "topic", message.Topic())
 	}
 }
-func (s *Server) process(h *commons.CommHandler, Message mqtt.Message) {
-	s.Logger.Info("subscribe topic:", Message.Topic())
-	resp, err := h.Handle(context.Background(), Message.Payload())
+
+func (s *Server) process(r *route, Message mqtt.Message, params map[string]string) {
+	s.Logger.Info("subscribe topic", "topic", Message.Topic())
+	ctx := withTopic(context.Background(), Message.Topic(), params)
+	if s.HandlerTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.HandlerTimeout)
+		defer cancel()
+	}
+
+	req := &request{payload: Message.Payload()}
+	if r.reply {
+		req = parseRequest(Message)
+	}
+	resp, err := r.handler.Handle(ctx, req.payload)
+	if req.replyTo != "" {
+		// 在订阅回调中同步等待发布确认会阻塞客户端的消息处理，响应异步发布
+		s.replies.Add(1)
+		go func() {
+			defer s.replies.Done()
+			s.reply(ctx, req, Message.Qos(), resp, err)
+		}()
+	}
+	s.metrics.processed.Add(1)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		s.metrics.timeouts.Add(1)
+	}
 	if err != nil {
+		s.metrics.failed.Add(1)
 		s.Logger.Info(err.Error())
 
 	} else {
-		s.Logger.Info("resp:", resp)
+		s.Logger.Info("resp", "data", resp)
 	}
 }
 
-func (s *Server) Close() {
-	if GetIns() != nil {
-		for topic := range s.topics {
-			GetIns().Close()
-			s.Logger.Info("Unsubscribe topic:%s", topic)
-		}
-		GetIns().GetClient().Disconnect(uint(250))
+// Stats 返回订阅处理的运行指标，可用于上报队列深度和丢弃数量
+func (s *Server) Stats() Stats {
+	stats := Stats{
+		Processed: s.metrics.processed.Load(),
+		Failed:    s.metrics.failed.Load(),
+		Timeouts:  s.metrics.timeouts.Load(),
+		Dropped:   s.metrics.dropped.Load(),
+	}
+	if s.dispatcher != nil {
+		stats.Workers = s.dispatcher.workers
+		stats.QueueDepth = s.dispatcher.queueDepth()
 	}
+	return stats
+}
+
+// Close 停止接收新消息，等待已接收的消息处理完成、响应发布完成后取消订阅并断开连接
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		// 获取写锁后不会再有消息进入工作池，也不会再有新的响应
+		s.closeMu.Lock()
+		s.closing = true
+		s.closeMu.Unlock()
+		if s.dispatcher != nil {
+			s.dispatcher.close()
+		}
+		// 工作池中的消息处理完成后再等待响应，客户端关闭前响应都已发布
+		s.replies.Wait()
+		if s.client() != nil {
+			// Close 会取消所有订阅并断开连接，同时适用于 MQTT 3.1.1 和 MQTT 5
+			err = s.client().Close()
+			for topic := range s.topics {
+				s.Logger.Info("Unsubscribe topic", "topic", topic)
+			}
+		}
+		close(s.done())
+	})
+	return err
 }
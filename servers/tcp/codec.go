@@ -0,0 +1,50 @@
+package tcp
+
+import "fmt"
+
+// Packet 解码后的帧，Command 为指令码，用于路由到处理器
+type Packet struct {
+	Command uint32
+	Body    []byte
+}
+
+// Codec 从帧中解析指令码和内容，并编码待发送的包
+type Codec interface {
+	Decode(frame []byte) (*Packet, error)
+	Encode(p *Packet) ([]byte, error)
+}
+
+// BinaryCodec 指令码位于帧的开头，其余部分为内容
+type BinaryCodec struct {
+	CommandSize  int  // 指令码的字节数：1、2 或 4，默认 1
+	LittleEndian bool // 指令码是否为小端序，默认大端序
+}
+
+func (c *BinaryCodec) size() int {
+	if c.CommandSize == 0 {
+		return 1
+	}
+	return c.CommandSize
+}
+
+func (c *BinaryCodec) Decode(frame []byte) (*Packet, error) {
+	size := c.size()
+	if size != 1 && size != 2 && size != 4 {
+		return nil, ErrInvalidSize
+	}
+	if len(frame) < size {
+		return nil, fmt.Errorf("%w: frame shorter than command", ErrInvalidFrame)
+	}
+	return &Packet{
+		Command: decodeUint(frame[:size], c.LittleEndian),
+		Body:    frame[size:],
+	}, nil
+}
+
+func (c *BinaryCodec) Encode(p *Packet) ([]byte, error) {
+	command, err := encodeUint(p.Command, c.size(), c.LittleEndian)
+	if err != nil {
+		return nil, err
+	}
+	return append(command, p.Body...), nil
+}
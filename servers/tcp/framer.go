@@ -0,0 +1,195 @@
+package tcp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/sagoo-cloud/nexframe/encoding/gbinary"
+)
+
+const DefaultMaxFrameSize = 64 * 1024
+
+var (
+	ErrFrameTooLarge   = errors.New("frame too large")
+	ErrInvalidFrame    = errors.New("invalid frame")
+	ErrInvalidSize     = errors.New("field size must be 1, 2 or 4")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionClosed   = errors.New("session closed")
+)
+
+// Framer 从字节流中切分出完整的帧，并为待发送的数据加上帧格式
+type Framer interface {
+	// Split 与 bufio.SplitFunc 的语义一致，返回的 token 为去掉帧格式后的内容
+	Split(data []byte, atEOF bool) (advance int, token []byte, err error)
+	// Frame 为待发送的内容加上帧格式
+	Frame(payload []byte) ([]byte, error)
+}
+
+// FrameLimiter 由限制了帧长度的 Framer 实现，服务端据此确定读取缓冲区的大小
+type FrameLimiter interface {
+	// FrameLimit 返回一帧在字节流中占用的最大字节数，包含帧格式
+	FrameLimit() int
+}
+
+// LengthFieldFramer 长度前缀的帧：长度字段 + 内容。
+// 长度字段位于帧的开头，Adjustment 用于长度字段的值包含其他部分的协议，内容长度 = 长度字段的值 + Adjustment。
+type LengthFieldFramer struct {
+	LengthSize   int  // 长度字段的字节数：1、2 或 4
+	LittleEndian bool // 长度字段是否为小端序，默认大端序
+	Adjustment   int  // 内容长度的修正值，例如长度包含长度字段本身时为 -LengthSize
+	MaxFrameSize int  // 内容的最大长度，默认 DefaultMaxFrameSize
+}
+
+func (f *LengthFieldFramer) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if f.LengthSize != 1 && f.LengthSize != 2 && f.LengthSize != 4 {
+		return 0, nil, ErrInvalidSize
+	}
+	if len(data) < f.LengthSize {
+		return 0, nil, incomplete(data, atEOF)
+	}
+	length := int(decodeUint(data[:f.LengthSize], f.LittleEndian)) + f.Adjustment
+	if length < 0 {
+		return 0, nil, fmt.Errorf("%w: negative length %d", ErrInvalidFrame, length)
+	}
+	if length > maxFrameSize(f.MaxFrameSize) {
+		return 0, nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, length)
+	}
+	total := f.LengthSize + length
+	if len(data) < total {
+		return 0, nil, incomplete(data, atEOF)
+	}
+	return total, data[f.LengthSize:total], nil
+}
+
+func (f *LengthFieldFramer) FrameLimit() int {
+	return f.LengthSize + maxFrameSize(f.MaxFrameSize)
+}
+
+func (f *LengthFieldFramer) Frame(payload []byte) ([]byte, error) {
+	if len(payload) > maxFrameSize(f.MaxFrameSize) {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(payload))
+	}
+	length := len(payload) - f.Adjustment
+	if length < 0 || (f.LengthSize < 4 && length >= 1<<(8*f.LengthSize)) {
+		return nil, fmt.Errorf("%w: length %d does not fit in %d bytes", ErrInvalidFrame, length, f.LengthSize)
+	}
+	header, err := encodeUint(uint32(length), f.LengthSize, f.LittleEndian)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, payload...), nil
+}
+
+// DelimiterFramer 以分隔符结尾的帧，例如以 \r\n 结尾的文本协议
+type DelimiterFramer struct {
+	Delimiter    []byte
+	MaxFrameSize int // 内容的最大长度，默认 DefaultMaxFrameSize
+}
+
+func (f *DelimiterFramer) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if len(f.Delimiter) == 0 {
+		return 0, nil, fmt.Errorf("%w: empty delimiter", ErrInvalidFrame)
+	}
+	if i := bytes.Index(data, f.Delimiter); i >= 0 {
+		return i + len(f.Delimiter), data[:i], nil
+	}
+	if len(data) > maxFrameSize(f.MaxFrameSize) {
+		return 0, nil, fmt.Errorf("%w: %d bytes without delimiter", ErrFrameTooLarge, len(data))
+	}
+	return 0, nil, incomplete(data, atEOF)
+}
+
+func (f *DelimiterFramer) FrameLimit() int {
+	return maxFrameSize(f.MaxFrameSize) + len(f.Delimiter)
+}
+
+func (f *DelimiterFramer) Frame(payload []byte) ([]byte, error) {
+	if bytes.Contains(payload, f.Delimiter) {
+		return nil, fmt.Errorf("%w: payload contains delimiter", ErrInvalidFrame)
+	}
+	frame := make([]byte, 0, len(payload)+len(f.Delimiter))
+	return append(append(frame, payload...), f.Delimiter...), nil
+}
+
+// FixedLengthFramer 固定长度的帧，发送时不足的部分补 0
+type FixedLengthFramer struct {
+	Size int
+}
+
+func (f *FixedLengthFramer) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if f.Size <= 0 {
+		return 0, nil, fmt.Errorf("%w: size must be positive", ErrInvalidFrame)
+	}
+	if len(data) < f.Size {
+		return 0, nil, incomplete(data, atEOF)
+	}
+	return f.Size, data[:f.Size], nil
+}
+
+func (f *FixedLengthFramer) FrameLimit() int {
+	return f.Size
+}
+
+func (f *FixedLengthFramer) Frame(payload []byte) ([]byte, error) {
+	if len(payload) > f.Size {
+		return nil, fmt.Errorf("%w: %d bytes exceeds frame size %d", ErrFrameTooLarge, len(payload), f.Size)
+	}
+	frame := make([]byte, f.Size)
+	copy(frame, payload)
+	return frame, nil
+}
+
+// incomplete 数据不足一帧时，连接已关闭则返回错误，否则继续读取
+func incomplete(data []byte, atEOF bool) error {
+	if atEOF && len(data) > 0 {
+		return fmt.Errorf("%w: unexpected EOF", ErrInvalidFrame)
+	}
+	return nil
+}
+
+func maxFrameSize(size int) int {
+	if size <= 0 {
+		return DefaultMaxFrameSize
+	}
+	return size
+}
+
+// decodeUint 使用 gbinary 解码 1、2 或 4 字节的无符号整数
+func decodeUint(b []byte, littleEndian bool) uint32 {
+	switch len(b) {
+	case 1:
+		return uint32(b[0])
+	case 2:
+		if littleEndian {
+			return uint32(gbinary.LeDecodeToUint16(b))
+		}
+		return uint32(gbinary.BeDecodeToUint16(b))
+	default:
+		if littleEndian {
+			return gbinary.LeDecodeToUint32(b)
+		}
+		return gbinary.BeDecodeToUint32(b)
+	}
+}
+
+// encodeUint 使用 gbinary 编码 1、2 或 4 字节的无符号整数
+func encodeUint(v uint32, size int, littleEndian bool) ([]byte, error) {
+	switch size {
+	case 1:
+		return []byte{byte(v)}, nil
+	case 2:
+		if littleEndian {
+			return gbinary.LeEncodeUint16(uint16(v)), nil
+		}
+		return gbinary.BeEncodeUint16(uint16(v)), nil
+	case 4:
+		if littleEndian {
+			return gbinary.LeEncodeUint32(v), nil
+		}
+		return gbinary.BeEncodeUint32(v), nil
+	default:
+		return nil, ErrInvalidSize
+	}
+}
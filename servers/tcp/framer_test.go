@@ -0,0 +1,79 @@
+package tcp
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func splitAll(t *testing.T, f Framer, data []byte) [][]byte {
+	t.Helper()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Split(f.Split)
+	var frames [][]byte
+	for scanner.Scan() {
+		frames = append(frames, append([]byte(nil), scanner.Bytes()...))
+	}
+	assert.NoError(t, scanner.Err())
+	return frames
+}
+
+func TestFramers(t *testing.T) {
+	framers := []Framer{
+		&LengthFieldFramer{LengthSize: 1},
+		&LengthFieldFramer{LengthSize: 2, LittleEndian: true},
+		&LengthFieldFramer{LengthSize: 4, Adjustment: -4},
+		&DelimiterFramer{Delimiter: []byte("\r\n")},
+	}
+	payloads := [][]byte{[]byte("hello"), []byte("a"), []byte("world!")}
+	for _, f := range framers {
+		var stream []byte
+		for _, p := range payloads {
+			frame, err := f.Frame(p)
+			assert.NoError(t, err)
+			stream = append(stream, frame...)
+		}
+		assert.Equal(t, payloads, splitAll(t, f, stream))
+	}
+
+	fixed := &FixedLengthFramer{Size: 4}
+	frame, err := fixed.Frame([]byte("ab"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'a', 'b', 0, 0}, frame)
+	assert.Equal(t, [][]byte{[]byte("abcd"), []byte("efgh")}, splitAll(t, fixed, []byte("abcdefgh")))
+	_, err = fixed.Frame([]byte("abcde"))
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+}
+
+func TestFramerErrors(t *testing.T) {
+	f := &LengthFieldFramer{LengthSize: 2, MaxFrameSize: 10}
+	_, _, err := f.Split([]byte{0, 11}, false)
+	assert.ErrorIs(t, err, ErrFrameTooLarge)
+
+	_, _, err = f.Split([]byte{0, 5, 'a'}, true)
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+
+	advance, token, err := f.Split([]byte{0, 5, 'a'}, false)
+	assert.NoError(t, err)
+	assert.Zero(t, advance)
+	assert.Nil(t, token)
+
+	_, err = (&LengthFieldFramer{LengthSize: 1}).Frame(make([]byte, 256))
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+}
+
+func TestBinaryCodec(t *testing.T) {
+	c := &BinaryCodec{CommandSize: 2}
+	data, err := c.Encode(&Packet{Command: 0x0102, Body: []byte("x")})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 'x'}, data)
+
+	p, err := c.Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, &Packet{Command: 0x0102, Body: []byte("x")}, p)
+
+	_, err = c.Decode([]byte{1})
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+}
@@ -0,0 +1,57 @@
+package tcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+// Router 按指令码将包路由到处理器。
+// 处理器收到 *Packet，可通过 SessionFromContext 获取会话；
+// 返回 []byte 时以相同的指令码作为响应发送，返回 *Packet 时原样发送，返回 nil 时不响应。
+type Router struct {
+	handlers map[uint32]*commons.CommHandler
+	Default  *commons.CommHandler // 未注册的指令码使用的处理器，为 nil 时返回 ErrUnknownCommand
+}
+
+func NewRouter() *Router {
+	return &Router{handlers: make(map[uint32]*commons.CommHandler)}
+}
+
+// Register 注册指令码的处理器
+func (r *Router) Register(command uint32, handler *commons.CommHandler) {
+	r.handlers[command] = handler
+}
+
+// Dispatch 处理一个包并发送响应
+func (r *Router) Dispatch(ctx context.Context, s *Session, p *Packet) error {
+	handler, ok := r.handlers[p.Command]
+	if !ok {
+		if r.Default == nil {
+			return fmt.Errorf("%w: 0x%x", ErrUnknownCommand, p.Command)
+		}
+		handler = r.Default
+	}
+
+	resp, err := handler.Handle(WithSession(ctx, s), p)
+	if err != nil {
+		return err
+	}
+
+	switch v := resp.(type) {
+	case nil:
+		return nil
+	case *Packet:
+		if v == nil {
+			return nil
+		}
+		return s.Send(v)
+	case []byte:
+		return s.Send(&Packet{Command: p.Command, Body: v})
+	case string:
+		return s.Send(&Packet{Command: p.Command, Body: []byte(v)})
+	default:
+		return fmt.Errorf("unsupported response type %T for command 0x%x", resp, p.Command)
+	}
+}
@@ -0,0 +1,242 @@
+package tcp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/configs"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+// Server 原始 TCP 设备接入服务。
+// 每个连接按 Framer 切分帧，经 Codec 解析出指令码后路由到处理器，同一连接的帧按顺序处理。
+type Server struct {
+	Address           string
+	Logger            *slog.Logger
+	Framer            Framer                 // 默认 2 字节大端序长度前缀
+	Codec             Codec                  // 默认 1 字节指令码
+	IdleTimeout       time.Duration          // 心跳超时，在该时间内没有收到任何帧时断开连接，为 0 时不限制
+	HeartbeatInterval time.Duration          // 服务端主动发送心跳的间隔，为 0 或 Heartbeat 为 nil 时不发送
+	Heartbeat         *Packet                // 服务端发送的心跳包
+	WriteTimeout      time.Duration          // 写超时
+	MaxFrameSize      int                    // Framer 未实现 FrameLimiter 时单帧的最大字节数，决定读取缓冲区的上限，默认 DefaultMaxFrameSize
+	OnConnect         func(s *Session) error // 连接建立时调用，返回错误时关闭连接
+	OnDisconnect      func(s *Session)       // 连接断开时调用
+
+	router   *Router
+	sessions *Sessions
+	listener net.Listener
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+func NewServer() *Server {
+	config := configs.LoadTcpConfig()
+	return &Server{
+		Address:      config.Address,
+		Logger:       slog.Default(),
+		Framer:       &LengthFieldFramer{LengthSize: 2},
+		Codec:        &BinaryCodec{CommandSize: 1},
+		IdleTimeout:  config.IdleTimeout,
+		WriteTimeout: config.WriteTimeout,
+		router:       NewRouter(),
+		sessions:     NewSessions(),
+	}
+}
+
+// Register 注册指令码的处理器
+func (s *Server) Register(command uint32, handler *commons.CommHandler) {
+	s.router.Register(command, handler)
+}
+
+// SetDefault 设置未注册指令码的处理器
+func (s *Server) SetDefault(handler *commons.CommHandler) {
+	s.router.Default = handler
+}
+
+// Sessions 返回会话管理，可用于绑定设备标识
+func (s *Server) Sessions() *Sessions {
+	return s.sessions
+}
+
+// Push 按会话ID或绑定的设备标识推送
+func (s *Server) Push(id string, p *Packet) error {
+	return s.sessions.Push(id, p)
+}
+
+// Addr 返回实际监听的地址，未启动时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Serve 开始监听并阻塞，直到调用 Close
+func (s *Server) Serve() error {
+	l, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = l.Close()
+		return net.ErrClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+	s.Logger.Info("TCP Server Start", "address", l.Addr().String())
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// tcpTransport TCP 连接的写入和关闭
+type tcpTransport struct {
+	conn         net.Conn
+	writeTimeout time.Duration
+}
+
+func (t *tcpTransport) Write(frame []byte) error {
+	if t.writeTimeout > 0 {
+		_ = t.conn.SetWriteDeadline(time.Now().Add(t.writeTimeout))
+	}
+	_, err := t.conn.Write(frame)
+	return err
+}
+
+func (t *tcpTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *tcpTransport) RemoteAddr() net.Addr {
+	return t.conn.RemoteAddr()
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	session := NewSession(&tcpTransport{conn: conn, writeTimeout: s.WriteTimeout}, s.Codec, s.Framer)
+	if s.OnConnect != nil {
+		if err := s.OnConnect(session); err != nil {
+			s.Logger.Warn("TCP connection rejected", "remote", conn.RemoteAddr().String(), "error", err)
+			_ = session.Close()
+			return
+		}
+	}
+	s.sessions.Add(session)
+	if s.isClosed() {
+		_ = session.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.sessions.Remove(session)
+		_ = session.Close()
+		if s.OnDisconnect != nil {
+			s.OnDisconnect(session)
+		}
+	}()
+	if s.HeartbeatInterval > 0 && s.Heartbeat != nil {
+		go s.heartbeat(ctx, session)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), s.bufferSize())
+	scanner.Split(s.Framer.Split)
+	for {
+		if s.IdleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.IdleTimeout))
+		}
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil && !session.Closed() {
+				s.Logger.Info("TCP connection closed", "session", session.Id(), "error", err)
+			}
+			return
+		}
+		session.Touch()
+
+		p, err := s.Codec.Decode(scanner.Bytes())
+		if err != nil {
+			s.Logger.Warn("Decode frame failed", "session", session.Id(), "error", err)
+			continue
+		}
+		// scanner 会复用缓冲区，处理器可能异步使用内容
+		p.Body = append([]byte(nil), p.Body...)
+		if err = s.router.Dispatch(ctx, session, p); err != nil {
+			s.Logger.Warn("Handle frame failed", "session", session.Id(), "command", p.Command, "error", err)
+		}
+	}
+}
+
+// bufferSize 返回读取缓冲区的上限，Framer 限制了帧长度时以其为准，避免合法的帧因缓冲区不足被拒绝
+func (s *Server) bufferSize() int {
+	if limiter, ok := s.Framer.(FrameLimiter); ok {
+		return limiter.FrameLimit()
+	}
+	return maxFrameSize(s.MaxFrameSize) + 8
+}
+
+func (s *Server) heartbeat(ctx context.Context, session *Session) {
+	ticker := time.NewTicker(s.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := session.Send(s.Heartbeat); err != nil {
+				_ = session.Close()
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Close 停止监听并关闭所有连接
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	s.sessions.Range(func(session *Session) bool {
+		_ = session.Close()
+		return true
+	})
+	s.wg.Wait()
+	return nil
+}
@@ -0,0 +1,148 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/stretchr/testify/assert"
+)
+
+type handlerFunc func(ctx context.Context, request interface{}) (interface{}, error)
+
+func (f handlerFunc) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	return f(ctx, request)
+}
+
+const (
+	cmdLogin uint32 = 0x01
+	cmdEcho  uint32 = 0x02
+	cmdPush  uint32 = 0x10
+)
+
+func startServer(t *testing.T, options ...func(s *Server)) *Server {
+	t.Helper()
+	s := NewServer()
+	s.Address = "127.0.0.1:0"
+	s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.IdleTimeout = 0
+	for _, option := range options {
+		option(s)
+	}
+	s.Register(cmdLogin, &commons.CommHandler{Handler: handlerFunc(func(ctx context.Context, request interface{}) (interface{}, error) {
+		p := request.(*Packet)
+		SessionFromContext(ctx).Set("device", string(p.Body))
+		s.Sessions().Bind(string(p.Body), SessionFromContext(ctx))
+		return []byte("ok"), nil
+	})})
+	s.Register(cmdEcho, &commons.CommHandler{Handler: handlerFunc(func(ctx context.Context, request interface{}) (interface{}, error) {
+		if _, ok := SessionFromContext(ctx).Get("device"); !ok {
+			return nil, errors.New("not logged in")
+		}
+		return request.(*Packet).Body, nil
+	})})
+
+	go func() { _ = s.Serve() }()
+	assert.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, 5*time.Millisecond)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func send(t *testing.T, conn net.Conn, command uint32, body string) {
+	t.Helper()
+	frame, err := (&LengthFieldFramer{LengthSize: 2}).Frame(append([]byte{byte(command)}, body...))
+	assert.NoError(t, err)
+	_, err = conn.Write(frame)
+	assert.NoError(t, err)
+}
+
+func read(t *testing.T, conn net.Conn) *Packet {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	header := make([]byte, 2)
+	_, err := io.ReadFull(conn, header)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	frame := make([]byte, int(header[0])<<8|int(header[1]))
+	_, err = io.ReadFull(conn, frame)
+	assert.NoError(t, err)
+	return &Packet{Command: uint32(frame[0]), Body: frame[1:]}
+}
+
+func TestServer(t *testing.T) {
+	s := startServer(t)
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	send(t, conn, cmdLogin, "d1")
+	assert.Equal(t, &Packet{Command: cmdLogin, Body: []byte("ok")}, read(t, conn))
+
+	send(t, conn, cmdEcho, "ping")
+	assert.Equal(t, &Packet{Command: cmdEcho, Body: []byte("ping")}, read(t, conn))
+
+	// 按绑定的设备标识推送
+	assert.NoError(t, s.Push("d1", &Packet{Command: cmdPush, Body: []byte("reboot")}))
+	assert.Equal(t, &Packet{Command: cmdPush, Body: []byte("reboot")}, read(t, conn))
+	assert.ErrorIs(t, s.Push("d2", &Packet{Command: cmdPush}), ErrSessionNotFound)
+
+	// 未注册的指令码不影响后续的帧
+	send(t, conn, 0x7f, "")
+	send(t, conn, cmdEcho, "again")
+	assert.Equal(t, []byte("again"), read(t, conn).Body)
+}
+
+func TestServerHeartbeat(t *testing.T) {
+	s := NewServer()
+	s.Address = "127.0.0.1:0"
+	s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.IdleTimeout = 300 * time.Millisecond
+	s.HeartbeatInterval = 50 * time.Millisecond
+	s.Heartbeat = &Packet{Command: 0xff}
+	disconnected := make(chan *Session, 1)
+	s.OnDisconnect = func(session *Session) { disconnected <- session }
+	go func() { _ = s.Serve() }()
+	assert.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, 5*time.Millisecond)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	assert.Equal(t, uint32(0xff), read(t, conn).Command)
+
+	// 客户端不发送任何帧，超时后服务端断开连接
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection not closed")
+	}
+	assert.Equal(t, 0, s.Sessions().Len())
+}
+
+func TestBufferFollowsFramerLimit(t *testing.T) {
+	// 服务端的限制小于 Framer 的限制时，Framer 允许的帧仍能完整读取
+	s := startServer(t, func(s *Server) { s.MaxFrameSize = 16 })
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	send(t, conn, cmdLogin, "dev-1")
+	read(t, conn)
+	body := string(make([]byte, 1024))
+	send(t, conn, cmdEcho, body)
+	if p := read(t, conn); assert.NotNil(t, p) {
+		assert.Equal(t, body, string(p.Body))
+	}
+}
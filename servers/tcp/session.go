@@ -0,0 +1,213 @@
+package tcp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/utils/guid"
+)
+
+// Transport 会话底层的连接，TCP 为一个连接，UDP 为一个远端地址
+type Transport interface {
+	Write(frame []byte) error
+	Close() error
+	RemoteAddr() net.Addr
+}
+
+// Session 一个设备连接的会话
+type Session struct {
+	id         string
+	transport  Transport
+	codec      Codec
+	framer     Framer // 为 nil 时不加帧格式，例如 UDP 每个数据报即为一帧
+	values     sync.Map
+	lastActive atomic.Int64
+	closed     atomic.Bool
+	writeMu    sync.Mutex
+}
+
+// NewSession 创建会话，framer 为 nil 时编码后的数据直接写入
+func NewSession(transport Transport, codec Codec, framer Framer) *Session {
+	s := &Session{
+		id:        guid.S(),
+		transport: transport,
+		codec:     codec,
+		framer:    framer,
+	}
+	s.Touch()
+	return s
+}
+
+// Id 会话ID
+func (s *Session) Id() string {
+	return s.id
+}
+
+// RemoteAddr 远端地址
+func (s *Session) RemoteAddr() net.Addr {
+	return s.transport.RemoteAddr()
+}
+
+// Set 保存会话数据，例如认证后的设备标识
+func (s *Session) Set(key string, value interface{}) {
+	s.values.Store(key, value)
+}
+
+// Get 获取会话数据
+func (s *Session) Get(key string) (interface{}, bool) {
+	return s.values.Load(key)
+}
+
+// Touch 更新最后活跃时间，收到任何帧时调用
+func (s *Session) Touch() {
+	s.lastActive.Store(time.Now().UnixNano())
+}
+
+// LastActive 最后活跃时间
+func (s *Session) LastActive() time.Time {
+	return time.Unix(0, s.lastActive.Load())
+}
+
+// Send 编码并发送一个包，可以在处理器之外调用以主动推送
+func (s *Session) Send(p *Packet) error {
+	if s.closed.Load() {
+		return ErrSessionClosed
+	}
+	data, err := s.codec.Encode(p)
+	if err != nil {
+		return err
+	}
+	if s.framer != nil {
+		if data, err = s.framer.Frame(data); err != nil {
+			return err
+		}
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.transport.Write(data)
+}
+
+// Close 关闭会话
+func (s *Session) Close() error {
+	if !s.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	return s.transport.Close()
+}
+
+// Closed 会话是否已关闭
+func (s *Session) Closed() bool {
+	return s.closed.Load()
+}
+
+type sessionKey struct{}
+
+// WithSession 将会话写入上下文
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext 在处理器中获取当前帧所属的会话
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// Sessions 会话管理，支持按会话ID或绑定的设备标识查找
+type Sessions struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+	bindings map[string]*Session // 设备标识 -> 会话
+	keys     map[string]string   // 会话ID -> 设备标识
+}
+
+func NewSessions() *Sessions {
+	return &Sessions{
+		sessions: make(map[string]*Session),
+		bindings: make(map[string]*Session),
+		keys:     make(map[string]string),
+	}
+}
+
+func (m *Sessions) Add(s *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.id] = s
+}
+
+// Remove 移除会话及其绑定
+func (m *Sessions) Remove(s *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, s.id)
+	if key, ok := m.keys[s.id]; ok {
+		if m.bindings[key] == s {
+			delete(m.bindings, key)
+		}
+		delete(m.keys, s.id)
+	}
+}
+
+// Bind 将设备标识绑定到会话，之后可以按设备标识推送。
+// 同一设备重新连接时绑定到新的会话，返回被替换的旧会话。
+func (m *Sessions) Bind(key string, s *Session) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.keys[s.id]; ok && m.bindings[old] == s {
+		delete(m.bindings, old)
+	}
+	previous := m.bindings[key]
+	if previous == s {
+		previous = nil
+	}
+	if previous != nil {
+		delete(m.keys, previous.id)
+	}
+	m.bindings[key] = s
+	m.keys[s.id] = key
+	return previous
+}
+
+// Get 按会话ID或设备标识查找会话
+func (m *Sessions) Get(id string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.sessions[id]; ok {
+		return s, true
+	}
+	s, ok := m.bindings[id]
+	return s, ok
+}
+
+func (m *Sessions) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.sessions)
+}
+
+// Range 遍历所有会话，f 返回 false 时停止
+func (m *Sessions) Range(f func(s *Session) bool) {
+	m.mu.RLock()
+	list := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, s)
+	}
+	m.mu.RUnlock()
+	for _, s := range list {
+		if !f(s) {
+			return
+		}
+	}
+}
+
+// Push 按会话ID或设备标识推送
+func (m *Sessions) Push(id string, p *Packet) error {
+	s, ok := m.Get(id)
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return s.Send(p)
+}
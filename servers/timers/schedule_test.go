@@ -0,0 +1,187 @@
+package timers
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/os/nx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewServer(log.New(io.Discard, "", 0))
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestParseSchedule(t *testing.T) {
+	schedule, err := ParseSchedule("*/10 * * * * *")
+	assert.NoError(t, err)
+	from := time.Date(2024, 1, 1, 8, 0, 3, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 1, 8, 0, 10, 0, time.UTC), schedule.Next(from))
+
+	// 时区前缀
+	schedule, err = ParseSchedule("CRON_TZ=Asia/Shanghai 0 30 8 * * *")
+	assert.NoError(t, err)
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	assert.Equal(t, time.Date(2024, 1, 2, 8, 30, 0, 0, shanghai).Unix(), schedule.Next(from).Unix())
+
+	_, err = ParseSchedule("not a cron")
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	s := newTestServer(t)
+	assert.ErrorIs(t, s.RegisterCron("bad", "* *", testHandler, nil), ErrInvalidSchedule)
+}
+
+func TestWithLocation(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	schedule, _ := ParseSchedule("0 0 9 * * *")
+	srv := &service{schedule: schedule, opts: options{location: shanghai}}
+	next := srv.nextTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 1, 1, 9, 0, 0, 0, shanghai).Unix(), next.Unix())
+
+	srv.opts.jitter = time.Minute
+	for i := 0; i < 20; i++ {
+		next = srv.nextTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		base := time.Date(2024, 1, 1, 9, 0, 0, 0, shanghai)
+		assert.False(t, next.Before(base))
+		assert.True(t, next.Before(base.Add(time.Minute)))
+	}
+}
+
+// slowHandler 每次执行耗时 d，记录开始次数和被取消的次数
+func slowHandler(d time.Duration, started, cancelled *atomic.Int32) HandlerFunc {
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		started.Add(1)
+		select {
+		case <-time.After(d):
+			return nil, nil
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	s := newTestServer(t)
+	var skipStarted, queueStarted, cancelStarted, cancelled, none atomic.Int32
+
+	opts := []Option{WithTimeout(0), WithPaused()}
+	assert.NoError(t, s.Register("skip", time.Hour, slowHandler(200*time.Millisecond, &skipStarted, &none), nil, append(opts, WithOverlap(OverlapSkip))...))
+	assert.NoError(t, s.Register("queue", time.Hour, slowHandler(50*time.Millisecond, &queueStarted, &none), nil, append(opts, WithOverlap(OverlapQueue))...))
+	assert.NoError(t, s.Register("cancel", time.Hour, slowHandler(time.Second, &cancelStarted, &cancelled), nil, append(opts, WithOverlap(OverlapCancel))...))
+	assert.NoError(t, s.Run())
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.Trigger("skip"))
+		assert.NoError(t, s.Trigger("queue"))
+	}
+	assert.NoError(t, s.Trigger("cancel"))
+	assert.Eventually(t, func() bool { return cancelStarted.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, s.Trigger("cancel"))
+
+	assert.Eventually(t, func() bool { return queueStarted.Load() == 3 }, 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return cancelStarted.Load() == 2 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), cancelled.Load())
+	assert.Equal(t, int32(1), skipStarted.Load())
+
+	status, err := s.Job("cancel")
+	assert.NoError(t, err)
+	assert.Equal(t, "cancel", status.Overlap)
+	assert.True(t, status.Paused)
+	assert.Equal(t, "@every 1h0m0s", status.Schedule)
+}
+
+func TestDynamicJobs(t *testing.T) {
+	s := newTestServer(t)
+	assert.NoError(t, s.Run())
+
+	var runs atomic.Int32
+	handler := func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		runs.Add(1)
+		return nil, nil
+	}
+	// 运行中注册，并在启动时立即执行
+	assert.NoError(t, s.Register("dynamic", 30*time.Millisecond, handler, nil, WithRunOnStart()))
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, 2*time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, s.Pause("dynamic"))
+	time.Sleep(50 * time.Millisecond) // 等待正在执行的一次结束
+	paused := runs.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, paused, runs.Load())
+	status, _ := s.Job("dynamic")
+	assert.True(t, status.Paused)
+	assert.True(t, status.Next.IsZero())
+
+	assert.NoError(t, s.Resume("dynamic"))
+	assert.Eventually(t, func() bool { return runs.Load() > paused }, time.Second, 5*time.Millisecond)
+
+	assert.NoError(t, s.Unregister("dynamic"))
+	assert.ErrorIs(t, s.Unregister("dynamic"), ErrJobNotFound)
+	assert.ErrorIs(t, s.Pause("dynamic"), ErrJobNotFound)
+	time.Sleep(50 * time.Millisecond)
+	stopped := runs.Load()
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+	assert.Empty(t, s.Jobs())
+}
+
+func TestCronSeconds(t *testing.T) {
+	s := newTestServer(t)
+	var runs atomic.Int32
+	assert.NoError(t, s.RegisterCron("every-second", "* * * * * *", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		runs.Add(1)
+		return nil, nil
+	}, nil))
+	assert.NoError(t, s.Run())
+	assert.Eventually(t, func() bool { return runs.Load() >= 1 }, 2500*time.Millisecond, 10*time.Millisecond)
+
+	jobs := s.Jobs()
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "* * * * * *", jobs[0].Schedule)
+	assert.False(t, jobs[0].Next.IsZero())
+}
+
+func TestSingleton(t *testing.T) {
+	locker := nx.NewMemoryLocker()
+	var runs [2]atomic.Int32
+	var tokens [2]atomic.Int64
+	servers := make([]*Server, 2)
+	for i := range servers {
+		i := i
+		s := newTestServer(t)
+		elector, err := nx.NewElector(locker, "timers", nx.WithLeaseTTL(200*time.Millisecond), nx.WithRenewInterval(20*time.Millisecond))
+		assert.NoError(t, err)
+		s.SetElector(elector)
+		assert.NoError(t, s.Register("singleton", 20*time.Millisecond, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			token, _ := nx.TokenFromContext(ctx)
+			tokens[i].Store(token)
+			runs[i].Add(1)
+			return nil, nil
+		}, nil, WithSingleton()))
+		assert.NoError(t, s.Run())
+		servers[i] = s
+	}
+
+	assert.Eventually(t, func() bool { return runs[0].Load()+runs[1].Load() >= 5 }, 2*time.Second, 10*time.Millisecond)
+	leader, follower := 0, 1
+	if runs[1].Load() > 0 {
+		leader, follower = 1, 0
+	}
+	assert.Zero(t, runs[follower].Load())
+	assert.Greater(t, tokens[leader].Load(), int64(0))
+	status, _ := servers[leader].Job("singleton")
+	assert.True(t, status.Singleton)
+
+	// 领导者停止后由另一个节点接任
+	assert.NoError(t, servers[leader].Close())
+	assert.Eventually(t, func() bool { return runs[follower].Load() > 0 }, 2*time.Second, 10*time.Millisecond)
+	assert.Greater(t, tokens[follower].Load(), tokens[leader].Load())
+}
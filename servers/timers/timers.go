@@ -5,10 +5,132 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sagoo-cloud/nexframe/os/nx"
+)
+
+var (
+	ErrJobNotFound     = errors.New("timer job not found")
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// HandlerFunc 定时服务的处理函数
+type HandlerFunc func(context.Context, map[string]interface{}) (interface{}, error)
+
+// OverlapPolicy 上一次执行尚未结束时，新的触发的处理方式
+type OverlapPolicy int
+
+const (
+	OverlapSkip   OverlapPolicy = iota // 跳过本次触发，默认
+	OverlapQueue                       // 排队，上一次结束后依次执行
+	OverlapCancel                      // 取消正在执行的上一次，随后执行本次
+	OverlapAllow                       // 允许并发执行
 )
 
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	case OverlapCancel:
+		return "cancel"
+	case OverlapAllow:
+		return "allow"
+	default:
+		return fmt.Sprintf("OverlapPolicy(%d)", int(p))
+	}
+}
+
+// cronParser 支持可选的秒字段、CRON_TZ=/TZ= 时区前缀和 @every、@daily 等描述符
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseSchedule 解析 cron 表达式，例如 "*/10 * * * * *"、"CRON_TZ=Asia/Shanghai 0 30 8 * * *"、"@every 5m"
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return schedule, nil
+}
+
+// everySchedule 固定间隔，cron.Every 会将间隔取整到秒，这里保留原始精度
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// options 定时服务的选项
+type options struct {
+	jitter     time.Duration
+	overlap    OverlapPolicy
+	runOnStart bool
+	timeout    time.Duration
+	location   *time.Location
+	paused     bool
+	singleton  bool
+}
+
+// Option 配置定时服务
+type Option func(*options)
+
+// WithJitter 每次触发前随机延迟 [0, jitter)，避免多个实例同时执行
+func WithJitter(jitter time.Duration) Option {
+	return func(o *options) {
+		o.jitter = jitter
+	}
+}
+
+// WithOverlap 设置上一次执行尚未结束时的处理方式，默认 OverlapSkip
+func WithOverlap(policy OverlapPolicy) Option {
+	return func(o *options) {
+		o.overlap = policy
+	}
+}
+
+// WithRunOnStart 服务启动（或运行中注册）时立即执行一次
+func WithRunOnStart() Option {
+	return func(o *options) {
+		o.runOnStart = true
+	}
+}
+
+// WithTimeout 单次执行的超时时间，为 0 时不限制。固定间隔的服务默认以间隔作为超时
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithLocation 设置 cron 表达式使用的时区，表达式中的 CRON_TZ= 前缀优先
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// WithPaused 注册后处于暂停状态，调用 Resume 后开始触发
+func WithPaused() Option {
+	return func(o *options) {
+		o.paused = true
+	}
+}
+
+// WithSingleton 多实例部署时只在领导者节点执行，需要通过 Server.SetElector 设置选举，
+// 未设置时与普通服务相同。失去领导权时取消正在执行的任务，
+// 处理函数可通过 nx.TokenFromContext 获取 fencing token
+func WithSingleton() Option {
+	return func(o *options) {
+		o.singleton = true
+	}
+}
+
 // Server 代表定时器服务器
 type Server struct {
 	handlers map[string]*service // 存储所有注册的服务
@@ -17,17 +139,50 @@ type Server struct {
 	wg       sync.WaitGroup      // 用于等待所有 goroutine 完成
 	ctx      context.Context     // 用于控制所有服务的生命周期
 	cancel   context.CancelFunc  // 用于取消 ctx
+	started  bool                // Run 之后注册的服务立即启动
+	elector  *nx.Elector         // 单例服务使用的领导者选举
+	hooks    []func(RunInfo)     // 每次执行结束后的回调
+}
+
+// RunInfo 一次执行的结果
+type RunInfo struct {
+	Job      string
+	Start    time.Time
+	Duration time.Duration
+	Err      error
 }
 
 // service 代表单个定时服务
 type service struct {
-	freq    time.Duration                                                      // 服务执行频率
-	handler func(context.Context, map[string]interface{}) (interface{}, error) // 服务处理函数
-	params  map[string]interface{}                                             // 服务参数
+	name     string
+	spec     string        // cron 表达式，固定间隔时为空
+	freq     time.Duration // 服务执行频率
+	schedule cron.Schedule
+	handler  HandlerFunc            // 服务处理函数
+	params   map[string]interface{} // 服务参数
+	opts     options
+
+	ctx    context.Context // Unregister 时取消
+	cancel context.CancelFunc
+	resume chan struct{} // Resume 时唤醒调度
+
+	mu        sync.Mutex
+	paused    bool
+	running   int
+	pending   int                // 排队等待执行的次数
+	runCancel context.CancelFunc // 取消正在执行的一次
+	next      time.Time
+	lastRun   time.Time
+	lastCost  time.Duration
+	lastErr   error
+	runs      int64
 }
 
 // NewServer 创建一个新的 Server 实例
 func NewServer(logger *log.Logger) *Server {
+	if logger == nil {
+		logger = log.Default()
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
 		handlers: make(map[string]*service),
@@ -37,69 +192,404 @@ func NewServer(logger *log.Logger) *Server {
 	}
 }
 
-// Register 注册一个新的定时服务
-func (s *Server) Register(name string, freq time.Duration, handler func(context.Context, map[string]interface{}) (interface{}, error), params map[string]interface{}) error {
-	if name == "" || freq <= 0 || handler == nil {
+// Register 注册一个按固定间隔执行的定时服务，服务运行中也可以注册
+func (s *Server) Register(name string, freq time.Duration, handler func(context.Context, map[string]interface{}) (interface{}, error), params map[string]interface{}, opts ...Option) error {
+	if freq <= 0 {
+		return errors.New("无效的参数")
+	}
+	// 固定间隔默认以间隔作为单次执行的超时，与之前的行为一致
+	opts = append([]Option{WithTimeout(freq)}, opts...)
+	return s.add(&service{name: name, freq: freq, schedule: everySchedule(freq), handler: handler, params: params}, opts)
+}
+
+// RegisterCron 注册一个按 cron 表达式执行的定时服务，表达式支持秒字段和时区，见 ParseSchedule
+func (s *Server) RegisterCron(name, spec string, handler func(context.Context, map[string]interface{}) (interface{}, error), params map[string]interface{}, opts ...Option) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	return s.add(&service{name: name, spec: spec, schedule: schedule, handler: handler, params: params}, opts)
+}
+
+func (s *Server) add(srv *service, opts []Option) error {
+	if srv.name == "" || srv.handler == nil {
 		return errors.New("无效的参数")
 	}
+	for _, opt := range opts {
+		opt(&srv.opts)
+	}
+	srv.paused = srv.opts.paused
+	srv.resume = make(chan struct{}, 1)
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, exists := s.handlers[srv.name]; exists {
+		return fmt.Errorf("服务 %s 已经注册", srv.name)
+	}
+	srv.ctx, srv.cancel = context.WithCancel(s.ctx)
+	s.handlers[srv.name] = srv
+	if s.started {
+		s.start(srv)
+	}
+	return nil
+}
 
-	if _, exists := s.handlers[name]; exists {
-		return fmt.Errorf("服务 %s 已经注册", name)
+// Unregister 移除定时服务，并取消正在执行的任务
+func (s *Server) Unregister(name string) error {
+	s.mu.Lock()
+	srv, ok := s.handlers[name]
+	delete(s.handlers, name)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
 	}
+	srv.cancel()
+	return nil
+}
 
-	s.handlers[name] = &service{
-		freq:    freq,
-		handler: handler,
-		params:  params,
+// Pause 暂停定时服务，正在执行的任务不受影响
+func (s *Server) Pause(name string) error {
+	srv, err := s.get(name)
+	if err != nil {
+		return err
 	}
+	srv.mu.Lock()
+	srv.paused = true
+	srv.mu.Unlock()
 	return nil
 }
 
-// Run 启动所有注册的服务
-func (s *Server) Run() error {
+// Resume 恢复暂停的定时服务
+func (s *Server) Resume(name string) error {
+	srv, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	srv.mu.Lock()
+	srv.paused = false
+	srv.mu.Unlock()
+	select {
+	case srv.resume <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Trigger 立即执行一次，遵循服务的重叠策略，暂停的服务也可以手动执行
+func (s *Server) Trigger(name string) error {
+	srv, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	s.trigger(srv)
+	return nil
+}
+
+func (s *Server) get(name string) (*service, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	srv, ok := s.handlers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return srv, nil
+}
+
+// SetElector 设置单例服务使用的领导者选举，应在 Run 之前调用。
+// Run 时启动选举，Close 时停止选举并释放租约
+func (s *Server) SetElector(elector *nx.Elector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elector = elector
+}
 
-	for name, srv := range s.handlers {
-		s.wg.Add(1)
-		go s.runService(name, srv)
+// OnRun 注册每次执行结束后的回调，用于记录执行历史和指标。
+// 回调在执行任务的 goroutine 中同步调用，不应长时间阻塞
+func (s *Server) OnRun(hook func(RunInfo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Run 启动所有注册的服务
+func (s *Server) Run() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	s.started = true
+	if s.elector != nil {
+		s.elector.Start()
+	}
+
+	for _, srv := range s.handlers {
+		s.start(srv)
 	}
 
 	return nil
 }
 
-// runService 运行单个服务
-func (s *Server) runService(name string, srv *service) {
+// Serve 启动所有注册的服务并阻塞，直到调用 Close
+func (s *Server) Serve() error {
+	if err := s.Run(); err != nil {
+		return err
+	}
+	<-s.ctx.Done()
+	return nil
+}
+
+func (s *Server) start(srv *service) {
+	s.wg.Add(1)
+	go s.runService(srv)
+}
+
+// nextTime 计算下一次触发时间，加上随机延迟
+func (srv *service) nextTime(now time.Time) time.Time {
+	if srv.opts.location != nil {
+		now = now.In(srv.opts.location)
+	}
+	next := srv.schedule.Next(now)
+	if srv.opts.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(srv.opts.jitter))))
+	}
+	return next
+}
+
+// runService 运行单个服务的调度
+func (s *Server) runService(srv *service) {
 	defer s.wg.Done()
-	ticker := time.NewTicker(srv.freq)
-	defer ticker.Stop()
+	if srv.opts.runOnStart {
+		s.trigger(srv)
+	}
 
 	for {
+		srv.mu.Lock()
+		paused := srv.paused
+		if paused {
+			srv.next = time.Time{}
+		} else {
+			srv.next = srv.nextTime(time.Now())
+		}
+		next := srv.next
+		srv.mu.Unlock()
+
+		if paused {
+			select {
+			case <-srv.ctx.Done():
+				return
+			case <-srv.resume:
+				continue
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
 		select {
-		case <-s.ctx.Done():
+		case <-srv.ctx.Done():
+			timer.Stop()
 			return
-		case <-ticker.C:
-			func() {
-				ctx, cancel := context.WithTimeout(s.ctx, srv.freq)
-				defer cancel()
-
-				resp, err := srv.handler(ctx, srv.params)
-				if err != nil {
-					s.logger.Printf("服务 %s 发生错误: %v", name, err)
-				} else {
-					s.logger.Printf("服务 %s 完成: %v", name, resp)
-				}
-			}()
+		case <-srv.resume:
+			timer.Stop()
+			continue
+		case <-timer.C:
+		}
+
+		srv.mu.Lock()
+		paused = srv.paused
+		srv.mu.Unlock()
+		if !paused {
+			s.trigger(srv)
+		}
+	}
+}
+
+// trigger 按重叠策略开始一次执行
+func (s *Server) trigger(srv *service) {
+	srv.mu.Lock()
+	if srv.ctx.Err() != nil {
+		srv.mu.Unlock()
+		return
+	}
+	if srv.running > 0 {
+		switch srv.opts.overlap {
+		case OverlapSkip:
+			srv.mu.Unlock()
+			s.logger.Printf("服务 %s 上一次执行尚未结束，跳过本次", srv.name)
+			return
+		case OverlapQueue:
+			srv.pending++
+			srv.mu.Unlock()
+			return
+		case OverlapCancel:
+			srv.pending = 1
+			if srv.runCancel != nil {
+				srv.runCancel()
+			}
+			srv.mu.Unlock()
+			return
+		}
+	}
+	srv.running++
+	srv.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.execute(srv)
+}
+
+// execute 执行一次，结束后继续执行排队的触发
+func (s *Server) execute(srv *service) {
+	defer s.wg.Done()
+	for {
+		s.runOnce(srv)
+
+		srv.mu.Lock()
+		if srv.pending > 0 && srv.ctx.Err() == nil {
+			srv.pending--
+			srv.mu.Unlock()
+			continue
 		}
+		srv.pending = 0
+		srv.running--
+		srv.mu.Unlock()
+		return
 	}
 }
 
+// leadership 单例服务返回领导权的上下文，不是领导者时 ok 为 false
+func (s *Server) leadership(srv *service) (leaderCtx context.Context, token int64, ok bool) {
+	s.mu.RLock()
+	elector := s.elector
+	s.mu.RUnlock()
+	if !srv.opts.singleton || elector == nil {
+		return nil, 0, true
+	}
+	return elector.Leadership()
+}
+
+func (s *Server) runOnce(srv *service) {
+	leaderCtx, token, ok := s.leadership(srv)
+	if !ok {
+		return
+	}
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if srv.opts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(srv.ctx, srv.opts.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(srv.ctx)
+	}
+	defer cancel()
+	if leaderCtx != nil {
+		ctx = nx.ContextWithToken(ctx, token)
+		stop := context.AfterFunc(leaderCtx, cancel)
+		defer stop()
+	}
+	srv.mu.Lock()
+	srv.runCancel = cancel
+	srv.mu.Unlock()
+
+	start := time.Now()
+	resp, err := srv.handler(ctx, srv.params)
+	cost := time.Since(start)
+
+	srv.mu.Lock()
+	srv.runCancel = nil
+	srv.lastRun, srv.lastCost, srv.lastErr = start, cost, err
+	srv.runs++
+	srv.mu.Unlock()
+
+	if err != nil {
+		s.logger.Printf("服务 %s 发生错误: %v", srv.name, err)
+	} else {
+		s.logger.Printf("服务 %s 完成: %v", srv.name, resp)
+	}
+
+	s.mu.RLock()
+	hooks := s.hooks
+	s.mu.RUnlock()
+	for _, hook := range hooks {
+		hook(RunInfo{Job: srv.name, Start: start, Duration: cost, Err: err})
+	}
+}
+
+// JobStatus 定时服务的状态
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"` // cron 表达式或 @every 间隔
+	Overlap      string        `json:"overlap"`
+	Paused       bool          `json:"paused"`
+	Singleton    bool          `json:"singleton"` // 只在领导者节点执行
+	Running      int           `json:"running"`   // 正在执行的数量
+	Pending      int           `json:"pending"`   // 排队等待执行的次数
+	Next         time.Time     `json:"next"`      // 下一次触发时间，暂停时为零值
+	LastRun      time.Time     `json:"lastRun"`
+	LastDuration time.Duration `json:"lastDuration"`
+	LastError    string        `json:"lastError"`
+	Runs         int64         `json:"runs"`
+}
+
+// Jobs 返回所有定时服务的状态，按名称排序
+func (s *Server) Jobs() []JobStatus {
+	s.mu.RLock()
+	list := make([]*service, 0, len(s.handlers))
+	for _, srv := range s.handlers {
+		list = append(list, srv)
+	}
+	s.mu.RUnlock()
+
+	jobs := make([]JobStatus, 0, len(list))
+	for _, srv := range list {
+		jobs = append(jobs, srv.status())
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Job 返回单个定时服务的状态
+func (s *Server) Job(name string) (JobStatus, error) {
+	srv, err := s.get(name)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return srv.status(), nil
+}
+
+func (srv *service) status() JobStatus {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	status := JobStatus{
+		Name:         srv.name,
+		Schedule:     srv.spec,
+		Overlap:      srv.opts.overlap.String(),
+		Paused:       srv.paused,
+		Singleton:    srv.opts.singleton,
+		Running:      srv.running,
+		Pending:      srv.pending,
+		Next:         srv.next,
+		LastRun:      srv.lastRun,
+		LastDuration: srv.lastCost,
+		Runs:         srv.runs,
+	}
+	if status.Schedule == "" {
+		status.Schedule = "@every " + srv.freq.String()
+	}
+	if srv.lastErr != nil {
+		status.LastError = srv.lastErr.Error()
+	}
+	return status
+}
+
 // Close 停止所有服务并等待它们完成
 func (s *Server) Close() error {
 	s.cancel()
 	s.wg.Wait()
+	s.mu.RLock()
+	elector := s.elector
+	s.mu.RUnlock()
+	if elector != nil {
+		return elector.Close()
+	}
 	return nil
 }
@@ -0,0 +1,285 @@
+package udp
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/configs"
+	"github.com/sagoo-cloud/nexframe/os/gpool"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/servers/tcp"
+)
+
+const maxDatagramSize = 64 * 1024
+
+// Server 原始 UDP 设备接入服务。
+// 每个数据报为一帧，经 Codec 解析出指令码后路由到处理器；
+// 按远端地址维护会话，超过 IdleTimeout 没有收到数据报的会话被移除。
+// 数据报由有界工作池处理，同一远端地址的数据报按到达顺序处理，处理缓慢的设备不会阻塞其他设备。
+type Server struct {
+	Address      string
+	Logger       *slog.Logger
+	Codec        tcp.Codec                  // 默认 1 字节指令码
+	IdleTimeout  time.Duration              // 会话超时，为 0 时会话一直保留到服务关闭
+	Workers      int                        // 工作协程数，默认为 CPU 核数
+	QueueLength  int                        // 每个工作协程的队列长度，队列已满时丢弃数据报
+	OnConnect    func(s *tcp.Session) error // 收到新地址的第一个数据报时调用，返回错误时丢弃该数据报
+	OnDisconnect func(s *tcp.Session)       // 会话超时或服务关闭时调用
+
+	router   *tcp.Router
+	sessions *tcp.Sessions
+	mu       sync.Mutex
+	conn     net.PacketConn
+	lanes    []*gpool.GPool // 按远端地址分配的单协程工作池
+	byAddr   map[string]*tcp.Session
+	closed   chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+func NewServer() *Server {
+	config := configs.LoadUdpConfig()
+	return &Server{
+		Address:     config.Address,
+		Logger:      slog.Default(),
+		Codec:       &tcp.BinaryCodec{CommandSize: 1},
+		IdleTimeout: config.IdleTimeout,
+		Workers:     config.Workers,
+		QueueLength: config.QueueLength,
+		router:      tcp.NewRouter(),
+		sessions:    tcp.NewSessions(),
+		byAddr:      make(map[string]*tcp.Session),
+		closed:      make(chan struct{}),
+	}
+}
+
+// Register 注册指令码的处理器
+func (s *Server) Register(command uint32, handler *commons.CommHandler) {
+	s.router.Register(command, handler)
+}
+
+// SetDefault 设置未注册指令码的处理器
+func (s *Server) SetDefault(handler *commons.CommHandler) {
+	s.router.Default = handler
+}
+
+// Sessions 返回会话管理，可用于绑定设备标识
+func (s *Server) Sessions() *tcp.Sessions {
+	return s.sessions
+}
+
+// Push 按会话ID或绑定的设备标识推送
+func (s *Server) Push(id string, p *tcp.Packet) error {
+	return s.sessions.Push(id, p)
+}
+
+// Addr 返回实际监听的地址，未启动时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// udpTransport 向远端地址发送数据报，关闭时只移除会话，不关闭共享的监听
+type udpTransport struct {
+	server *Server
+	conn   net.PacketConn
+	addr   net.Addr
+}
+
+func (t *udpTransport) Write(frame []byte) error {
+	_, err := t.conn.WriteTo(frame, t.addr)
+	return err
+}
+
+func (t *udpTransport) Close() error {
+	t.server.removeAddr(t.addr.String())
+	return nil
+}
+
+func (t *udpTransport) RemoteAddr() net.Addr {
+	return t.addr
+}
+
+// Serve 开始监听并阻塞，直到调用 Close
+func (s *Server) Serve() error {
+	conn, err := net.ListenPacket("udp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+		s.mu.Unlock()
+		_ = conn.Close()
+		return nil
+	default:
+	}
+	s.conn = conn
+	s.lanes = s.newLanes()
+	// 读取循环结束前 Close 不会关闭工作池
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+	s.Logger.Info("UDP Server Start", "address", conn.LocalAddr().String())
+
+	if s.IdleTimeout > 0 {
+		s.wg.Add(1)
+		go s.expire()
+	}
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.closed:
+				return nil
+			default:
+				return err
+			}
+		}
+		session := s.session(conn, addr)
+		if session == nil {
+			continue
+		}
+		session.Touch()
+
+		p, err := s.Codec.Decode(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			s.Logger.Warn("Decode datagram failed", "remote", addr.String(), "error", err)
+			continue
+		}
+		s.dispatch(addr.String(), session, p)
+	}
+}
+
+// newLanes 创建工作池，每个远端地址固定由其中一个单协程的工作池处理
+func (s *Server) newLanes() []*gpool.GPool {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	queueLength := s.QueueLength
+	if queueLength <= 0 {
+		queueLength = 1
+	}
+	lanes := make([]*gpool.GPool, workers)
+	for i := range lanes {
+		lanes[i] = gpool.NewGPoolWithQueue(1, queueLength)
+	}
+	return lanes
+}
+
+// lane 返回远端地址对应的工作池下标
+func (s *Server) lane(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(s.lanes)))
+}
+
+// dispatch 将数据报交给远端地址对应的工作池，队列已满时丢弃
+func (s *Server) dispatch(key string, session *tcp.Session, p *tcp.Packet) {
+	accepted := s.lanes[s.lane(key)].TryAddJob(func(ctx context.Context) error {
+		if err := s.router.Dispatch(context.Background(), session, p); err != nil {
+			s.Logger.Warn("Handle datagram failed", "session", session.Id(), "command", p.Command, "error", err)
+		}
+		return nil
+	})
+	if !accepted {
+		s.Logger.Warn("UDP datagram dropped, queue is full", "session", session.Id(), "command", p.Command)
+	}
+}
+
+// session 返回远端地址对应的会话，新地址时创建会话
+func (s *Server) session(conn net.PacketConn, addr net.Addr) *tcp.Session {
+	key := addr.String()
+	s.mu.Lock()
+	session, ok := s.byAddr[key]
+	s.mu.Unlock()
+	if ok {
+		return session
+	}
+
+	session = tcp.NewSession(&udpTransport{server: s, conn: conn, addr: addr}, s.Codec, nil)
+	if s.OnConnect != nil {
+		if err := s.OnConnect(session); err != nil {
+			s.Logger.Warn("UDP session rejected", "remote", key, "error", err)
+			return nil
+		}
+	}
+	s.mu.Lock()
+	s.byAddr[key] = session
+	s.mu.Unlock()
+	s.sessions.Add(session)
+	return session
+}
+
+func (s *Server) removeAddr(key string) {
+	s.mu.Lock()
+	session, ok := s.byAddr[key]
+	delete(s.byAddr, key)
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.sessions.Remove(session)
+	if s.OnDisconnect != nil {
+		s.OnDisconnect(session)
+	}
+}
+
+// expire 定期移除超时的会话
+func (s *Server) expire() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.closed:
+			return
+		case now := <-ticker.C:
+			s.sessions.Range(func(session *tcp.Session) bool {
+				if now.Sub(session.LastActive()) >= s.IdleTimeout {
+					_ = session.Close()
+				}
+				return true
+			})
+		}
+	}
+}
+
+// Close 停止接收数据报，等待已接收的数据报处理完成后关闭监听并移除所有会话
+func (s *Server) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+		s.mu.Lock()
+		conn, lanes := s.conn, s.lanes
+		s.mu.Unlock()
+		if conn != nil {
+			// 先结束读取循环，连接保持打开，处理中的数据报仍可回复
+			_ = conn.SetReadDeadline(time.Now())
+		}
+		s.wg.Wait()
+		for _, lane := range lanes {
+			lane.Wait()
+			lane.Shutdown()
+		}
+		if conn != nil {
+			_ = conn.Close()
+		}
+		s.sessions.Range(func(session *tcp.Session) bool {
+			_ = session.Close()
+			return true
+		})
+	})
+	return nil
+}
@@ -0,0 +1,118 @@
+package udp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/servers/tcp"
+	"github.com/stretchr/testify/assert"
+)
+
+type handlerFunc func(ctx context.Context, request interface{}) (interface{}, error)
+
+func (f handlerFunc) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	return f(ctx, request)
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	s.Address = "127.0.0.1:0"
+	s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.IdleTimeout = 200 * time.Millisecond
+	s.Register(0x01, &commons.CommHandler{Handler: handlerFunc(func(ctx context.Context, request interface{}) (interface{}, error) {
+		p := request.(*tcp.Packet)
+		s.Sessions().Bind(string(p.Body), tcp.SessionFromContext(ctx))
+		return []byte("ok"), nil
+	})})
+	disconnected := make(chan struct{}, 1)
+	s.OnDisconnect = func(*tcp.Session) { disconnected <- struct{}{} }
+	go func() { _ = s.Serve() }()
+	assert.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, 5*time.Millisecond)
+	defer s.Close()
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 64)
+	readPacket := func() []byte {
+		_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, err := conn.Read(buf)
+		assert.NoError(t, err)
+		return buf[:n]
+	}
+
+	_, err = conn.Write([]byte{0x01, 'd', '1'})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 'o', 'k'}, readPacket())
+
+	assert.NoError(t, s.Push("d1", &tcp.Packet{Command: 0x10, Body: []byte("on")}))
+	assert.Equal(t, []byte{0x10, 'o', 'n'}, readPacket())
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle session not removed")
+	}
+	assert.ErrorIs(t, s.Push("d1", &tcp.Packet{Command: 0x10}), tcp.ErrSessionNotFound)
+}
+
+func TestSlowHandlerDoesNotBlockOtherAddresses(t *testing.T) {
+	s := NewServer()
+	s.Address = "127.0.0.1:0"
+	s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.Workers = 4
+	release := make(chan struct{})
+	s.Register(0x01, &commons.CommHandler{Handler: handlerFunc(func(ctx context.Context, request interface{}) (interface{}, error) {
+		<-release
+		return []byte("slow"), nil
+	})})
+	s.Register(0x02, &commons.CommHandler{Handler: handlerFunc(func(ctx context.Context, request interface{}) (interface{}, error) {
+		return []byte("fast"), nil
+	})})
+	go func() { _ = s.Serve() }()
+	assert.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, 5*time.Millisecond)
+	defer s.Close()
+	defer close(release)
+
+	slow, err := net.Dial("udp", s.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer slow.Close()
+	// 选择与慢设备分配到不同工作池的地址
+	var fast net.Conn
+	for i := 0; i < 32 && fast == nil; i++ {
+		conn, err := net.Dial("udp", s.Addr().String())
+		if !assert.NoError(t, err) {
+			return
+		}
+		if s.lane(conn.LocalAddr().String()) != s.lane(slow.LocalAddr().String()) {
+			fast = conn
+		} else {
+			_ = conn.Close()
+		}
+	}
+	if !assert.NotNil(t, fast) {
+		return
+	}
+	defer fast.Close()
+
+	_, err = slow.Write([]byte{0x01})
+	assert.NoError(t, err)
+	_, err = fast.Write([]byte{0x02})
+	assert.NoError(t, err)
+
+	buf := make([]byte, 64)
+	_ = fast.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := fast.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x02, 'f', 'a', 's', 't'}, buf[:n])
+}
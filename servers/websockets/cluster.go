@@ -0,0 +1,194 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sagoo-cloud/nexframe/net/backplane"
+)
+
+const defaultChannel = "websockets"
+
+var (
+	ErrConnectionNotFound = errors.New("connection not found")
+	ErrEmptyUserId        = errors.New("user id cannot be empty")
+	ErrSendBufferFull     = errors.New("connection send buffer is full")
+)
+
+// WithBackplane 设置跨节点消息分发的 Backplane。
+// 设置后 Broadcast、SendToId、SendToUser 会将消息转发到所有节点；
+// 如果 Backplane 同时实现了 backplane.Presence，则用作在线状态登记。
+func WithBackplane(bp backplane.Backplane) ServerOption {
+	return func(s *Server) {
+		s.backplane = bp
+		if presence, ok := bp.(backplane.Presence); ok && s.presence == nil {
+			s.presence = presence
+		}
+	}
+}
+
+// WithPresence 设置在线状态登记，默认使用进程内存实现
+func WithPresence(presence backplane.Presence) ServerOption {
+	return func(s *Server) {
+		s.presence = presence
+	}
+}
+
+// WithChannel 设置 Backplane 频道名，同一集群内的服务需使用相同的频道
+func WithChannel(channel string) ServerOption {
+	return func(s *Server) {
+		if channel != "" {
+			s.channel = channel
+		}
+	}
+}
+
+// WithNodeId 设置节点ID，默认随机生成
+func WithNodeId(nodeId string) ServerOption {
+	return func(s *Server) {
+		if nodeId != "" {
+			s.nodeId = nodeId
+		}
+	}
+}
+
+// NodeId 返回当前节点ID
+func (s *Server) NodeId() string {
+	return s.nodeId
+}
+
+// Conn 获取本节点上的连接
+func (s *Server) Conn(connectionId string) (*Conn, bool) {
+	s.connsMu.RLock()
+	defer s.connsMu.RUnlock()
+	c, ok := s.conns[connectionId]
+	return c, ok
+}
+
+// Broadcast 向集群内所有连接广播消息
+func (s *Server) Broadcast(message []byte) error {
+	s.deliverAll(message)
+	return s.publish(backplane.KindBroadcast, "", message)
+}
+
+// SendToId 向指定连接发送消息，连接不在本节点时通过 Backplane 转发。
+// 消息放入连接的发送队列后即返回，队列已满时返回 ErrSendBufferFull
+func (s *Server) SendToId(connectionId string, message []byte) error {
+	if c, ok := s.Conn(connectionId); ok {
+		if !s.deliver(c, message) {
+			return ErrSendBufferFull
+		}
+		return nil
+	}
+	if s.backplane == nil {
+		return ErrConnectionNotFound
+	}
+	return s.publish(backplane.KindConnection, connectionId, message)
+}
+
+// SendToUser 向指定用户在集群内的所有连接发送消息
+func (s *Server) SendToUser(userId string, message []byte) error {
+	if userId == "" {
+		return ErrEmptyUserId
+	}
+	s.deliverUser(userId, message)
+	return s.publish(backplane.KindUser, userId, message)
+}
+
+// BindUser 将本节点上的连接绑定到用户ID，一个用户可以拥有多个连接
+func (s *Server) BindUser(connectionId, userId string) error {
+	if userId == "" {
+		return ErrEmptyUserId
+	}
+	c, ok := s.Conn(connectionId)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	if old := c.UserId(); old != "" && old != userId {
+		if err := s.presence.Leave(s.ctx, old, connectionId); err != nil {
+			return err
+		}
+	}
+	c.setUserId(userId)
+	return s.presence.Join(s.ctx, userId, connectionId)
+}
+
+// UserConnections 获取用户在集群内的所有连接ID
+func (s *Server) UserConnections(ctx context.Context, userId string) ([]string, error) {
+	return s.presence.Connections(ctx, userId)
+}
+
+// subscribe 订阅 Backplane 频道
+func (s *Server) subscribe() error {
+	if s.backplane == nil {
+		return nil
+	}
+	sub, err := s.backplane.Subscribe(s.ctx, s.channel, s.onEnvelope)
+	if err != nil {
+		return err
+	}
+	s.subscription = sub
+	return nil
+}
+
+// onEnvelope 处理来自其他节点的消息
+func (s *Server) onEnvelope(env *backplane.Envelope) {
+	if env.Origin == s.nodeId {
+		return
+	}
+	switch env.Kind {
+	case backplane.KindBroadcast:
+		s.deliverAll(env.Payload)
+	case backplane.KindConnection:
+		if c, ok := s.Conn(env.Target); ok {
+			s.deliver(c, env.Payload)
+		}
+	case backplane.KindUser:
+		s.deliverUser(env.Target, env.Payload)
+	}
+}
+
+func (s *Server) publish(kind, target string, message []byte) error {
+	if s.backplane == nil {
+		return nil
+	}
+	return s.backplane.Publish(s.ctx, s.channel, &backplane.Envelope{
+		Kind:    kind,
+		Target:  target,
+		Origin:  s.nodeId,
+		Payload: message,
+	})
+}
+
+// deliver 将消息放入连接的发送队列，队列已满时丢弃消息，不阻塞其他连接的投递
+func (s *Server) deliver(c *Conn, message []byte) bool {
+	if c.Send(message) {
+		return true
+	}
+	s.logger.Warn("WebSocket send buffer full, message dropped", "connection", c.ID())
+	return false
+}
+
+func (s *Server) deliverAll(message []byte) {
+	for _, c := range s.snapshotConns() {
+		s.deliver(c, message)
+	}
+}
+
+func (s *Server) deliverUser(userId string, message []byte) {
+	for _, c := range s.snapshotConns() {
+		if c.UserId() == userId {
+			s.deliver(c, message)
+		}
+	}
+}
+
+func (s *Server) snapshotConns() []*Conn {
+	s.connsMu.RLock()
+	defer s.connsMu.RUnlock()
+	conns := make([]*Conn, 0, len(s.conns))
+	for _, c := range s.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
@@ -0,0 +1,152 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn 表示服务端持有的一个WebSocket连接，写操作是并发安全的。
+// Broadcast、SendToUser 等推送先写入连接的发送队列，由连接自己的协程写出，
+// 写入超过 writeTimeout 时连接被关闭，缓慢的客户端不会阻塞其他连接
+type Conn struct {
+	id           string
+	ws           *websocket.Conn
+	writeMu      sync.Mutex
+	writeTimeout time.Duration
+	send         chan []byte
+	mu           sync.RWMutex
+	userId       string
+	ctx          context.Context
+	cancel       context.CancelFunc
+	subs         map[string]context.CancelFunc // 订阅ID与取消函数
+	wg           sync.WaitGroup                // 正在处理的消息
+}
+
+func newConn(parent context.Context, id string, ws *websocket.Conn, sendBufferSize int, writeTimeout time.Duration) *Conn {
+	ctx, cancel := context.WithCancel(parent)
+	c := &Conn{
+		id:           id,
+		ws:           ws,
+		writeTimeout: writeTimeout,
+		send:         make(chan []byte, sendBufferSize),
+		ctx:          ctx,
+		cancel:       cancel,
+		subs:         make(map[string]context.CancelFunc),
+	}
+	go c.writeLoop()
+	return c
+}
+
+// Send 将消息放入发送队列，不等待写出；队列已满或连接已关闭时返回 false
+func (c *Conn) Send(message []byte) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeLoop 写出发送队列中的消息，写入失败或超时时关闭连接
+func (c *Conn) writeLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case message := <-c.send:
+			if err := c.WriteMessage(websocket.TextMessage, message); err != nil {
+				_ = c.Close()
+				return
+			}
+		}
+	}
+}
+
+// Context 返回连接的上下文，连接关闭时取消
+func (c *Conn) Context() context.Context {
+	return c.ctx
+}
+
+// ID 返回连接ID
+func (c *Conn) ID() string {
+	return c.id
+}
+
+// UserId 返回连接绑定的用户ID，未绑定时为空
+func (c *Conn) UserId() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.userId
+}
+
+func (c *Conn) setUserId(userId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.userId = userId
+}
+
+// WriteMessage 写入一条消息，等待写出完成
+func (c *Conn) WriteMessage(mt int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.setWriteDeadline()
+	return c.ws.WriteMessage(mt, data)
+}
+
+// WriteJSON 以JSON格式写入一条消息
+func (c *Conn) WriteJSON(mt int, v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.setWriteDeadline()
+
+	w, err := c.ws.NextWriter(mt)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return json.NewEncoder(w).Encode(v)
+}
+
+// setWriteDeadline 设置本次写入的截止时间，调用方需持有 writeMu
+func (c *Conn) setWriteDeadline() {
+	if c.writeTimeout > 0 {
+		_ = c.ws.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+}
+
+// Close 关闭连接
+func (c *Conn) Close() error {
+	c.cancel()
+	return c.ws.Close()
+}
+
+// addSubscription 登记订阅，订阅ID已存在时返回 false
+func (c *Conn) addSubscription(id string, cancel context.CancelFunc) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.subs[id]; exists {
+		return false
+	}
+	c.subs[id] = cancel
+	return true
+}
+
+// removeSubscription 取消并注销订阅
+func (c *Conn) removeSubscription(id string) bool {
+	c.mu.Lock()
+	cancel, exists := c.subs[id]
+	delete(c.subs, id)
+	c.mu.Unlock()
+	if exists {
+		cancel()
+	}
+	return exists
+}
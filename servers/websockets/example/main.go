@@ -27,6 +27,7 @@ func main() {
 	server := websockets.NewServer(
 		websockets.WithLogger(logger),
 		websockets.WithMaxConnections(100),
+		websockets.WithAddress(":8080"),
 	)
 
 	// 注册 echo 处理程序
@@ -38,7 +39,7 @@ func main() {
 
 	// 在一个新的 goroutine 中启动服务器
 	go func() {
-		if err := server.Serve(":8080"); err != nil {
+		if err := server.Serve(); err != nil {
 			logger.Error("Server error", "error", err)
 		}
 	}()
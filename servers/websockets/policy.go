@@ -0,0 +1,112 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/utils/httputil"
+	"golang.org/x/time/rate"
+)
+
+var (
+	ErrRateLimited = errors.New("message rate limit exceeded")
+)
+
+const (
+	defaultWriteTimeout   = 10 * time.Second
+	defaultSendBufferSize = 256
+)
+
+// AuthFunc 在协议升级前执行的认证函数，返回错误时拒绝连接。
+// 返回的上下文中携带的值会附加到连接的上下文上，处理函数可以通过 auth.ClaimsFromContext 获取
+type AuthFunc = contracts.AuthFunc
+
+// WithAuth 设置连接认证函数
+func WithAuth(fn AuthFunc) ServerOption {
+	return func(s *Server) {
+		s.auth = fn
+	}
+}
+
+// WithAllowedOrigins 设置允许的 Origin，支持完整的来源（https://example.com）、
+// 主机名（example.com）、通配子域名（*.example.com）以及 "*"。
+// 未设置时不校验 Origin；不携带 Origin 的请求（非浏览器客户端）始终允许。
+func WithAllowedOrigins(origins ...string) ServerOption {
+	return func(s *Server) {
+		s.upgrader.CheckOrigin = httputil.OriginChecker(origins)
+	}
+}
+
+// WithMaxMessageSize 设置单条消息的最大字节数，超过时连接会被关闭
+func WithMaxMessageSize(n int64) ServerOption {
+	return func(s *Server) {
+		s.maxMessageSize = n
+	}
+}
+
+// WithRateLimit 设置单个连接每秒允许的消息数和突发数，超出的消息会被丢弃并返回错误
+func WithRateLimit(perSecond float64, burst int) ServerOption {
+	return func(s *Server) {
+		s.rateLimit = rate.Limit(perSecond)
+		s.rateBurst = burst
+		if s.rateBurst <= 0 {
+			s.rateBurst = 1
+		}
+	}
+}
+
+// WithIdleTimeout 设置空闲超时，连接在该时间内没有收到任何业务消息时会被关闭。
+// 心跳帧不计入活跃，正在运行的订阅也不会阻止空闲关闭。
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.idleTimeout = timeout
+	}
+}
+
+// WithWriteTimeout 设置单次写入的超时时间，默认 10 秒，超时的连接会被关闭；为 0 时不限制
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.writeTimeout = timeout
+	}
+}
+
+// WithSendBufferSize 设置每个连接发送队列的长度，默认 256，队列已满时推送的消息被丢弃
+func WithSendBufferSize(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.sendBufferSize = n
+		}
+	}
+}
+
+// authenticate 执行认证，返回的上下文只用于提供值
+func (s *Server) authenticate(r *http.Request) (context.Context, error) {
+	if s.auth == nil {
+		return nil, nil
+	}
+	return s.auth(r)
+}
+
+// newLimiter 为连接创建限流器，未设置限流时返回 nil
+func (s *Server) newLimiter() *rate.Limiter {
+	if s.rateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(s.rateLimit, s.rateBurst)
+}
+
+// valueContext 使用 values 提供的值，但取消信号来自内嵌的 Context
+type valueContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valueContext) Value(key any) any {
+	if v := c.values.Value(key); v != nil {
+		return v
+	}
+	return c.Context.Value(key)
+}
@@ -0,0 +1,96 @@
+package websockets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+type userKey struct{}
+
+type userHandler struct{}
+
+func (h *userHandler) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	return ctx.Value(userKey{}), nil
+}
+
+func TestAuthAndOrigin(t *testing.T) {
+	server := NewServer(
+		WithAllowedOrigins("https://app.example.com", "*.trusted.com"),
+		WithAuth(func(r *http.Request) (context.Context, error) {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				return nil, errors.New("missing token")
+			}
+			return context.WithValue(r.Context(), userKey{}, token), nil
+		}),
+	)
+	defer server.Close()
+	server.Register("whoami", &commons.CommHandler{Handler: &userHandler{}})
+
+	testServer := httptest.NewServer(http.HandlerFunc(server.wsHandler))
+	defer testServer.Close()
+	url := "ws" + strings.TrimPrefix(testServer.URL, "http")
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without token, got %v", err)
+	}
+
+	header := http.Header{"Origin": []string{"https://evil.com"}}
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?token=alice", header)
+	if err == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 for disallowed origin, got %v", err)
+	}
+
+	header = http.Header{"Origin": []string{"https://api.trusted.com"}}
+	ws, _, err := websocket.DefaultDialer.Dial(url+"?token=alice", header)
+	if err != nil {
+		t.Fatalf("Could not connect with allowed origin: %v", err)
+	}
+	defer ws.Close()
+
+	if err := ws.WriteJSON(contracts.Payload{Id: "1", Route: "whoami"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+	if msg := readMessage(t, ws); msg.Data != "alice" {
+		t.Errorf("Expected claims from auth context, got %+v", msg)
+	}
+}
+
+func TestRateLimitAndIdleTimeout(t *testing.T) {
+	server := NewServer(WithRateLimit(1, 1), WithIdleTimeout(200*time.Millisecond), WithConcurrency(1))
+	defer server.Close()
+	server.Register("test", &commons.CommHandler{Handler: &mockHandler{}})
+
+	ws := dialTest(t, server)
+	if err := ws.WriteJSON(contracts.Payload{Id: "1", Route: "test"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+	if err := ws.WriteJSON(contracts.Payload{Id: "2", Route: "test"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+
+	// 被拒绝的消息在读取时直接返回，可能先于正常响应到达
+	types := map[string]string{}
+	for i := 0; i < 2; i++ {
+		msg := readMessage(t, ws)
+		types[msg.Id] = msg.Type
+	}
+	if types["1"] != MessageTypeReply || types["2"] != MessageTypeError {
+		t.Fatalf("Expected reply and rate limit error, got %v", types)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := ws.ReadMessage(); err == nil {
+		t.Fatalf("Expected idle connection to be closed")
+	}
+}
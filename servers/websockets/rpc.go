@@ -0,0 +1,208 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+// 客户端消息类型，对应 contracts.Payload.Type
+const (
+	MessageTypeRequest     = ""            // 普通请求，处理结果作为响应返回
+	MessageTypeSubscribe   = "subscribe"   // 订阅，处理函数持续推送事件直到取消订阅
+	MessageTypeUnsubscribe = "unsubscribe" // 取消订阅，Id 为订阅时使用的消息ID
+)
+
+// 服务端消息类型，对应 Message.Type
+const (
+	MessageTypeReply = "reply" // 请求的响应
+	MessageTypeError = "error" // 请求处理失败
+	MessageTypePush  = "push"  // 服务端主动推送
+	MessageTypeEvent = "event" // 订阅产生的事件
+	MessageTypeEnd   = "end"   // 订阅结束
+)
+
+const (
+	defaultTimeout     = 10 * time.Second
+	defaultConcurrency = 1
+)
+
+var (
+	ErrMissingMessageId   = errors.New("message id is required")
+	ErrSubscriptionExists = errors.New("subscription already exists")
+)
+
+// Message 服务端发送给客户端的消息。
+// 请求携带了 Id 时，响应会使用该格式并原样返回 Id，否则保持直接返回处理结果的旧格式。
+type Message struct {
+	Id    string      `json:"id,omitempty"`
+	Type  string      `json:"type"`
+	Route string      `json:"route,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// route 表示一个已注册的请求路由
+type route struct {
+	handler *commons.CommHandler
+	timeout time.Duration
+}
+
+// RouteOption 路由选项
+type RouteOption func(*route)
+
+// WithRouteTimeout 设置路由的处理超时时间，未设置时使用服务器的默认超时
+func WithRouteTimeout(timeout time.Duration) RouteOption {
+	return func(r *route) {
+		r.timeout = timeout
+	}
+}
+
+// WithDefaultTimeout 设置请求的默认处理超时时间，默认 10 秒
+func WithDefaultTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		if timeout > 0 {
+			s.timeout = timeout
+		}
+	}
+}
+
+// WithConcurrency 设置单个连接同时处理的消息数，默认 1，即按接收顺序逐条处理。
+// 大于 1 时携带消息ID的请求会并发处理，响应可能乱序，客户端需按 Id 关联；
+// 未携带消息ID的请求仍按顺序处理，保证旧客户端收到的响应顺序不变
+func WithConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// messageId 读取消息中的ID，消息无法解析或未携带ID时返回空字符串
+func messageId(message []byte) string {
+	var p struct {
+		Id string `json:"id"`
+	}
+	_ = json.Unmarshal(message, &p)
+	return p.Id
+}
+
+// SubscriptionHandler 订阅处理函数。
+// 通过 stream 持续推送事件，客户端取消订阅或连接断开时 ctx 会被取消，处理函数应随之返回。
+type SubscriptionHandler func(ctx context.Context, params map[string]interface{}, stream *Stream) error
+
+// Stream 订阅的事件流
+type Stream struct {
+	conn  *Conn
+	id    string
+	route string
+}
+
+// Id 返回订阅ID
+func (st *Stream) Id() string {
+	return st.id
+}
+
+// ConnectionId 返回订阅所属的连接ID
+func (st *Stream) ConnectionId() string {
+	return st.conn.ID()
+}
+
+// Send 向客户端推送一个事件
+func (st *Stream) Send(data interface{}) error {
+	return st.conn.WriteJSON(websocket.TextMessage, Message{
+		Id:    st.id,
+		Type:  MessageTypeEvent,
+		Route: st.route,
+		Data:  data,
+	})
+}
+
+// RegisterSubscription 注册订阅路由
+func (s *Server) RegisterSubscription(name string, handler SubscriptionHandler) {
+	s.handlersMu.Lock()
+	defer s.handlersMu.Unlock()
+	s.subscriptions[name] = handler
+}
+
+// Push 向指定连接主动推送消息，连接不在本节点时通过 Backplane 转发
+func (s *Server) Push(connectionId, route string, data interface{}) error {
+	message, err := json.Marshal(Message{
+		Type:  MessageTypePush,
+		Route: route,
+		Data:  data,
+	})
+	if err != nil {
+		return err
+	}
+	return s.SendToId(connectionId, message)
+}
+
+// handleSubscribe 处理订阅请求，订阅在独立的 goroutine 中运行
+func (s *Server) handleSubscribe(c *Conn, mt int, payload *contracts.Payload) error {
+	if payload.Id == "" {
+		return s.writeError(c, mt, payload, ErrMissingMessageId.Error())
+	}
+
+	s.handlersMu.RLock()
+	handler, exists := s.subscriptions[payload.Route]
+	s.handlersMu.RUnlock()
+	if !exists {
+		return s.writeError(c, mt, payload, "Handler not found")
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	if !c.addSubscription(payload.Id, cancel) {
+		cancel()
+		return s.writeError(c, mt, payload, ErrSubscriptionExists.Error())
+	}
+	if err := s.writeReply(c, mt, payload, nil); err != nil {
+		c.removeSubscription(payload.Id)
+		return err
+	}
+
+	stream := &Stream{conn: c, id: payload.Id, route: payload.Route}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		err := handler(ctx, payload.Params, stream)
+		c.removeSubscription(payload.Id)
+
+		end := Message{Id: payload.Id, Type: MessageTypeEnd, Route: payload.Route}
+		if err != nil && !errors.Is(err, context.Canceled) {
+			end.Data = contracts.ResponseFailed(err)
+		}
+		if c.ctx.Err() == nil {
+			if err := c.WriteJSON(mt, end); err != nil {
+				s.logger.Error("WebSocket write error", "connection", c.ID(), "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// handleUnsubscribe 处理取消订阅请求
+func (s *Server) handleUnsubscribe(c *Conn, mt int, payload *contracts.Payload) error {
+	if payload.Id == "" {
+		return s.writeError(c, mt, payload, ErrMissingMessageId.Error())
+	}
+	c.removeSubscription(payload.Id)
+	return nil
+}
+
+// writeReply 写入请求的响应
+func (s *Server) writeReply(c *Conn, mt int, payload *contracts.Payload, response interface{}) error {
+	if payload == nil || payload.Id == "" {
+		return s.writeJSON(c, mt, response)
+	}
+	return s.writeJSON(c, mt, Message{
+		Id:    payload.Id,
+		Type:  MessageTypeReply,
+		Route: payload.Route,
+		Data:  response,
+	})
+}
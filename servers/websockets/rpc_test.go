@@ -0,0 +1,199 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/servers/commons"
+)
+
+type slowHandler struct{}
+
+func (h *slowHandler) ServeHandle(ctx context.Context, request interface{}) (interface{}, error) {
+	select {
+	case <-time.After(time.Second):
+		return "done", nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func dialTest(t *testing.T, server *Server) *websocket.Conn {
+	testServer := httptest.NewServer(http.HandlerFunc(server.wsHandler))
+	t.Cleanup(testServer.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(testServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Could not connect to WebSocket server: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func readMessage(t *testing.T, ws *websocket.Conn) Message {
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("Could not read message: %v", err)
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("Could not parse message %s: %v", data, err)
+	}
+	return msg
+}
+
+func TestMessageIdAndRouteTimeout(t *testing.T) {
+	server := NewServer(WithConcurrency(4))
+	defer server.Close()
+	server.Register("test", &commons.CommHandler{Handler: &mockHandler{}})
+	server.Register("slow", &commons.CommHandler{Handler: &slowHandler{}}, WithRouteTimeout(50*time.Millisecond))
+
+	ws := dialTest(t, server)
+
+	// 慢请求先发出，由于并发处理，后发出的请求先返回
+	if err := ws.WriteJSON(contracts.Payload{Id: "1", Route: "slow"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+	if err := ws.WriteJSON(contracts.Payload{Id: "2", Route: "test"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+
+	msg := readMessage(t, ws)
+	if msg.Id != "2" || msg.Type != MessageTypeReply || msg.Route != "test" {
+		t.Fatalf("Unexpected reply: %+v", msg)
+	}
+	if data, _ := msg.Data.(map[string]interface{}); data["message"] != "Hello, WebSocket!" {
+		t.Errorf("Unexpected reply data: %+v", msg.Data)
+	}
+
+	msg = readMessage(t, ws)
+	if msg.Id != "1" || msg.Type != MessageTypeError {
+		t.Fatalf("Expected timeout error for slow route, got %+v", msg)
+	}
+}
+
+func TestRequestsWithoutIdStayOrdered(t *testing.T) {
+	server := NewServer(WithConcurrency(4))
+	defer server.Close()
+	server.Register("test", &commons.CommHandler{Handler: &mockHandler{}})
+	server.Register("slow", &commons.CommHandler{Handler: &slowHandler{}}, WithRouteTimeout(50*time.Millisecond))
+
+	ws := dialTest(t, server)
+
+	// 未携带消息ID的请求即使开启了并发也按接收顺序返回
+	if err := ws.WriteJSON(contracts.Payload{Route: "slow"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+	if err := ws.WriteJSON(contracts.Payload{Route: "test"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, first, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("Could not read message: %v", err)
+	}
+	if strings.Contains(string(first), "Hello, WebSocket!") {
+		t.Fatalf("Reply to the second request arrived first: %s", first)
+	}
+	_, second, err := ws.ReadMessage()
+	if err != nil {
+		t.Fatalf("Could not read message: %v", err)
+	}
+	if !strings.Contains(string(second), "Hello, WebSocket!") {
+		t.Fatalf("Unexpected reply: %s", second)
+	}
+}
+
+func TestPush(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	ws := dialTest(t, server)
+	var conn *Conn
+	deadline := time.Now().Add(2 * time.Second)
+	for conn == nil && time.Now().Before(deadline) {
+		if conns := server.snapshotConns(); len(conns) == 1 {
+			conn = conns[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if conn == nil {
+		t.Fatalf("connection was not registered")
+	}
+
+	if err := server.Push(conn.ID(), "notice", "hello"); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	msg := readMessage(t, ws)
+	if msg.Type != MessageTypePush || msg.Route != "notice" || msg.Data != "hello" {
+		t.Errorf("Unexpected push: %+v", msg)
+	}
+}
+
+func TestSubscription(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	stopped := make(chan struct{})
+	server.RegisterSubscription("ticks", func(ctx context.Context, params map[string]interface{}, stream *Stream) error {
+		defer close(stopped)
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				if err := stream.Send(i); err != nil {
+					return err
+				}
+			}
+		}
+	})
+
+	ws := dialTest(t, server)
+	if err := ws.WriteJSON(contracts.Payload{Id: "sub1", Type: MessageTypeSubscribe, Route: "ticks"}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+
+	msg := readMessage(t, ws)
+	if msg.Id != "sub1" || msg.Type != MessageTypeReply {
+		t.Fatalf("Expected subscribe ack, got %+v", msg)
+	}
+	for i := 0; i < 2; i++ {
+		msg = readMessage(t, ws)
+		if msg.Id != "sub1" || msg.Type != MessageTypeEvent || msg.Data != float64(i) {
+			t.Fatalf("Unexpected event: %+v", msg)
+		}
+	}
+
+	if err := ws.WriteJSON(contracts.Payload{Id: "sub1", Type: MessageTypeUnsubscribe}); err != nil {
+		t.Fatalf("Could not send message: %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Subscription was not cancelled")
+	}
+	for {
+		msg = readMessage(t, ws)
+		if msg.Type == MessageTypeEnd {
+			break
+		}
+		if msg.Type != MessageTypeEvent {
+			t.Fatalf("Unexpected message: %+v", msg)
+		}
+	}
+	if msg.Id != "sub1" || msg.Data != nil {
+		t.Errorf("Unexpected end message: %+v", msg)
+	}
+}
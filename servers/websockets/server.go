@@ -4,26 +4,56 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"github.com/sagoo-cloud/nexframe/configs"
 	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/net/backplane"
 	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
 	"log/slog"
+	"net"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 )
 
+var ErrServerStarted = errors.New("server already started")
+
 type Server struct {
-	handlers     map[string]*commons.CommHandler
-	handlersMu   sync.RWMutex
-	upgrader     websocket.Upgrader
-	maxConns     int
-	activeConns  int32
-	activeConnMu sync.Mutex
-	ctx          context.Context
-	cancel       context.CancelFunc
-	logger       *slog.Logger
+	Address string // Serve 监听的地址，默认读取 servers.websocketHost 和 servers.websocketPort 配置
+
+	handlers       map[string]*route
+	subscriptions  map[string]SubscriptionHandler
+	handlersMu     sync.RWMutex
+	timeout        time.Duration
+	concurrency    int
+	upgrader       websocket.Upgrader
+	maxConns       int
+	activeConns    int32
+	activeConnMu   sync.Mutex
+	ctx            context.Context
+	cancel         context.CancelFunc
+	logger         *slog.Logger
+	conns          map[string]*Conn
+	connsMu        sync.RWMutex
+	nodeId         string
+	channel        string
+	backplane      backplane.Backplane
+	presence       backplane.Presence
+	subscription   backplane.Subscription
+	auth           AuthFunc
+	maxMessageSize int64
+	rateLimit      rate.Limit
+	rateBurst      int
+	idleTimeout    time.Duration
+	writeTimeout   time.Duration
+	sendBufferSize int
+	serveMu        sync.Mutex
+	listener       net.Listener
+	httpServer     *http.Server
+	closed         bool
 }
 
 type ServerOption func(*Server)
@@ -34,6 +64,13 @@ func WithMaxConnections(n int) ServerOption {
 	}
 }
 
+// WithAddress 设置 Serve 监听的地址
+func WithAddress(address string) ServerOption {
+	return func(s *Server) {
+		s.Address = address
+	}
+}
+
 func WithLogger(logger *slog.Logger) ServerOption {
 	return func(s *Server) {
 		s.logger = logger
@@ -42,55 +79,107 @@ func WithLogger(logger *slog.Logger) ServerOption {
 
 func NewServer(opts ...ServerOption) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
+	config := configs.LoadWebSocketConfig()
 	s := &Server{
-		handlers: make(map[string]*commons.CommHandler),
+		Address:       net.JoinHostPort(config.WebSocketHost, config.WebSocketPort),
+		handlers:      make(map[string]*route),
+		subscriptions: make(map[string]SubscriptionHandler),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // You might want to implement a more secure check
 			},
 		},
-		maxConns: 1000, // Default max connections
-		ctx:      ctx,
-		cancel:   cancel,
-		logger:   slog.Default(),
+		maxConns:       1000, // Default max connections
+		timeout:        defaultTimeout,
+		concurrency:    defaultConcurrency,
+		writeTimeout:   defaultWriteTimeout,
+		sendBufferSize: defaultSendBufferSize,
+		ctx:            ctx,
+		cancel:         cancel,
+		logger:         slog.Default(),
+		conns:          make(map[string]*Conn),
+		nodeId:         guid.S(),
+		channel:        defaultChannel,
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	if s.presence == nil {
+		s.presence = backplane.NewMemory()
+	}
+	if err := s.subscribe(); err != nil {
+		s.logger.Error("Backplane subscribe failed", "error", err)
+	}
+
 	return s
 }
 
-func (s *Server) Register(name string, handler *commons.CommHandler) {
+func (s *Server) Register(name string, handler *commons.CommHandler, opts ...RouteOption) {
+	r := &route{handler: handler}
+	for _, opt := range opts {
+		opt(r)
+	}
+
 	s.handlersMu.Lock()
 	defer s.handlersMu.Unlock()
-	s.handlers[name] = handler
+	s.handlers[name] = r
 }
 
-func (s *Server) Serve(addr string) error {
+// Serve 在 Address 上使用独立的监听启动服务，路径为 /ws，阻塞直到调用 Close。
+// 需要与HTTP接口共用端口时，使用 nf.APIFramework.BindWebSocket 挂载。
+func (s *Server) Serve() error {
+	s.serveMu.Lock()
+	if s.closed {
+		s.serveMu.Unlock()
+		return nil
+	}
+	if s.httpServer != nil {
+		s.serveMu.Unlock()
+		return ErrServerStarted
+	}
+	l, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		s.serveMu.Unlock()
+		return err
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/ws", s.wsHandler)
+	srv := &http.Server{Handler: mux}
+	s.listener, s.httpServer = l, srv
+	s.serveMu.Unlock()
 
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	s.logger.Info("WebSocket Server starting", "address", l.Addr().String())
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
 	}
+	return nil
+}
 
-	go func() {
-		<-s.ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := srv.Shutdown(shutdownCtx); err != nil {
-			s.logger.Error("Server shutdown error", "error", err)
-		}
-	}()
+// Addr 返回 Serve 实际监听的地址，未启动时返回 nil
+func (s *Server) Addr() net.Addr {
+	s.serveMu.Lock()
+	defer s.serveMu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
 
-	s.logger.Info("WebSocket Server starting", "address", addr)
-	return srv.ListenAndServe()
+// ServeHTTP 实现 http.Handler，可挂载到已有的路由上，例如 nf.APIFramework.BindWebSocket
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.wsHandler(w, r)
 }
 
 func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
+	values, err := s.authenticate(r)
+	if err != nil {
+		s.logger.Warn("WebSocket authentication failed", "remote", r.RemoteAddr, "error", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
 	s.activeConnMu.Lock()
 	if s.activeConns >= int32(s.maxConns) {
 		s.activeConnMu.Unlock()
@@ -106,70 +195,184 @@ func (s *Server) wsHandler(w http.ResponseWriter, r *http.Request) {
 		s.activeConnMu.Unlock()
 	}()
 
-	c, err := s.upgrader.Upgrade(w, r, nil)
+	ws, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.logger.Error("WebSocket upgrade failed", "error", err)
 		return
 	}
-	defer c.Close()
+	c := s.addConn(ws, values)
+	defer s.removeConn(c)
 
+	if s.maxMessageSize > 0 {
+		ws.SetReadLimit(s.maxMessageSize)
+	}
+	limiter := s.newLimiter()
+	var idle *time.Timer
+	if s.idleTimeout > 0 {
+		idle = time.AfterFunc(s.idleTimeout, func() {
+			s.logger.Info("WebSocket idle timeout", "connection", c.ID())
+			_ = c.Close()
+		})
+		defer idle.Stop()
+	}
+
+	// 每个连接最多同时处理 concurrency 条消息，达到上限时暂停读取。
+	// 未携带消息ID的请求无法与响应关联，始终在读取协程中按顺序处理
+	sem := make(chan struct{}, s.concurrency)
 	for {
-		mt, message, err := c.ReadMessage()
+		mt, message, err := ws.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				s.logger.Error("WebSocket read error", "error", err)
 			}
 			break
 		}
+		if idle != nil {
+			idle.Reset(s.idleTimeout)
+		}
+		if limiter != nil && !limiter.Allow() {
+			if err := s.reject(c, mt, message, ErrRateLimited); err != nil {
+				break
+			}
+			continue
+		}
 
-		if err := s.handleMessage(c, mt, message); err != nil {
-			s.logger.Error("Message handling error", "error", err)
-			break
+		if s.concurrency == 1 || messageId(message) == "" {
+			if err := s.handleMessage(c, mt, message); err != nil {
+				s.logger.Error("Message handling error", "error", err)
+				break
+			}
+			continue
 		}
+
+		sem <- struct{}{}
+		c.wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				c.wg.Done()
+			}()
+			if err := s.handleMessage(c, mt, message); err != nil {
+				s.logger.Error("Message handling error", "error", err)
+				_ = c.Close()
+			}
+		}()
+	}
+}
+
+// addConn 登记新建立的连接，values 为认证时返回的上下文
+func (s *Server) addConn(ws *websocket.Conn, values context.Context) *Conn {
+	parent := s.ctx
+	if values != nil {
+		parent = valueContext{Context: s.ctx, values: values}
 	}
+	c := newConn(parent, guid.S(), ws, s.sendBufferSize, s.writeTimeout)
+	s.connsMu.Lock()
+	s.conns[c.ID()] = c
+	s.connsMu.Unlock()
+	return c
 }
 
-func (s *Server) handleMessage(c *websocket.Conn, mt int, message []byte) error {
+// removeConn 注销并关闭连接，等待正在处理的消息和订阅结束
+func (s *Server) removeConn(c *Conn) {
+	s.connsMu.Lock()
+	delete(s.conns, c.ID())
+	s.connsMu.Unlock()
+
+	if userId := c.UserId(); userId != "" {
+		if err := s.presence.Leave(context.Background(), userId, c.ID()); err != nil {
+			s.logger.Error("Presence leave failed", "user", userId, "error", err)
+		}
+	}
+	_ = c.Close()
+	c.wg.Wait()
+}
+
+func (s *Server) handleMessage(c *Conn, mt int, message []byte) error {
 	payload := &contracts.Payload{}
 	if err := json.Unmarshal(message, payload); err != nil {
-		return s.writeError(c, mt, "Invalid JSON payload")
+		return s.writeError(c, mt, nil, "Invalid JSON payload")
+	}
+
+	switch payload.Type {
+	case MessageTypeSubscribe:
+		return s.handleSubscribe(c, mt, payload)
+	case MessageTypeUnsubscribe:
+		return s.handleUnsubscribe(c, mt, payload)
 	}
 
 	s.handlersMu.RLock()
-	handler, exists := s.handlers[payload.Route]
+	r, exists := s.handlers[payload.Route]
 	s.handlersMu.RUnlock()
 
 	if !exists {
-		return s.writeError(c, mt, "Handler not found")
+		return s.writeError(c, mt, payload, "Handler not found")
 	}
 
-	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	timeout := r.timeout
+	if timeout <= 0 {
+		timeout = s.timeout
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
 	defer cancel()
 
-	response, err := handler.Handle(ctx, payload.Params)
+	response, err := r.handler.Handle(ctx, payload.Params)
 	if err != nil {
-		return s.writeError(c, mt, err.Error())
+		return s.writeError(c, mt, payload, err.Error())
 	}
 
-	return s.writeJSON(c, mt, response)
+	return s.writeReply(c, mt, payload, response)
 }
 
-func (s *Server) writeError(c *websocket.Conn, mt int, message string) error {
-	resp := contracts.ResponseFailed(errors.New(message))
-	return s.writeJSON(c, mt, resp)
+// reject 拒绝处理一条消息并返回错误
+func (s *Server) reject(c *Conn, mt int, message []byte, reason error) error {
+	payload := &contracts.Payload{}
+	if err := json.Unmarshal(message, payload); err != nil {
+		payload = nil
+	}
+	return s.writeError(c, mt, payload, reason.Error())
 }
 
-func (s *Server) writeJSON(c *websocket.Conn, mt int, v interface{}) error {
-	w, err := c.NextWriter(mt)
-	if err != nil {
-		return err
+// writeError 写入错误响应，请求携带了 Id 时使用 Message 格式返回
+func (s *Server) writeError(c *Conn, mt int, payload *contracts.Payload, message string) error {
+	resp := contracts.ResponseFailed(errors.New(message))
+	if payload == nil || payload.Id == "" {
+		return s.writeJSON(c, mt, resp)
 	}
-	defer w.Close()
+	return s.writeJSON(c, mt, Message{
+		Id:    payload.Id,
+		Type:  MessageTypeError,
+		Route: payload.Route,
+		Data:  resp,
+	})
+}
 
-	return json.NewEncoder(w).Encode(v)
+func (s *Server) writeJSON(c *Conn, mt int, v interface{}) error {
+	return c.WriteJSON(mt, v)
 }
 
+// Close 关闭所有连接，并停止 Serve 启动的监听
 func (s *Server) Close() error {
+	s.serveMu.Lock()
+	s.closed = true
+	srv := s.httpServer
+	s.serveMu.Unlock()
+
 	s.cancel()
+	if s.subscription != nil {
+		if err := s.subscription.Unsubscribe(); err != nil {
+			s.logger.Error("Backplane unsubscribe failed", "error", err)
+		}
+	}
+	for _, c := range s.snapshotConns() {
+		_ = c.Close()
+	}
+	if srv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			s.logger.Error("Server shutdown error", "error", err)
+		}
+	}
 	return nil
 }
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"github.com/gorilla/websocket"
 	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/net/backplane"
 	"github.com/sagoo-cloud/nexframe/servers/commons"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -142,3 +145,146 @@ func TestServerClose(t *testing.T) {
 		}
 	}
 }
+
+func TestBackplaneFanout(t *testing.T) {
+	bp := backplane.NewMemory()
+	serverA := NewServer(WithBackplane(bp))
+	serverB := NewServer(WithBackplane(bp))
+	defer serverA.Close()
+	defer serverB.Close()
+
+	testServerA := httptest.NewServer(http.HandlerFunc(serverA.wsHandler))
+	defer testServerA.Close()
+	testServerB := httptest.NewServer(http.HandlerFunc(serverB.wsHandler))
+	defer testServerB.Close()
+
+	wsA, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(testServerA.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Could not connect to server A: %v", err)
+	}
+	defer wsA.Close()
+	wsB, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(testServerB.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Could not connect to server B: %v", err)
+	}
+	defer wsB.Close()
+
+	// 等待两个节点都登记连接
+	waitFor := func(s *Server) *Conn {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if conns := s.snapshotConns(); len(conns) == 1 {
+				return conns[0]
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("connection was not registered")
+		return nil
+	}
+	connA := waitFor(serverA)
+	connB := waitFor(serverB)
+
+	readText := func(ws *websocket.Conn) string {
+		ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("Could not read message: %v", err)
+		}
+		return string(message)
+	}
+
+	// 节点B广播，两个节点上的连接都应该收到
+	if err := serverB.Broadcast([]byte("broadcast")); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+	if msg := readText(wsA); msg != "broadcast" {
+		t.Errorf("Expected 'broadcast' on A, got %q", msg)
+	}
+	if msg := readText(wsB); msg != "broadcast" {
+		t.Errorf("Expected 'broadcast' on B, got %q", msg)
+	}
+
+	// 节点A向节点B上的连接定向发送
+	if err := serverA.SendToId(connB.ID(), []byte("direct")); err != nil {
+		t.Fatalf("SendToId failed: %v", err)
+	}
+	if msg := readText(wsB); msg != "direct" {
+		t.Errorf("Expected 'direct' on B, got %q", msg)
+	}
+
+	// 用户在两个节点上各有一个连接
+	if err := serverA.BindUser(connA.ID(), "user1"); err != nil {
+		t.Fatalf("BindUser failed: %v", err)
+	}
+	if err := serverB.BindUser(connB.ID(), "user1"); err != nil {
+		t.Fatalf("BindUser failed: %v", err)
+	}
+	ids, err := serverA.UserConnections(context.Background(), "user1")
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("Expected 2 connections for user1, got %v (%v)", ids, err)
+	}
+	if err := serverA.SendToUser("user1", []byte("user")); err != nil {
+		t.Fatalf("SendToUser failed: %v", err)
+	}
+	if msg := readText(wsA); msg != "user" {
+		t.Errorf("Expected 'user' on A, got %q", msg)
+	}
+	if msg := readText(wsB); msg != "user" {
+		t.Errorf("Expected 'user' on B, got %q", msg)
+	}
+}
+
+func TestSlowConnectionDoesNotBlockBroadcast(t *testing.T) {
+	server := NewServer(WithSendBufferSize(2), WithWriteTimeout(200*time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	defer server.Close()
+	testServer := httptest.NewServer(http.HandlerFunc(server.wsHandler))
+	defer testServer.Close()
+	url := "ws" + strings.TrimPrefix(testServer.URL, "http")
+
+	// stalled 从不读取，服务端的写入最终会阻塞
+	stalled, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Could not connect to server: %v", err)
+	}
+	defer stalled.Close()
+	healthy, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Could not connect to server: %v", err)
+	}
+	defer healthy.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.snapshotConns()) != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var received atomic.Int32
+	go func() {
+		for {
+			if _, _, err := healthy.ReadMessage(); err != nil {
+				return
+			}
+			received.Add(1)
+		}
+	}()
+
+	// 持续广播直到停滞的连接因写入超时被关闭，每次广播都不应被阻塞
+	message := []byte(strings.Repeat("x", 1<<20))
+	deadline = time.Now().Add(5 * time.Second)
+	for len(server.snapshotConns()) > 1 && time.Now().Before(deadline) {
+		start := time.Now()
+		if err := server.Broadcast(message); err != nil {
+			t.Fatalf("Broadcast failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Fatalf("Broadcast blocked on a stalled connection for %v", elapsed)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := len(server.snapshotConns()); n != 1 {
+		t.Fatalf("Expected the stalled connection to be closed, %d connections left", n)
+	}
+	if received.Load() == 0 {
+		t.Error("Healthy connection did not receive any broadcast")
+	}
+}
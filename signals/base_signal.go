@@ -1,9 +1,16 @@
 package signals
 
+import (
+	"context"
+	"time"
+)
+
 // keyedListener 表示监听器和用于标识的可选键的组合。
 type keyedListener[T any] struct {
 	key      string
 	listener SignalListener[T]
+	priority int
+	timeout  time.Duration // 为 0 时不限制
 }
 
 // BaseSignal 提供 Signal 接口的基本实现。
@@ -20,8 +27,22 @@ type keyedListener[T any] struct {
 //		// 发射信号的自定义实现
 //	}
 type BaseSignal[T any] struct {
-	subscribers    []keyedListener[T]
+	subscribers    []keyedListener[T] // 按优先级从高到低排列，相同优先级按添加顺序
 	subscribersMap map[string]SignalListener[T]
+	timeout        time.Duration // 监听器的默认超时时间
+}
+
+// newBaseSignal 创建 BaseSignal，timeout 为未通过 WithTimeout 设置时监听器的默认超时时间
+func newBaseSignal[T any](timeout time.Duration, opts []Option) BaseSignal[T] {
+	o := &signalOptions{timeout: timeout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return BaseSignal[T]{
+		subscribers:    make([]keyedListener[T], 0),
+		subscribersMap: make(map[string]SignalListener[T]),
+		timeout:        o.timeout,
+	}
 }
 
 // AddListener 向信号添加监听器。每当信号被发射时，监听器将被调用。
@@ -38,25 +59,50 @@ type BaseSignal[T any] struct {
 //	}, "key1")
 //	fmt.Println("添加监听器后的订阅者数量:", count)
 func (s *BaseSignal[T]) AddListener(listener SignalListener[T], key ...string) int {
+	if len(key) > 0 {
+		return s.AddListenerWithOptions(listener, WithKey(key[0]))
+	}
+	return s.AddListenerWithOptions(listener)
+}
+
+// AddListenerWithOptions 添加监听器，可以设置键、优先级和超时时间。
+// 返回添加后的订阅者数量，监听器为 nil 或键已存在时返回 -1。
+//
+// 示例:
+//
+//	signal := signals.New[int]()
+//	signal.AddListenerWithOptions(func(ctx context.Context, payload int) {
+//		// 先于默认优先级的监听器执行
+//	}, signals.WithKey("audit"), signals.WithPriority(10), signals.WithListenerTimeout(time.Second))
+func (s *BaseSignal[T]) AddListenerWithOptions(listener SignalListener[T], opts ...ListenerOption) int {
 	if listener == nil {
 		return -1 // 返回 -1 表示添加失败
 	}
+	o := &listenerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	sub := keyedListener[T]{key: o.key, listener: listener, priority: o.priority, timeout: s.timeout}
+	if o.hasTimeout {
+		sub.timeout = o.timeout
+	}
 
-	if len(key) > 0 {
-		if _, ok := s.subscribersMap[key[0]]; ok {
+	if o.key != "" {
+		if _, ok := s.subscribersMap[o.key]; ok {
 			return -1
 		}
-		s.subscribersMap[key[0]] = listener
-		s.subscribers = append(s.subscribers, keyedListener[T]{
-			key:      key[0],
-			listener: listener,
-		})
-	} else {
-		s.subscribers = append(s.subscribers, keyedListener[T]{
-			listener: listener,
-		})
+		s.subscribersMap[o.key] = listener
 	}
 
+	// 插入到相同优先级的最后一个监听器之后
+	i := len(s.subscribers)
+	for i > 0 && s.subscribers[i-1].priority < sub.priority {
+		i--
+	}
+	s.subscribers = append(s.subscribers, keyedListener[T]{})
+	copy(s.subscribers[i+1:], s.subscribers[i:])
+	s.subscribers[i] = sub
+
 	return len(s.subscribers)
 }
 
@@ -139,6 +185,54 @@ func (s *BaseSignal[T]) IsEmpty() bool {
 	return len(s.subscribers) == 0
 }
 
+// snapshot 返回当前监听器的副本，Emit 在副本上调用监听器，监听器中可以添加或移除监听器
+func (s *BaseSignal[T]) snapshot() []keyedListener[T] {
+	return append([]keyedListener[T](nil), s.subscribers...)
+}
+
+// call 调用监听器，捕获 panic 并检查超时。
+// 异步调用时监听器在单独的 goroutine 中执行，超时后立即返回而不等待监听器结束；
+// 同步调用时监听器结束后才检查是否超时
+func call[T any](ctx context.Context, sub keyedListener[T], payload T, async bool) error {
+	listenerCtx, cancel := ctx, context.CancelFunc(func() {})
+	if sub.timeout > 0 {
+		listenerCtx, cancel = context.WithTimeout(ctx, sub.timeout)
+	}
+	defer cancel()
+
+	run := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = ErrListenerPanicked
+			}
+		}()
+		sub.listener(listenerCtx, payload)
+		return nil
+	}
+
+	var err error
+	if async {
+		done := make(chan error, 1)
+		go func() {
+			done <- run()
+		}()
+		select {
+		case <-listenerCtx.Done():
+			err = listenerCtx.Err()
+		case err = <-done:
+		}
+	} else {
+		err = run()
+		if err == nil {
+			err = listenerCtx.Err()
+		}
+	}
+	if err != nil {
+		return &ListenerError{Key: sub.key, Err: err}
+	}
+	return nil
+}
+
 // Emit 在 BaseSignal 中未实现，如果被调用会返回一个错误。
 // 它应该由派生类型实现。
 //
@@ -0,0 +1,128 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/signals"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryQueue 进程内的 queue.Queue 实现
+type memoryQueue struct {
+	mu    sync.Mutex
+	lists map[string][]string
+}
+
+func (m *memoryQueue) Enqueue(_ context.Context, key string, message string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lists == nil {
+		m.lists = make(map[string][]string)
+	}
+	m.lists[key] = append(m.lists[key], message)
+	return true, nil
+}
+
+func (m *memoryQueue) Dequeue(_ context.Context, key string) (string, string, string, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.lists[key]) == 0 {
+		return "", "", "", 0, nil
+	}
+	message := m.lists[key][0]
+	m.lists[key] = m.lists[key][1:]
+	return message, "", "", 1, nil
+}
+
+func (m *memoryQueue) AckMsg(context.Context, string, string) (bool, error) {
+	return true, nil
+}
+
+func (m *memoryQueue) BatchEnqueue(ctx context.Context, key string, messages []string) (bool, error) {
+	for _, message := range messages {
+		if _, err := m.Enqueue(ctx, key, message); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+type event struct {
+	N int `json:"n"`
+}
+
+func listen(t *testing.T, bus *signals.Bus, name string) (*signals.BusSignal[event], func() int) {
+	t.Helper()
+	e, err := signals.Event[event](bus, name)
+	assert.NoError(t, err)
+	var mu sync.Mutex
+	count := 0
+	e.AddListener(func(ctx context.Context, payload event) {
+		mu.Lock()
+		count += payload.N
+		mu.Unlock()
+	})
+	return e, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+}
+
+func TestQueue(t *testing.T) {
+	q := &memoryQueue{}
+	a := signals.NewBus(signals.WithTransport(NewQueue(q, "", 10*time.Millisecond)))
+	b := signals.NewBus(signals.WithTransport(NewQueue(q, "", 10*time.Millisecond)))
+	defer b.Close()
+
+	created, countA := listen(t, a, "order.created")
+	_, countB := listen(t, b, "order.created")
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, created.Emit(context.Background(), event{N: 1}))
+	}
+	// 每个事件只由一个进程处理一次
+	assert.Eventually(t, func() bool { return countA()+countB() == 10 }, 2*time.Second, 10*time.Millisecond)
+
+	// 关闭后事件保留在队列中，由其他进程处理
+	assert.NoError(t, a.Close())
+	before := countA()
+	_, err := q.Enqueue(context.Background(), "signals:order.created", `{"payload":{"n":5}}`)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return countB() >= 5 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, before, countA())
+}
+
+func TestRedis(t *testing.T) {
+	rd := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer rd.Close()
+	if err := rd.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("redis unavailable: %v", err)
+	}
+	prefix := fmt.Sprintf("signals-test-%d:", time.Now().UnixNano())
+	a := signals.NewBus(signals.WithTransport(NewRedis(rd, prefix)))
+	b := signals.NewBus(signals.WithTransport(NewRedis(rd, prefix)))
+	defer a.Close()
+	defer b.Close()
+
+	created, countA := listen(t, a, "order.created")
+	_, countB := listen(t, b, "order.created")
+	_, otherB := listen(t, b, "order.paid")
+	assert.NoError(t, created.Emit(context.Background(), event{N: 2}))
+
+	assert.Equal(t, 2, countA())
+	assert.Eventually(t, func() bool { return countB() == 2 }, 2*time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	// 发出事件的进程不会通过通道再收到一次
+	assert.Equal(t, 2, countA())
+	assert.Equal(t, 0, otherB())
+}
+
+func TestMQTTTopic(t *testing.T) {
+	assert.Equal(t, "signals/order/created", NewMQTT(nil, 1, "").topic("order.created"))
+	assert.Equal(t, "app/events/order", NewMQTT(nil, 1, "app/events/").topic("order"))
+}
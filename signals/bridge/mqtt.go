@@ -0,0 +1,61 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sagoo-cloud/nexframe/net/mqttclient"
+)
+
+// MQTT 基于 MQTT 订阅的广播通道，持久化取决于 QoS 和会话设置
+type MQTT struct {
+	client *mqttclient.Client
+	qos    byte
+	prefix string
+	topics []string
+}
+
+// NewMQTT 创建 MQTT 通道，主题为 prefix + 事件名称，事件名称中的 . 替换为 /，
+// prefix 为空时使用 signals/
+func NewMQTT(client *mqttclient.Client, qos byte, prefix string) *MQTT {
+	if prefix == "" {
+		prefix = "signals/"
+	}
+	return &MQTT{client: client, qos: qos, prefix: prefix}
+}
+
+func (m *MQTT) topic(event string) string {
+	return m.prefix + strings.ReplaceAll(event, ".", "/")
+}
+
+func (m *MQTT) Publish(ctx context.Context, topic string, data []byte) error {
+	return m.client.PublishWithOptions(ctx, m.topic(topic), m.qos, data)
+}
+
+func (m *MQTT) Subscribe(topic string, handler func(context.Context, []byte)) error {
+	t := m.topic(topic)
+	err := m.client.RegisterHandler(mqttclient.Handler{
+		Topic: t,
+		Qos:   m.qos,
+		Handle: func(_ paho.Client, msg paho.Message) {
+			handler(context.Background(), msg.Payload())
+		},
+	})
+	if err == nil {
+		m.topics = append(m.topics, t)
+	}
+	return err
+}
+
+// Close 取消订阅，MQTT 客户端由调用方关闭
+func (m *MQTT) Close() error {
+	var err error
+	for _, t := range m.topics {
+		if e := m.client.UnregisterHandler(t); e != nil && err == nil {
+			err = e
+		}
+	}
+	m.topics = nil
+	return err
+}
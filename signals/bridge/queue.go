@@ -0,0 +1,90 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sagoo-cloud/nexframe/servers/queue"
+)
+
+// ErrEnqueueFailed 队列驱动返回入队失败
+var ErrEnqueueFailed = errors.New("enqueue event failed")
+
+// Queue 基于 servers/queue 的队列通道，事件持久化在队列中，每个事件只由一个进程处理
+type Queue struct {
+	q        queue.Queue
+	prefix   string
+	interval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue 创建队列通道，队列名称为 prefix + 事件名称，prefix 为空时使用 signals:。
+// 队列为空时每隔 interval 拉取一次，interval 不大于 0 时为 1 秒
+func NewQueue(q queue.Queue, prefix string, interval time.Duration) *Queue {
+	if prefix == "" {
+		prefix = "signals:"
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{q: q, prefix: prefix, interval: interval, ctx: ctx, cancel: cancel}
+}
+
+// Exclusive 队列语义，事件由任意一个订阅的进程处理
+func (q *Queue) Exclusive() bool {
+	return true
+}
+
+func (q *Queue) Publish(ctx context.Context, topic string, data []byte) error {
+	ok, err := q.q.Enqueue(ctx, q.prefix+topic, string(data))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrEnqueueFailed
+	}
+	return nil
+}
+
+func (q *Queue) Subscribe(topic string, handler func(context.Context, []byte)) error {
+	if q.ctx.Err() != nil {
+		return q.ctx.Err()
+	}
+	q.wg.Add(1)
+	go q.poll(q.prefix+topic, handler)
+	return nil
+}
+
+// poll 拉取并处理事件，处理完成后确认
+func (q *Queue) poll(key string, handler func(context.Context, []byte)) {
+	defer q.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		message, _, token, _, err := q.q.Dequeue(q.ctx, key)
+		if err != nil || message == "" {
+			timer.Reset(q.interval)
+			continue
+		}
+		handler(q.ctx, []byte(message))
+		_, _ = q.q.AckMsg(q.ctx, key, token)
+		timer.Reset(0)
+	}
+}
+
+func (q *Queue) Close() error {
+	q.cancel()
+	q.wg.Wait()
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package bridge 提供 signals.Bus 的跨进程通道：Redis 发布订阅、servers/queue 队列和 MQTT。
+package bridge
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis 基于 Redis 发布订阅的广播通道，事件不持久化，订阅前发布的事件不会收到
+type Redis struct {
+	rd     redis.UniversalClient
+	prefix string
+
+	mu       sync.Mutex
+	pubsub   *redis.PubSub
+	handlers map[string]func(context.Context, []byte)
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// NewRedis 创建 Redis 通道，频道名称为 prefix + 事件名称，prefix 为空时使用 signals:
+func NewRedis(rd redis.UniversalClient, prefix string) *Redis {
+	if prefix == "" {
+		prefix = "signals:"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Redis{
+		rd:       rd,
+		prefix:   prefix,
+		handlers: make(map[string]func(context.Context, []byte)),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+func (r *Redis) Publish(ctx context.Context, topic string, data []byte) error {
+	return r.rd.Publish(ctx, r.prefix+topic, data).Err()
+}
+
+func (r *Redis) Subscribe(topic string, handler func(context.Context, []byte)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx.Err() != nil {
+		return redis.ErrClosed
+	}
+	channel := r.prefix + topic
+	r.handlers[channel] = handler
+	if r.pubsub != nil {
+		return r.pubsub.Subscribe(r.ctx, channel)
+	}
+	r.pubsub = r.rd.Subscribe(r.ctx, channel)
+	// 等待订阅确认，确保返回后发布的事件可以收到
+	if _, err := r.pubsub.Receive(r.ctx); err != nil {
+		_ = r.pubsub.Close()
+		r.pubsub = nil
+		delete(r.handlers, channel)
+		return err
+	}
+	go r.loop(r.pubsub.Channel())
+	return nil
+}
+
+// loop 按顺序处理收到的事件
+func (r *Redis) loop(ch <-chan *redis.Message) {
+	defer close(r.done)
+	for msg := range ch {
+		r.mu.Lock()
+		handler := r.handlers[msg.Channel]
+		r.mu.Unlock()
+		if handler != nil {
+			handler(r.ctx, []byte(msg.Payload))
+		}
+	}
+}
+
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	r.cancel()
+	pubsub := r.pubsub
+	r.mu.Unlock()
+	if pubsub == nil {
+		return nil
+	}
+	err := pubsub.Close()
+	<-r.done
+	return err
+}
@@ -0,0 +1,247 @@
+package signals
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBusClosed  = errors.New("event bus is closed")
+	ErrEventType  = errors.New("event already registered with another payload type")
+	ErrPublish    = errors.New("publish event failed")
+	ErrEventEmpty = errors.New("event name is empty")
+)
+
+// Transport 跨进程传递事件的通道，实现见 signals/bridge 包。
+// 默认为广播语义：每个进程都收到事件，Bus 会跳过本进程发出的事件，因为它们已在本地投递。
+// 如果通道同时实现了 ExclusiveTransport 且 Exclusive 返回 true，则为队列语义：
+// 每个事件只由一个进程处理，Emit 不在本地投递，所有投递都经过通道
+type Transport interface {
+	// Publish 发布事件，topic 为事件名称
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe 订阅事件，收到事件时调用 handler
+	Subscribe(topic string, handler func(ctx context.Context, data []byte)) error
+	// Close 关闭通道，停止接收事件
+	Close() error
+}
+
+// ExclusiveTransport 队列语义的通道，见 Transport
+type ExclusiveTransport interface {
+	Exclusive() bool
+}
+
+// Message 在通道中传递的事件
+type Message struct {
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	Source  string          `json:"source"` // 发出事件的 Bus 实例ID
+	Time    time.Time       `json:"time"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Bus 命名事件总线，同名事件在所有连接到相同通道的进程之间共享。
+// 没有设置通道时只在进程内投递
+type Bus struct {
+	id        string
+	transport Transport
+	exclusive bool
+	onError   func(event string, err error)
+
+	mu     sync.Mutex
+	events map[string]interface{}
+	closed bool
+}
+
+// BusOption 配置 Bus
+type BusOption func(*Bus)
+
+// WithTransport 设置跨进程传递事件的通道
+func WithTransport(transport Transport) BusOption {
+	return func(b *Bus) {
+		b.transport = transport
+	}
+}
+
+// WithInstanceID 设置实例ID，用于识别本进程发出的事件，默认随机生成
+func WithInstanceID(id string) BusOption {
+	return func(b *Bus) {
+		if id != "" {
+			b.id = id
+		}
+	}
+}
+
+// WithErrorHandler 设置处理远程事件时的错误回调，包括解码失败和监听器的错误，默认记录日志
+func WithErrorHandler(fn func(event string, err error)) BusOption {
+	return func(b *Bus) {
+		if fn != nil {
+			b.onError = fn
+		}
+	}
+}
+
+// NewBus 创建事件总线
+func NewBus(opts ...BusOption) *Bus {
+	b := &Bus{
+		id:     randomID(),
+		events: make(map[string]interface{}),
+		onError: func(event string, err error) {
+			slog.Error("handle remote event failed", "event", event, "error", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if t, ok := b.transport.(ExclusiveTransport); ok {
+		b.exclusive = t.Exclusive()
+	}
+	return b
+}
+
+// ID 返回实例ID
+func (b *Bus) ID() string {
+	return b.id
+}
+
+// Close 关闭通道，之后 Emit 返回 ErrBusClosed
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if b.transport != nil {
+		return b.transport.Close()
+	}
+	return nil
+}
+
+func (b *Bus) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// BusSignal 总线上的命名事件，实现 Signal 接口。
+// 监听器只在本进程注册，Emit 的事件会投递到所有进程的监听器
+type BusSignal[T any] struct {
+	Signal[T]
+	name string
+	bus  *Bus
+}
+
+// Event 返回总线上的命名事件，同名事件只创建一次，再次获取时 opts 被忽略。
+// 事件的负载使用JSON编码，同名事件必须使用相同的负载类型，否则返回 ErrEventType。
+// 监听器默认异步调用，见 NewWith
+//
+// 示例:
+//
+//	bus := signals.NewBus(signals.WithTransport(bridge.NewRedis(rdb, "")))
+//	created, err := signals.Event[Order](bus, "order.created")
+//	created.AddListener(func(ctx context.Context, order Order) {
+//		// 任意进程 Emit 的事件都会到达这里
+//	})
+//	err = created.Emit(ctx, order)
+func Event[T any](b *Bus, name string, opts ...Option) (*BusSignal[T], error) {
+	if name == "" {
+		return nil, ErrEventEmpty
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	if v, ok := b.events[name]; ok {
+		e, ok := v.(*BusSignal[T])
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrEventType, name)
+		}
+		return e, nil
+	}
+
+	e := &BusSignal[T]{Signal: NewWith[T](opts...), name: name, bus: b}
+	if b.transport != nil {
+		if err := b.transport.Subscribe(name, e.receive); err != nil {
+			return nil, err
+		}
+	}
+	b.events[name] = e
+	return e, nil
+}
+
+// Name 返回事件名称
+func (e *BusSignal[T]) Name() string {
+	return e.name
+}
+
+// Emit 投递事件。广播通道下先在本进程投递，再发布到通道；
+// 队列通道下只发布到通道。返回的错误包括本地监听器的错误和发布失败的 ErrPublish
+func (e *BusSignal[T]) Emit(ctx context.Context, payload T) error {
+	b := e.bus
+	if b.isClosed() {
+		return ErrBusClosed
+	}
+	var errs []error
+	if b.transport == nil || !b.exclusive {
+		errs = append(errs, e.Signal.Emit(ctx, payload))
+	}
+	if b.transport != nil {
+		errs = append(errs, e.publish(ctx, payload))
+	}
+	return errors.Join(errs...)
+}
+
+func (e *BusSignal[T]) publish(ctx context.Context, payload T) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPublish, e.name, err)
+	}
+	data, err := json.Marshal(Message{
+		ID:      randomID(),
+		Event:   e.name,
+		Source:  e.bus.id,
+		Time:    time.Now(),
+		Payload: raw,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPublish, e.name, err)
+	}
+	if err = e.bus.transport.Publish(ctx, e.name, data); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrPublish, e.name, err)
+	}
+	return nil
+}
+
+// receive 处理通道收到的事件
+func (e *BusSignal[T]) receive(ctx context.Context, data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		e.bus.onError(e.name, fmt.Errorf("decode message: %w", err))
+		return
+	}
+	if !e.bus.exclusive && msg.Source == e.bus.id {
+		return
+	}
+	var payload T
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		e.bus.onError(e.name, fmt.Errorf("decode payload of message %s: %w", msg.ID, err))
+		return
+	}
+	if err := e.Signal.Emit(ctx, payload); err != nil {
+		e.bus.onError(e.name, fmt.Errorf("message %s: %w", msg.ID, err))
+	}
+}
+
+func randomID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,180 @@
+package signals
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryHub 进程内模拟的通道，exclusive 为 true 时每个事件只投递给一个订阅者
+type memoryHub struct {
+	mu        sync.Mutex
+	exclusive bool
+	subs      map[string][]func(context.Context, []byte)
+	next      int
+}
+
+type memoryTransport struct {
+	hub    *memoryHub
+	failed bool
+}
+
+func (h *memoryHub) transport() *memoryTransport {
+	return &memoryTransport{hub: h}
+}
+
+func (m *memoryTransport) Publish(ctx context.Context, topic string, data []byte) error {
+	if m.failed {
+		return errors.New("broker unavailable")
+	}
+	m.hub.mu.Lock()
+	subs := append([]func(context.Context, []byte){}, m.hub.subs[topic]...)
+	if m.hub.exclusive && len(subs) > 0 {
+		subs = subs[m.hub.next%len(subs) : m.hub.next%len(subs)+1]
+		m.hub.next++
+	}
+	m.hub.mu.Unlock()
+	for _, sub := range subs {
+		sub(ctx, data)
+	}
+	return nil
+}
+
+func (m *memoryTransport) Subscribe(topic string, handler func(context.Context, []byte)) error {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	if m.hub.subs == nil {
+		m.hub.subs = make(map[string][]func(context.Context, []byte))
+	}
+	m.hub.subs[topic] = append(m.hub.subs[topic], handler)
+	return nil
+}
+
+func (m *memoryTransport) Close() error {
+	return nil
+}
+
+type exclusiveTransport struct {
+	*memoryTransport
+}
+
+func (exclusiveTransport) Exclusive() bool {
+	return true
+}
+
+type order struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func collect(t *testing.T, b *Bus, name string) (*BusSignal[order], func() []order) {
+	t.Helper()
+	e, err := Event[order](b, name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var mu sync.Mutex
+	var got []order
+	e.AddListener(func(ctx context.Context, o order) {
+		mu.Lock()
+		got = append(got, o)
+		mu.Unlock()
+	})
+	return e, func() []order {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]order(nil), got...)
+	}
+}
+
+func TestBusBroadcast(t *testing.T) {
+	hub := &memoryHub{}
+	a := NewBus(WithTransport(hub.transport()), WithInstanceID("a"))
+	b := NewBus(WithTransport(hub.transport()), WithInstanceID("b"))
+	defer a.Close()
+	defer b.Close()
+
+	created, gotA := collect(t, a, "order.created")
+	_, gotB := collect(t, b, "order.created")
+
+	if err := created.Emit(context.Background(), order{ID: "o-1", Amount: 3}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// 本进程只投递一次，另一个进程通过通道收到
+	if got := gotA(); len(got) != 1 || got[0].ID != "o-1" {
+		t.Errorf("Expected one local delivery, got %v", got)
+	}
+	if got := gotB(); len(got) != 1 || got[0].Amount != 3 {
+		t.Errorf("Expected one remote delivery, got %v", got)
+	}
+
+	again, err := Event[order](a, "order.created")
+	if err != nil || again != created {
+		t.Errorf("Expected the same event, got %v %v", again, err)
+	}
+	if _, err = Event[string](a, "order.created"); !errors.Is(err, ErrEventType) {
+		t.Errorf("Expected ErrEventType, got %v", err)
+	}
+	if _, err = Event[order](a, ""); !errors.Is(err, ErrEventEmpty) {
+		t.Errorf("Expected ErrEventEmpty, got %v", err)
+	}
+}
+
+func TestBusExclusive(t *testing.T) {
+	hub := &memoryHub{exclusive: true}
+	a := NewBus(WithTransport(exclusiveTransport{hub.transport()}))
+	b := NewBus(WithTransport(exclusiveTransport{hub.transport()}))
+
+	created, gotA := collect(t, a, "order.created")
+	_, gotB := collect(t, b, "order.created")
+	for i := 0; i < 4; i++ {
+		if err := created.Emit(context.Background(), order{Amount: i}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// 每个事件只处理一次，发出事件的进程也可能处理
+	if len(gotA()) != 2 || len(gotB()) != 2 {
+		t.Errorf("Expected events to be shared, got %v and %v", gotA(), gotB())
+	}
+}
+
+func TestBusErrors(t *testing.T) {
+	hub := &memoryHub{}
+	transport := hub.transport()
+	var mu sync.Mutex
+	var remoteErrs []error
+	a := NewBus(WithTransport(transport))
+	b := NewBus(WithTransport(hub.transport()), WithErrorHandler(func(event string, err error) {
+		mu.Lock()
+		remoteErrs = append(remoteErrs, err)
+		mu.Unlock()
+	}))
+
+	created, err := Event[order](a, "order.created")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	remote, _ := Event[order](b, "order.created", WithTimeout(10*time.Millisecond))
+	remote.AddListener(func(ctx context.Context, o order) { <-ctx.Done() })
+
+	if err = created.Emit(context.Background(), order{ID: "o-2"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mu.Lock()
+	if len(remoteErrs) != 1 || !errors.Is(remoteErrs[0], context.DeadlineExceeded) {
+		t.Errorf("Expected remote listener timeout, got %v", remoteErrs)
+	}
+	mu.Unlock()
+
+	transport.failed = true
+	if err = created.Emit(context.Background(), order{ID: "o-3"}); !errors.Is(err, ErrPublish) {
+		t.Errorf("Expected ErrPublish, got %v", err)
+	}
+
+	_ = a.Close()
+	if err = created.Emit(context.Background(), order{}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("Expected ErrBusClosed, got %v", err)
+	}
+}
@@ -1,5 +1,7 @@
 package signals
 
+import "time"
+
 // NewSync 创建一个新的信号，可用于同步发射和监听事件。
 //
 // 示例:
@@ -10,12 +12,16 @@ package signals
 //	    // ...
 //	})
 //	signal.Emit(context.Background(), 42)
+//
+// 监听器默认不限制执行时间，需要设置时使用 NewSyncWith。
 func NewSync[T any]() Signal[T] {
+	return NewSyncWith[T]()
+}
+
+// NewSyncWith 创建同步信号，可通过 WithTimeout 设置监听器的默认超时时间。
+func NewSyncWith[T any](opts ...Option) Signal[T] {
 	s := &SyncSignal[T]{
-		BaseSignal: BaseSignal[T]{
-			subscribers:    make([]keyedListener[T], 0),
-			subscribersMap: make(map[string]SignalListener[T]),
-		},
+		BaseSignal: newBaseSignal[T](0, opts),
 	}
 	return s
 }
@@ -30,12 +36,20 @@ func NewSync[T any]() Signal[T] {
 //	    // ...
 //	})
 //	signal.Emit(context.Background(), 42)
+//
+// 每个监听器默认最多执行 100 毫秒，需要修改时使用 NewWith。
 func New[T any]() Signal[T] {
+	return NewWith[T]()
+}
+
+// NewWith 创建异步信号，可通过 WithTimeout 修改监听器的默认超时时间。
+//
+// 示例:
+//
+//	signal := signals.NewWith[int](signals.WithTimeout(5 * time.Second))
+func NewWith[T any](opts ...Option) Signal[T] {
 	s := &AsyncSignal[T]{
-		BaseSignal: BaseSignal[T]{
-			subscribers:    make([]keyedListener[T], 0),
-			subscribersMap: make(map[string]SignalListener[T]),
-		},
+		BaseSignal: newBaseSignal[T](100*time.Millisecond, opts),
 	}
 	return s
 }
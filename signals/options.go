@@ -0,0 +1,81 @@
+package signals
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrListenerPanicked 监听器发生 panic
+var ErrListenerPanicked = errors.New("listener panicked")
+
+// ListenerError 单个监听器的错误，Emit 返回的错误由所有失败监听器的 ListenerError 组成，
+// 可通过 errors.As 获取，或通过 errors.Is 判断 context.DeadlineExceeded、ErrListenerPanicked 等原因
+type ListenerError struct {
+	Key string // 监听器的键，添加时没有指定键则为空
+	Err error
+}
+
+func (e *ListenerError) Error() string {
+	if e.Key == "" {
+		return e.Err.Error()
+	}
+	return "listener " + e.Key + ": " + e.Err.Error()
+}
+
+func (e *ListenerError) Unwrap() error {
+	return e.Err
+}
+
+// signalOptions 信号的配置
+type signalOptions struct {
+	timeout time.Duration
+}
+
+// Option 配置信号
+type Option func(*signalOptions)
+
+// WithTimeout 设置每个监听器的默认超时时间，为 0 时不限制，用于 NewWith 和 NewSyncWith。
+// 异步信号默认 100 毫秒，同步信号默认不限制
+func WithTimeout(d time.Duration) Option {
+	return func(o *signalOptions) {
+		if d >= 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// listenerOptions 监听器的配置
+type listenerOptions struct {
+	key        string
+	priority   int
+	timeout    time.Duration
+	hasTimeout bool
+}
+
+// ListenerOption 配置监听器
+type ListenerOption func(*listenerOptions)
+
+// WithKey 设置监听器的键，用于移除监听器，相同的键只能添加一次
+func WithKey(key string) ListenerOption {
+	return func(o *listenerOptions) {
+		o.key = key
+	}
+}
+
+// WithPriority 设置监听器的优先级，数值越大越先执行，默认为 0。
+// 同步信号按优先级依次调用；异步信号按优先级分批调用，同一优先级的监听器并发执行，
+// 上一批全部结束后才开始下一批
+func WithPriority(priority int) ListenerOption {
+	return func(o *listenerOptions) {
+		o.priority = priority
+	}
+}
+
+// WithListenerTimeout 设置监听器的超时时间，覆盖信号的默认值，为 0 时不限制
+func WithListenerTimeout(d time.Duration) ListenerOption {
+	return func(o *listenerOptions) {
+		if d >= 0 {
+			o.timeout, o.hasTimeout = d, true
+		}
+	}
+}
@@ -8,6 +8,7 @@ type Signal[T any] interface {
 	//
 	// 如果上下文有截止日期或可取消属性，监听器必须遵守它。
 	// 如果信号是异步的（默认），监听器将在单独的 goroutine 中被调用。
+	// 所有监听器都会被调用，返回的错误由每个失败监听器的 *ListenerError 组成。
 	//
 	// 示例：
 	// signal := signals.New[int]()
@@ -92,3 +93,22 @@ type Signal[T any] interface {
 	// fmt.Println("信号是否为空？", signal.IsEmpty()) // 应打印 false
 	IsEmpty() bool
 }
+
+// ListenerOptioner 是支持监听器选项的信号实现的可选接口，New、NewSync 等返回的信号都实现了该接口。
+//
+// 示例：
+//
+//	signal := signals.New[int]()
+//	signal.(signals.ListenerOptioner[int]).AddListenerWithOptions(func(ctx context.Context, payload int) {
+//		// 监听器实现
+//	}, signals.WithKey("audit"), signals.WithPriority(10))
+type ListenerOptioner[T any] interface {
+	// AddListenerWithOptions 添加监听器，可以设置键、优先级和超时时间。
+	// 返回添加后的订阅者数量，监听器为 nil 或键已存在时返回 -1。
+	AddListenerWithOptions(handler SignalListener[T], opts ...ListenerOption) int
+}
+
+var (
+	_ ListenerOptioner[int] = (*SyncSignal[int])(nil)
+	_ ListenerOptioner[int] = (*AsyncSignal[int])(nil)
+)
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"sync"
-	"time"
 )
 
 // AsyncSignal 是实现 Signal 接口的结构体。
@@ -23,9 +22,10 @@ type AsyncSignal[T any] struct {
 // 如果上下文有截止日期或可取消属性，监听器必须遵守它。
 // 这意味着当上下文被取消时，监听器应停止处理。
 // 在发射时，它在单独的 goroutine 中调用监听器，所以监听器是异步调用的。
-// 然而，它在返回之前等待所有监听器完成。如果你不想
+// 然而，它在返回之前等待所有监听器完成或超时。如果你不想
 // 等待监听器完成，你可以在单独的 goroutine 中调用 Emit 方法。
-// 另外，你必须知道 Emit 不保证发射值的类型安全。
+// 设置了不同优先级时，按优先级从高到低分批调用，同一优先级的监听器并发执行。
+// 返回的错误由所有超时、panic 的监听器的 *ListenerError 组成，上下文取消后不再调用剩余的批次。
 //
 // 示例:
 //
@@ -38,66 +38,35 @@ type AsyncSignal[T any] struct {
 //	signal.Emit(context.Background(), "Hello, world!")
 func (s *AsyncSignal[T]) Emit(ctx context.Context, payload T) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(s.subscribers))
-
-	for _, sub := range s.subscribers {
-		if sub.listener == nil {
-			continue
+	subscribers := s.snapshot()
+	s.mu.Unlock()
+
+	var errs []error
+	for start := 0; start < len(subscribers); {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		end := start + 1
+		for end < len(subscribers) && subscribers[end].priority == subscribers[start].priority {
+			end++
 		}
 
-		wg.Add(1)
-		go func(listener SignalListener[T]) {
-			defer wg.Done()
-			defer func() {
-				if r := recover(); r != nil {
-					errChan <- errors.New("listener panicked")
-				}
-			}()
-
-			listenerCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
-			defer cancel()
-
-			done := make(chan struct{})
-			go func() {
-				defer func() {
-					if r := recover(); r != nil {
-						errChan <- errors.New("listener panicked")
-					}
-					close(done)
-				}()
-				listener(listenerCtx, payload)
-			}()
-
-			select {
-			case <-listenerCtx.Done():
-				errChan <- listenerCtx.Err()
-			case <-done:
-				// 监听器正常完成或 panic 被捕获
-			}
-		}(sub.listener)
-	}
-
-	go func() {
-		wg.Wait()
-		close(errChan)
-	}()
-
-	// 等待所有监听器完成或上下文取消
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case err := <-errChan:
-		if err != nil {
-			return err
+		batch := subscribers[start:end]
+		batchErrs := make([]error, len(batch))
+		var wg sync.WaitGroup
+		for i, sub := range batch {
+			wg.Add(1)
+			go func(i int, sub keyedListener[T]) {
+				defer wg.Done()
+				batchErrs[i] = call(ctx, sub, payload, true)
+			}(i, sub)
 		}
-	case <-time.After(150 * time.Millisecond):
-		return errors.New("emit timeout")
+		wg.Wait()
+		errs = append(errs, batchErrs...)
+		start = end
 	}
-
-	return nil
+	return errors.Join(errs...)
 }
 
 // AddListener 添加了并发安全的实现
@@ -134,3 +103,10 @@ func (s *AsyncSignal[T]) IsEmpty() bool {
 	defer s.mu.Unlock()
 	return s.BaseSignal.IsEmpty()
 }
+
+// AddListenerWithOptions 添加了并发安全的实现
+func (s *AsyncSignal[T]) AddListenerWithOptions(listener SignalListener[T], opts ...ListenerOption) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.BaseSignal.AddListenerWithOptions(listener, opts...)
+}
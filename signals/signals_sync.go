@@ -23,7 +23,9 @@ type SyncSignal[T any] struct {
 // 如果上下文有截止日期或可取消属性，监听器必须遵守它。
 // 这意味着当上下文被取消时，监听器应停止处理。
 // 与 AsyncSignal 的 Emit 方法不同，此方法不会在单独的 goroutine 中调用监听器，
-// 因此监听器是同步调用的，一个接一个。
+// 因此监听器是同步调用的，按优先级从高到低一个接一个。
+// 某个监听器 panic 或超时不影响后续监听器，返回的错误由所有失败监听器的 *ListenerError 组成；
+// 上下文取消后不再调用剩余的监听器。
 //
 // 示例:
 //
@@ -39,41 +41,18 @@ type SyncSignal[T any] struct {
 //	}
 func (s *SyncSignal[T]) Emit(ctx context.Context, payload T) error {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
+	subscribers := s.snapshot()
+	s.mu.RUnlock()
 
-	var emitErr error
-	for _, sub := range s.subscribers {
-		if sub.listener != nil {
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						emitErr = errors.New("listener panicked")
-					}
-				}()
-				select {
-				case <-ctx.Done():
-					emitErr = ctx.Err()
-					return
-				default:
-					sub.listener(ctx, payload)
-				}
-			}()
-			if emitErr != nil {
-				break
-			}
+	var errs []error
+	for _, sub := range subscribers {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
 		}
+		errs = append(errs, call(ctx, sub, payload, false))
 	}
-
-	if emitErr != nil {
-		return emitErr
-	}
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-		return nil
-	}
+	return errors.Join(errs...)
 }
 
 // AddListener 添加了并发安全的实现
@@ -110,3 +89,10 @@ func (s *SyncSignal[T]) IsEmpty() bool {
 	defer s.mu.RUnlock()
 	return s.BaseSignal.IsEmpty()
 }
+
+// AddListenerWithOptions 添加了并发安全的实现
+func (s *SyncSignal[T]) AddListenerWithOptions(listener SignalListener[T], opts ...ListenerOption) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.BaseSignal.AddListenerWithOptions(listener, opts...)
+}
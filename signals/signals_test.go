@@ -2,6 +2,7 @@ package signals
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -259,3 +260,85 @@ func TestErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+// TestListenerOptions 测试监听器的优先级、超时和多错误
+func TestListenerOptions(t *testing.T) {
+	t.Run("Priority order", func(t *testing.T) {
+		for _, signal := range []Signal[int]{NewSync[int](), New[int]()} {
+			var mu sync.Mutex
+			var order []string
+			record := func(name string) SignalListener[int] {
+				return func(ctx context.Context, payload int) {
+					mu.Lock()
+					order = append(order, name)
+					mu.Unlock()
+				}
+			}
+			signal.AddListener(record("default"))
+			o := signal.(ListenerOptioner[int])
+			o.AddListenerWithOptions(record("low"), WithPriority(-1))
+			o.AddListenerWithOptions(record("high"), WithPriority(10), WithKey("high"))
+			o.AddListenerWithOptions(record("high2"), WithPriority(10))
+			if n := o.AddListenerWithOptions(record("dup"), WithKey("high")); n != -1 {
+				t.Errorf("Expected -1 for duplicate key, got %d", n)
+			}
+
+			if err := signal.Emit(context.Background(), 1); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := fmt.Sprint([]string{"high", "high2", "default", "low"})
+			if fmt.Sprint(order) != expected {
+				// 异步信号同一优先级内的顺序不确定
+				if _, ok := signal.(*AsyncSignal[int]); !ok || order[2] != "default" || order[3] != "low" {
+					t.Errorf("Expected order %s, got %v", expected, order)
+				}
+			}
+		}
+	})
+
+	t.Run("Configurable timeout and multi error", func(t *testing.T) {
+		signal := NewWith[int](WithTimeout(time.Second))
+		signal.AddListener(func(ctx context.Context, payload int) {
+			time.Sleep(150 * time.Millisecond) // 超过旧的 100 毫秒限制
+		})
+		if err := signal.Emit(context.Background(), 1); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		o := signal.(ListenerOptioner[int])
+		o.AddListenerWithOptions(func(ctx context.Context, payload int) {
+			<-ctx.Done()
+		}, WithKey("slow"), WithListenerTimeout(20*time.Millisecond))
+		o.AddListenerWithOptions(func(ctx context.Context, payload int) {
+			panic("boom")
+		}, WithKey("broken"))
+
+		err := signal.Emit(context.Background(), 1)
+		if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrListenerPanicked) {
+			t.Fatalf("Expected timeout and panic errors, got %v", err)
+		}
+		var keys []string
+		for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+			var le *ListenerError
+			if errors.As(e, &le) {
+				keys = append(keys, le.Key)
+			}
+		}
+		if len(keys) != 2 {
+			t.Errorf("Expected 2 listener errors, got %v", keys)
+		}
+	})
+
+	t.Run("Sync continues after panic", func(t *testing.T) {
+		signal := NewSync[int]()
+		called := false
+		signal.AddListener(func(ctx context.Context, payload int) { panic("boom") })
+		signal.AddListener(func(ctx context.Context, payload int) { called = true })
+		if err := signal.Emit(context.Background(), 1); !errors.Is(err, ErrListenerPanicked) {
+			t.Errorf("Expected panic error, got %v", err)
+		}
+		if !called {
+			t.Errorf("Expected second listener to be called")
+		}
+	})
+}
@@ -190,3 +190,27 @@ func TestGetTimeout(t *testing.T) {
 		t.Errorf("Expected default timeout 30s, got %v", timeout)
 	}
 }
+
+// TestOriginAllowed 测试 Origin 允许列表的匹配
+func TestOriginAllowed(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{[]string{"app.example.com"}, "", true},
+		{[]string{"app.example.com"}, "https://app.example.com", true},
+		{[]string{"app.example.com"}, "https://evil.com", false},
+		{[]string{"https://app.example.com"}, "https://app.example.com", true},
+		{[]string{"https://app.example.com"}, "http://app.example.com", false},
+		{[]string{"*.example.com"}, "https://a.b.example.com", true},
+		{[]string{"*.example.com"}, "https://example.org", false},
+		{[]string{"*"}, "https://any.host", true},
+		{[]string{"app.example.com"}, "://bad", false},
+	}
+	for _, tt := range tests {
+		if got := OriginAllowed(tt.allowed, tt.origin); got != tt.want {
+			t.Errorf("OriginAllowed(%v, %q) = %v, want %v", tt.allowed, tt.origin, got, tt.want)
+		}
+	}
+}
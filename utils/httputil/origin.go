@@ -0,0 +1,47 @@
+package httputil
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OriginAllowed 校验 Origin 是否在允许列表中，不携带 Origin 的请求（非浏览器客户端）始终允许。
+// 允许列表支持完整的来源（https://example.com）、主机名（example.com）、
+// 通配子域名（*.example.com）以及 "*"
+func OriginAllowed(allowed []string, origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range allowed {
+		if matchOrigin(pattern, origin, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// OriginChecker 根据允许列表生成 Origin 校验函数，可用作 websocket.Upgrader.CheckOrigin
+func OriginChecker(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		return OriginAllowed(allowed, r.Header.Get("Origin"))
+	}
+}
+
+func matchOrigin(pattern, origin, host string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.Contains(pattern, "://"):
+		return strings.EqualFold(pattern, origin)
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, strings.ToLower(pattern[1:]))
+	default:
+		return strings.EqualFold(pattern, host)
+	}
+}
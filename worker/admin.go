@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sagoo-cloud/nexframe/contracts"
+	"github.com/sagoo-cloud/nexframe/g"
+)
+
+// Handler 返回队列和任务的管理接口，可通过 APIFramework.BindPrefixHandler 挂载到任意前缀下，
+// 请求先经过 authorize 鉴权（见 contracts.Authorized）：
+//
+//	GET    /queues                                  队列统计
+//	GET    /queues/{queue}                          单个队列的统计
+//	GET    /queues/{queue}/tasks?state=pending&page=1&size=20
+//	                                                按状态列出任务，state 为 pending、active、scheduled、retry、archived 或 completed
+//	GET    /queues/{queue}/tasks/{id}               任务详情
+//	POST   /queues/{queue}/tasks/{id}/retry         立即执行
+//	POST   /queues/{queue}/tasks/{id}/archive       归档
+//	DELETE /queues/{queue}/tasks/{id}               删除
+//	GET    /crons?group=                            周期任务
+//	GET    /callbacks?limit=20                      最近的回调记录
+func (wk *Worker) Handler(authorize func(r *http.Request) error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /queues", func(w http.ResponseWriter, r *http.Request) {
+		queues, err := wk.Queues(r.Context())
+		respond(w, r, queues, err)
+	})
+	mux.HandleFunc("GET /queues/{queue}", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := wk.Queue(r.Context(), r.PathValue("queue"))
+		respond(w, r, stats, err)
+	})
+	mux.HandleFunc("GET /queues/{queue}/tasks", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		state := TaskState(query.Get("state"))
+		if state == "" {
+			state = TaskPending
+		}
+		page, ok := intQuery(w, r, "page", 1)
+		if !ok {
+			return
+		}
+		size, ok := intQuery(w, r, "size", 20)
+		if !ok {
+			return
+		}
+		tasks, err := wk.Tasks(r.Context(), r.PathValue("queue"), state, page, size)
+		respond(w, r, tasks, err)
+	})
+	mux.HandleFunc("GET /queues/{queue}/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		task, err := wk.Task(r.Context(), r.PathValue("queue"), r.PathValue("id"))
+		respond(w, r, task, err)
+	})
+	mux.HandleFunc("POST /queues/{queue}/tasks/{id}/retry", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, nil, wk.RetryTask(r.Context(), r.PathValue("queue"), r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /queues/{queue}/tasks/{id}/archive", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, nil, wk.ArchiveTask(r.Context(), r.PathValue("queue"), r.PathValue("id")))
+	})
+	mux.HandleFunc("DELETE /queues/{queue}/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, nil, wk.DeleteTask(r.Context(), r.PathValue("queue"), r.PathValue("id")))
+	})
+	mux.HandleFunc("GET /crons", func(w http.ResponseWriter, r *http.Request) {
+		crons, err := wk.CronTasksByGroup(r.Context(), r.URL.Query().Get("group"))
+		respond(w, r, crons, err)
+	})
+	mux.HandleFunc("GET /callbacks", func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := intQuery(w, r, "limit", 20)
+		if !ok {
+			return
+		}
+		deliveries, err := wk.CallbackDeliveries(r.Context(), limit)
+		respond(w, r, deliveries, err)
+	})
+
+	return contracts.Authorized(authorize, mux)
+}
+
+// intQuery 读取正整数查询参数，参数无效时响应 400 并返回 false
+func intQuery(w http.ResponseWriter, r *http.Request, name string, def int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		contracts.JSONStatusResp(w, http.StatusBadRequest, "invalid "+name, nil)
+		return 0, false
+	}
+	return n, true
+}
+
+func respond(w http.ResponseWriter, r *http.Request, data interface{}, err error) {
+	switch {
+	case err == nil:
+		contracts.JSONStatusResp(w, http.StatusOK, "ok", data)
+	case errors.Is(err, ErrQueueNotFound), errors.Is(err, ErrTaskNotFound):
+		contracts.JSONStatusResp(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, ErrTaskStateInvalid):
+		contracts.JSONStatusResp(w, http.StatusBadRequest, err.Error(), nil)
+	default:
+		g.Log.Errorf(r.Context(), "worker admin request failed: %v", err)
+		contracts.JSONStatusResp(w, http.StatusInternalServerError, err.Error(), nil)
+	}
+}
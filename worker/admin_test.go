@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// newInspectWorker 创建只用于查看的 Worker，不启动任务处理，没有可用的 Redis 时跳过
+func newInspectWorker(t *testing.T) *Worker {
+	t.Helper()
+	ops := getOptionsOrSetDefault(nil)
+	WithRedisUri("redis://127.0.0.1:6379/0")(ops)
+	WithGroup(fmt.Sprintf("inspect-%d", time.Now().UnixNano()))(ops)
+	WithRedisPeriodKey(ops.group + ":period")(ops)
+	rs, err := redisConnOpt(ops)
+	assert.NoError(t, err)
+	rd := rs.MakeRedisClient().(redis.UniversalClient)
+	if err = rd.Ping(context.Background()).Err(); err != nil {
+		_ = rd.Close()
+		t.Skipf("redis unavailable: %v", err)
+	}
+	wk := &Worker{
+		ops:       *ops,
+		redis:     rd,
+		redisOpt:  rs,
+		client:    asynq.NewClient(rs),
+		inspector: asynq.NewInspector(rs),
+		hooks:     &runHooks{},
+		callbacks: newCallbackSender(ops, rd),
+	}
+	t.Cleanup(func() {
+		_ = wk.inspector.DeleteQueue(ops.group, true)
+		rd.Del(context.Background(), ops.redisPeriodKey)
+		_ = wk.client.Close()
+		_ = wk.inspector.Close()
+		_ = rd.Close()
+	})
+	return wk
+}
+
+func serve(h http.Handler, method, target string) (int, map[string]interface{}) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	var res map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func TestAdminAuthorize(t *testing.T) {
+	wk := &Worker{}
+	h := wk.Handler(func(r *http.Request) error {
+		if r.Header.Get("Authorization") == "" {
+			return errors.New("unauthorized")
+		}
+		return nil
+	})
+	code, res := serve(h, http.MethodGet, "/queues")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, float64(http.StatusUnauthorized), res["code"])
+
+	h = wk.Handler(nil)
+	code, _ = serve(h, http.MethodGet, "/queues/q/tasks?state=unknown")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = serve(h, http.MethodGet, "/queues/q/tasks?page=0")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestInspect(t *testing.T) {
+	wk := newInspectWorker(t)
+	ctx := context.Background()
+	queue := wk.ops.group
+	_, err := wk.client.Enqueue(asynq.NewTask(queue+".once", []byte("a")), asynq.Queue(queue), asynq.TaskID("t-1"))
+	assert.NoError(t, err)
+	_, err = wk.client.Enqueue(asynq.NewTask(queue+".once", []byte("b")), asynq.Queue(queue), asynq.TaskID("t-2"),
+		asynq.ProcessIn(time.Hour))
+	assert.NoError(t, err)
+
+	stats, err := wk.Queue(ctx, queue)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Pending)
+	assert.Equal(t, 1, stats.Scheduled)
+	queues, err := wk.Queues(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, queues)
+
+	tasks, err := wk.Tasks(ctx, queue, TaskScheduled, 1, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "t-2", tasks[0].ID)
+		assert.Equal(t, TaskScheduled, tasks[0].State)
+	}
+
+	assert.NoError(t, wk.RetryTask(ctx, queue, "t-2"))
+	task, err := wk.Task(ctx, queue, "t-2")
+	assert.NoError(t, err)
+	assert.Equal(t, TaskPending, task.State)
+	assert.NoError(t, wk.ArchiveTask(ctx, queue, "t-2"))
+	task, err = wk.Task(ctx, queue, "t-2")
+	assert.NoError(t, err)
+	assert.Equal(t, TaskArchived, task.State)
+
+	h := wk.Handler(nil)
+	code, res := serve(h, http.MethodGet, "/queues/"+queue+"/tasks?state=archived")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, res["data"], 1)
+	code, _ = serve(h, http.MethodDelete, "/queues/"+queue+"/tasks/t-2")
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = serve(h, http.MethodGet, "/queues/"+queue+"/tasks/t-2")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = serve(h, http.MethodGet, "/queues/missing-queue")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	assert.NoError(t, wk.Cron(WithRunUuid("c-1"), WithRunGroup("reports"), WithRunExpr("0 * * * *")))
+	assert.NoError(t, wk.Cron(WithRunUuid("c-2"), WithRunGroup("billing"), WithRunExpr("0 * * * *")))
+	crons, err := wk.CronTasksByGroup(ctx, "reports")
+	assert.NoError(t, err)
+	if assert.Len(t, crons, 1) {
+		assert.Equal(t, "c-1", crons[0].Uid)
+	}
+	code, res = serve(h, http.MethodGet, "/crons")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, res["data"], 2)
+}
@@ -0,0 +1,231 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/auth"
+	"github.com/sagoo-cloud/nexframe/g"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
+)
+
+// 回调请求头
+const (
+	CallbackHeaderDelivery  = "X-Callback-Delivery"  // 投递ID，同一次投递的重试使用相同的ID，可用于去重
+	CallbackHeaderAttempt   = "X-Callback-Attempt"   // 第几次尝试，从 1 开始
+	CallbackHeaderTimestamp = "X-Callback-Timestamp" // 发送时的Unix时间戳（秒）
+	CallbackHeaderSignature = "X-Callback-Signature" // HMAC-SHA256(timestamp + "." + body)，十六进制
+)
+
+var (
+	ErrCallbackSignature = errors.New("callback signature is invalid")
+	ErrCallbackExpired   = errors.New("callback timestamp is out of tolerance")
+)
+
+// CallbackDelivery 一次回调请求的记录
+type CallbackDelivery struct {
+	ID         string        `json:"id"`
+	TaskID     string        `json:"taskId"`
+	Group      string        `json:"group"`
+	Uid        string        `json:"uid"`
+	URL        string        `json:"url"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Response   string        `json:"response,omitempty"` // 响应内容，最多保留 512 字节
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Time       time.Time     `json:"time"`
+}
+
+// Succeeded 请求是否成功
+func (d CallbackDelivery) Succeeded() bool {
+	return d.Error == ""
+}
+
+// callbackSender 将任务参数投递到回调地址，每个地址独立重试，
+// 任务重试时跳过已投递成功的地址
+type callbackSender struct {
+	ops    *Options
+	client *http.Client
+	redis  redis.UniversalClient
+}
+
+func newCallbackSender(ops *Options, rd redis.UniversalClient) *callbackSender {
+	return &callbackSender{
+		ops:    ops,
+		client: &http.Client{Timeout: time.Duration(ops.callbackTimeout) * time.Second},
+		redis:  rd,
+	}
+}
+
+func (c *callbackSender) logKey() string {
+	return c.ops.group + ":callback:deliveries"
+}
+
+func (c *callbackSender) doneKey(taskID string) string {
+	return c.ops.group + ":callback:done:" + taskID
+}
+
+// send 投递到所有回调地址，有地址最终失败时返回错误，由任务的重试策略决定是否重新投递
+func (c *callbackSender) send(ctx context.Context, payload Payload) error {
+	body := []byte(payload.String())
+	taskID, _ := asynq.GetTaskID(ctx)
+	retried, _ := asynq.GetRetryCount(ctx)
+
+	var done map[string]bool
+	if taskID != "" {
+		key := c.doneKey(taskID)
+		if retried == 0 {
+			// 周期任务每次执行使用相同的任务ID，首次执行时清除上一次的投递状态
+			c.redis.Del(ctx, key)
+		} else if urls, err := c.redis.SMembers(ctx, key).Result(); err == nil {
+			done = make(map[string]bool, len(urls))
+			for _, url := range urls {
+				done[url] = true
+			}
+		}
+	}
+
+	var errs []error
+	for _, url := range c.ops.callbacks {
+		if done[url] {
+			continue
+		}
+		base := CallbackDelivery{ID: guid.S(), TaskID: taskID, Group: payload.Group, Uid: payload.Uid, URL: url}
+		if err := c.deliver(ctx, base, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", url, err))
+			continue
+		}
+		if taskID != "" {
+			key := c.doneKey(taskID)
+			c.redis.SAdd(ctx, key, url)
+			c.redis.Expire(ctx, key, 24*time.Hour)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver 投递到单个地址，失败时按退避策略重试，4xx（408、429 除外）不重试
+func (c *callbackSender) deliver(ctx context.Context, d CallbackDelivery, body []byte) error {
+	var err error
+	for attempt := 0; attempt <= c.ops.callbackRetry; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(c.ops.callbackBackoff(attempt-1, err))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-timer.C:
+			}
+		}
+		d.Attempt = attempt + 1
+		var retry bool
+		retry, err = c.post(ctx, &d, body)
+		c.record(ctx, d)
+		if err == nil {
+			return nil
+		}
+		g.Log.Debugf(ctx, "callback %s attempt %d failed: %v", d.URL, d.Attempt, err)
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post 发送一次请求，返回失败后是否可以重试
+func (c *callbackSender) post(ctx context.Context, d *CallbackDelivery, body []byte) (retry bool, err error) {
+	d.Time = time.Now()
+	d.StatusCode, d.Response, d.Error = 0, "", ""
+	defer func() {
+		d.Duration = time.Since(d.Time)
+		if err != nil {
+			d.Error = err.Error()
+		}
+	}()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	timestamp := strconv.FormatInt(d.Time.Unix(), 10)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(CallbackHeaderDelivery, d.ID)
+	r.Header.Set(CallbackHeaderAttempt, strconv.Itoa(d.Attempt))
+	r.Header.Set(CallbackHeaderTimestamp, timestamp)
+	if c.ops.callbackSecret != "" {
+		r.Header.Set(CallbackHeaderSignature, auth.GenerateSignature(timestamp+"."+string(body), c.ops.callbackSecret))
+	}
+
+	res, err := c.client.Do(r)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer res.Body.Close()
+	response, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	d.StatusCode, d.Response = res.StatusCode, string(response)
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return false, nil
+	}
+	retry = res.StatusCode >= 500 || res.StatusCode == http.StatusRequestTimeout || res.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("%w: %s", ErrHttpCallbackInvalidStatusCode, res.Status)
+}
+
+// record 保存回调记录，只保留最近的 callbackLogSize 条
+func (c *callbackSender) record(ctx context.Context, d CallbackDelivery) {
+	data, _ := json.Marshal(d)
+	p := c.redis.Pipeline()
+	p.LPush(ctx, c.logKey(), data)
+	p.LTrim(ctx, c.logKey(), 0, int64(c.ops.callbackLogSize-1))
+	if _, err := p.Exec(ctx); err != nil {
+		g.Log.Errorf(ctx, "save callback delivery failed: %v", err)
+	}
+}
+
+// CallbackDeliveries 返回最近的回调记录，按时间倒序
+func (wk *Worker) CallbackDeliveries(ctx context.Context, limit int) ([]CallbackDelivery, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	list, err := wk.redis.LRange(ctx, wk.callbacks.logKey(), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]CallbackDelivery, 0, len(list))
+	for _, item := range list {
+		var d CallbackDelivery
+		if err = json.Unmarshal([]byte(item), &d); err == nil {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+// VerifyCallback 供回调的接收方验证签名，tolerance 为允许的时间偏差，为 0 时不检查时间
+func VerifyCallback(r *http.Request, body []byte, secret string, tolerance time.Duration) error {
+	timestamp := r.Header.Get(CallbackHeaderTimestamp)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrCallbackSignature
+	}
+	if tolerance > 0 {
+		if diff := time.Since(time.Unix(ts, 0)); diff > tolerance || diff < -tolerance {
+			return ErrCallbackExpired
+		}
+	}
+	expected := auth.GenerateSignature(timestamp+"."+string(body), secret)
+	if !hmac.Equal([]byte(r.Header.Get(CallbackHeaderSignature)), []byte(expected)) {
+		return ErrCallbackSignature
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSender(t *testing.T, urls ...string) *callbackSender {
+	t.Helper()
+	ops := getOptionsOrSetDefault(nil)
+	WithGroup("callback-test-" + strconv.FormatInt(time.Now().UnixNano(), 10))(ops)
+	WithCallback(urls...)(ops)
+	WithCallbackSecret("secret")(ops)
+	WithCallbackTimeout(1)(ops)
+	WithCallbackRetry(2)(ops)
+	WithCallbackBackoff(func(int, error) time.Duration { return time.Millisecond })(ops)
+	rd := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	t.Cleanup(func() { _ = rd.Close() })
+	return newCallbackSender(ops, rd)
+}
+
+func TestCallbackSend(t *testing.T) {
+	var mu sync.Mutex
+	var deliveries []string
+	var attempts int
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, VerifyCallback(r, body, "secret", time.Minute))
+		assert.ErrorIs(t, VerifyCallback(r, body, "other", time.Minute), ErrCallbackSignature)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		deliveries = append(deliveries, r.Header.Get(CallbackHeaderDelivery))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+	var received Payload
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		_ = json.Unmarshal(body, &received)
+		mu.Unlock()
+	}))
+	defer ok.Close()
+
+	c := newTestSender(t, flaky.URL, ok.URL)
+	assert.Equal(t, []string{flaky.URL, ok.URL}, c.ops.callbacks)
+	err := c.send(context.Background(), Payload{Group: "orders", Uid: "o-1", Payload: []byte("x")})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	// 同一次投递的重试使用相同的投递ID
+	assert.Equal(t, deliveries[0], deliveries[2])
+	assert.Equal(t, "o-1", received.Uid)
+}
+
+func TestCallbackNoRetry(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	c := newTestSender(t, srv.URL)
+	err := c.send(context.Background(), Payload{Uid: "o-2"})
+	assert.ErrorIs(t, err, ErrHttpCallbackInvalidStatusCode)
+	assert.Equal(t, 1, attempts)
+
+	if c.redis.Ping(context.Background()).Err() == nil {
+		wk := &Worker{redis: c.redis, callbacks: c}
+		list, err := wk.CallbackDeliveries(context.Background(), 10)
+		assert.NoError(t, err)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, http.StatusBadRequest, list[0].StatusCode)
+			assert.False(t, list[0].Succeeded())
+		}
+		c.redis.Del(context.Background(), c.logKey())
+	}
+}
+
+func TestVerifyCallback(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set(CallbackHeaderTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	assert.ErrorIs(t, VerifyCallback(r, nil, "secret", time.Minute), ErrCallbackExpired)
+	r.Header.Set(CallbackHeaderTimestamp, "bad")
+	assert.ErrorIs(t, VerifyCallback(r, nil, "secret", 0), ErrCallbackSignature)
+}
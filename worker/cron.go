@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
+)
+
+// manualSeparator 手动触发的周期任务ID为 uid + manualSeparator + 随机串，
+// 避免与已排队的计划任务ID冲突，处理时还原为原始 uid
+const manualSeparator = "@manual-"
+
+// cronUid 返回任务ID对应的周期任务 uid
+func cronUid(taskID string) string {
+	uid, _, _ := strings.Cut(taskID, manualSeparator)
+	return uid
+}
+
+// RunInfo 周期任务一次执行的结果
+type RunInfo struct {
+	Uid      string
+	Group    string
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// runHooks 执行结果的回调，处理器持有 Worker 的副本，这里使用指针共享
+type runHooks struct {
+	mu    sync.RWMutex
+	hooks []func(RunInfo)
+}
+
+func (h *runHooks) fire(info RunInfo) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, hook := range h.hooks {
+		hook(info)
+	}
+}
+
+// OnRun 注册周期任务每次执行结束后的回调，用于记录执行历史和指标
+func (wk *Worker) OnRun(hook func(RunInfo)) {
+	if wk == nil || wk.hooks == nil {
+		return
+	}
+	wk.hooks.mu.Lock()
+	defer wk.hooks.mu.Unlock()
+	wk.hooks.hooks = append(wk.hooks.hooks, hook)
+}
+
+// CronTask 周期任务的状态
+type CronTask struct {
+	Uid       string    `json:"uid"`
+	Group     string    `json:"group"`
+	Expr      string    `json:"expr"`
+	Next      time.Time `json:"next"`
+	Processed int64     `json:"processed"`
+	Paused    bool      `json:"paused"`
+}
+
+// CronTasks 返回所有周期任务，按 uid 排序
+func (wk *Worker) CronTasks(ctx context.Context) ([]CronTask, error) {
+	m, err := wk.redis.HGetAll(ctx, wk.ops.redisPeriodKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]CronTask, 0, len(m))
+	for _, v := range m {
+		var item periodTask
+		item.FromString(v)
+		list = append(list, CronTask{
+			Uid:       item.Uid,
+			Group:     strings.TrimSuffix(item.Group, ".cron"),
+			Expr:      item.Expr,
+			Next:      time.Unix(item.Next, 0),
+			Processed: item.Processed,
+			Paused:    item.Paused,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Uid < list[j].Uid })
+	return list, nil
+}
+
+// TriggerCron 立即执行一次周期任务，不影响计划的下一次执行，暂停的任务也可以手动执行
+func (wk *Worker) TriggerCron(ctx context.Context, uid string) error {
+	item, err := wk.getPeriodTask(ctx, uid)
+	if err != nil {
+		return err
+	}
+	t := asynq.NewTask(item.Group, item.Payload, asynq.TaskID(item.Uid+manualSeparator+guid.S()))
+	taskOpts := []asynq.Option{
+		asynq.Queue(wk.ops.group),
+		asynq.MaxRetry(wk.ops.maxRetry),
+		asynq.Timeout(time.Duration(item.Timeout) * time.Second),
+	}
+	if item.MaxRetry > 0 {
+		taskOpts = append(taskOpts, asynq.MaxRetry(item.MaxRetry))
+	}
+	_, err = wk.client.EnqueueContext(ctx, t, taskOpts...)
+	return err
+}
+
+// PauseCron 暂停周期任务，已经排队的任务不受影响
+func (wk *Worker) PauseCron(ctx context.Context, uid string) error {
+	return wk.updatePeriodTask(ctx, uid, func(item *periodTask) {
+		item.Paused = true
+	})
+}
+
+// ResumeCron 恢复周期任务，从当前时间重新计算下一次执行时间，暂停期间错过的执行不再补偿
+func (wk *Worker) ResumeCron(ctx context.Context, uid string) error {
+	return wk.updatePeriodTask(ctx, uid, func(item *periodTask) {
+		if !item.Paused {
+			return
+		}
+		item.Paused = false
+		if next, err := getNext(item.Expr, 0); err == nil {
+			item.Next = next
+		}
+	})
+}
+
+func (wk *Worker) getPeriodTask(ctx context.Context, uid string) (*periodTask, error) {
+	res, err := wk.redis.HGet(ctx, wk.ops.redisPeriodKey, uid).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCronNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var item periodTask
+	if err = json.Unmarshal([]byte(res), &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// updatePeriodTask 在锁内读取、修改并保存周期任务，避免与 scan 同时写入
+func (wk *Worker) updatePeriodTask(ctx context.Context, uid string, fn func(item *periodTask)) error {
+	if err := wk.lock.Lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		_ = wk.lock.Unlock(ctx)
+	}()
+	item, err := wk.getPeriodTask(ctx, uid)
+	if err != nil {
+		return err
+	}
+	fn(item)
+	return wk.redis.HSet(ctx, wk.ops.redisPeriodKey, uid, item.String()).Err()
+}
@@ -9,4 +9,15 @@ var (
 	ErrExprInvalid                   = fmt.Errorf("expr is invalid")
 	ErrSaveCron                      = fmt.Errorf("save cron failed")
 	ErrHttpCallbackInvalidStatusCode = fmt.Errorf("http callback invalid status code")
+	ErrCronNotFound                  = fmt.Errorf("cron task not found")
+	ErrTopicEmpty                    = fmt.Errorf("task topic is empty")
+	ErrTopicExists                   = fmt.Errorf("task topic already defined")
+	ErrHandlerNil                    = fmt.Errorf("task handler is nil")
+	ErrDuplicateTask                 = fmt.Errorf("task already exists")
+	ErrSkipRetry                     = fmt.Errorf("skip retry for the task")
+	ErrRawPayload                    = fmt.Errorf("raw codec requires []byte payload")
+	ErrServerStarted                 = fmt.Errorf("task server already started")
+	ErrQueueNotFound                 = fmt.Errorf("queue not found")
+	ErrTaskNotFound                  = fmt.Errorf("task not found")
+	ErrTaskStateInvalid              = fmt.Errorf("task state is invalid")
 )
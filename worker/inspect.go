@@ -0,0 +1,222 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskState 任务状态
+type TaskState string
+
+const (
+	TaskPending   TaskState = "pending"   // 等待处理
+	TaskActive    TaskState = "active"    // 处理中
+	TaskScheduled TaskState = "scheduled" // 计划在将来处理
+	TaskRetry     TaskState = "retry"     // 失败后等待重试
+	TaskArchived  TaskState = "archived"  // 重试用尽后归档
+	TaskCompleted TaskState = "completed" // 已成功，在保留期内
+)
+
+// QueueStats 队列统计
+type QueueStats struct {
+	Queue       string        `json:"queue"`
+	Size        int           `json:"size"` // 除已完成外的任务总数
+	Pending     int           `json:"pending"`
+	Active      int           `json:"active"`
+	Scheduled   int           `json:"scheduled"`
+	Retry       int           `json:"retry"`
+	Archived    int           `json:"archived"`
+	Completed   int           `json:"completed"`
+	Aggregating int           `json:"aggregating"`
+	Processed   int           `json:"processed"` // 当天处理的任务数，包括失败的
+	Failed      int           `json:"failed"`    // 当天失败的任务数
+	Paused      bool          `json:"paused"`
+	Latency     time.Duration `json:"latency"` // 最早的等待任务已等待的时间
+	MemoryUsage int64         `json:"memoryUsage"`
+	Timestamp   time.Time     `json:"timestamp"`
+}
+
+// TaskDetail 任务详情
+type TaskDetail struct {
+	ID            string        `json:"id"`
+	Queue         string        `json:"queue"`
+	Type          string        `json:"type"`
+	Payload       []byte        `json:"payload"`
+	State         TaskState     `json:"state"`
+	MaxRetry      int           `json:"maxRetry"`
+	Retried       int           `json:"retried"`
+	LastErr       string        `json:"lastErr,omitempty"`
+	LastFailedAt  time.Time     `json:"lastFailedAt,omitempty"`
+	Timeout       time.Duration `json:"timeout"`
+	Deadline      time.Time     `json:"deadline,omitempty"`
+	NextProcessAt time.Time     `json:"nextProcessAt,omitempty"`
+	CompletedAt   time.Time     `json:"completedAt,omitempty"`
+	Result        []byte        `json:"result,omitempty"`
+}
+
+func newTaskDetail(info *asynq.TaskInfo) TaskDetail {
+	return TaskDetail{
+		ID:            info.ID,
+		Queue:         info.Queue,
+		Type:          info.Type,
+		Payload:       info.Payload,
+		State:         TaskState(info.State.String()),
+		MaxRetry:      info.MaxRetry,
+		Retried:       info.Retried,
+		LastErr:       info.LastErr,
+		LastFailedAt:  info.LastFailedAt,
+		Timeout:       info.Timeout,
+		Deadline:      info.Deadline,
+		NextProcessAt: info.NextProcessAt,
+		CompletedAt:   info.CompletedAt,
+		Result:        info.Result,
+	}
+}
+
+// inspectError 将 asynq 的错误转换为本包的错误
+func inspectError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, asynq.ErrQueueNotFound):
+		return fmt.Errorf("%w: %v", ErrQueueNotFound, err)
+	case errors.Is(err, asynq.ErrTaskNotFound):
+		return fmt.Errorf("%w: %v", ErrTaskNotFound, err)
+	}
+	return err
+}
+
+// Queues 返回所有队列的统计，包括 Worker 和 Server 使用的队列
+func (wk *Worker) Queues(ctx context.Context) ([]QueueStats, error) {
+	names, err := wk.inspector.Queues()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]QueueStats, 0, len(names))
+	for _, name := range names {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		stats, err := wk.Queue(ctx, name)
+		if errors.Is(err, ErrQueueNotFound) {
+			// 队列在列出后被删除
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *stats)
+	}
+	return list, nil
+}
+
+// Queue 返回队列的统计
+func (wk *Worker) Queue(_ context.Context, queue string) (*QueueStats, error) {
+	info, err := wk.inspector.GetQueueInfo(queue)
+	if err != nil {
+		// GetQueueInfo 返回的错误没有包装 asynq.ErrQueueNotFound
+		if names, e := wk.inspector.Queues(); e == nil && !slices.Contains(names, queue) {
+			return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, queue)
+		}
+		return nil, inspectError(err)
+	}
+	return &QueueStats{
+		Queue:       info.Queue,
+		Size:        info.Size,
+		Pending:     info.Pending,
+		Active:      info.Active,
+		Scheduled:   info.Scheduled,
+		Retry:       info.Retry,
+		Archived:    info.Archived,
+		Completed:   info.Completed,
+		Aggregating: info.Aggregating,
+		Processed:   info.Processed,
+		Failed:      info.Failed,
+		Paused:      info.Paused,
+		Latency:     info.Latency,
+		MemoryUsage: info.MemoryUsage,
+		Timestamp:   info.Timestamp,
+	}, nil
+}
+
+// Tasks 分页列出队列中指定状态的任务，page 从 1 开始
+func (wk *Worker) Tasks(_ context.Context, queue string, state TaskState, page, size int) ([]TaskDetail, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 20
+	}
+	opts := []asynq.ListOption{asynq.Page(page), asynq.PageSize(size)}
+	var list []*asynq.TaskInfo
+	var err error
+	switch state {
+	case TaskPending:
+		list, err = wk.inspector.ListPendingTasks(queue, opts...)
+	case TaskActive:
+		list, err = wk.inspector.ListActiveTasks(queue, opts...)
+	case TaskScheduled:
+		list, err = wk.inspector.ListScheduledTasks(queue, opts...)
+	case TaskRetry:
+		list, err = wk.inspector.ListRetryTasks(queue, opts...)
+	case TaskArchived:
+		list, err = wk.inspector.ListArchivedTasks(queue, opts...)
+	case TaskCompleted:
+		list, err = wk.inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrTaskStateInvalid, state)
+	}
+	if err != nil {
+		return nil, inspectError(err)
+	}
+	tasks := make([]TaskDetail, 0, len(list))
+	for _, info := range list {
+		tasks = append(tasks, newTaskDetail(info))
+	}
+	return tasks, nil
+}
+
+// Task 返回任务详情
+func (wk *Worker) Task(_ context.Context, queue, id string) (*TaskDetail, error) {
+	info, err := wk.inspector.GetTaskInfo(queue, id)
+	if err != nil {
+		return nil, inspectError(err)
+	}
+	detail := newTaskDetail(info)
+	return &detail, nil
+}
+
+// RetryTask 立即执行计划中、等待重试或已归档的任务
+func (wk *Worker) RetryTask(_ context.Context, queue, id string) error {
+	return inspectError(wk.inspector.RunTask(queue, id))
+}
+
+// ArchiveTask 归档等待中、计划中或等待重试的任务，归档的任务不会被处理
+func (wk *Worker) ArchiveTask(_ context.Context, queue, id string) error {
+	return inspectError(wk.inspector.ArchiveTask(queue, id))
+}
+
+// DeleteTask 删除任务，处理中的任务不能删除
+func (wk *Worker) DeleteTask(_ context.Context, queue, id string) error {
+	return inspectError(wk.inspector.DeleteTask(queue, id))
+}
+
+// CronTasksByGroup 返回指定组的周期任务，group 为空时返回所有周期任务
+func (wk *Worker) CronTasksByGroup(ctx context.Context, group string) ([]CronTask, error) {
+	list, err := wk.CronTasks(ctx)
+	if err != nil || group == "" {
+		return list, err
+	}
+	filtered := make([]CronTask, 0, len(list))
+	for _, task := range list {
+		if task.Group == group {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered, nil
+}
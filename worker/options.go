@@ -18,12 +18,20 @@ type Options struct {
 	handler           func(ctx context.Context, p Payload) error                //任务的处理函数
 	handlerNeedWorker func(worker Worker, ctx context.Context, p Payload) error //需要Worker参数的任务处理函数
 	handlerAggregator func(ctx context.Context, task *asynq.Task) error         //聚合任务的处理函数
-	callback          string                                                    //任务完成后的回调地址
+	callbacks         []string                                                  //任务的HTTP回调地址
 	clearArchived     int                                                       //清除已归档任务的时间间隔
 	timeout           int                                                       //任务处理器的超时时间
 
-	redisLinkMode  string //redis连接模式
-	concurrencyNum int    //任务处理器的并发数
+	redisLinkMode     string //redis连接模式
+	concurrencyNum    int    //任务处理器的并发数
+	workflowRetention int    //工作流结束后状态的保留时间
+
+	//HTTP回调参数
+	callbackSecret  string      //回调签名的密钥，为空时不签名
+	callbackTimeout int         //单次回调请求的超时时间
+	callbackRetry   int         //单个地址回调失败后的重试次数，与任务的重试相互独立
+	callbackBackoff BackoffFunc //回调重试的退避策略
+	callbackLogSize int         //保留的回调记录条数
 
 	//聚合参数
 	useAggregator    bool //是否使用聚合器
@@ -97,10 +105,58 @@ func WithHandlerNeedWorker(fun func(worker Worker, ctx context.Context, p Payloa
 	}
 }
 
-// WithCallback 设置任务完成后的回调地址
-func WithCallback(s string) func(*Options) {
+// WithCallback 设置任务的HTTP回调地址，没有设置处理函数时任务参数POST到每个地址，可多次调用添加多个地址
+func WithCallback(urls ...string) func(*Options) {
 	return func(options *Options) {
-		getOptionsOrSetDefault(options).callback = s
+		ops := getOptionsOrSetDefault(options)
+		for _, url := range urls {
+			if url != "" {
+				ops.callbacks = append(ops.callbacks, url)
+			}
+		}
+	}
+}
+
+// WithCallbackSecret 设置回调签名的密钥，设置后请求头带有 X-Callback-Signature，接收方可使用 VerifyCallback 验证
+func WithCallbackSecret(secret string) func(*Options) {
+	return func(options *Options) {
+		getOptionsOrSetDefault(options).callbackSecret = secret
+	}
+}
+
+// WithCallbackTimeout 单次回调请求的超时时间，默认为 10 秒
+func WithCallbackTimeout(second int) func(*Options) {
+	return func(options *Options) {
+		if second > 0 {
+			getOptionsOrSetDefault(options).callbackTimeout = second
+		}
+	}
+}
+
+// WithCallbackRetry 单个地址回调失败后的重试次数，默认为 3，为 0 时不重试
+func WithCallbackRetry(count int) func(*Options) {
+	return func(options *Options) {
+		if count >= 0 {
+			getOptionsOrSetDefault(options).callbackRetry = count
+		}
+	}
+}
+
+// WithCallbackBackoff 回调重试的退避策略，默认从 500 毫秒开始指数退避，最长 10 秒
+func WithCallbackBackoff(backoff BackoffFunc) func(*Options) {
+	return func(options *Options) {
+		if backoff != nil {
+			getOptionsOrSetDefault(options).callbackBackoff = backoff
+		}
+	}
+}
+
+// WithCallbackLogSize 在 Redis 中保留的回调记录条数，默认为 1000
+func WithCallbackLogSize(size int) func(*Options) {
+	return func(options *Options) {
+		if size > 0 {
+			getOptionsOrSetDefault(options).callbackLogSize = size
+		}
 	}
 }
 
@@ -131,6 +187,15 @@ func WithConcurrencyNum(s int) func(*Options) {
 	}
 }
 
+// WithWorkflowRetention 工作流结束后状态的保留时间，默认 7 天
+func WithWorkflowRetention(second int) func(*Options) {
+	return func(options *Options) {
+		if second > 0 {
+			getOptionsOrSetDefault(options).workflowRetention = second
+		}
+	}
+}
+
 // WithHandleAggregator 设置聚合任务的回调处理器
 func WithHandlerAggregator(fun func(ctx context.Context, task *asynq.Task) error) func(*Options) {
 	return func(options *Options) {
@@ -193,6 +258,11 @@ func getOptionsOrSetDefault(options *Options) *Options {
 	clearArchived := g.Cfg.GetInt("task.clearArchived", 300)
 	timeout := g.Cfg.GetInt("task.timeout", 30)
 	concurrencyNum := g.Cfg.GetInt("task.concurrencyNum", 100)
+	workflowRetention := g.Cfg.GetInt("task.workflowRetention", 7*24*3600)
+	callbackSecret := g.Cfg.GetString("task.callbackSecret")
+	callbackTimeout := g.Cfg.GetInt("task.callbackTimeout", 10)
+	callbackRetry := g.Cfg.GetInt("task.callbackRetry", 3)
+	callbackLogSize := g.Cfg.GetInt("task.callbackLogSize", 1000)
 
 	groupMaxSize := g.Cfg.GetInt("task.groupMaxSize", 100)
 	groupMaxDelay := g.Cfg.GetInt("task.groupMaxDelay", 3000)
@@ -201,15 +271,22 @@ func getOptionsOrSetDefault(options *Options) *Options {
 
 	if options == nil {
 		return &Options{
-			group:          "task",
-			redisUri:       redisUri,
-			redisPeriodKey: "period",
-			retention:      retention,
-			maxRetry:       maxRetry,
-			clearArchived:  clearArchived,
-			timeout:        timeout,
-			concurrencyNum: concurrencyNum,
-			redisLinkMode:  redisLinkMode,
+			group:             "task",
+			redisUri:          redisUri,
+			redisPeriodKey:    "period",
+			retention:         retention,
+			maxRetry:          maxRetry,
+			clearArchived:     clearArchived,
+			timeout:           timeout,
+			concurrencyNum:    concurrencyNum,
+			workflowRetention: workflowRetention,
+			redisLinkMode:     redisLinkMode,
+			//回调参数
+			callbackSecret:  callbackSecret,
+			callbackTimeout: callbackTimeout,
+			callbackRetry:   callbackRetry,
+			callbackBackoff: ExponentialBackoff(500*time.Millisecond, 10*time.Second),
+			callbackLogSize: callbackLogSize,
 			//聚合参数
 			groupMaxDelay:    groupMaxDelay,
 			groupGracePeriod: groupGracePeriod,
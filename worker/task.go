@@ -0,0 +1,462 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/g"
+)
+
+// Priority 任务优先级，每个优先级对应一个队列，按 6:3:1 的权重分配处理能力
+type Priority int
+
+const (
+	PriorityDefault Priority = iota
+	PriorityCritical
+	PriorityLow
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityCritical:
+		return "critical"
+	case PriorityLow:
+		return "low"
+	default:
+		return "default"
+	}
+}
+
+func (p Priority) weight() int {
+	switch p {
+	case PriorityCritical:
+		return 6
+	case PriorityLow:
+		return 1
+	default:
+		return 3
+	}
+}
+
+// BackoffFunc 根据已重试的次数和本次的错误返回下一次重试的延迟
+type BackoffFunc func(retried int, err error) time.Duration
+
+// ExponentialBackoff 指数退避，延迟为 base * 2^retried，加上最多 50% 的随机抖动，不超过 max
+func ExponentialBackoff(base, max time.Duration) BackoffFunc {
+	return func(retried int, err error) time.Duration {
+		d := time.Duration(float64(base) * math.Pow(2, float64(retried)))
+		if d <= 0 || d > max {
+			d = max
+		}
+		if half := int64(d / 2); half > 0 {
+			d += time.Duration(rand.Int63n(half))
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// taskOptions 任务的选项，Define 时设置默认值，Enqueue 时可以覆盖
+type taskOptions struct {
+	codec     Codec
+	backoff   BackoffFunc
+	priority  Priority
+	maxRetry  int
+	timeout   time.Duration
+	deadline  time.Time
+	uniqueKey string
+	uniqueTTL time.Duration
+	processIn time.Duration
+	processAt time.Time
+	retention time.Duration
+}
+
+// TaskOption 配置任务
+type TaskOption func(*taskOptions)
+
+// WithTaskCodec 设置任务参数的编解码，默认JSON，只在 Define 时有效
+func WithTaskCodec(codec Codec) TaskOption {
+	return func(o *taskOptions) {
+		if codec != nil {
+			o.codec = codec
+		}
+	}
+}
+
+// WithTaskBackoff 设置重试的退避策略，默认使用 asynq 的指数退避，只在 Define 时有效
+func WithTaskBackoff(backoff BackoffFunc) TaskOption {
+	return func(o *taskOptions) {
+		o.backoff = backoff
+	}
+}
+
+// WithTaskPriority 设置任务优先级。设置了唯一键的任务在 Enqueue 时覆盖优先级无效，见 WithTaskUnique
+func WithTaskPriority(p Priority) TaskOption {
+	return func(o *taskOptions) {
+		o.priority = p
+	}
+}
+
+// WithTaskRetry 设置最大重试次数，为 0 时不重试
+func WithTaskRetry(maxRetry int) TaskOption {
+	return func(o *taskOptions) {
+		if maxRetry >= 0 {
+			o.maxRetry = maxRetry
+		}
+	}
+}
+
+// WithTaskTimeout 单次处理的超时时间
+func WithTaskTimeout(timeout time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithTaskDeadline 任务的截止时间，超过后不再处理和重试
+func WithTaskDeadline(deadline time.Time) TaskOption {
+	return func(o *taskOptions) {
+		o.deadline = deadline
+	}
+}
+
+// WithTaskUnique 设置唯一键，同一任务类型下相同唯一键的任务在队列中或完成后 ttl 内入队返回 ErrDuplicateTask。
+// 相同唯一键的任务失败归档后可以重新入队。
+// asynq 只在同一队列内检查任务ID冲突，因此唯一任务始终进入 Define 时优先级对应的队列，Enqueue 时覆盖的优先级被忽略
+func WithTaskUnique(key string, ttl time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.uniqueKey, o.uniqueTTL = key, ttl
+	}
+}
+
+// WithTaskProcessIn 延迟 d 后处理
+func WithTaskProcessIn(d time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.processIn, o.processAt = d, time.Time{}
+	}
+}
+
+// WithTaskProcessAt 在指定时间处理
+func WithTaskProcessAt(t time.Time) TaskOption {
+	return func(o *taskOptions) {
+		o.processAt, o.processIn = t, 0
+	}
+}
+
+// WithTaskRetention 任务成功后保留的时间，用于查询结果
+func WithTaskRetention(d time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.retention = d
+	}
+}
+
+// uniqueID 返回唯一键对应的任务ID，以任务类型为前缀，避免不同任务类型的唯一键冲突
+func (o *taskOptions) uniqueID(topic string) string {
+	return topic + ":" + o.uniqueKey
+}
+
+// asynqOptions 转换为 asynq 的入队选项
+func (o *taskOptions) asynqOptions(topic, queue string) []asynq.Option {
+	opts := []asynq.Option{asynq.Queue(queue), asynq.MaxRetry(o.maxRetry)}
+	if o.timeout > 0 {
+		opts = append(opts, asynq.Timeout(o.timeout))
+	}
+	if !o.deadline.IsZero() {
+		opts = append(opts, asynq.Deadline(o.deadline))
+	}
+	retention := o.retention
+	if o.uniqueKey != "" {
+		opts = append(opts, asynq.TaskID(o.uniqueID(topic)))
+		if o.uniqueTTL > retention {
+			retention = o.uniqueTTL
+		}
+	}
+	if retention > 0 {
+		opts = append(opts, asynq.Retention(retention))
+	}
+	if o.processIn > 0 {
+		opts = append(opts, asynq.ProcessIn(o.processIn))
+	} else if !o.processAt.IsZero() {
+		opts = append(opts, asynq.ProcessAt(o.processAt))
+	}
+	return opts
+}
+
+// TaskInfo 入队后的任务信息
+type TaskInfo struct {
+	ID        string
+	Topic     string
+	Queue     string
+	MaxRetry  int
+	ProcessAt time.Time
+}
+
+// TaskID 返回正在处理的任务ID，只在任务处理函数中有效
+func TaskID(ctx context.Context) (string, bool) {
+	return asynq.GetTaskID(ctx)
+}
+
+// RetryCount 返回正在处理的任务已重试的次数，只在任务处理函数中有效
+func RetryCount(ctx context.Context) (int, bool) {
+	return asynq.GetRetryCount(ctx)
+}
+
+// Server 类型化任务的服务，负责入队和处理通过 Define 定义的任务。
+// 队列名称为 组名:优先级，例如 task:critical，与 Worker 使用的队列相互独立
+type Server struct {
+	ops       Options
+	redisOpt  asynq.RedisConnOpt
+	redis     redis.UniversalClient // 保存工作流状态
+	client    *asynq.Client
+	inspector *asynq.Inspector // 查询唯一键冲突的任务状态
+	mux       *asynq.ServeMux
+
+	mu        sync.Mutex
+	topics    map[string]*taskOptions
+	steps     map[string]*stepEntry // 工作流步骤，键为步骤的 topic
+	srv       *asynq.Server
+	started   bool
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer 创建类型化任务的服务，使用与 New 相同的 Redis、组名和并发数配置
+func NewServer(options ...func(*Options)) (*Server, error) {
+	ops := getOptionsOrSetDefault(nil)
+	for _, f := range options {
+		f(ops)
+	}
+	rs, err := redisConnOpt(ops)
+	if err != nil {
+		return nil, err
+	}
+	redisClient, ok := rs.MakeRedisClient().(redis.UniversalClient)
+	if !ok {
+		return nil, fmt.Errorf("failed to create redis client: %w", ErrRedisInvalid)
+	}
+	s := &Server{
+		ops:       *ops,
+		redisOpt:  rs,
+		redis:     redisClient,
+		client:    asynq.NewClient(rs),
+		inspector: asynq.NewInspector(rs),
+		mux:       asynq.NewServeMux(),
+		topics:    make(map[string]*taskOptions),
+		steps:     make(map[string]*stepEntry),
+		done:      make(chan struct{}),
+	}
+	s.mux.HandleFunc(compensateTopic, s.handleCompensate)
+	return s, nil
+}
+
+var (
+	defaultServer     *Server
+	defaultServerErr  error
+	defaultServerOnce sync.Once
+)
+
+// DefaultServer 返回使用配置文件创建的默认服务，Define 定义的任务注册在默认服务上
+func DefaultServer() (*Server, error) {
+	defaultServerOnce.Do(func() {
+		defaultServer, defaultServerErr = NewServer()
+	})
+	return defaultServer, defaultServerErr
+}
+
+// queue 返回优先级对应的队列名称
+func (s *Server) queue(p Priority) string {
+	return s.ops.group + ":" + p.String()
+}
+
+// removeArchived 删除已归档的任务，使相同唯一键的任务可以重新入队；任务不是归档状态时返回 false
+func (s *Server) removeArchived(queue, id string) bool {
+	info, err := s.inspector.GetTaskInfo(queue, id)
+	if err != nil || info.State != asynq.TaskStateArchived {
+		return false
+	}
+	return s.inspector.DeleteTask(queue, id) == nil
+}
+
+// register 注册任务的处理函数
+func (s *Server) register(topic string, o *taskOptions, handler asynq.HandlerFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrServerStarted
+	}
+	if _, ok := s.topics[topic]; ok {
+		return fmt.Errorf("%w: %s", ErrTopicExists, topic)
+	}
+	s.topics[topic] = o
+	s.mux.HandleFunc(topic, handler)
+	return nil
+}
+
+// retryDelay 按任务定义的退避策略计算重试延迟
+func (s *Server) retryDelay(n int, err error, t *asynq.Task) time.Duration {
+	s.mu.Lock()
+	o := s.topics[t.Type()]
+	s.mu.Unlock()
+	if o != nil && o.backoff != nil {
+		return o.backoff(n, err)
+	}
+	return asynq.DefaultRetryDelayFunc(n, err, t)
+}
+
+// Serve 开始处理任务并阻塞，直到调用 Close
+func (s *Server) Serve() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.started {
+		s.mu.Unlock()
+		return ErrServerStarted
+	}
+	s.started = true
+	queues := make(map[string]int)
+	for _, p := range []Priority{PriorityCritical, PriorityDefault, PriorityLow} {
+		queues[s.queue(p)] = p.weight()
+	}
+	s.srv = asynq.NewServer(s.redisOpt, asynq.Config{
+		Concurrency:    s.ops.concurrencyNum,
+		Queues:         queues,
+		RetryDelayFunc: s.retryDelay,
+		LogLevel:       asynq.WarnLevel,
+	})
+	srv := s.srv
+	s.mu.Unlock()
+
+	if err := srv.Start(s.mux); err != nil {
+		return err
+	}
+	<-s.done
+	return nil
+}
+
+// Close 停止处理任务，等待正在处理的任务结束
+func (s *Server) Close() (err error) {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.closed = true
+		srv := s.srv
+		s.mu.Unlock()
+		if srv != nil {
+			srv.Shutdown()
+		}
+		close(s.done)
+		err = errors.Join(s.client.Close(), s.inspector.Close(), s.redis.Close())
+	})
+	return err
+}
+
+// Task 类型化的任务定义，Enqueue 入队的参数由 Server 解码后交给处理函数
+type Task[T any] struct {
+	topic  string
+	server *Server
+	opts   taskOptions
+	Error  error // 定义失败的原因，不为空时 Enqueue 直接返回该错误
+}
+
+// Define 在默认服务上定义任务，见 DefineOn
+func Define[T any](topic string, handler func(context.Context, T) error, opts ...TaskOption) *Task[T] {
+	s, err := DefaultServer()
+	if err != nil {
+		return &Task[T]{topic: topic, Error: err}
+	}
+	return DefineOn(s, topic, handler, opts...)
+}
+
+// DefineOn 在指定服务上定义任务，topic 为任务类型，需要在 Serve 之前定义。
+// 处理函数返回错误时按重试策略重试，返回 ErrSkipRetry 或参数无法解码时不再重试
+func DefineOn[T any](s *Server, topic string, handler func(context.Context, T) error, opts ...TaskOption) *Task[T] {
+	t := &Task[T]{topic: topic, server: s}
+	t.opts = taskOptions{codec: JSONCodec{}, maxRetry: s.ops.maxRetry}
+	for _, opt := range opts {
+		opt(&t.opts)
+	}
+	switch {
+	case topic == "":
+		t.Error = ErrTopicEmpty
+	case handler == nil:
+		t.Error = ErrHandlerNil
+	default:
+		t.Error = s.register(topic, &t.opts, t.handler(handler))
+	}
+	return t
+}
+
+// handler 解码参数并调用处理函数
+func (t *Task[T]) handler(fn func(context.Context, T) error) asynq.HandlerFunc {
+	codec := t.opts.codec
+	return func(ctx context.Context, task *asynq.Task) error {
+		var payload T
+		if err := codec.Unmarshal(task.Payload(), &payload); err != nil {
+			g.Log.Errorf(ctx, "decode task %s failed: %v", t.topic, err)
+			return fmt.Errorf("%w: decode payload: %v", asynq.SkipRetry, err)
+		}
+		err := fn(ctx, payload)
+		if errors.Is(err, ErrSkipRetry) {
+			return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+		}
+		return err
+	}
+}
+
+// Topic 返回任务类型
+func (t *Task[T]) Topic() string {
+	return t.topic
+}
+
+// queue 返回入队的队列，唯一任务固定使用定义时的优先级，使相同唯一键的任务总在同一队列中检查冲突
+func (t *Task[T]) queue(o *taskOptions) string {
+	if o.uniqueKey != "" {
+		return t.server.queue(t.opts.priority)
+	}
+	return t.server.queue(o.priority)
+}
+
+// Enqueue 编码参数并入队，opts 覆盖定义时的选项（编解码和退避策略除外）
+func (t *Task[T]) Enqueue(ctx context.Context, payload T, opts ...TaskOption) (*TaskInfo, error) {
+	if t.Error != nil {
+		return nil, t.Error
+	}
+	o := t.opts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	data, err := t.opts.codec.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode task %s: %w", t.topic, err)
+	}
+	queue := t.queue(&o)
+	task := asynq.NewTask(t.topic, data)
+	info, err := t.server.client.EnqueueContext(ctx, task, o.asynqOptions(t.topic, queue)...)
+	if errors.Is(err, asynq.ErrTaskIDConflict) && t.server.removeArchived(queue, o.uniqueID(t.topic)) {
+		info, err = t.server.client.EnqueueContext(ctx, task, o.asynqOptions(t.topic, queue)...)
+	}
+	if errors.Is(err, asynq.ErrTaskIDConflict) || errors.Is(err, asynq.ErrDuplicateTask) {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateTask, o.uniqueKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TaskInfo{
+		ID:        info.ID,
+		Topic:     t.topic,
+		Queue:     info.Queue,
+		MaxRetry:  info.MaxRetry,
+		ProcessAt: info.NextProcessAt,
+	}, nil
+}
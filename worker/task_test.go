@@ -0,0 +1,170 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/sagoo-cloud/nexframe/configs"
+	"github.com/sagoo-cloud/nexframe/g"
+	"github.com/sagoo-cloud/nexframe/os/zlog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	// 不调用 g.Init，避免连接数据库
+	g.Cfg = configs.GetInstance()
+	g.Log = zlog.NewLogger()
+	os.Exit(m.Run())
+}
+
+type orderCreated struct {
+	OrderID string `json:"orderId"`
+	Amount  int    `json:"amount"`
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer(WithRedisUri("redis://127.0.0.1:6379/0"), WithGroup("typed"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestCodecs(t *testing.T) {
+	in := orderCreated{OrderID: "o-1", Amount: 3}
+	for _, codec := range []Codec{JSONCodec{}, GobCodec{}} {
+		data, err := codec.Marshal(in)
+		assert.NoError(t, err)
+		var out orderCreated
+		assert.NoError(t, codec.Unmarshal(data, &out))
+		assert.Equal(t, in, out)
+	}
+
+	data, err := RawCodec{}.Marshal([]byte("raw"))
+	assert.NoError(t, err)
+	var out []byte
+	assert.NoError(t, RawCodec{}.Unmarshal(data, &out))
+	assert.Equal(t, "raw", string(out))
+	_, err = RawCodec{}.Marshal("raw")
+	assert.ErrorIs(t, err, ErrRawPayload)
+}
+
+func TestDefine(t *testing.T) {
+	s := newTestServer(t)
+	handler := func(ctx context.Context, p orderCreated) error { return nil }
+
+	task := DefineOn(s, "order.created", handler, WithTaskPriority(PriorityCritical), WithTaskRetry(5))
+	assert.NoError(t, task.Error)
+	assert.Equal(t, "order.created", task.Topic())
+	assert.Equal(t, "typed:critical", s.queue(task.opts.priority))
+	assert.Equal(t, 5, task.opts.maxRetry)
+
+	dup := DefineOn(s, "order.created", handler)
+	assert.ErrorIs(t, dup.Error, ErrTopicExists)
+	_, err := dup.Enqueue(context.Background(), orderCreated{})
+	assert.ErrorIs(t, err, ErrTopicExists)
+
+	assert.ErrorIs(t, DefineOn(s, "", handler).Error, ErrTopicEmpty)
+	assert.ErrorIs(t, DefineOn[orderCreated](s, "nil", nil).Error, ErrHandlerNil)
+}
+
+func TestTaskHandler(t *testing.T) {
+	s := newTestServer(t)
+	var got orderCreated
+	fail := errors.New("temporary")
+	task := DefineOn(s, "order.paid", func(ctx context.Context, p orderCreated) error {
+		got = p
+		switch p.OrderID {
+		case "skip":
+			return fmt.Errorf("invalid order: %w", ErrSkipRetry)
+		case "fail":
+			return fail
+		}
+		return nil
+	}, WithTaskCodec(GobCodec{}))
+	assert.NoError(t, task.Error)
+
+	data, _ := GobCodec{}.Marshal(orderCreated{OrderID: "o-2", Amount: 9})
+	assert.NoError(t, s.mux.ProcessTask(context.Background(), asynq.NewTask("order.paid", data)))
+	assert.Equal(t, orderCreated{OrderID: "o-2", Amount: 9}, got)
+
+	data, _ = GobCodec{}.Marshal(orderCreated{OrderID: "skip"})
+	err := s.mux.ProcessTask(context.Background(), asynq.NewTask("order.paid", data))
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+
+	data, _ = GobCodec{}.Marshal(orderCreated{OrderID: "fail"})
+	err = s.mux.ProcessTask(context.Background(), asynq.NewTask("order.paid", data))
+	assert.ErrorIs(t, err, fail)
+	assert.NotErrorIs(t, err, asynq.SkipRetry)
+
+	// 无法解码的参数不再重试
+	err = s.mux.ProcessTask(context.Background(), asynq.NewTask("order.paid", []byte("{")))
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+}
+
+func TestTaskOptions(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	o := taskOptions{maxRetry: 3}
+	for _, opt := range []TaskOption{
+		WithTaskTimeout(time.Minute),
+		WithTaskDeadline(deadline),
+		WithTaskUnique("order:1", 10*time.Minute),
+		WithTaskRetention(time.Minute),
+		WithTaskProcessAt(deadline),
+		WithTaskProcessIn(time.Second),
+	} {
+		opt(&o)
+	}
+	types := make(map[asynq.OptionType]interface{})
+	for _, opt := range o.asynqOptions("order.paid", "typed:default") {
+		types[opt.Type()] = opt.Value()
+	}
+	assert.Equal(t, "typed:default", types[asynq.QueueOpt])
+	assert.Equal(t, 3, types[asynq.MaxRetryOpt])
+	assert.Equal(t, time.Minute, types[asynq.TimeoutOpt])
+	assert.Equal(t, deadline, types[asynq.DeadlineOpt])
+	assert.Equal(t, "order.paid:order:1", types[asynq.TaskIDOpt])
+	assert.Equal(t, 10*time.Minute, types[asynq.RetentionOpt])
+	assert.Equal(t, time.Second, types[asynq.ProcessInOpt])
+	assert.NotContains(t, types, asynq.ProcessAtOpt)
+}
+
+func TestUniqueTaskQueue(t *testing.T) {
+	s := newTestServer(t)
+	task := DefineOn(s, "order.refund", func(ctx context.Context, p orderCreated) error { return nil })
+	assert.NoError(t, task.Error)
+
+	o := task.opts
+	WithTaskPriority(PriorityCritical)(&o)
+	assert.Equal(t, "typed:critical", task.queue(&o))
+
+	// 唯一任务忽略入队时的优先级，不同优先级下相同唯一键的任务进入同一队列
+	WithTaskUnique("order:1", time.Minute)(&o)
+	assert.Equal(t, "typed:default", task.queue(&o))
+	WithTaskPriority(PriorityLow)(&o)
+	assert.Equal(t, "typed:default", task.queue(&o))
+}
+
+func TestRetryDelay(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+	for retried := 0; retried < 10; retried++ {
+		d := backoff(retried, nil)
+		base := time.Second << retried
+		if base > 10*time.Second {
+			base = 10 * time.Second
+		}
+		assert.GreaterOrEqual(t, d, base)
+		assert.LessOrEqual(t, d, 10*time.Second)
+	}
+
+	s := newTestServer(t)
+	DefineOn(s, "fixed", func(ctx context.Context, p orderCreated) error { return nil },
+		WithTaskBackoff(func(retried int, err error) time.Duration { return time.Duration(retried) * time.Minute }))
+	assert.Equal(t, 2*time.Minute, s.retryDelay(2, nil, asynq.NewTask("fixed", nil)))
+	assert.Positive(t, s.retryDelay(2, nil, asynq.NewTask("other", nil)))
+}
@@ -1,7 +1,6 @@
 package worker
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -13,8 +12,6 @@ import (
 	"github.com/sagoo-cloud/nexframe/g"
 	"github.com/sagoo-cloud/nexframe/os/nx"
 	"github.com/sagoo-cloud/nexframe/utils/guid"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 )
@@ -26,6 +23,8 @@ type Worker struct {
 	lock      *nx.Nx
 	client    *asynq.Client
 	inspector *asynq.Inspector
+	hooks     *runHooks
+	callbacks *callbackSender
 	Error     error
 }
 
@@ -38,6 +37,7 @@ type periodTask struct {
 	Processed int64  `json:"processed"`
 	MaxRetry  int    `json:"maxRetry"`
 	Timeout   int    `json:"timeout"`
+	Paused    bool   `json:"paused"`
 }
 
 // 将周期任务转化为JSON字符串
@@ -78,13 +78,17 @@ func (p periodTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) (err
 	group := strings.TrimSuffix(strings.TrimSuffix(t.Type(), ".once"), ".cron")
 	payload := Payload{
 		Group:   group,
-		Uid:     t.ResultWriter().TaskID(),
+		Uid:     cronUid(t.ResultWriter().TaskID()),
 		Payload: t.Payload(),
 	}
+	start := time.Now()
 	defer func() {
 		if err != nil {
 			g.Log.Debugf(ctx, "run task failed. uuid: %s task: %s Error:%s", uid, payload, err)
 		}
+		if strings.HasSuffix(t.Type(), ".cron") {
+			p.tk.hooks.fire(RunInfo{Uid: payload.Uid, Group: group, Start: start, Duration: time.Since(start), Err: err})
+		}
 	}()
 	if p.tk.ops.handler != nil {
 		err = p.tk.ops.handler(ctx, payload)
@@ -92,8 +96,8 @@ func (p periodTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) (err
 		err = p.tk.ops.handlerAggregator(ctx, t)
 	} else if p.tk.ops.handlerNeedWorker != nil {
 		err = p.tk.ops.handlerNeedWorker(p.tk, ctx, payload)
-	} else if p.tk.ops.callback != "" {
-		err = p.httpCallback(ctx, payload)
+	} else if len(p.tk.ops.callbacks) > 0 {
+		err = p.tk.callbacks.send(ctx, payload)
 	} else {
 		g.Log.Debugf(ctx, "no task handler. uuid: %s task: %s Error:%s", uid, payload, err)
 	}
@@ -102,39 +106,6 @@ func (p periodTaskHandler) ProcessTask(ctx context.Context, t *asynq.Task) (err
 	return
 }
 
-// HTTP回调函数
-func (p periodTaskHandler) httpCallback(ctx context.Context, payload Payload) (err error) {
-	client := &http.Client{}
-	body := payload.String()
-	r, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tk.ops.callback, bytes.NewReader([]byte(body)))
-	if err != nil {
-		g.Log.Errorf(ctx, "创建HTTP请求失败: %v", err)
-		return err
-	}
-	r.Header.Add("Content-Type", "application/json")
-
-	// 确保响应体正确关闭，防止资源泄漏
-	res, err := client.Do(r)
-	if err != nil {
-		g.Log.Errorf(ctx, "执行HTTP请求失败: %v", err)
-		return err
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			g.Log.Errorf(ctx, "body close err: %v", err)
-		}
-	}(res.Body)
-
-	responseBytes, _ := io.ReadAll(res.Body)
-	if res.StatusCode != http.StatusOK {
-		err = errors.New("HTTP回调状态码异常：" + res.Status)
-		g.Log.Debugf(ctx, "HTTP回调状态码非200: %v 响应内容: %s", err, string(responseBytes))
-		return err
-	}
-	return nil
-}
-
 // New 创建一个新的任务处理器
 func New(options ...func(*Options)) *Worker {
 	ops := getOptionsOrSetDefault(nil)
@@ -145,26 +116,9 @@ func New(options ...func(*Options)) *Worker {
 	if ops == nil {
 		return &Worker{Error: fmt.Errorf("options cannot be nil")}
 	}
-	if ops.redisUri == "" {
-		return &Worker{Error: fmt.Errorf("redis URI is empty: %w", ErrRedisNil)}
-	}
-
-	rs, err := asynq.ParseRedisURI(ops.redisUri)
+	rs, err := redisConnOpt(ops)
 	if err != nil {
-		return &Worker{Error: fmt.Errorf("invalid redis URI: %w", err)}
-	}
-	addsList := strings.Split(ops.redisUri, ",")
-
-	switch ops.redisLinkMode {
-	case "cluster":
-		rs = asynq.RedisClusterClientOpt{
-			Addrs: addsList,
-		}
-	case "sentinel":
-		rs = &asynq.RedisFailoverClientOpt{
-			MasterName:    "sagoo-master",
-			SentinelAddrs: addsList,
-		}
+		return &Worker{Error: err}
 	}
 
 	// 创建 Redis 客户端
@@ -189,6 +143,8 @@ func New(options ...func(*Options)) *Worker {
 		lock:      nxLock,
 		client:    client,
 		inspector: inspector,
+		hooks:     &runHooks{},
+		callbacks: newCallbackSender(ops, redisClient),
 	}
 
 	if ops.handlerAggregator != nil {
@@ -241,6 +197,32 @@ func New(options ...func(*Options)) *Worker {
 	return worker
 }
 
+// redisConnOpt 根据连接地址和连接模式创建 Redis 连接参数
+func redisConnOpt(ops *Options) (asynq.RedisConnOpt, error) {
+	if ops.redisUri == "" {
+		return nil, fmt.Errorf("redis URI is empty: %w", ErrRedisNil)
+	}
+
+	rs, err := asynq.ParseRedisURI(ops.redisUri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URI: %w", err)
+	}
+	addsList := strings.Split(ops.redisUri, ",")
+
+	switch ops.redisLinkMode {
+	case "cluster":
+		rs = asynq.RedisClusterClientOpt{
+			Addrs: addsList,
+		}
+	case "sentinel":
+		rs = &asynq.RedisFailoverClientOpt{
+			MasterName:    "sagoo-master",
+			SentinelAddrs: addsList,
+		}
+	}
+	return rs, nil
+}
+
 // 传入上下文，以便在需要时取消定时任务
 func (wk *Worker) schedulePeriodicTasks(ctx context.Context) {
 	// 确保在函数退出前取消定时器
@@ -317,7 +299,8 @@ func (wk *Worker) Once(options ...func(*RunOptions)) (err error) {
 		t := asynq.NewTask(strings.Join([]string{ops.group, "once"}, "."), ops.payload, asynq.TaskID(ops.uid))
 		_, err = wk.client.Enqueue(t, taskOpts...)
 		if ops.replace && errors.Is(err, asynq.ErrTaskIDConflict) {
-			ctx := wk.getDefaultTimeoutCtx()
+			ctx, cancel := wk.getDefaultTimeoutCtx()
+			defer cancel()
 			if ops.ctx != nil {
 				ctx = ops.ctx
 			}
@@ -357,7 +340,8 @@ func (wk *Worker) Cron(options ...func(*RunOptions)) (err error) {
 		MaxRetry: ops.maxRetry,
 		Timeout:  ops.timeout,
 	}
-	ctx := wk.getDefaultTimeoutCtx()
+	ctx, cancel := wk.getDefaultTimeoutCtx()
+	defer cancel()
 	res, err := wk.redis.HGet(ctx, wk.ops.redisPeriodKey, ops.uid).Result()
 	if err == nil {
 		var oldT periodTask
@@ -370,6 +354,9 @@ func (wk *Worker) Cron(options ...func(*RunOptions)) (err error) {
 			if err != nil {
 				return err
 			}
+		} else {
+			// 重复注册时保留暂停状态
+			t.Paused = oldT.Paused
 		}
 	}
 	_, err = wk.redis.HSet(ctx, wk.ops.redisPeriodKey, ops.uid, t.String()).Result()
@@ -421,7 +408,8 @@ func (wk *Worker) processed(ctx context.Context, uid string) {
 
 // scan 扫描并处理任务队列
 func (wk *Worker) scan() {
-	ctx := wk.getDefaultTimeoutCtx()
+	ctx, cancel := wk.getDefaultTimeoutCtx()
+	defer cancel()
 	if err := wk.lock.Lock(ctx); err != nil {
 		return
 	}
@@ -438,6 +426,9 @@ func (wk *Worker) scan() {
 		for _, v := range m {
 			var item periodTask
 			item.FromString(v)
+			if item.Paused {
+				continue
+			}
 			next, _ := getNext(item.Expr, item.Next)
 			t := asynq.NewTask(item.Group, item.Payload, asynq.TaskID(item.Uid))
 			taskOpts := []asynq.Option{
@@ -476,7 +467,8 @@ func (wk *Worker) clearArchived() {
 	if err != nil {
 		return
 	}
-	ctx := wk.getDefaultTimeoutCtx()
+	ctx, cancel := wk.getDefaultTimeoutCtx()
+	defer cancel()
 	for _, item := range list {
 		last := carbon.CreateFromStdTime(item.LastFailedAt)
 		if !last.IsZero() && item.Retried < item.MaxRetry {
@@ -524,10 +516,8 @@ func (wk *Worker) clearArchived() {
 }
 
 // getDefaultTimeoutCtx 获取带有默认超时的上下文
-func (wk *Worker) getDefaultTimeoutCtx() context.Context {
-	c, cancel := context.WithTimeout(context.Background(), time.Duration(wk.ops.timeout)*time.Second)
-	defer cancel()
-	return c
+func (wk *Worker) getDefaultTimeoutCtx() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Duration(wk.ops.timeout)*time.Second)
 }
 
 // getNext 计算下一次执行时间
@@ -0,0 +1,638 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+	"github.com/sagoo-cloud/nexframe/g"
+	"github.com/sagoo-cloud/nexframe/utils/guid"
+)
+
+// 工作流状态
+const (
+	WorkflowRunning            = "running"             // 执行中
+	WorkflowSucceeded          = "succeeded"           // 所有步骤成功
+	WorkflowCompensating       = "compensating"        // 有步骤失败，正在执行补偿
+	WorkflowFailed             = "failed"              // 有步骤失败，补偿已完成
+	WorkflowCompensationFailed = "compensation_failed" // 补偿失败，需要人工处理
+)
+
+// compensateTopic 执行补偿的内部任务
+const compensateTopic = "workflow:compensate"
+
+var (
+	ErrWorkflowEmpty    = errors.New("workflow has no steps")
+	ErrWorkflowNotFound = errors.New("workflow not found")
+	ErrStepNotDefined   = errors.New("workflow step not defined on the server")
+)
+
+var (
+	// completeScript 记录步骤已结束并减少阶段的待完成数，ARGV[2] 不为空时同时保存步骤的结果和参数。
+	// 执行中时返回剩余数量；正在补偿时仍然保存结果和减少待完成数，供补偿使用，但不推进：
+	// 补偿在等待且阶段的步骤已全部结束时返回 -2，由调用方重新开始补偿，其余情况返回 -1。
+	// 重复完成（任务重试）时不再减少，只返回当前剩余数量，由调用方幂等地推进
+	completeScript = redis.NewScript(`
+		local status = redis.call("hget", KEYS[1], "status")
+		if status ~= "running" and status ~= "compensating" then
+			return -1
+		end
+		if redis.call("hsetnx", KEYS[1], ARGV[1], 1) == 0 then
+			if status ~= "running" then
+				return -1
+			end
+			return tonumber(redis.call("hget", KEYS[1], ARGV[6]) or "0")
+		end
+		if ARGV[2] ~= "" then
+			redis.call("hset", KEYS[1], ARGV[2], ARGV[3], ARGV[4], ARGV[5])
+		end
+		redis.call("hset", KEYS[1], "updated", ARGV[7])
+		local pending = redis.call("hincrby", KEYS[1], ARGV[6], -1)
+		if status == "running" then
+			return pending
+		end
+		if pending == 0 and redis.call("hdel", KEYS[1], "waiting") == 1 then
+			return -2
+		end
+		return -1
+	`)
+	// failScript 步骤最终失败，执行中时切换为补偿中并返回 1，由调用方开始补偿。
+	// 同时记录该步骤已结束，补偿在等待且阶段的步骤已全部结束时返回 2，其余情况返回 0
+	failScript = redis.NewScript(`
+		local status = redis.call("hget", KEYS[1], "status")
+		local started = 0
+		if status == "running" then
+			redis.call("hset", KEYS[1], "status", "compensating", "error", ARGV[3], "failed", ARGV[4])
+			started = 1
+		elseif status ~= "compensating" then
+			return 0
+		end
+		redis.call("hset", KEYS[1], "updated", ARGV[5])
+		if redis.call("hsetnx", KEYS[1], ARGV[1], 1) == 1 then
+			local pending = redis.call("hincrby", KEYS[1], ARGV[2], -1)
+			if started == 0 and pending == 0 and redis.call("hdel", KEYS[1], "waiting") == 1 then
+				return 2
+			end
+		end
+		return started
+	`)
+	// waitScript 补偿前检查当前阶段是否还有执行中的步骤，有时记录等待并返回 1，
+	// 由该阶段最后结束的步骤重新开始补偿；工作流不在补偿中时返回 -1
+	waitScript = redis.NewScript(`
+		if redis.call("hget", KEYS[1], "status") ~= "compensating" then
+			return -1
+		end
+		if tonumber(redis.call("hget", KEYS[1], ARGV[1]) or "0") > 0 then
+			redis.call("hset", KEYS[1], "waiting", 1)
+			return 1
+		end
+		return 0
+	`)
+	// advanceScript 进入下一阶段，重复调用不会重置待完成数
+	advanceScript = redis.NewScript(`
+		if redis.call("hget", KEYS[1], "status") ~= "running" then
+			return 0
+		end
+		redis.call("hsetnx", KEYS[1], ARGV[2], ARGV[3])
+		if tonumber(redis.call("hget", KEYS[1], "stage")) < tonumber(ARGV[1]) then
+			redis.call("hset", KEYS[1], "stage", ARGV[1])
+		end
+		redis.call("hset", KEYS[1], "updated", ARGV[4])
+		return 1
+	`)
+	// transitionScript 状态为 ARGV[1] 时切换到 ARGV[2]，并写入其余字段，ARGV[3] 大于 0 时设置过期时间
+	transitionScript = redis.NewScript(`
+		if redis.call("hget", KEYS[1], "status") ~= ARGV[1] then
+			return 0
+		end
+		redis.call("hset", KEYS[1], "status", ARGV[2])
+		for i = 4, #ARGV, 2 do
+			redis.call("hset", KEYS[1], ARGV[i], ARGV[i + 1])
+		end
+		if tonumber(ARGV[3]) > 0 then
+			redis.call("expire", KEYS[1], ARGV[3])
+		end
+		return 1
+	`)
+)
+
+// stepEntry 已定义的步骤
+type stepEntry struct {
+	opts       *taskOptions
+	compensate func(ctx context.Context, input, output json.RawMessage) error
+}
+
+// stepPayload 步骤任务的参数
+type stepPayload struct {
+	Workflow string          `json:"workflow"`
+	Stage    int             `json:"stage"`
+	Index    int             `json:"index"`
+	Input    json.RawMessage `json:"input"`
+}
+
+type workflowKey struct{}
+
+// WorkflowID 返回正在执行的步骤所属的工作流ID，只在步骤处理函数中有效
+func WorkflowID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(workflowKey{}).(string)
+	return id, ok
+}
+
+// Step 类型化的工作流步骤，处理函数的结果作为下一阶段的参数。
+// 步骤的参数和结果使用JSON编码
+type Step[In, Out any] struct {
+	topic  string
+	server *Server
+	entry  *stepEntry
+	Error  error // 定义失败的原因，不为空时使用该步骤的工作流无法启动
+}
+
+// DefineStep 定义工作流步骤，需要在 Serve 之前定义。
+// 处理函数返回错误时按重试策略重试，重试用尽或返回 ErrSkipRetry 时工作流失败并执行补偿
+func DefineStep[In, Out any](s *Server, topic string, fn func(context.Context, In) (Out, error), opts ...TaskOption) *Step[In, Out] {
+	st := &Step[In, Out]{topic: topic, server: s}
+	o := &taskOptions{maxRetry: s.ops.maxRetry}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.codec = JSONCodec{}
+	st.entry = &stepEntry{opts: o}
+	switch {
+	case topic == "":
+		st.Error = ErrTopicEmpty
+	case fn == nil:
+		st.Error = ErrHandlerNil
+	default:
+		st.Error = s.register(topic, o, st.handler(fn))
+	}
+	if st.Error == nil {
+		s.mu.Lock()
+		s.steps[topic] = st.entry
+		s.mu.Unlock()
+	}
+	return st
+}
+
+// OnCompensate 设置补偿函数。工作流失败时，已成功的步骤按相反的顺序执行补偿，
+// 补偿函数收到步骤当时的参数和结果
+func (st *Step[In, Out]) OnCompensate(fn func(ctx context.Context, in In, out Out) error) *Step[In, Out] {
+	st.entry.compensate = func(ctx context.Context, input, output json.RawMessage) error {
+		var in In
+		var out Out
+		if err := decodeRaw(input, &in); err != nil {
+			return err
+		}
+		if err := decodeRaw(output, &out); err != nil {
+			return err
+		}
+		return fn(ctx, in, out)
+	}
+	return st
+}
+
+// With 使用指定的参数执行步骤
+func (st *Step[In, Out]) With(in In) WorkflowStep {
+	data, err := json.Marshal(in)
+	if err == nil && data == nil {
+		data = []byte("null")
+	}
+	return WorkflowStep{topic: st.topic, server: st.server, input: data, err: errors.Join(st.Error, err)}
+}
+
+// Chained 使用上一阶段的结果作为参数执行步骤，上一阶段为分组时参数为各步骤结果组成的数组
+func (st *Step[In, Out]) Chained() WorkflowStep {
+	return WorkflowStep{topic: st.topic, server: st.server, err: st.Error}
+}
+
+func (st *Step[In, Out]) handler(fn func(context.Context, In) (Out, error)) asynq.HandlerFunc {
+	return func(ctx context.Context, task *asynq.Task) error {
+		s := st.server
+		var p stepPayload
+		if err := json.Unmarshal(task.Payload(), &p); err != nil {
+			return fmt.Errorf("%w: decode step payload: %v", asynq.SkipRetry, err)
+		}
+		status, err := s.redis.HGet(ctx, s.workflowKey(p.Workflow), "status").Result()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if status != WorkflowRunning {
+			// 工作流已结束或正在补偿，不再执行，只记录该步骤已结束，补偿不再等待它
+			return s.completeStep(ctx, p, nil)
+		}
+
+		var in In
+		if err = decodeRaw(p.Input, &in); err != nil {
+			err = fmt.Errorf("decode step %s input: %w", st.topic, err)
+			s.failWorkflow(ctx, p, err)
+			return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+		}
+		out, err := fn(context.WithValue(ctx, workflowKey{}, p.Workflow), in)
+		if err != nil {
+			if finalAttempt(ctx, err) {
+				s.failWorkflow(ctx, p, err)
+			}
+			if errors.Is(err, ErrSkipRetry) {
+				return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+			}
+			return err
+		}
+		output, err := json.Marshal(out)
+		if err != nil {
+			err = fmt.Errorf("encode step %s output: %w", st.topic, err)
+			s.failWorkflow(ctx, p, err)
+			return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+		}
+		return s.completeStep(ctx, p, output)
+	}
+}
+
+func decodeRaw(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// finalAttempt 是否为最后一次执行，之后任务不再重试
+func finalAttempt(ctx context.Context, err error) bool {
+	if errors.Is(err, ErrSkipRetry) {
+		return true
+	}
+	retried, ok := asynq.GetRetryCount(ctx)
+	maxRetry, ok2 := asynq.GetMaxRetry(ctx)
+	return !ok || !ok2 || retried >= maxRetry
+}
+
+// WorkflowStep 工作流中的一个步骤，通过 Step.With 或 Step.Chained 创建
+type WorkflowStep struct {
+	topic  string
+	server *Server
+	input  json.RawMessage // 为空时使用上一阶段的结果
+	err    error
+}
+
+// stageDef 保存在 Redis 中的阶段定义
+type stageDef struct {
+	Steps []stepDef `json:"steps"`
+}
+
+type stepDef struct {
+	Topic string          `json:"topic"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// Workflow 工作流，由按顺序执行的阶段组成，每个阶段包含一个步骤（链）或多个并行的步骤（分组）。
+// 阶段中所有步骤成功后进入下一阶段，分组之后的阶段即为分组的完成回调
+type Workflow struct {
+	server *Server
+	name   string
+	stages []stageDef
+	err    error
+}
+
+// NewWorkflow 创建工作流，name 用于查询和日志
+func (s *Server) NewWorkflow(name string) *Workflow {
+	return &Workflow{server: s, name: name}
+}
+
+// Then 添加一个阶段，在上一阶段成功后执行
+func (w *Workflow) Then(step WorkflowStep) *Workflow {
+	return w.Group(step)
+}
+
+// Group 添加一个并行执行的阶段，所有步骤成功后才进入下一阶段
+func (w *Workflow) Group(steps ...WorkflowStep) *Workflow {
+	stage := stageDef{}
+	for _, step := range steps {
+		if step.err != nil {
+			w.err = errors.Join(w.err, fmt.Errorf("step %s: %w", step.topic, step.err))
+			continue
+		}
+		if step.server != w.server {
+			w.err = errors.Join(w.err, fmt.Errorf("%w: %s", ErrStepNotDefined, step.topic))
+			continue
+		}
+		stage.Steps = append(stage.Steps, stepDef{Topic: step.topic, Input: step.input})
+	}
+	if len(stage.Steps) > 0 {
+		w.stages = append(w.stages, stage)
+	}
+	return w
+}
+
+// Start 保存工作流状态并开始执行第一阶段，返回工作流ID
+func (w *Workflow) Start(ctx context.Context) (string, error) {
+	if w.err != nil {
+		return "", w.err
+	}
+	if len(w.stages) == 0 {
+		return "", ErrWorkflowEmpty
+	}
+	s := w.server
+	def, err := json.Marshal(w.stages)
+	if err != nil {
+		return "", err
+	}
+	id := guid.S()
+	now := time.Now().Unix()
+	key := s.workflowKey(id)
+	err = s.redis.HSet(ctx, key,
+		"name", w.name,
+		"status", WorkflowRunning,
+		"stage", 0,
+		"stages", def,
+		pendingField(0), len(w.stages[0].Steps),
+		"created", now,
+		"updated", now,
+	).Err()
+	if err != nil {
+		return "", err
+	}
+	if err = s.enqueueStage(ctx, id, w.stages, 0, nil); err != nil {
+		// 第一阶段没有完整入队，已入队的步骤看到工作流结束后不会执行
+		s.transition(ctx, id, WorkflowRunning, WorkflowFailed, "error", err.Error())
+		return "", err
+	}
+	return id, nil
+}
+
+func (s *Server) workflowKey(id string) string {
+	return s.ops.group + ":workflow:" + id
+}
+
+func pendingField(stage int) string {
+	return "pending:" + strconv.Itoa(stage)
+}
+
+func resultField(stage, index int) string {
+	return fmt.Sprintf("result:%d:%d", stage, index)
+}
+
+func inputField(stage, index int) string {
+	return fmt.Sprintf("input:%d:%d", stage, index)
+}
+
+func settledField(stage, index int) string {
+	return fmt.Sprintf("settled:%d:%d", stage, index)
+}
+
+func compensatedField(stage, index int) string {
+	return fmt.Sprintf("compensated:%d:%d", stage, index)
+}
+
+// enqueueStage 将阶段的所有步骤入队，任务ID由工作流ID、阶段和序号组成，重复入队会被忽略
+func (s *Server) enqueueStage(ctx context.Context, id string, stages []stageDef, stage int, previous json.RawMessage) error {
+	for i, step := range stages[stage].Steps {
+		s.mu.Lock()
+		entry := s.steps[step.Topic]
+		s.mu.Unlock()
+		if entry == nil {
+			return fmt.Errorf("%w: %s", ErrStepNotDefined, step.Topic)
+		}
+		input := step.Input
+		if input == nil {
+			input = previous
+		}
+		data, err := json.Marshal(stepPayload{Workflow: id, Stage: stage, Index: i, Input: input})
+		if err != nil {
+			return err
+		}
+		opts := append(entry.opts.asynqOptions(step.Topic, s.queue(entry.opts.priority)), asynq.TaskID(fmt.Sprintf("%s:%d:%d", id, stage, i)))
+		_, err = s.client.EnqueueContext(ctx, asynq.NewTask(step.Topic, data), opts...)
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+// completeStep 保存步骤结果，阶段完成时进入下一阶段或结束工作流。
+// output 为 nil 时步骤没有执行，只记录已结束
+func (s *Server) completeStep(ctx context.Context, p stepPayload, output json.RawMessage) error {
+	var result, input string
+	if output != nil {
+		result, input = resultField(p.Stage, p.Index), inputField(p.Stage, p.Index)
+	}
+	key := s.workflowKey(p.Workflow)
+	pending, err := completeScript.Run(ctx, s.redis, []string{key},
+		settledField(p.Stage, p.Index),
+		result, string(output),
+		input, string(p.Input),
+		pendingField(p.Stage), time.Now().Unix(),
+	).Int64()
+	switch {
+	case err != nil:
+		return err
+	case pending == -2:
+		s.enqueueCompensate(ctx, p.Workflow, "compensate:resume")
+		return nil
+	case pending != 0:
+		return nil
+	}
+	return s.advance(ctx, p.Workflow, p.Stage)
+}
+
+// advance 阶段完成后执行下一阶段，最后一个阶段完成时工作流成功，可重复调用
+func (s *Server) advance(ctx context.Context, id string, stage int) error {
+	key := s.workflowKey(id)
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	var stages []stageDef
+	if err = json.Unmarshal([]byte(fields["stages"]), &stages); err != nil {
+		return err
+	}
+	output := stageOutput(fields, stage, len(stages[stage].Steps))
+	next := stage + 1
+	if next == len(stages) {
+		s.transition(ctx, id, WorkflowRunning, WorkflowSucceeded, "output", string(output))
+		return nil
+	}
+	ok, err := advanceScript.Run(ctx, s.redis, []string{key}, next, pendingField(next), len(stages[next].Steps), time.Now().Unix()).Int()
+	if err != nil || ok == 0 {
+		return err
+	}
+	return s.enqueueStage(ctx, id, stages, next, output)
+}
+
+// stageOutput 阶段的结果，单个步骤为其结果，分组为各步骤结果组成的数组
+func stageOutput(fields map[string]string, stage, count int) json.RawMessage {
+	if count == 1 {
+		return json.RawMessage(fields[resultField(stage, 0)])
+	}
+	results := make([]string, count)
+	for i := range results {
+		results[i] = fields[resultField(stage, i)]
+	}
+	return json.RawMessage("[" + strings.Join(results, ",") + "]")
+}
+
+// failWorkflow 步骤最终失败，开始补偿
+func (s *Server) failWorkflow(ctx context.Context, p stepPayload, cause error) {
+	g.Log.Errorf(ctx, "workflow %s step %d:%d failed: %v", p.Workflow, p.Stage, p.Index, cause)
+	result, err := failScript.Run(ctx, s.redis, []string{s.workflowKey(p.Workflow)},
+		settledField(p.Stage, p.Index), pendingField(p.Stage),
+		cause.Error(), fmt.Sprintf("%d:%d", p.Stage, p.Index), time.Now().Unix(),
+	).Int()
+	switch {
+	case err != nil:
+		g.Log.Errorf(ctx, "workflow %s fail step %d:%d failed: %v", p.Workflow, p.Stage, p.Index, err)
+	case result == 1:
+		s.enqueueCompensate(ctx, p.Workflow, "compensate")
+	case result == 2:
+		s.enqueueCompensate(ctx, p.Workflow, "compensate:resume")
+	}
+}
+
+// enqueueCompensate 将补偿任务入队，任务ID为 工作流ID:suffix，重复入队会被忽略
+func (s *Server) enqueueCompensate(ctx context.Context, id, suffix string) {
+	data, _ := json.Marshal(stepPayload{Workflow: id})
+	_, err := s.client.EnqueueContext(ctx, asynq.NewTask(compensateTopic, data),
+		asynq.Queue(s.queue(PriorityCritical)),
+		asynq.MaxRetry(s.ops.maxRetry),
+		asynq.TaskID(id+":"+suffix),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		g.Log.Errorf(ctx, "enqueue workflow %s compensation failed: %v", id, err)
+	}
+}
+
+// handleCompensate 按相反的顺序补偿已成功的步骤，已补偿的步骤在重试时跳过。
+// 失败步骤所在的阶段还有执行中的步骤时先不补偿，由该阶段最后结束的步骤重新开始补偿，
+// 使失败之后才完成的步骤也能被补偿
+func (s *Server) handleCompensate(ctx context.Context, task *asynq.Task) error {
+	var p stepPayload
+	if err := json.Unmarshal(task.Payload(), &p); err != nil {
+		return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+	}
+	key := s.workflowKey(p.Workflow)
+	current, err := s.redis.HGet(ctx, key, "stage").Int()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	waiting, err := waitScript.Run(ctx, s.redis, []string{key}, pendingField(current)).Int()
+	if err != nil || waiting != 0 {
+		return err
+	}
+	fields, err := s.redis.HGetAll(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	var stages []stageDef
+	if err = json.Unmarshal([]byte(fields["stages"]), &stages); err != nil {
+		return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+	}
+
+	for stage := current; stage >= 0; stage-- {
+		steps := stages[stage].Steps
+		for i := len(steps) - 1; i >= 0; i-- {
+			output, done := fields[resultField(stage, i)]
+			if !done || fields[compensatedField(stage, i)] != "" {
+				continue
+			}
+			s.mu.Lock()
+			entry := s.steps[steps[i].Topic]
+			s.mu.Unlock()
+			if entry != nil && entry.compensate != nil {
+				err = entry.compensate(context.WithValue(ctx, workflowKey{}, p.Workflow),
+					json.RawMessage(fields[inputField(stage, i)]), json.RawMessage(output))
+				if err != nil {
+					if finalAttempt(ctx, err) {
+						s.transition(ctx, p.Workflow, WorkflowCompensating, WorkflowCompensationFailed,
+							"compensationError", fmt.Sprintf("step %s: %v", steps[i].Topic, err))
+						return fmt.Errorf("%w: %v", asynq.SkipRetry, err)
+					}
+					return err
+				}
+			}
+			if err = s.redis.HSet(ctx, key, compensatedField(stage, i), 1).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	s.transition(ctx, p.Workflow, WorkflowCompensating, WorkflowFailed)
+	return nil
+}
+
+// transition 切换工作流状态，结束状态会设置保留时间，返回是否切换成功
+func (s *Server) transition(ctx context.Context, id, from, to string, fields ...string) bool {
+	ttl := 0
+	if to != WorkflowRunning && to != WorkflowCompensating {
+		ttl = s.ops.workflowRetention
+	}
+	args := []interface{}{from, to, ttl, "updated", time.Now().Unix()}
+	for _, f := range fields {
+		args = append(args, f)
+	}
+	ok, err := transitionScript.Run(ctx, s.redis, []string{s.workflowKey(id)}, args...).Int()
+	if err != nil {
+		g.Log.Errorf(ctx, "workflow %s transition %s -> %s failed: %v", id, from, to, err)
+		return false
+	}
+	return ok == 1
+}
+
+// WorkflowInfo 工作流的状态
+type WorkflowInfo struct {
+	ID                string          `json:"id"`
+	Name              string          `json:"name"`
+	Status            string          `json:"status"`
+	Stage             int             `json:"stage"`  // 当前阶段，从 0 开始
+	Stages            int             `json:"stages"` // 阶段总数
+	FailedStep        string          `json:"failedStep,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	CompensationError string          `json:"compensationError,omitempty"`
+	Output            json.RawMessage `json:"output,omitempty"` // 成功时为最后一个阶段的结果
+	Created           time.Time       `json:"created"`
+	Updated           time.Time       `json:"updated"`
+}
+
+// Workflow 查询工作流的状态
+func (s *Server) Workflow(ctx context.Context, id string) (*WorkflowInfo, error) {
+	fields, err := s.redis.HGetAll(ctx, s.workflowKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, id)
+	}
+	var stages []stageDef
+	_ = json.Unmarshal([]byte(fields["stages"]), &stages)
+	stage, _ := strconv.Atoi(fields["stage"])
+	created, _ := strconv.ParseInt(fields["created"], 10, 64)
+	updated, _ := strconv.ParseInt(fields["updated"], 10, 64)
+	info := &WorkflowInfo{
+		ID:                id,
+		Name:              fields["name"],
+		Status:            fields["status"],
+		Stage:             stage,
+		Stages:            len(stages),
+		Error:             fields["error"],
+		CompensationError: fields["compensationError"],
+		Created:           time.Unix(created, 0),
+		Updated:           time.Unix(updated, 0),
+	}
+	if failed := fields["failed"]; failed != "" {
+		var st, i int
+		if _, err = fmt.Sscanf(failed, "%d:%d", &st, &i); err == nil && st < len(stages) && i < len(stages[st].Steps) {
+			info.FailedStep = stages[st].Steps[i].Topic
+		}
+	}
+	if output := fields["output"]; output != "" {
+		info.Output = json.RawMessage(output)
+	}
+	return info, nil
+}
@@ -0,0 +1,193 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type reservation struct {
+	OrderID string `json:"orderId"`
+	Item    string `json:"item"`
+}
+
+func TestWorkflowDefinition(t *testing.T) {
+	s := newTestServer(t)
+	reserve := DefineStep(s, "wf.def.reserve", func(ctx context.Context, in reservation) (string, error) {
+		return in.Item, nil
+	})
+	assert.NoError(t, reserve.Error)
+	assert.ErrorIs(t, DefineStep(s, "wf.def.reserve", func(ctx context.Context, in reservation) (string, error) {
+		return "", nil
+	}).Error, ErrTopicExists)
+
+	_, err := s.NewWorkflow("empty").Start(context.Background())
+	assert.ErrorIs(t, err, ErrWorkflowEmpty)
+
+	other := newTestServer(t)
+	foreign := DefineStep(other, "wf.def.foreign", func(ctx context.Context, in string) (string, error) { return in, nil })
+	_, err = s.NewWorkflow("foreign").Then(foreign.With("x")).Start(context.Background())
+	assert.ErrorIs(t, err, ErrStepNotDefined)
+
+	broken := DefineStep[string, string](s, "", nil)
+	_, err = s.NewWorkflow("broken").Then(reserve.With(reservation{})).Then(broken.Chained()).Start(context.Background())
+	assert.ErrorIs(t, err, ErrTopicEmpty)
+}
+
+func TestStageOutput(t *testing.T) {
+	fields := map[string]string{
+		resultField(0, 0): `"a"`,
+		resultField(1, 0): `1`,
+		resultField(1, 1): `{"b":2}`,
+	}
+	assert.JSONEq(t, `"a"`, string(stageOutput(fields, 0, 1)))
+	assert.JSONEq(t, `[1,{"b":2}]`, string(stageOutput(fields, 1, 2)))
+}
+
+func TestFinalAttempt(t *testing.T) {
+	assert.True(t, finalAttempt(context.Background(), errors.New("x")))
+	assert.True(t, finalAttempt(context.Background(), fmt.Errorf("x: %w", ErrSkipRetry)))
+}
+
+// newServingServer 启动服务处理任务，没有可用的 Redis 时跳过
+func newServingServer(t *testing.T, group string) *Server {
+	t.Helper()
+	s, err := NewServer(WithRedisUri("redis://127.0.0.1:6379/0"), WithGroup(group), WithMaxRetry(1))
+	assert.NoError(t, err)
+	if err = s.redis.Ping(context.Background()).Err(); err != nil {
+		_ = s.Close()
+		t.Skipf("redis unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func waitWorkflow(t *testing.T, s *Server, id string, status string) *WorkflowInfo {
+	t.Helper()
+	var info *WorkflowInfo
+	assert.Eventually(t, func() bool {
+		var err error
+		info, err = s.Workflow(context.Background(), id)
+		return err == nil && info.Status == status
+	}, 10*time.Second, 50*time.Millisecond)
+	return info
+}
+
+func TestWorkflowRun(t *testing.T) {
+	s := newServingServer(t, fmt.Sprintf("wf-run-%d", time.Now().UnixNano()))
+	var mu sync.Mutex
+	var released []string
+
+	reserve := DefineStep(s, "wf.reserve", func(ctx context.Context, in reservation) (string, error) {
+		id, ok := WorkflowID(ctx)
+		assert.True(t, ok)
+		assert.NotEmpty(t, id)
+		return in.OrderID + ":" + in.Item, nil
+	}).OnCompensate(func(ctx context.Context, in reservation, out string) error {
+		mu.Lock()
+		released = append(released, out)
+		mu.Unlock()
+		return nil
+	})
+	notify := DefineStep(s, "wf.notify", func(ctx context.Context, reserved []string) (int, error) {
+		return len(reserved), nil
+	})
+	charge := DefineStep(s, "wf.charge", func(ctx context.Context, count int) (int, error) {
+		if count > 2 {
+			return 0, fmt.Errorf("too many items: %w", ErrSkipRetry)
+		}
+		return count * 10, nil
+	})
+	go func() { _ = s.Serve() }()
+
+	ctx := context.Background()
+	id, err := s.NewWorkflow("order").
+		Group(reserve.With(reservation{"o-1", "apple"}), reserve.With(reservation{"o-1", "pear"})).
+		Then(notify.Chained()).
+		Then(charge.Chained()).
+		Start(ctx)
+	assert.NoError(t, err)
+	info := waitWorkflow(t, s, id, WorkflowSucceeded)
+	assert.Equal(t, "order", info.Name)
+	assert.Equal(t, 3, info.Stages)
+	assert.Equal(t, 2, info.Stage)
+	assert.JSONEq(t, `20`, string(info.Output))
+	ttl, err := s.redis.TTL(ctx, s.workflowKey(id)).Result()
+	assert.NoError(t, err)
+	assert.Positive(t, ttl)
+
+	// 第三个阶段失败，前两个阶段的步骤按相反的顺序补偿
+	id, err = s.NewWorkflow("order").
+		Group(reserve.With(reservation{"o-2", "a"}), reserve.With(reservation{"o-2", "b"}), reserve.With(reservation{"o-2", "c"})).
+		Then(notify.Chained()).
+		Then(charge.Chained()).
+		Start(ctx)
+	assert.NoError(t, err)
+	info = waitWorkflow(t, s, id, WorkflowFailed)
+	assert.Equal(t, "wf.charge", info.FailedStep)
+	assert.Contains(t, info.Error, "too many items")
+	mu.Lock()
+	assert.Equal(t, []string{"o-2:c", "o-2:b", "o-2:a"}, released)
+	mu.Unlock()
+
+	_, err = s.Workflow(ctx, "missing")
+	assert.ErrorIs(t, err, ErrWorkflowNotFound)
+}
+
+func TestWorkflowCompensationFailed(t *testing.T) {
+	s := newServingServer(t, fmt.Sprintf("wf-comp-%d", time.Now().UnixNano()))
+	first := DefineStep(s, "wf.first", func(ctx context.Context, in string) (json.RawMessage, error) {
+		return json.RawMessage(`{"ok":true}`), nil
+	}).OnCompensate(func(ctx context.Context, in string, out json.RawMessage) error {
+		return fmt.Errorf("undo %s: %w", in, ErrSkipRetry)
+	})
+	second := DefineStep(s, "wf.second", func(ctx context.Context, in json.RawMessage) (string, error) {
+		return "", errors.New("boom")
+	}, WithTaskRetry(0))
+	go func() { _ = s.Serve() }()
+
+	id, err := s.NewWorkflow("broken").Then(first.With("x")).Then(second.Chained()).Start(context.Background())
+	assert.NoError(t, err)
+	info := waitWorkflow(t, s, id, WorkflowCompensationFailed)
+	assert.Equal(t, "boom", info.Error)
+	assert.Contains(t, info.CompensationError, "undo x")
+}
+
+func TestWorkflowGroupSiblingFinishesAfterFailure(t *testing.T) {
+	s := newServingServer(t, fmt.Sprintf("wf-late-%d", time.Now().UnixNano()))
+	failed := make(chan struct{})
+	var mu sync.Mutex
+	var released []string
+
+	slow := DefineStep(s, "wf.late.slow", func(ctx context.Context, in string) (string, error) {
+		// 兄弟步骤失败、工作流进入补偿后才完成
+		<-failed
+		return in, nil
+	}).OnCompensate(func(ctx context.Context, in, out string) error {
+		mu.Lock()
+		released = append(released, out)
+		mu.Unlock()
+		return nil
+	})
+	fail := DefineStep(s, "wf.late.fail", func(ctx context.Context, in string) (string, error) {
+		return "", fmt.Errorf("out of stock: %w", ErrSkipRetry)
+	})
+	go func() { _ = s.Serve() }()
+
+	id, err := s.NewWorkflow("late").Group(slow.With("a"), fail.With("b")).Start(context.Background())
+	assert.NoError(t, err)
+	waitWorkflow(t, s, id, WorkflowCompensating)
+	close(failed)
+
+	info := waitWorkflow(t, s, id, WorkflowFailed)
+	assert.Equal(t, "wf.late.fail", info.FailedStep)
+	mu.Lock()
+	assert.Equal(t, []string{"a"}, released)
+	mu.Unlock()
+}